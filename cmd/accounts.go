@@ -0,0 +1,213 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+
+	"shiftylogic.dev/hockey-tools/internal/data"
+	"shiftylogic.dev/hockey-tools/internal/helpers"
+)
+
+const (
+	kMinPasswordLength = 8
+	kClientIDSize      = 24
+	kClientSecretSize  = 40
+)
+
+/**
+ *
+ * user add / user passwd
+ *
+ **/
+
+func userAddCommand(fs *flag.FlagSet) func(*cli, []string) error {
+	pwdFile := fs.String("password-file", "", "read the password from a file instead of stdin")
+
+	return func(c *cli, args []string) error {
+		if len(args) != 1 {
+			return kUsageError
+		}
+
+		hash, err := c.readPasswordHash(*pwdFile)
+		if err != nil {
+			return err
+		}
+
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		id, err := store.Users().Add(args[0], hash)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(c.stdout, "Added user %s (id %d)\n", args[0], id)
+		return nil
+	}
+}
+
+func userPasswdCommand(fs *flag.FlagSet) func(*cli, []string) error {
+	pwdFile := fs.String("password-file", "", "read the password from a file instead of stdin")
+
+	return func(c *cli, args []string) error {
+		if len(args) != 1 {
+			return kUsageError
+		}
+
+		hash, err := c.readPasswordHash(*pwdFile)
+		if err != nil {
+			return err
+		}
+
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		if err := store.Users().SetPassword(args[0], hash); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(c.stdout, "Password changed for %s\n", args[0])
+		return nil
+	}
+}
+
+// Reads the first line of the file (or stdin) and returns its hash. When
+// stdin is a terminal the password is read without echoing it.
+func (c *cli) readPasswordHash(file string) (string, error) {
+	in := c.stdin
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		in = f
+	} else {
+		fmt.Fprint(c.stderr, "Password: ")
+
+		if f, ok := in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+			pwd, err := term.ReadPassword(int(f.Fd()))
+			fmt.Fprintln(c.stderr)
+			if err != nil {
+				return "", err
+			}
+
+			return hashPassword(string(pwd))
+		}
+	}
+
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && line == "" {
+		return "", errors.New("no password given")
+	}
+
+	return hashPassword(strings.TrimRight(line, "\r\n"))
+}
+
+func hashPassword(pwd string) (string, error) {
+	if pwd == "" {
+		return "", errors.New("no password given")
+	}
+
+	if len(pwd) < kMinPasswordLength {
+		return "", fmt.Errorf("password must be at least %d characters", kMinPasswordLength)
+	}
+
+	return helpers.HashPassword(pwd)
+}
+
+/**
+ *
+ * client add
+ *
+ **/
+
+type stringList []string
+
+func (l *stringList) String() string     { return strings.Join(*l, ",") }
+func (l *stringList) Set(v string) error { *l = append(*l, v); return nil }
+
+func clientAddCommand(fs *flag.FlagSet) func(*cli, []string) error {
+	id := fs.String("id", "", "client id (generated when empty)")
+	name := fs.String("name", "", "display name")
+	public := fs.Bool("public", false, "public client (PKCE only, no secret)")
+
+	var redirects stringList
+	fs.Var(&redirects, "redirect", "allowed redirect URI (repeatable)")
+
+	return func(c *cli, args []string) error {
+		if len(args) != 0 || *name == "" || len(redirects) == 0 {
+			return kUsageError
+		}
+
+		var err error
+		if *id == "" {
+			if *id, err = helpers.GenerateStringSecure(kClientIDSize, helpers.AlphaNumeric); err != nil {
+				return err
+			}
+		}
+
+		var secret, hash string
+		if !*public {
+			if secret, err = helpers.GenerateStringSecure(kClientSecretSize, helpers.AlphaNumeric); err != nil {
+				return err
+			}
+
+			if hash, err = helpers.HashPassword(secret); err != nil {
+				return err
+			}
+		}
+
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		if err := store.Clients().Add(data.NewClient(*id, *name, hash, redirects...)); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(c.stdout, "Client ID:     %s\n", *id)
+		if secret != "" {
+			fmt.Fprintf(c.stdout, "Client Secret: %s\n", secret)
+			fmt.Fprintln(c.stderr, "The secret is not stored and cannot be shown again.")
+		}
+
+		return nil
+	}
+}
@@ -23,16 +23,19 @@
 package main
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/hex"
 	"errors"
-	"log"
+	"log/slog"
 	"net/url"
+	"slices"
 	"strconv"
 	"time"
 
+	"shiftylogic.dev/hockey-tools/internal/data"
 	"shiftylogic.dev/hockey-tools/internal/helpers"
 	"shiftylogic.dev/hockey-tools/internal/services"
 )
@@ -60,12 +63,22 @@ var (
 	kBadUserPasswordError = errors.New("invalid user or password")
 )
 
-type fixedAuthorizer struct {
-	store services.KeyValueStore
+/**
+ *
+ * Users and clients come from the data store when there is one. Without a
+ * data file, the single built-in development user and client are used.
+ *
+ **/
+
+type authorizer struct {
+	store   services.KeyValueStore
+	users   data.Users
+	clients data.Clients
 }
 
-func (v *fixedAuthorizer) GenerateAuthorizationRequest(data services.AuthCodeData, ttl time.Duration) (string, error) {
+func (v *authorizer) GenerateAuthorizationRequest(ctx context.Context, data services.AuthCodeData, ttl time.Duration) (string, error) {
 	var err error
+	store := services.TraceKeyValues(ctx, v.store)
 
 	for i := 0; i < kAuthGenRetries; i++ {
 		code, err := helpers.GenerateStringSecure(kAuthCodeSize, helpers.AlphaNumeric)
@@ -73,7 +86,7 @@ func (v *fixedAuthorizer) GenerateAuthorizationRequest(data services.AuthCodeDat
 			return "", err
 		}
 
-		err = v.store.CheckAndSet(kAuthCodeCacheNamespace, code, data, ttl)
+		err = store.CheckAndSet(kAuthCodeCacheNamespace, code, data, ttl)
 		if err == nil {
 			return code, nil
 		}
@@ -82,7 +95,9 @@ func (v *fixedAuthorizer) GenerateAuthorizationRequest(data services.AuthCodeDat
 	return "", err
 }
 
-func (v *fixedAuthorizer) GenerateQRRequest(ttl time.Duration) (string, string, string, error) {
+func (v *authorizer) GenerateQRRequest(ctx context.Context, ttl time.Duration) (string, string, string, error) {
+	store := services.TraceKeyValues(ctx, v.store)
+
 	key, err := helpers.GenerateStringSecure(kQRSecretSize, helpers.AlphaNumeric)
 	if err != nil {
 		return "", "", "", err
@@ -95,7 +110,7 @@ func (v *fixedAuthorizer) GenerateQRRequest(ttl time.Duration) (string, string,
 			return "", "", "", err
 		}
 
-		err = v.store.CheckAndSet(kQRCacheNamespace, token, key, ttl)
+		err = store.CheckAndSet(kQRCacheNamespace, token, key, ttl)
 		if err == nil {
 			break
 		}
@@ -115,7 +130,26 @@ func (v *fixedAuthorizer) GenerateQRRequest(ttl time.Duration) (string, string,
 	return ts, token, hex.EncodeToString(hash), nil
 }
 
-func (v *fixedAuthorizer) Authenticate(user, pwd string) (string, error) {
+func (v *authorizer) Authenticate(user, pwd string) (string, error) {
+	if v.users == nil {
+		return authenticateFixed(user, pwd)
+	}
+
+	u, err := v.users.ByEmail(user)
+	if errors.Is(err, data.ErrorUnknownUser) {
+		return "", kBadUserPasswordError
+	} else if err != nil {
+		return "", err
+	}
+
+	if !helpers.CheckPassword(u.PasswordHash(), pwd) {
+		return "", kBadUserPasswordError
+	}
+
+	return strconv.FormatInt(int64(u.ID()), 10), nil
+}
+
+func authenticateFixed(user, pwd string) (string, error) {
 	res := subtle.ConstantTimeCompare([]byte(kUser), []byte(user))
 	res += subtle.ConstantTimeCompare([]byte(kPwd), []byte(pwd))
 	if res != 2 {
@@ -125,15 +159,27 @@ func (v *fixedAuthorizer) Authenticate(user, pwd string) (string, error) {
 	return "1", nil
 }
 
-func (v *fixedAuthorizer) ValidateClient(cid, redir string) bool {
+func (v *authorizer) ValidateClient(cid, redir string) bool {
 	redir, err := url.QueryUnescape(redir)
 	if err != nil {
-		log.Printf("Failed to unescape redirect URI - %v", err)
+		slog.Warn("Failed to unescape redirect URI", "error", err)
 		return false
 	}
 
-	res := subtle.ConstantTimeCompare([]byte(kClientID), []byte(cid))
-	res += subtle.ConstantTimeCompare([]byte(kRedirectURI), []byte(redir))
+	if v.clients == nil {
+		res := subtle.ConstantTimeCompare([]byte(kClientID), []byte(cid))
+		res += subtle.ConstantTimeCompare([]byte(kRedirectURI), []byte(redir))
+
+		return res == 2
+	}
+
+	client, err := v.clients.ByID(cid)
+	if err != nil {
+		if !errors.Is(err, data.ErrorUnknownClient) {
+			slog.Warn("Failed to look up client", "client", cid, "error", err)
+		}
+		return false
+	}
 
-	return res == 2
+	return slices.Contains(client.RedirectURIs(), redir)
 }
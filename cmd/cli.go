@@ -0,0 +1,252 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"shiftylogic.dev/hockey-tools/internal/data"
+	"shiftylogic.dev/hockey-tools/internal/data/local"
+	"shiftylogic.dev/hockey-tools/internal/services"
+)
+
+const (
+	kDefaultCommand = "serve"
+
+	// The -data flag is applied as an environment override so that config
+	// reloads keep honoring it.
+	kDataFileEnvKey = kConfigEnvPrefix + "_ROOT_DATA_FILE"
+)
+
+var (
+	kUsageError = errors.New("usage error")
+)
+
+/**
+ *
+ * Subcommands. Names may be one or two words ("user add"); the longest
+ * match wins. Each command gets its own flag set with the shared -config
+ * and -data flags already registered.
+ *
+ **/
+
+type command struct {
+	name    string
+	args    string
+	summary string
+	setup   func(fs *flag.FlagSet) func(cli *cli, args []string) error
+}
+
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	configFile string
+	dataFile   string
+}
+
+func commands() []command {
+	return []command{
+		{"serve", "", "Run the web server (the default)", serveCommand},
+		{"routes", "", "List the routes the server would register", routesCommand},
+		{"config check", "", "Load and validate the config, listing every problem", configCheckCommand},
+		{"migrate", "", "Apply pending database migrations", migrateCommand},
+		{"import", "<file>", "Import teams, players, seasons and more (CSV or JSON; see -kind)", importCommand},
+		{"export", "", "Export teams, players, seasons and more (CSV or JSON; see -kind)", exportCommand},
+		{"games import", "<file>", "Import game scoresheets (CSV or JSON)", gamesImportCommand},
+		{"review list", "", "List scoresheet entries waiting for review", reviewListCommand},
+		{"review resolve", "<id> <player>", "Assign a player (external ID or id) to a review entry", reviewResolveCommand},
+		{"review dismiss", "<id>", "Close a review entry without a player", reviewDismissCommand},
+		{"stats players", "", "Player totals for a season, date range, tag or team", statsPlayersCommand},
+		{"stats rebuild", "", "Recompute the per-game stats cache for every game", statsRebuildCommand},
+		{"standings", "", "League standings for a season, date range or tag", standingsCommand},
+		{"user add", "<email>", "Add a user; the password is read from stdin", userAddCommand},
+		{"user passwd", "<email>", "Change a user's password; read from stdin", userPasswdCommand},
+		{"client add", "", "Register an OAuth2 client", clientAddCommand},
+	}
+}
+
+func findCommand(args []string) (command, []string, bool) {
+	for _, words := range []int{2, 1} {
+		if len(args) < words {
+			continue
+		}
+
+		name := strings.Join(args[:words], " ")
+		for _, cmd := range commands() {
+			if cmd.name == name {
+				return cmd, args[words:], true
+			}
+		}
+	}
+
+	return command{}, nil, false
+}
+
+func (c *cli) run(args []string) error {
+	// No command (or only flags) keeps the old behavior of just serving
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		args = append([]string{kDefaultCommand}, args...)
+	}
+
+	if args[0] == "help" {
+		c.usage()
+		return nil
+	}
+
+	cmd, rest, ok := findCommand(args)
+	if !ok {
+		fmt.Fprintf(c.stderr, "Unknown command: %s\n\n", strings.Join(args, " "))
+		c.usage()
+		return kUsageError
+	}
+
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.StringVar(&c.configFile, "config", "", "config file (overrides "+kConfigFileEnvKey+")")
+	fs.StringVar(&c.dataFile, "data", "", "SQLite data file (overrides Root.DataFile)")
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: %s %s [flags] %s\n\n%s\n\n", programName(), cmd.name, cmd.args, cmd.summary)
+		fs.PrintDefaults()
+	}
+
+	run := cmd.setup(fs)
+	if err := fs.Parse(rest); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return kUsageError
+	}
+
+	if c.configFile != "" {
+		os.Setenv(kConfigFileEnvKey, c.configFile)
+	}
+
+	if c.dataFile != "" {
+		os.Setenv(kDataFileEnvKey, c.dataFile)
+	}
+
+	err := run(c, fs.Args())
+	if errors.Is(err, kUsageError) {
+		fs.Usage()
+	}
+
+	return err
+}
+
+func (c *cli) usage() {
+	fmt.Fprintf(c.stderr, "Usage: %s <command> [flags] [args]\n\nCommands:\n", programName())
+	for _, cmd := range commands() {
+		fmt.Fprintf(c.stderr, "  %-14s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(c.stderr, "\nEvery command accepts -config <file> and -data <file>.\n")
+}
+
+func programName() string {
+	return filepath.Base(os.Args[0])
+}
+
+// Every data command needs a data file, from -data or the config
+func dataFile() (string, error) {
+	config, err := resolveConfig()
+	if err != nil {
+		return "", err
+	}
+
+	if config.Base.DataFile == "" {
+		return "", errors.New("no data file configured (use -data or Root.DataFile)")
+	}
+
+	return config.Base.DataFile, nil
+}
+
+func openStore() (data.Store, error) {
+	file, err := dataFile()
+	if err != nil {
+		return nil, err
+	}
+
+	return local.Open(file)
+}
+
+/**
+ *
+ * config check & migrate
+ *
+ **/
+
+func configCheckCommand(fs *flag.FlagSet) func(*cli, []string) error {
+	return func(c *cli, args []string) error {
+		_, err := readConfig()
+
+		var verr *services.ValidationError
+		if errors.As(err, &verr) {
+			for _, problem := range verr.Problems {
+				fmt.Fprintln(c.stdout, problem)
+			}
+			return fmt.Errorf("%d config problem(s)", len(verr.Problems))
+		} else if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(c.stdout, "Config OK")
+		return nil
+	}
+}
+
+func migrateCommand(fs *flag.FlagSet) func(*cli, []string) error {
+	dryRun := fs.Bool("dry-run", false, "only list the pending migrations")
+
+	return func(c *cli, args []string) error {
+		file, err := dataFile()
+		if err != nil {
+			return err
+		}
+
+		applied, err := local.Migrate(file, *dryRun)
+		for _, m := range applied {
+			verb := "Applied"
+			if *dryRun {
+				verb = "Pending"
+			}
+			fmt.Fprintf(c.stdout, "%s %03d %s\n", verb, m.Version, m.Name)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if len(applied) == 0 {
+			fmt.Fprintf(c.stdout, "Schema is up to date (version %d)\n", local.SchemaVersion())
+		}
+
+		return nil
+	}
+}
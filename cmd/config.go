@@ -23,17 +23,23 @@
 package main
 
 import (
+	"errors"
+	"log/slog"
+
 	"shiftylogic.dev/hockey-tools/internal/helpers"
 	"shiftylogic.dev/hockey-tools/internal/services"
 	"shiftylogic.dev/hockey-tools/internal/services/auth"
+	"shiftylogic.dev/hockey-tools/internal/services/league"
 )
 
 const (
 	kConfigFileEnvKey = "SL_CONFIG"
+	kConfigEnvPrefix  = "SL"
 )
 
 type ServicesConfig struct {
-	Auth auth.Config `json:"auth" yaml:"Auth"`
+	Auth   auth.Config   `json:"auth" yaml:"Auth"`
+	League league.Config `json:"league" yaml:"League"`
 }
 
 type AppConfig struct {
@@ -41,17 +47,72 @@ type AppConfig struct {
 	Services ServicesConfig  `json:"services" yaml:"Services"`
 }
 
+/**
+ *
+ * Layers the config file (if any) and then SL_* environment overrides over
+ * the defaults, resolves secret files and validates the result. Every
+ * problem found is reported before giving up.
+ *
+ **/
 func loadConfig() AppConfig {
+	config, err := readConfig()
+	if err != nil {
+		var verr *services.ValidationError
+		if errors.As(err, &verr) {
+			for _, problem := range verr.Problems {
+				slog.Error("Invalid config", "problem", problem)
+			}
+		}
+
+		helpers.Fatal("Failed to load config", "error", err)
+	}
+
+	return config
+}
+
+func readConfig() (AppConfig, error) {
+	config, err := resolveConfig()
+	if err != nil {
+		return config, err
+	}
+
+	return config, config.Validate()
+}
+
+// Everything readConfig does short of validating the result, for commands
+// that only need a field or two.
+func resolveConfig() (AppConfig, error) {
 	config := AppConfig{
 		services.DefaultConfig(),
 		ServicesConfig{
-			Auth: auth.DefaultConfig(),
+			Auth:   auth.DefaultConfig(),
+			League: league.DefaultConfig(),
 		},
 	}
 
 	if configFile := helpers.ReadEnvWithDefault(kConfigFileEnvKey, ""); configFile != "" {
-		services.LoadConfig(configFile, &config)
+		if err := services.LoadConfig(configFile, &config); err != nil {
+			return config, err
+		}
 	}
 
-	return config
+	if err := services.ApplyEnvOverrides(kConfigEnvPrefix, &config); err != nil {
+		return config, err
+	}
+
+	if err := services.ResolveSecrets(&config); err != nil {
+		return config, err
+	}
+
+	return config, nil
+}
+
+func (config AppConfig) Validate() error {
+	var p services.Problems
+
+	p.Merge(config.Base.Validate())
+	p.Merge(config.Services.Auth.Validate())
+	p.Merge(config.Services.League.Validate())
+
+	return p.Err()
 }
@@ -0,0 +1,219 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"shiftylogic.dev/hockey-tools/internal/data"
+	"shiftylogic.dev/hockey-tools/internal/data/scoresheet"
+)
+
+/**
+ *
+ * games import / review list / review resolve / review dismiss
+ *
+ **/
+
+func gamesImportCommand(fs *flag.FlagSet) func(*cli, []string) error {
+	format := fs.String("format", "", "csv or json")
+	countdown := fs.Bool("clock-down", false, "times are what was left on the clock")
+	dryRun := fs.Bool("dry-run", false, "match and validate, but save nothing")
+
+	return func(c *cli, args []string) error {
+		if len(args) != 1 {
+			return kUsageError
+		}
+
+		f, err := parseFormat(*format, args[0])
+		if err != nil {
+			return err
+		}
+
+		in := c.stdin
+		if args[0] != "-" {
+			file, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			in = file
+		}
+
+		sheets, err := scoresheet.Read(in, f)
+		if err != nil {
+			return err
+		}
+
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		results := scoresheet.Import(store, sheets, scoresheet.Options{
+			ClockCountsDown: *countdown,
+			DryRun:          *dryRun,
+		})
+
+		failed, queued := 0, 0
+		for _, r := range results {
+			status := "updated"
+			switch {
+			case len(r.Errors) > 0:
+				status = "FAILED"
+				failed++
+			case *dryRun:
+				status = "ok"
+			case r.Created:
+				status = "created"
+			}
+
+			fmt.Fprintf(c.stdout, "%s: %s (%d goals, %d penalties, %d to review)\n",
+				r.ExternalID, status, r.Goals, r.Penalties, len(r.Queued))
+
+			for _, err := range r.Errors {
+				fmt.Fprintf(c.stdout, "  error: %v\n", err)
+			}
+
+			for _, w := range r.Warnings {
+				fmt.Fprintf(c.stdout, "  warning: %s\n", w)
+			}
+
+			queued += len(r.Queued)
+		}
+
+		if queued > 0 && !*dryRun {
+			fmt.Fprintf(c.stdout, "%d entries are waiting in the review queue ('review list')\n", queued)
+		}
+
+		if failed > 0 {
+			return fmt.Errorf("%d of %d games failed to import", failed, len(results))
+		}
+
+		return nil
+	}
+}
+
+func reviewListCommand(fs *flag.FlagSet) func(*cli, []string) error {
+	return func(c *cli, args []string) error {
+		if len(args) != 0 {
+			return kUsageError
+		}
+
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		items, err := data.ListAll(store.Reviews().Open)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range items {
+			game := strconv.FormatInt(int64(item.Game()), 10)
+			if g, err := store.Games().ByID(item.Game()); err == nil && g.ExternalID() != "" {
+				game = g.ExternalID()
+			}
+
+			fmt.Fprintf(c.stdout, "%d\tgame %s, %s %d, %s (team %d): %s\n",
+				item.ID(), game, item.Event(), item.Index()+1, item.Role(), item.Team(), item.Reason())
+		}
+
+		fmt.Fprintf(c.stdout, "%d open\n", len(items))
+		return nil
+	}
+}
+
+func reviewResolveCommand(fs *flag.FlagSet) func(*cli, []string) error {
+	return func(c *cli, args []string) error {
+		if len(args) != 2 {
+			return kUsageError
+		}
+
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return kUsageError
+		}
+
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		player, err := findPlayer(store, args[1])
+		if err != nil {
+			return err
+		}
+
+		if err := store.Reviews().Resolve(data.EntityID(id), player.ID()); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(c.stdout, "Resolved %d as %s\n", id, player.Name())
+		return nil
+	}
+}
+
+func reviewDismissCommand(fs *flag.FlagSet) func(*cli, []string) error {
+	return func(c *cli, args []string) error {
+		if len(args) != 1 {
+			return kUsageError
+		}
+
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return kUsageError
+		}
+
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		return store.Reviews().Dismiss(data.EntityID(id))
+	}
+}
+
+// Players are given by external ID, or by id
+func findPlayer(store data.Store, ref string) (data.Player, error) {
+	p, err := store.Players().ByExternalID(ref)
+	if !errors.Is(err, data.ErrorUnknownPlayerID) {
+		return p, err
+	}
+
+	id, perr := strconv.ParseInt(ref, 10, 64)
+	if perr != nil {
+		return nil, fmt.Errorf("%w '%s'", data.ErrorUnknownPlayerID, ref)
+	}
+
+	return store.Players().ByID(data.EntityID(id))
+}
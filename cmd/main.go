@@ -23,65 +23,22 @@
 package main
 
 import (
-	"context"
-	"log"
-	"time"
-
-	"shiftylogic.dev/hockey-tools/internal/services"
-	"shiftylogic.dev/hockey-tools/internal/web"
+	"errors"
+	"fmt"
+	"os"
 )
 
 func main() {
-	ctx, shutdown := context.WithCancel(context.Background())
-
-	go func() {
-		defer shutdown()
-
-		svcs := loadServices(ctx)
-		config := loadConfig()
-
-		options := append(
-			selectMiddleware(config.Base),
-			services.WithServices(svcs))
-
-		// This needs to be the last thing added (as middleware) before we start
-		// adding other handlers
-		if config.Base.Profiler {
-			options = append(options, web.WithProfiler())
-		}
-
-		options = append(options, getRoutes(config.Services)...)
-		options = append(options, services.WithStaticRoutes(config.Base.Statics)...)
-
-		router := web.NewRouter(options...)
-
-		services.Start(config.Base, router)
-	}()
-
-	// Wait for the services to be stopped
-	<-ctx.Done()
-
-	// Allow a bit more time for the background goroutines to shutdown.
-	// If they are paying attention to the Context passed to them, this
-	// should be pretty quick.
-	time.Sleep(time.Second)
-	log.Print("Bye for realz!")
+	c := &cli{
+		stdin:  os.Stdin,
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+	}
+
+	if err := c.run(os.Args[1:]); errors.Is(err, kUsageError) {
+		os.Exit(2)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", programName(), err)
+		os.Exit(1)
+	}
 }
-
-// func main() {
-// 	store, err := local.Open("./.data/hockey.db")
-// 	if err != nil {
-// 		log.Fatalf("failed to open data store : %v\n", err)
-// 	}
-// 	defer store.Close()
-//
-// 	// Just grabbing the first "page" of results
-// 	facilities, _, err := store.Facilities().List(0)
-// 	if err != nil {
-// 		log.Fatalf("failed to fetch facilities: %v\n", err)
-// 	}
-//
-// 	for _, f := range facilities {
-// 		log.Printf("Facilities (%d) - %s\n", f.ID(), f.Name())
-// 	}
-// }
@@ -0,0 +1,199 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/helpers"
+	"shiftylogic.dev/hockey-tools/internal/services"
+	"shiftylogic.dev/hockey-tools/internal/services/auth"
+	"shiftylogic.dev/hockey-tools/internal/web"
+)
+
+const (
+	kConfigPollInterval = 5 * time.Second
+)
+
+var (
+	// Config paths that take effect without a restart
+	kReloadableFields = []string{
+		"Root.Log.Level",
+		"Root.CORS",
+		"Root.RateLimits",
+		"Services.Auth.CodeTTL",
+		"Services.Auth.tokenTTL",
+		"Services.Auth.QRScan.Prefix",
+		"Services.Auth.QRScan.TTL",
+	}
+)
+
+type liveMiddleware struct {
+	cors       *web.Swappable
+	rateLimits *web.Swappable
+}
+
+func newLiveMiddleware(config services.Config) *liveMiddleware {
+	rateLimits, err := config.RateLimits.Middleware()
+	if err != nil {
+		helpers.Fatal("Failed to configure rate limits", "error", err)
+	}
+
+	return &liveMiddleware{
+		cors:       web.NewSwappable(config.CORS.Middleware()),
+		rateLimits: web.NewSwappable(rateLimits),
+	}
+}
+
+/**
+ *
+ * Re-reads the config on SIGHUP or when the config file changes. A config
+ * that fails to load or validate is rejected as a whole and the running
+ * one stays in place. Otherwise the reloadable settings are swapped in and
+ * any other changes are reported as needing a restart.
+ *
+ **/
+func watchConfig(ctx context.Context, current AppConfig, live *liveMiddleware) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	configFile := helpers.ReadEnvWithDefault(kConfigFileEnvKey, "")
+	modTime := fileModTime(configFile)
+
+	ticker := time.NewTicker(kConfigPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-hup:
+			current = reloadConfig(current, live, "SIGHUP")
+
+		case <-ticker.C:
+			if configFile == "" {
+				continue
+			}
+
+			if t := fileModTime(configFile); !t.Equal(modTime) {
+				modTime = t
+				current = reloadConfig(current, live, "file change")
+			}
+		}
+	}
+}
+
+func reloadConfig(current AppConfig, live *liveMiddleware, reason string) AppConfig {
+	next, err := readConfig()
+	if err != nil {
+		slog.Error("Config reload rejected; keeping current config", "reason", reason, "error", err)
+		return current
+	}
+
+	changed := services.ChangedFields(current, next)
+	if len(changed) == 0 {
+		slog.Info("Config reloaded; no changes", "reason", reason)
+		return current
+	}
+
+	var applied, restart []string
+	for _, field := range changed {
+		if services.FieldWithin(field, kReloadableFields) {
+			applied = append(applied, field)
+		} else {
+			restart = append(restart, field)
+		}
+	}
+
+	if len(applied) > 0 {
+		applyConfig(next, live, applied)
+	}
+
+	if len(restart) > 0 {
+		slog.Warn("Config changes need a restart to take effect", "fields", restart)
+	}
+
+	slog.Info("Config reloaded", "reason", reason, "applied", applied)
+
+	// Only the applied settings are current; the rest still reflect startup
+	for _, field := range restart {
+		services.CopyField(&next, current, field)
+	}
+
+	return next
+}
+
+// Swaps in only what changed; rebuilding the rate limiters, for one,
+// starts their counts over.
+func applyConfig(config AppConfig, live *liveMiddleware, changed []string) {
+	within := func(path string) bool {
+		for _, field := range changed {
+			if services.FieldWithin(field, []string{path}) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if within("Root.Log") {
+		if err := services.SetLogLevel(config.Base.Log); err != nil {
+			slog.Error("Failed to apply log level", "error", err)
+		}
+	}
+
+	if within("Root.CORS") {
+		live.cors.Swap(config.Base.CORS.Middleware())
+	}
+
+	if within("Root.RateLimits") {
+		if rateLimits, err := config.Base.RateLimits.Middleware(); err != nil {
+			slog.Error("Failed to apply rate limits; keeping current limits", "error", err)
+		} else {
+			live.rateLimits.Swap(rateLimits)
+		}
+	}
+
+	if within("Services.Auth") {
+		auth.Reload(config.Services.Auth)
+	}
+}
+
+func fileModTime(file string) time.Time {
+	if file == "" {
+		return time.Time{}
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return info.ModTime()
+}
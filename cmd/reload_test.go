@@ -0,0 +1,66 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/services"
+	"shiftylogic.dev/hockey-tools/internal/test"
+)
+
+func TestReloadKeepsRateLimitsOnError(t *testing.T) {
+	limited := func(limit uint) []services.RateLimitPolicyConfig {
+		return []services.RateLimitPolicyConfig{{Name: "all", Routes: []string{"*"}, Limit: limit, Window: time.Minute, Mapper: []string{"ip"}}}
+	}
+
+	config := AppConfig{Base: services.DefaultConfig()}
+	config.Base.RateLimits.Enabled = true
+	config.Base.RateLimits.Policies = limited(1)
+
+	live := newLiveMiddleware(config.Base)
+	handler := live.rateLimits.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	status := func() int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/teams", nil))
+		return rec.Code
+	}
+
+	test.Expect(t, http.StatusOK, status(), "first request")
+	test.Expect(t, http.StatusTooManyRequests, status(), "over the limit")
+
+	broken := config
+	broken.Base.RateLimits.Policies = limited(100)
+	broken.Base.RateLimits.TrustedProxies = []string{"10.0.0.0/33x"}
+	applyConfig(broken, live, []string{"Root.RateLimits.TrustedProxies", "Root.RateLimits.Policies"})
+	test.Expect(t, http.StatusTooManyRequests, status(), "old limits stay in place")
+
+	fixed := broken
+	fixed.Base.RateLimits.TrustedProxies = []string{"10.0.0.0/8"}
+	applyConfig(fixed, live, []string{"Root.RateLimits.TrustedProxies"})
+	test.Expect(t, http.StatusOK, status(), "new limits swapped in")
+}
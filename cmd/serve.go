@@ -0,0 +1,139 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"sync"
+	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/helpers"
+	"shiftylogic.dev/hockey-tools/internal/services"
+	"shiftylogic.dev/hockey-tools/internal/trace"
+	"shiftylogic.dev/hockey-tools/internal/web"
+)
+
+func serveCommand(fs *flag.FlagSet) func(*cli, []string) error {
+	return func(c *cli, args []string) error {
+		if len(args) > 0 {
+			return kUsageError
+		}
+
+		serve()
+		return nil
+	}
+}
+
+func routesCommand(fs *flag.FlagSet) func(*cli, []string) error {
+	return func(c *cli, args []string) error {
+		if len(args) > 0 {
+			return kUsageError
+		}
+
+		config := loadConfig()
+		if err := services.ConfigureLogging(config.Base.Log); err != nil {
+			return err
+		}
+
+		// Only the route table is needed, so skip restoring and writing
+		// the memory store snapshot.
+		config.Base.Store.SnapshotFile = ""
+
+		ctx, cancel := context.WithCancel(context.Background())
+		var stopped sync.WaitGroup
+
+		router, _ := buildRouter(ctx, config, &stopped)
+		web.DumpRouter(router)
+
+		// Close the stores opened for the router before exiting
+		cancel()
+		stopped.Wait()
+		return nil
+	}
+}
+
+func buildRouter(ctx context.Context, config AppConfig, stopped *sync.WaitGroup) (web.Router, *liveMiddleware) {
+	svcs := loadServices(ctx, config.Base, stopped)
+	live := newLiveMiddleware(config.Base)
+
+	options := append(
+		selectMiddleware(config.Base, live),
+		services.WithServices(svcs))
+
+	// This needs to be the last thing added (as middleware) before we start
+	// adding other handlers. With an admin listener, the profiler lives there.
+	if config.Base.Profiler && !config.Base.HasAdminListener() {
+		options = append(options, web.WithProfiler())
+	}
+
+	options = append(options, getRoutes(config.Services)...)
+	options = append(options, services.WithStaticRoutes(config.Base.Statics)...)
+
+	return web.NewRouter(options...), live
+}
+
+func serve() {
+	ctx, shutdown := context.WithCancel(context.Background())
+
+	var tracer *trace.Provider
+	var stopped sync.WaitGroup
+
+	go func() {
+		defer shutdown()
+
+		config := loadConfig()
+		if err := services.ConfigureLogging(config.Base.Log); err != nil {
+			helpers.Fatal("Failed to configure logging", "error", err)
+		}
+
+		var err error
+		if tracer, err = services.ConfigureTracing(config.Base.Tracing); err != nil {
+			helpers.Fatal("Failed to configure tracing", "error", err)
+		}
+
+		router, live := buildRouter(ctx, config, &stopped)
+
+		go watchConfig(ctx, config, live)
+
+		services.Start(config.Base, router)
+	}()
+
+	// Wait for the services to be stopped
+	<-ctx.Done()
+
+	// Wait for the shutdown work (final store snapshot, closing the data
+	// store) to finish before exiting.
+	stopped.Wait()
+
+	if tracer != nil {
+		flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := tracer.Shutdown(flushCtx); err != nil {
+			slog.Warn("Failed to flush traces", "error", err)
+		}
+		cancel()
+	}
+
+	slog.Info("Bye for realz!")
+}
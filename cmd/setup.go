@@ -24,42 +24,133 @@ package main
 
 import (
 	"context"
+	"sync"
 
+	"shiftylogic.dev/hockey-tools/internal/data"
+	"shiftylogic.dev/hockey-tools/internal/data/local"
+	"shiftylogic.dev/hockey-tools/internal/health"
+	"shiftylogic.dev/hockey-tools/internal/helpers"
 	"shiftylogic.dev/hockey-tools/internal/services"
 	"shiftylogic.dev/hockey-tools/internal/services/auth"
+	"shiftylogic.dev/hockey-tools/internal/services/league"
 	"shiftylogic.dev/hockey-tools/internal/web"
 )
 
-func loadServices(ctx context.Context) services.Services {
-	kvs := services.NewMemoryStore(ctx)
+// Anything that must finish after ctx is done (the shutdown snapshot,
+// closing the data store) is added to stopped.
+func loadServices(ctx context.Context, config services.Config, stopped *sync.WaitGroup) services.Services {
+	storeOptions := append(config.Store.Options(), services.WithSnapshotGroup(stopped))
+	if config.Metrics {
+		storeOptions = append(storeOptions, services.WithStoreMetrics())
+	}
+
+	kvs := services.NewMemoryStore(ctx, storeOptions...)
+
+	var store data.Store
+	if config.DataFile != "" {
+		var err error
+		if store, err = local.Open(config.DataFile); err != nil {
+			helpers.Fatal("Failed to open data store", "file", config.DataFile, "error", err)
+		}
+
+		stopped.Add(1)
+		context.AfterFunc(ctx, func() {
+			defer stopped.Done()
+			store.Close()
+		})
+	}
+
+	if config.Health.Enabled {
+		health.Default.SetTimeout(config.Health.Timeout)
+		health.Default.Register("kvs", services.KeyValueCheck(kvs))
+
+		if store != nil {
+			health.Default.Register("sqlite", services.DataStoreCheck(store))
+		}
+	}
 
 	return &services.ServicesContainer{
 		EphemeralStore: &services.SimpleDataStore{
 			KVS: kvs,
 		},
-		Authy: &fixedAuthorizer{
-			store: kvs,
-		},
+		Authy:     newAuthorizer(kvs, store),
+		DataStore: store,
+	}
+}
+
+func newAuthorizer(kvs services.KeyValueStore, store data.Store) services.Authorizer {
+	if store == nil {
+		return &authorizer{store: kvs}
+	}
+
+	return &authorizer{
+		store:   kvs,
+		users:   store.Users(),
+		clients: store.Clients(),
 	}
 }
 
-func selectMiddleware(config services.Config) []web.RouterOptionFunc {
+func selectMiddleware(config services.Config, live *liveMiddleware) []web.RouterOptionFunc {
 	options := []web.RouterOptionFunc{
-		web.WithLogging(),
+		web.WithRequestID(),
+	}
+
+	// Probes are answered before tracing, logging and any throttling
+	if config.Health.Enabled {
+		options = append(options, web.WithHealth(health.Default))
+	}
+
+	// Tracing wraps everything below so that its server span covers the
+	// rest of the middleware chain.
+	if config.Tracing.Enabled {
+		options = append(options, web.WithTracing())
+	}
+
+	// Metrics go next so that responses from every other middleware
+	// (throttling, load shedding, panics) are counted. They are only
+	// served on the admin listener, never the public router.
+	if config.Metrics {
+		options = append(options, web.WithRequestMetrics())
+	}
+
+	if config.Logging {
+		options = append(options, web.WithLogging())
+	}
+
+	options = append(options,
 		web.WithPanicRecovery(),
 		web.WithNoIFrame(),
 		web.WithNoCache(), // TODO: Remove this at some point later
+	)
+
+	// CORS and rate limits are always given a slot so that a config reload
+	// can turn them on, off or change them.
+	options = append(options, web.WithSwappable(live.cors))
+
+	// Client certificates establish the subject before it is used for
+	// prioritization or rate limiting
+	if config.TLS.Enabled() && config.TLS.ClientAuth.Enabled() {
+		options = append(options, web.WithClientCertificates(config.TLS.ClientAuth.Options()))
 	}
 
-	if config.CORS.Enabled() {
-		options = append(options, web.WithCors(config.CORS.Options()))
+	if config.Concurrency.Enabled {
+		limiter := web.NewConcurrencyLimiter(config.Concurrency.Options())
+		options = append(options, web.WithConcurrencyLimit(limiter))
 	}
 
+	options = append(options, web.WithSwappable(live.rateLimits))
+
 	return options
 }
 
 func getRoutes(config ServicesConfig) []web.RouterOptionFunc {
-	return []web.RouterOptionFunc{
+	options := []web.RouterOptionFunc{
 		auth.WithOAuth2(config.Auth),
 	}
+
+	if config.League.Enabled {
+		options = append(options, league.WithLeague(config.League))
+	}
+
+	return options
 }
@@ -0,0 +1,226 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/data"
+	"shiftylogic.dev/hockey-tools/internal/data/standings"
+)
+
+/**
+ *
+ * stats players / stats rebuild / standings
+ *
+ **/
+
+const kDateLayout = "2006-01-02"
+
+func statsPlayersCommand(fs *flag.FlagSet) func(*cli, []string) error {
+	from := fs.String("from", "", "first day to count (YYYY-MM-DD)")
+	to := fs.String("to", "", "first day not to count (YYYY-MM-DD)")
+	season := fs.String("season", "", "only games in this season (external ID or id)")
+	tag := fs.String("tag", "", "only games with this tag")
+	team := fs.String("team", "", "only lines for this team (external ID or name)")
+
+	return func(c *cli, args []string) error {
+		if len(args) != 0 {
+			return kUsageError
+		}
+
+		var scope data.StatsScope
+		var err error
+
+		if scope.From, err = parseDate(*from); err != nil {
+			return err
+		}
+
+		if scope.To, err = parseDate(*to); err != nil {
+			return err
+		}
+
+		scope.Tag = *tag
+
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		if scope.Season, err = findSeason(store, *season); err != nil {
+			return err
+		}
+
+		if *team != "" {
+			t, err := data.FindTeam(store.Teams(), *team)
+			if err != nil {
+				return err
+			}
+			scope.Team = t.ID()
+		}
+
+		totals, err := store.Stats().Players(scope)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(w, "PLAYER\tNAME\tGP\tG\tA\tPTS\tPIM\tPPG\tSHG\tGWG\t+/-\tP/GP\tSTREAK\t")
+
+		for _, s := range totals {
+			name := ""
+			if p, err := store.Players().ByID(s.Player); err == nil {
+				name = p.Name()
+			}
+
+			fmt.Fprintf(w, "%d\t%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%+d\t%.2f\t%d\t\n",
+				s.Player, name, s.GamesPlayed, s.Goals, s.Assists, s.Points, s.PenaltyMinutes,
+				s.PowerPlayGoals, s.ShortHandedGoals, s.GameWinningGoals, s.PlusMinus,
+				s.PointsPerGame, s.PointStreak)
+		}
+
+		return w.Flush()
+	}
+}
+
+func statsRebuildCommand(fs *flag.FlagSet) func(*cli, []string) error {
+	return func(c *cli, args []string) error {
+		if len(args) != 0 {
+			return kUsageError
+		}
+
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		n, err := store.Stats().Rebuild()
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(c.stdout, "Rebuilt stats for %d games\n", n)
+		return nil
+	}
+}
+
+func standingsCommand(fs *flag.FlagSet) func(*cli, []string) error {
+	from := fs.String("from", "", "first day to count (YYYY-MM-DD)")
+	to := fs.String("to", "", "first day not to count (YYYY-MM-DD)")
+	season := fs.String("season", "", "only games in this season (external ID or id)")
+	tag := fs.String("tag", "", "only games with this tag")
+	points := fs.String("points", "", "points system: "+strings.Join(standings.PointSystems(), " or "))
+	tiebreakers := fs.String("tiebreakers", "", "comma separated: head-to-head, regulation-wins, goal-differential")
+
+	return func(c *cli, args []string) error {
+		if len(args) != 0 {
+			return kUsageError
+		}
+
+		var scope data.StatsScope
+		var err error
+
+		if scope.From, err = parseDate(*from); err != nil {
+			return err
+		}
+
+		if scope.To, err = parseDate(*to); err != nil {
+			return err
+		}
+
+		scope.Tag = *tag
+
+		var names []string
+		if *tiebreakers != "" {
+			names = strings.Split(*tiebreakers, ",")
+		}
+
+		rules, err := standings.NewRules(*points, names)
+		if err != nil {
+			return err
+		}
+
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		if scope.Season, err = findSeason(store, *season); err != nil {
+			return err
+		}
+
+		games, err := standings.Load(store.Games(), scope)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', tabwriter.AlignRight)
+		fmt.Fprintln(w, "TEAM\tGP\tW\tL\tT\tOTL\tPTS\tRW\tGF\tGA\tDIFF\tHOME\tAWAY\tL10\tSTRK\t")
+
+		for _, r := range standings.Compute(games, rules) {
+			name := fmt.Sprintf("team %d", r.Team)
+			if t, err := store.Teams().ByID(r.Team); err == nil {
+				name = t.Name()
+			}
+
+			fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%+d\t%s\t%s\t%s\t%s\t\n",
+				name, r.GamesPlayed, r.Wins, r.Losses, r.Ties, r.OvertimeLosses, r.Points, r.RegulationWins,
+				r.GoalsFor, r.GoalsAgainst, r.Diff, r.Home, r.Away, r.LastTen, r.Streak)
+		}
+
+		return w.Flush()
+	}
+}
+
+func findSeason(store data.Store, ref string) (data.EntityID, error) {
+	if ref == "" {
+		return 0, nil
+	}
+
+	s, err := data.FindSeason(store.Seasons(), ref)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.ID(), nil
+}
+
+func parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.ParseInLocation(kDateLayout, value, time.Local)
+	if err != nil {
+		return t, fmt.Errorf("invalid date '%s' (want YYYY-MM-DD)", value)
+	}
+
+	return t, nil
+}
@@ -0,0 +1,176 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"shiftylogic.dev/hockey-tools/internal/data/transfer"
+)
+
+/**
+ *
+ * import / export wrap the transfer package. The format follows the file
+ * extension unless -format says otherwise; stdin/stdout default to CSV.
+ *
+ **/
+
+func exportCommand(fs *flag.FlagSet) func(*cli, []string) error {
+	kind := fs.String("kind", "", "facilities, teams, players, staff, leagues, seasons, divisions or memberships")
+	format := fs.String("format", "", "csv or json")
+	output := fs.String("o", "", "output file (default stdout)")
+
+	return func(c *cli, args []string) error {
+		if len(args) != 0 || *kind == "" {
+			return kUsageError
+		}
+
+		k, f, err := parseTransfer(*kind, *format, *output)
+		if err != nil {
+			return err
+		}
+
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		out := c.stdout
+		if *output != "" {
+			file, err := os.Create(*output)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			out = file
+		}
+
+		return transfer.Export(store, out, k, f)
+	}
+}
+
+func importCommand(fs *flag.FlagSet) func(*cli, []string) error {
+	kind := fs.String("kind", "", "facilities, teams, players, staff, leagues, seasons, divisions or memberships")
+	format := fs.String("format", "", "csv or json")
+	dryRun := fs.Bool("dry-run", false, "validate every row but write nothing")
+
+	var mappings stringList
+	fs.Var(&mappings, "map", "map a column to a field, as 'Header=field' (repeatable)")
+
+	return func(c *cli, args []string) error {
+		if len(args) != 1 || *kind == "" {
+			return kUsageError
+		}
+
+		k, f, err := parseTransfer(*kind, *format, args[0])
+		if err != nil {
+			return err
+		}
+
+		headers := map[string]string{}
+		for _, m := range mappings {
+			from, to, ok := strings.Cut(m, "=")
+			if !ok {
+				return fmt.Errorf("bad -map '%s'; expected 'Header=field'", m)
+			}
+			headers[from] = to
+		}
+
+		in := c.stdin
+		if args[0] != "-" {
+			file, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			in = file
+		}
+
+		store, err := openStore()
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		result, err := transfer.Import(store, in, transfer.Options{
+			Kind:    k,
+			Format:  f,
+			Headers: headers,
+			DryRun:  *dryRun,
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(result.Ignored) > 0 {
+			fmt.Fprintf(c.stderr, "Ignored columns: %s\n", strings.Join(result.Ignored, ", "))
+		}
+
+		for _, rerr := range result.Errors {
+			fmt.Fprintln(c.stdout, rerr)
+		}
+
+		if len(result.Errors) > 0 {
+			return fmt.Errorf("%d of %d rows have errors; nothing was imported", result.FailedRows(), result.Rows)
+		}
+
+		verb := "Imported"
+		if *dryRun {
+			verb = "Would import"
+		}
+		fmt.Fprintf(c.stdout, "%s %d %s (%d new, %d updated)\n", verb, result.Rows, k, result.Created, result.Updated)
+		return nil
+	}
+}
+
+func parseTransfer(kind, format, file string) (transfer.Kind, transfer.Format, error) {
+	k, err := transfer.ParseKind(kind)
+	if err != nil {
+		return "", "", err
+	}
+
+	f, err := parseFormat(format, file)
+	return k, f, err
+}
+
+func parseFormat(format, file string) (transfer.Format, error) {
+	if format == "" {
+		format = string(transfer.FormatCSV)
+		if ext := strings.TrimPrefix(filepath.Ext(file), "."); ext != "" {
+			format = ext
+		}
+	}
+
+	f, err := transfer.ParseFormat(format)
+	if errors.Is(err, transfer.ErrorUnknownFormat) {
+		err = fmt.Errorf("%w (use -format)", err)
+	}
+
+	return f, err
+}
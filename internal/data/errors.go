@@ -31,4 +31,16 @@ var (
 	ErrorUnknownFacilityID = errors.New("unknown facility id")
 	ErrorUnknownPlayerID   = errors.New("unknown player id")
 	ErrorUnknownStaffID    = errors.New("unknown staff id")
+	ErrorUnknownTeamID     = errors.New("unknown team id")
+	ErrorUnknownGameID     = errors.New("unknown game id")
+	ErrorUnknownLeagueID   = errors.New("unknown league id")
+	ErrorUnknownSeasonID   = errors.New("unknown season id")
+	ErrorUnknownDivisionID = errors.New("unknown division id")
+	ErrorNotInSeason       = errors.New("team is not in that season")
+	ErrorUnknownReviewID   = errors.New("unknown review item")
+	ErrorReviewClosed      = errors.New("review item already closed")
+	ErrorUnknownUser       = errors.New("unknown user")
+	ErrorDuplicateUser     = errors.New("user already exists")
+	ErrorUnknownClient     = errors.New("unknown client")
+	ErrorDuplicateClient   = errors.New("client already exists")
 )
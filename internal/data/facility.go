@@ -24,6 +24,7 @@ package data
 
 type Facility interface {
 	ID() EntityID
+	ExternalID() string
 	Name() string
 	Address() string
 	City() string
@@ -33,4 +34,28 @@ type Facility interface {
 type Facilities interface {
 	List(token int64) ([]Facility, int64, error)
 	ByID(id EntityID) (Facility, error)
+	ByExternalID(ext string) (Facility, error)
+
+	// Inserts the facility, or replaces the existing one when ID() is set.
+	Save(f Facility) (EntityID, error)
+}
+
+type facility struct {
+	id         EntityID
+	externalID string
+	name       string
+	address    string
+	city       string
+	state      string
+}
+
+func (f *facility) ID() EntityID       { return f.id }
+func (f *facility) ExternalID() string { return f.externalID }
+func (f *facility) Name() string       { return f.name }
+func (f *facility) Address() string    { return f.address }
+func (f *facility) City() string       { return f.city }
+func (f *facility) State() string      { return f.state }
+
+func NewFacility(id EntityID, externalID, name, address, city, state string) Facility {
+	return &facility{id, externalID, name, address, city, state}
 }
@@ -26,6 +26,9 @@ import (
 	"time"
 )
 
+// Timestamps are seconds since the start of the game (period lengths are
+// also in seconds), so events sort and compare across periods.
+
 type ScoringEvent interface {
 	Timestamp() int
 	TeamID() EntityID
@@ -55,6 +58,8 @@ type PenaltyEvents interface {
 
 type GameOverview interface {
 	ID() EntityID
+	ExternalID() string
+	Season() EntityID // 0 when not filed under one
 	Tags() []string
 	When() time.Time
 	Where() EntityID
@@ -73,7 +78,121 @@ type GameDetails interface {
 }
 
 type Games interface {
-	List(token int64) ([]GameOverview, int64)
-	ByTeam(id EntityID) []GameOverview
-	DetailsByGame(game EntityID) GameDetails
+	List(token int64) ([]GameOverview, int64, error)
+	ByID(id EntityID) (GameOverview, error)
+	ByExternalID(ext string) (GameOverview, error)
+	ByTeam(id EntityID) ([]GameOverview, error)
+	BySeason(season EntityID) ([]GameOverview, error)
+	DetailsByGame(game EntityID) (GameDetails, error)
+
+	// Details for many games at once, in the order given. Unknown games are
+	// left out.
+	DetailsByGames(games []EntityID) ([]GameDetails, error)
+
+	// Saves the game and replaces all of its events, as one transaction.
+	// Inserts when the overview has no ID.
+	Save(game GameDetails) (EntityID, error)
+}
+
+/**
+ *
+ * Plain implementations, for building games to save.
+ *
+ **/
+
+type scoringEvent struct {
+	timestamp       int
+	team            EntityID
+	scorer          EntityID
+	primaryAssist   EntityID
+	secondaryAssist EntityID
+	other           []EntityID
+	defenders       []EntityID
+}
+
+func (e *scoringEvent) Timestamp() int            { return e.timestamp }
+func (e *scoringEvent) TeamID() EntityID          { return e.team }
+func (e *scoringEvent) Scorer() EntityID          { return e.scorer }
+func (e *scoringEvent) PrimaryAssist() EntityID   { return e.primaryAssist }
+func (e *scoringEvent) SecondaryAssist() EntityID { return e.secondaryAssist }
+func (e *scoringEvent) Other() []EntityID         { return e.other }
+func (e *scoringEvent) Defenders() []EntityID     { return e.defenders }
+
+// Other is the scoring team's skaters on the ice; defenders are the other
+// team's. Unknown players are 0.
+func NewScoringEvent(timestamp int, team, scorer, primary, secondary EntityID, other, defenders []EntityID) ScoringEvent {
+	return &scoringEvent{timestamp, team, scorer, primary, secondary, other, defenders}
+}
+
+type penaltyEvent struct {
+	timestamp  int
+	team       EntityID
+	committer  EntityID
+	minutes    int
+	infraction string
+	servedBy   EntityID
+}
+
+func (e *penaltyEvent) Timestamp() int      { return e.timestamp }
+func (e *penaltyEvent) TeamID() EntityID    { return e.team }
+func (e *penaltyEvent) Committer() EntityID { return e.committer }
+func (e *penaltyEvent) Minutes() int        { return e.minutes }
+func (e *penaltyEvent) Infraction() string  { return e.infraction }
+func (e *penaltyEvent) ServedBy() EntityID  { return e.servedBy }
+
+func NewPenaltyEvent(timestamp int, team, committer EntityID, minutes int, infraction string, servedBy EntityID) PenaltyEvent {
+	return &penaltyEvent{timestamp, team, committer, minutes, infraction, servedBy}
+}
+
+type gameOverview struct {
+	id           EntityID
+	externalID   string
+	season       EntityID
+	tags         []string
+	when         time.Time
+	where        EntityID
+	home         EntityID
+	visitor      EntityID
+	homeScore    int
+	visitorScore int
+}
+
+func (g *gameOverview) ID() EntityID       { return g.id }
+func (g *gameOverview) ExternalID() string { return g.externalID }
+func (g *gameOverview) Season() EntityID   { return g.season }
+func (g *gameOverview) Tags() []string     { return g.tags }
+func (g *gameOverview) When() time.Time    { return g.when }
+func (g *gameOverview) Where() EntityID    { return g.where }
+func (g *gameOverview) Home() EntityID     { return g.home }
+func (g *gameOverview) Visitor() EntityID  { return g.visitor }
+func (g *gameOverview) HomeScore() int     { return g.homeScore }
+func (g *gameOverview) VisitorScore() int  { return g.visitorScore }
+
+func NewGameOverview(
+	id EntityID,
+	externalID string,
+	season EntityID,
+	tags []string,
+	when time.Time,
+	where, home, visitor EntityID,
+	homeScore, visitorScore int,
+) GameOverview {
+	return &gameOverview{id, externalID, season, tags, when, where, home, visitor, homeScore, visitorScore}
+}
+
+type gameDetails struct {
+	overview      GameOverview
+	periodLengths []int
+	goals         []ScoringEvent
+	penalties     []PenaltyEvent
+}
+
+func (g *gameDetails) ID() EntityID              { return g.overview.ID() }
+func (g *gameDetails) Overview() GameOverview    { return g.overview }
+func (g *gameDetails) PeriodLengths() []int      { return g.periodLengths }
+func (g *gameDetails) Goals() []ScoringEvent     { return g.goals }
+func (g *gameDetails) Penalties() []PenaltyEvent { return g.penalties }
+
+func NewGameDetails(overview GameOverview, periodLengths []int, goals []ScoringEvent, penalties []PenaltyEvent) GameDetails {
+	return &gameDetails{overview, periodLengths, goals, penalties}
 }
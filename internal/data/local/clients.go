@@ -0,0 +1,156 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package local
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/data"
+)
+
+const (
+	kFetchClientsQuery = `
+		SELECT id, name, secret_hash, redirect_uris FROM clients
+			ORDER BY id ASC
+	`
+
+	kFetchClientQuery = `
+		SELECT id, name, secret_hash, redirect_uris FROM clients
+			WHERE id = ?
+	`
+
+	kAddClientQuery = `
+		INSERT INTO clients (id, name, secret_hash, redirect_uris) VALUES (?, ?, ?, ?)
+	`
+
+	// Redirect URIs are stored one per line
+	kRedirectSeparator = "\n"
+)
+
+type client struct {
+	id           string
+	name         string
+	secretHash   string
+	redirectURIs []string
+}
+
+func (c *client) ID() string             { return c.id }
+func (c *client) Name() string           { return c.name }
+func (c *client) SecretHash() string     { return c.secretHash }
+func (c *client) RedirectURIs() []string { return c.redirectURIs }
+
+type clients struct {
+	db        conn
+	fetchList *sql.Stmt
+	fetchID   *sql.Stmt
+	add       *sql.Stmt
+}
+
+func newClients(db conn) (*clients, error) {
+	fetchList, err := db.Prepare(kFetchClientsQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchID, err := db.Prepare(kFetchClientQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	add, err := db.Prepare(kAddClientQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	return &clients{
+		db,
+		fetchList,
+		fetchID,
+		add,
+	}, nil
+}
+
+func scanClient(row interface{ Scan(...any) error }) (*client, error) {
+	c := &client{}
+
+	var redirects string
+	if err := row.Scan(&c.id, &c.name, &c.secretHash, &redirects); err != nil {
+		return nil, err
+	}
+
+	c.redirectURIs = strings.Split(redirects, kRedirectSeparator)
+	return c, nil
+}
+
+func (c *clients) List() ([]data.Client, error) {
+	defer observeQuery("clients.list", time.Now())
+
+	rows, err := c.fetchList.Query()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	data := []data.Client{}
+	for rows.Next() {
+		nc, err := scanClient(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		data = append(data, nc)
+	}
+
+	return data, rows.Err()
+}
+
+func (c *clients) ByID(id string) (data.Client, error) {
+	defer observeQuery("clients.byID", time.Now())
+
+	ret, err := scanClient(c.fetchID.QueryRow(id))
+	if err == nil {
+		return ret, nil
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, data.ErrorUnknownClient
+	}
+
+	return nil, err
+}
+
+func (c *clients) Add(v data.Client) error {
+	defer observeQuery("clients.add", time.Now())
+
+	redirects := strings.Join(v.RedirectURIs(), kRedirectSeparator)
+
+	_, err := c.add.Exec(v.ID(), v.Name(), v.SecretHash(), redirects)
+	if isConstraintError(err) {
+		return data.ErrorDuplicateClient
+	}
+
+	return err
+}
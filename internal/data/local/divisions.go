@@ -0,0 +1,228 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package local
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/data"
+)
+
+const (
+	kDivisionColumns = `id, COALESCE(external_id, ''), league, name`
+
+	kFetchDivisionsQuery = `
+		SELECT ` + kDivisionColumns + ` FROM divisions
+			WHERE id > ?
+			ORDER BY id ASC
+			LIMIT 100
+	`
+
+	kFetchDivisionQuery = `
+		SELECT ` + kDivisionColumns + ` FROM divisions
+			WHERE id = ?
+	`
+
+	kFetchDivisionExternalQuery = `
+		SELECT ` + kDivisionColumns + ` FROM divisions
+			WHERE external_id = ?
+	`
+
+	kFetchDivisionsLeagueQuery = `
+		SELECT ` + kDivisionColumns + ` FROM divisions
+			WHERE league = ?
+			ORDER BY id ASC
+	`
+
+	kFetchDivisionsSeasonQuery = `
+		SELECT ` + kDivisionColumns + ` FROM divisions
+			WHERE id IN (SELECT division FROM season_teams WHERE season = ?)
+			ORDER BY id ASC
+	`
+
+	kSaveDivisionQuery = `
+		INSERT INTO divisions (id, external_id, league, name)
+			VALUES (NULLIF(?, 0), NULLIF(?, ''), ?, ?)
+			ON CONFLICT(id) DO UPDATE SET
+				external_id = excluded.external_id,
+				league = excluded.league,
+				name = excluded.name
+	`
+)
+
+type division struct {
+	id         int64
+	externalID string
+	league     int64
+	name       string
+}
+
+func (d *division) ID() data.EntityID     { return data.EntityID(d.id) }
+func (d *division) ExternalID() string    { return d.externalID }
+func (d *division) League() data.EntityID { return data.EntityID(d.league) }
+func (d *division) Name() string          { return d.name }
+
+func scanDivision(row interface{ Scan(...any) error }) (*division, error) {
+	d := &division{}
+	return d, row.Scan(&d.id, &d.externalID, &d.league, &d.name)
+}
+
+type divisions struct {
+	db          conn
+	fetchList   *sql.Stmt
+	fetchID     *sql.Stmt
+	fetchExt    *sql.Stmt
+	fetchLeague *sql.Stmt
+	fetchSeason *sql.Stmt
+	save        *sql.Stmt
+}
+
+func newDivisions(db conn) (*divisions, error) {
+	fetchList, err := db.Prepare(kFetchDivisionsQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchID, err := db.Prepare(kFetchDivisionQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchExt, err := db.Prepare(kFetchDivisionExternalQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchLeague, err := db.Prepare(kFetchDivisionsLeagueQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchSeason, err := db.Prepare(kFetchDivisionsSeasonQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	save, err := db.Prepare(kSaveDivisionQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	return &divisions{
+		db,
+		fetchList,
+		fetchID,
+		fetchExt,
+		fetchLeague,
+		fetchSeason,
+		save,
+	}, nil
+}
+
+func (d *divisions) List(token int64) ([]data.Division, int64, error) {
+	defer observeQuery("divisions.list", time.Now())
+
+	rows, err := d.fetchList.Query(token)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer rows.Close()
+
+	data := []data.Division{}
+	for rows.Next() {
+		nd, err := scanDivision(rows)
+		if err != nil {
+			return nil, token, err
+		}
+
+		token = nd.id
+		data = append(data, nd)
+	}
+
+	return data, token, rows.Err()
+}
+
+func (d *divisions) ByID(id data.EntityID) (data.Division, error) {
+	defer observeQuery("divisions.byID", time.Now())
+
+	return fetchDivision(d.fetchID, id)
+}
+
+func (d *divisions) ByExternalID(ext string) (data.Division, error) {
+	defer observeQuery("divisions.byExternalID", time.Now())
+
+	return fetchDivision(d.fetchExt, ext)
+}
+
+func fetchDivision(stmt *sql.Stmt, arg any) (data.Division, error) {
+	ret, err := scanDivision(stmt.QueryRow(arg))
+	if err == nil {
+		return ret, nil
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, data.ErrorUnknownDivisionID
+	}
+
+	return nil, err
+}
+
+func (d *divisions) ByLeague(league data.EntityID) ([]data.Division, error) {
+	defer observeQuery("divisions.byLeague", time.Now())
+
+	return queryDivisions(d.fetchLeague, league)
+}
+
+func (d *divisions) BySeason(season data.EntityID) ([]data.Division, error) {
+	defer observeQuery("divisions.bySeason", time.Now())
+
+	return queryDivisions(d.fetchSeason, season)
+}
+
+func queryDivisions(stmt *sql.Stmt, arg any) ([]data.Division, error) {
+	rows, err := stmt.Query(arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	data := []data.Division{}
+	for rows.Next() {
+		nd, err := scanDivision(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		data = append(data, nd)
+	}
+
+	return data, rows.Err()
+}
+
+func (d *divisions) Save(v data.Division) (data.EntityID, error) {
+	defer observeQuery("divisions.save", time.Now())
+
+	return saveRow(d.save, v.ID(), v.ExternalID(), v.League(), v.Name())
+}
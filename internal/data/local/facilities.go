@@ -25,56 +25,82 @@ package local
 import (
 	"database/sql"
 	"errors"
-	"fmt"
+	"time"
 
 	"shiftylogic.dev/hockey-tools/internal/data"
 )
 
 const (
 	kFetchFacilitiesQuery = `
-		SELECT id, name FROM facilities
+		SELECT id, COALESCE(external_id, ''), name, address, city, state FROM facilities
 			WHERE id > ?
 			ORDER BY id ASC
 			LIMIT 100
 	`
 
 	kFetchFacilityQuery = `
-		SELECT id, name FROM facilities
+		SELECT id, COALESCE(external_id, ''), name, address, city, state FROM facilities
 			WHERE id = ?
 	`
+
+	kFetchFacilityExternalQuery = `
+		SELECT id, COALESCE(external_id, ''), name, address, city, state FROM facilities
+			WHERE external_id = ?
+	`
+
+	kSaveFacilityQuery = `
+		INSERT INTO facilities (id, external_id, name, address, city, state)
+			VALUES (NULLIF(?, 0), NULLIF(?, ''), ?, ?, ?, ?)
+			ON CONFLICT(id) DO UPDATE SET
+				external_id = excluded.external_id,
+				name = excluded.name,
+				address = excluded.address,
+				city = excluded.city,
+				state = excluded.state
+	`
 )
 
 type facility struct {
-	id      int64
-	name    string
-	address string
-	city    string
-	state   string
+	id         int64
+	externalID string
+	name       string
+	address    string
+	city       string
+	state      string
 }
 
-func (f *facility) ID() data.EntityID { return data.EntityID(f.id) }
-func (f *facility) Name() string      { return f.name }
-func (f *facility) Address() string   { return f.address }
-func (f *facility) City() string      { return f.city }
-func (f *facility) State() string     { return f.state }
+func (f *facility) ID() data.EntityID  { return data.EntityID(f.id) }
+func (f *facility) ExternalID() string { return f.externalID }
+func (f *facility) Name() string       { return f.name }
+func (f *facility) Address() string    { return f.address }
+func (f *facility) City() string       { return f.city }
+func (f *facility) State() string      { return f.state }
 
 type facilities struct {
-	db        *sql.DB
+	db        conn
 	fetchList *sql.Stmt
 	fetchID   *sql.Stmt
+	fetchExt  *sql.Stmt
+	save      *sql.Stmt
 }
 
-func newFacilities(db *sql.DB) (*facilities, error) {
-	if err := ensureFacilities(db); err != nil {
+func newFacilities(db conn) (*facilities, error) {
+	fetchList, err := db.Prepare(kFetchFacilitiesQuery)
+	if err != nil {
 		return nil, err
 	}
 
-	fetchList, err := db.Prepare(kFetchFacilitiesQuery)
+	fetchID, err := db.Prepare(kFetchFacilityQuery)
 	if err != nil {
 		return nil, err
 	}
 
-	fetchID, err := db.Prepare(kFetchFacilityQuery)
+	fetchExt, err := db.Prepare(kFetchFacilityExternalQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	save, err := db.Prepare(kSaveFacilityQuery)
 	if err != nil {
 		return nil, err
 	}
@@ -83,19 +109,24 @@ func newFacilities(db *sql.DB) (*facilities, error) {
 		db,
 		fetchList,
 		fetchID,
+		fetchExt,
+		save,
 	}, nil
 }
 
 func (f *facilities) List(token int64) ([]data.Facility, int64, error) {
+	defer observeQuery("facilities.list", time.Now())
+
 	rows, err := f.fetchList.Query(token)
 	if err != nil {
 		return nil, -1, err
 	}
+	defer rows.Close()
 
 	data := []data.Facility{}
 	for rows.Next() {
 		f := &facility{}
-		err = rows.Scan(&f.id, &f.name)
+		err = rows.Scan(&f.id, &f.externalID, &f.name, &f.address, &f.city, &f.state)
 		if err != nil {
 			return nil, token, err
 		}
@@ -104,43 +135,45 @@ func (f *facilities) List(token int64) ([]data.Facility, int64, error) {
 		data = append(data, f)
 	}
 
-	return data, token, nil
+	return data, token, rows.Err()
 }
 
 func (f *facilities) ByID(id data.EntityID) (data.Facility, error) {
+	defer observeQuery("facilities.byID", time.Now())
+
 	ret := &facility{}
 
-	err := f.fetchID.QueryRow(id).Scan(&ret.id, &ret.name)
+	err := f.fetchID.QueryRow(id).Scan(&ret.id, &ret.externalID, &ret.name, &ret.address, &ret.city, &ret.state)
 	if err == nil {
 		return ret, nil
 	}
 
 	if errors.Is(err, sql.ErrNoRows) {
-		return nil, err
+		return nil, data.ErrorUnknownFacilityID
 	}
 
-	return nil, data.ErrorUnknownFacilityID
+	return nil, err
 }
 
-/**
- *
- * Functions used for manipulating 'facilities' tables.
- *
- */
+func (f *facilities) ByExternalID(ext string) (data.Facility, error) {
+	defer observeQuery("facilities.byExternalID", time.Now())
 
-const (
-	kFacilitiesTableCreate = `
-		CREATE TABLE IF NOT EXISTS facilities (
-			id INTEGER PRIMARY KEY,
-			name TEXT NOT NULL
-		)
-	`
-)
+	ret := &facility{}
+
+	err := f.fetchExt.QueryRow(ext).Scan(&ret.id, &ret.externalID, &ret.name, &ret.address, &ret.city, &ret.state)
+	if err == nil {
+		return ret, nil
+	}
 
-func ensureFacilities(db *sql.DB) error {
-	if _, err := db.Exec(kFacilitiesTableCreate); err != nil {
-		return fmt.Errorf("[ensureFacilities] failed to create 'facilities' table - %w", err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, data.ErrorUnknownFacilityID
 	}
 
-	return nil
+	return nil, err
+}
+
+func (f *facilities) Save(v data.Facility) (data.EntityID, error) {
+	defer observeQuery("facilities.save", time.Now())
+
+	return saveRow(f.save, v.ID(), v.ExternalID(), v.Name(), v.Address(), v.City(), v.State())
 }
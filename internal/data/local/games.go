@@ -0,0 +1,611 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package local
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/data"
+)
+
+const (
+	kGameColumns = `id, COALESCE(external_id, ''), COALESCE(season, 0), tags, played, facility, home, visitor, home_score, visitor_score`
+
+	kFetchGamesQuery = `
+		SELECT ` + kGameColumns + ` FROM games
+			WHERE id > ?
+			ORDER BY id ASC
+			LIMIT 100
+	`
+
+	kFetchGameQuery = `
+		SELECT ` + kGameColumns + ` FROM games
+			WHERE id = ?
+	`
+
+	kFetchGameExternalQuery = `
+		SELECT ` + kGameColumns + ` FROM games
+			WHERE external_id = ?
+	`
+
+	kFetchGamesTeamQuery = `
+		SELECT ` + kGameColumns + ` FROM games
+			WHERE home = ? OR visitor = ?
+			ORDER BY played ASC, id ASC
+	`
+
+	kFetchGamesSeasonQuery = `
+		SELECT ` + kGameColumns + ` FROM games
+			WHERE season = ?
+			ORDER BY played ASC, id ASC
+	`
+
+	kFetchGamePeriodsQuery = `SELECT period_lengths FROM games WHERE id = ?`
+
+	kFetchGameGoalsQuery = `
+		SELECT game, time, team, scorer, primary_assist, secondary_assist, on_ice, defenders FROM game_goals
+			WHERE game = ?
+			ORDER BY idx ASC
+	`
+
+	kFetchGamePenaltiesQuery = `
+		SELECT game, time, team, committer, minutes, infraction, served_by FROM game_penalties
+			WHERE game = ?
+			ORDER BY idx ASC
+	`
+
+	// The bulk versions take the game ids as a JSON array
+	kFetchGamesDetailsQuery = `
+		SELECT ` + kGameColumns + `, period_lengths FROM games
+			WHERE id IN (SELECT value FROM json_each(?))
+	`
+
+	kFetchGamesGoalsQuery = `
+		SELECT game, time, team, scorer, primary_assist, secondary_assist, on_ice, defenders FROM game_goals
+			WHERE game IN (SELECT value FROM json_each(?))
+			ORDER BY game ASC, idx ASC
+	`
+
+	kFetchGamesPenaltiesQuery = `
+		SELECT game, time, team, committer, minutes, infraction, served_by FROM game_penalties
+			WHERE game IN (SELECT value FROM json_each(?))
+			ORDER BY game ASC, idx ASC
+	`
+
+	kSaveGameQuery = `
+		INSERT INTO games (id, external_id, season, tags, played, facility, home, visitor, home_score, visitor_score, period_lengths)
+			VALUES (NULLIF(?, 0), NULLIF(?, ''), NULLIF(?, 0), ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(id) DO UPDATE SET
+				external_id = excluded.external_id,
+				season = excluded.season,
+				tags = excluded.tags,
+				played = excluded.played,
+				facility = excluded.facility,
+				home = excluded.home,
+				visitor = excluded.visitor,
+				home_score = excluded.home_score,
+				visitor_score = excluded.visitor_score,
+				period_lengths = excluded.period_lengths
+	`
+
+	kDeleteGameGoalsQuery     = `DELETE FROM game_goals WHERE game = ?`
+	kDeleteGamePenaltiesQuery = `DELETE FROM game_penalties WHERE game = ?`
+
+	kInsertGameGoalQuery = `
+		INSERT INTO game_goals (game, idx, time, team, scorer, primary_assist, secondary_assist, on_ice, defenders)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	kInsertGamePenaltyQuery = `
+		INSERT INTO game_penalties (game, idx, time, team, committer, minutes, infraction, served_by)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	kTagSeparator = ","
+)
+
+type game struct {
+	id           int64
+	externalID   string
+	season       int64
+	tags         []string
+	played       int64
+	facility     int64
+	home         int64
+	visitor      int64
+	homeScore    int
+	visitorScore int
+}
+
+func (g *game) ID() data.EntityID      { return data.EntityID(g.id) }
+func (g *game) ExternalID() string     { return g.externalID }
+func (g *game) Season() data.EntityID  { return data.EntityID(g.season) }
+func (g *game) Tags() []string         { return g.tags }
+func (g *game) When() time.Time        { return time.Unix(g.played, 0) }
+func (g *game) Where() data.EntityID   { return data.EntityID(g.facility) }
+func (g *game) Home() data.EntityID    { return data.EntityID(g.home) }
+func (g *game) Visitor() data.EntityID { return data.EntityID(g.visitor) }
+func (g *game) HomeScore() int         { return g.homeScore }
+func (g *game) VisitorScore() int      { return g.visitorScore }
+
+// Extra destinations are scanned from any columns after the game's own
+func scanGame(row interface{ Scan(...any) error }, extra ...any) (*game, error) {
+	g := &game{}
+
+	var tags string
+	dest := []any{&g.id, &g.externalID, &g.season, &tags, &g.played, &g.facility, &g.home, &g.visitor, &g.homeScore, &g.visitorScore}
+	err := row.Scan(append(dest, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	if tags != "" {
+		g.tags = strings.Split(tags, kTagSeparator)
+	}
+
+	return g, nil
+}
+
+type games struct {
+	db             conn
+	fetchList      *sql.Stmt
+	fetchID        *sql.Stmt
+	fetchExt       *sql.Stmt
+	fetchTeam      *sql.Stmt
+	fetchSeason    *sql.Stmt
+	fetchPeriods   *sql.Stmt
+	fetchGoals     *sql.Stmt
+	fetchPenalties *sql.Stmt
+	fetchDetails   *sql.Stmt
+	fetchAllGoals  *sql.Stmt
+	fetchAllPens   *sql.Stmt
+}
+
+func newGames(db conn) (*games, error) {
+	fetchList, err := db.Prepare(kFetchGamesQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchID, err := db.Prepare(kFetchGameQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchExt, err := db.Prepare(kFetchGameExternalQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchTeam, err := db.Prepare(kFetchGamesTeamQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchSeason, err := db.Prepare(kFetchGamesSeasonQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchPeriods, err := db.Prepare(kFetchGamePeriodsQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchGoals, err := db.Prepare(kFetchGameGoalsQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchPenalties, err := db.Prepare(kFetchGamePenaltiesQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchDetails, err := db.Prepare(kFetchGamesDetailsQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchAllGoals, err := db.Prepare(kFetchGamesGoalsQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchAllPens, err := db.Prepare(kFetchGamesPenaltiesQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	return &games{
+		db,
+		fetchList,
+		fetchID,
+		fetchExt,
+		fetchTeam,
+		fetchSeason,
+		fetchPeriods,
+		fetchGoals,
+		fetchPenalties,
+		fetchDetails,
+		fetchAllGoals,
+		fetchAllPens,
+	}, nil
+}
+
+func (g *games) List(token int64) ([]data.GameOverview, int64, error) {
+	defer observeQuery("games.list", time.Now())
+
+	rows, err := g.fetchList.Query(token)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer rows.Close()
+
+	data := []data.GameOverview{}
+	for rows.Next() {
+		ng, err := scanGame(rows)
+		if err != nil {
+			return nil, token, err
+		}
+
+		token = ng.id
+		data = append(data, ng)
+	}
+
+	return data, token, rows.Err()
+}
+
+func (g *games) ByID(id data.EntityID) (data.GameOverview, error) {
+	defer observeQuery("games.byID", time.Now())
+
+	return fetchGame(g.fetchID, id)
+}
+
+func (g *games) ByExternalID(ext string) (data.GameOverview, error) {
+	defer observeQuery("games.byExternalID", time.Now())
+
+	return fetchGame(g.fetchExt, ext)
+}
+
+func fetchGame(stmt *sql.Stmt, arg any) (data.GameOverview, error) {
+	ret, err := scanGame(stmt.QueryRow(arg))
+	if err == nil {
+		return ret, nil
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, data.ErrorUnknownGameID
+	}
+
+	return nil, err
+}
+
+func (g *games) ByTeam(team data.EntityID) ([]data.GameOverview, error) {
+	defer observeQuery("games.byTeam", time.Now())
+
+	return queryGames(g.fetchTeam, team, team)
+}
+
+func (g *games) BySeason(season data.EntityID) ([]data.GameOverview, error) {
+	defer observeQuery("games.bySeason", time.Now())
+
+	return queryGames(g.fetchSeason, season)
+}
+
+func queryGames(stmt *sql.Stmt, args ...any) ([]data.GameOverview, error) {
+	rows, err := stmt.Query(args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	data := []data.GameOverview{}
+	for rows.Next() {
+		ng, err := scanGame(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		data = append(data, ng)
+	}
+
+	return data, rows.Err()
+}
+
+func (g *games) DetailsByGame(id data.EntityID) (data.GameDetails, error) {
+	defer observeQuery("games.details", time.Now())
+
+	return g.details(nil, id)
+}
+
+// Reads within tx when there is one, so uncommitted events are seen
+func (g *games) details(tx *sql.Tx, id data.EntityID) (data.GameDetails, error) {
+	stmt := func(s *sql.Stmt) *sql.Stmt {
+		if tx == nil {
+			return s
+		}
+		return tx.Stmt(s)
+	}
+
+	overview, err := fetchGame(stmt(g.fetchID), id)
+	if err != nil {
+		return nil, err
+	}
+
+	var periods string
+	if err := stmt(g.fetchPeriods).QueryRow(id).Scan(&periods); err != nil {
+		return nil, err
+	}
+
+	goals, err := readGoals(stmt(g.fetchGoals), id)
+	if err != nil {
+		return nil, err
+	}
+
+	penalties, err := readPenalties(stmt(g.fetchPenalties), id)
+	if err != nil {
+		return nil, err
+	}
+
+	return newDetails(overview, periods, goals[id], penalties[id]), nil
+}
+
+// Three queries, however many games. Unknown ids are skipped; the rest come
+// back in the order asked for.
+func (g *games) DetailsByGames(ids []data.EntityID) ([]data.GameDetails, error) {
+	defer observeQuery("games.detailsByGames", time.Now())
+
+	list := make([]string, len(ids))
+	for i, id := range ids {
+		list[i] = strconv.FormatInt(int64(id), 10)
+	}
+	arg := "[" + strings.Join(list, ",") + "]"
+
+	rows, err := g.fetchDetails.Query(arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	overviews := map[data.EntityID]*game{}
+	periods := map[data.EntityID]string{}
+	for rows.Next() {
+		var p string
+		ng, err := scanGame(rows, &p)
+		if err != nil {
+			return nil, err
+		}
+
+		overviews[ng.ID()] = ng
+		periods[ng.ID()] = p
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	goals, err := readGoals(g.fetchAllGoals, arg)
+	if err != nil {
+		return nil, err
+	}
+
+	penalties, err := readPenalties(g.fetchAllPens, arg)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := []data.GameDetails{}
+	for _, id := range ids {
+		if o, ok := overviews[id]; ok {
+			ret = append(ret, newDetails(o, periods[id], goals[id], penalties[id]))
+		}
+	}
+
+	return ret, nil
+}
+
+func newDetails(overview data.GameOverview, periods string, goals []data.ScoringEvent, penalties []data.PenaltyEvent) data.GameDetails {
+	if goals == nil {
+		goals = []data.ScoringEvent{}
+	}
+
+	if penalties == nil {
+		penalties = []data.PenaltyEvent{}
+	}
+
+	return data.NewGameDetails(overview, decodeInts(periods), goals, penalties)
+}
+
+// Events grouped by game
+func readGoals(stmt *sql.Stmt, arg any) (map[data.EntityID][]data.ScoringEvent, error) {
+	rows, err := stmt.Query(arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	goals := map[data.EntityID][]data.ScoringEvent{}
+	for rows.Next() {
+		var ts int
+		var game, team, scorer, primary, secondary data.EntityID
+		var onIce, defenders string
+
+		if err := rows.Scan(&game, &ts, &team, &scorer, &primary, &secondary, &onIce, &defenders); err != nil {
+			return nil, err
+		}
+
+		goals[game] = append(goals[game], data.NewScoringEvent(ts, team, scorer, primary, secondary, decodeIDs(onIce), decodeIDs(defenders)))
+	}
+
+	return goals, rows.Err()
+}
+
+func readPenalties(stmt *sql.Stmt, arg any) (map[data.EntityID][]data.PenaltyEvent, error) {
+	rows, err := stmt.Query(arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	penalties := map[data.EntityID][]data.PenaltyEvent{}
+	for rows.Next() {
+		var ts, minutes int
+		var game, team, committer, servedBy data.EntityID
+		var infraction string
+
+		if err := rows.Scan(&game, &ts, &team, &committer, &minutes, &infraction, &servedBy); err != nil {
+			return nil, err
+		}
+
+		penalties[game] = append(penalties[game], data.NewPenaltyEvent(ts, team, committer, minutes, infraction, servedBy))
+	}
+
+	return penalties, rows.Err()
+}
+
+func (g *games) Save(details data.GameDetails) (data.EntityID, error) {
+	defer observeQuery("games.save", time.Now())
+
+	tx, err := begin(g.db)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	o := details.Overview()
+	res, err := tx.Exec(kSaveGameQuery,
+		o.ID(),
+		o.ExternalID(),
+		o.Season(),
+		strings.Join(o.Tags(), kTagSeparator),
+		o.When().Unix(),
+		o.Where(),
+		o.Home(),
+		o.Visitor(),
+		o.HomeScore(),
+		o.VisitorScore(),
+		encodeInts(details.PeriodLengths()))
+	if err != nil {
+		return 0, err
+	}
+
+	id := o.ID()
+	if id == 0 {
+		nid, err := res.LastInsertId()
+		if err != nil {
+			return 0, err
+		}
+		id = data.EntityID(nid)
+	}
+
+	if _, err := tx.Exec(kDeleteGameGoalsQuery, id); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(kDeleteGamePenaltiesQuery, id); err != nil {
+		return 0, err
+	}
+
+	for i, e := range details.Goals() {
+		_, err := tx.Exec(kInsertGameGoalQuery,
+			id, i, e.Timestamp(), e.TeamID(), e.Scorer(), e.PrimaryAssist(), e.SecondaryAssist(),
+			encodeIDs(e.Other()), encodeIDs(e.Defenders()))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	for i, e := range details.Penalties() {
+		_, err := tx.Exec(kInsertGamePenaltyQuery,
+			id, i, e.Timestamp(), e.TeamID(), e.Committer(), e.Minutes(), e.Infraction(), e.ServedBy())
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	details, err = g.details(tx.Tx, id)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := snapshotGameLineup(tx.Tx, details.Overview()); err != nil {
+		return 0, err
+	}
+
+	if err := refreshGameStats(tx.Tx, details); err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+/**
+ *
+ * Lists of ids and numbers are stored space separated
+ *
+ **/
+
+func encodeInts(values []int) string {
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = strconv.Itoa(v)
+	}
+
+	return strings.Join(s, " ")
+}
+
+func decodeInts(s string) []int {
+	values := []int{}
+	for _, f := range strings.Fields(s) {
+		if v, err := strconv.Atoi(f); err == nil {
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
+
+func encodeIDs(ids []data.EntityID) string {
+	values := make([]int, len(ids))
+	for i, id := range ids {
+		values[i] = int(id)
+	}
+
+	return encodeInts(values)
+}
+
+func decodeIDs(s string) []data.EntityID {
+	values := decodeInts(s)
+
+	ids := make([]data.EntityID, len(values))
+	for i, v := range values {
+		ids[i] = data.EntityID(v)
+	}
+
+	return ids
+}
@@ -0,0 +1,163 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package local
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/data"
+)
+
+const (
+	kFetchLeaguesQuery = `
+		SELECT id, COALESCE(external_id, ''), name FROM leagues
+			WHERE id > ?
+			ORDER BY id ASC
+			LIMIT 100
+	`
+
+	kFetchLeagueQuery = `
+		SELECT id, COALESCE(external_id, ''), name FROM leagues
+			WHERE id = ?
+	`
+
+	kFetchLeagueExternalQuery = `
+		SELECT id, COALESCE(external_id, ''), name FROM leagues
+			WHERE external_id = ?
+	`
+
+	kSaveLeagueQuery = `
+		INSERT INTO leagues (id, external_id, name)
+			VALUES (NULLIF(?, 0), NULLIF(?, ''), ?)
+			ON CONFLICT(id) DO UPDATE SET
+				external_id = excluded.external_id,
+				name = excluded.name
+	`
+)
+
+type league struct {
+	id         int64
+	externalID string
+	name       string
+}
+
+func (l *league) ID() data.EntityID  { return data.EntityID(l.id) }
+func (l *league) ExternalID() string { return l.externalID }
+func (l *league) Name() string       { return l.name }
+
+type leagues struct {
+	db        conn
+	fetchList *sql.Stmt
+	fetchID   *sql.Stmt
+	fetchExt  *sql.Stmt
+	save      *sql.Stmt
+}
+
+func newLeagues(db conn) (*leagues, error) {
+	fetchList, err := db.Prepare(kFetchLeaguesQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchID, err := db.Prepare(kFetchLeagueQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchExt, err := db.Prepare(kFetchLeagueExternalQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	save, err := db.Prepare(kSaveLeagueQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	return &leagues{
+		db,
+		fetchList,
+		fetchID,
+		fetchExt,
+		save,
+	}, nil
+}
+
+func (l *leagues) List(token int64) ([]data.League, int64, error) {
+	defer observeQuery("leagues.list", time.Now())
+
+	rows, err := l.fetchList.Query(token)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer rows.Close()
+
+	data := []data.League{}
+	for rows.Next() {
+		nl := &league{}
+		err = rows.Scan(&nl.id, &nl.externalID, &nl.name)
+		if err != nil {
+			return nil, token, err
+		}
+
+		token = nl.id
+		data = append(data, nl)
+	}
+
+	return data, token, rows.Err()
+}
+
+func (l *leagues) ByID(id data.EntityID) (data.League, error) {
+	defer observeQuery("leagues.byID", time.Now())
+
+	return l.fetchOne(l.fetchID, id)
+}
+
+func (l *leagues) ByExternalID(ext string) (data.League, error) {
+	defer observeQuery("leagues.byExternalID", time.Now())
+
+	return l.fetchOne(l.fetchExt, ext)
+}
+
+func (l *leagues) fetchOne(stmt *sql.Stmt, arg any) (data.League, error) {
+	ret := &league{}
+
+	err := stmt.QueryRow(arg).Scan(&ret.id, &ret.externalID, &ret.name)
+	if err == nil {
+		return ret, nil
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, data.ErrorUnknownLeagueID
+	}
+
+	return nil, err
+}
+
+func (l *leagues) Save(v data.League) (data.EntityID, error) {
+	defer observeQuery("leagues.save", time.Now())
+
+	return saveRow(l.save, v.ID(), v.ExternalID(), v.Name())
+}
@@ -23,51 +23,253 @@
 package local
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 
 	"shiftylogic.dev/hockey-tools/internal/data"
+	"shiftylogic.dev/hockey-tools/internal/metrics"
 )
 
+var (
+	queryDuration = metrics.NewHistogramVec(
+		"db_query_duration_seconds",
+		"Local (SQLite) store query latency by query.",
+		nil,
+		"query")
+)
+
+// Intended for use with defer: defer observeQuery("players.list", time.Now())
+func observeQuery(query string, start time.Time) {
+	queryDuration.With(query).ObserveSince(start)
+}
+
+// Where collections prepare their statements: the database itself, or a
+// transaction for a store scoped to one (see Atomically).
+type conn interface {
+	Prepare(query string) (*sql.Stmt, error)
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
 type localStore struct {
 	db         *sql.DB
+	tx         *sql.Tx // set when scoped to a transaction
 	facilities *facilities
 	players    *players
 	staff      *staff
+	teams      *teams
+	leagues    *leagues
+	seasons    *seasons
+	divisions  *divisions
+	games      *games
+	reviews    *reviews
+	stats      *playerStats
+	users      *users
+	clients    *clients
+}
+
+func (store *localStore) Close() { store.db.Close() }
+func (store *localStore) Ping(ctx context.Context) error {
+	return store.db.PingContext(ctx)
 }
 
-func (store *localStore) Close()                      { store.db.Close() }
 func (store *localStore) Facilities() data.Facilities { return store.facilities }
 func (store *localStore) Players() data.Players       { return store.players }
 func (store *localStore) Staff() data.Staff           { return store.staff }
+func (store *localStore) Teams() data.Teams           { return store.teams }
+func (store *localStore) Leagues() data.Leagues       { return store.leagues }
+func (store *localStore) Seasons() data.Seasons       { return store.seasons }
+func (store *localStore) Divisions() data.Divisions   { return store.divisions }
+func (store *localStore) Games() data.Games           { return store.games }
+func (store *localStore) Reviews() data.Reviews       { return store.reviews }
+func (store *localStore) Stats() data.Stats           { return store.stats }
+func (store *localStore) Users() data.Users           { return store.users }
+func (store *localStore) Clients() data.Clients       { return store.clients }
+
+// Runs fn against a copy of the store with every statement prepared in one
+// transaction, committed only if fn succeeds. Writes that use their own
+// transaction join this one instead.
+func (store *localStore) Atomically(fn func(data.Store) error) error {
+	if store.tx != nil {
+		return fn(store)
+	}
+
+	defer observeQuery("store.atomically", time.Now())
 
+	tx, err := store.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	scoped, err := prepareStore(store.db, tx)
+	if err != nil {
+		return err
+	}
+	scoped.tx = tx
+
+	if err := fn(scoped); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Opens (creating if needed) the database in dataFile and brings its schema
+// up to date before preparing any queries.
 func Open(dataFile string) (data.Store, error) {
 	db, err := sql.Open("sqlite3", dataFile)
 	if err != nil {
 		return nil, fmt.Errorf("[local.Open] failed to open database file - %w", err)
 	}
 
-	facilities, err := newFacilities(db)
+	if _, err := migrate(db, false); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	store, err := prepareStore(db, db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+func prepareStore(db *sql.DB, c conn) (*localStore, error) {
+	facilities, err := newFacilities(c)
+	if err != nil {
+		return nil, err
+	}
+
+	players, err := newPlayers(c)
+	if err != nil {
+		return nil, err
+	}
+
+	staff, err := newStaff(c)
+	if err != nil {
+		return nil, err
+	}
+
+	teams, err := newTeams(c)
+	if err != nil {
+		return nil, err
+	}
+
+	leagues, err := newLeagues(c)
+	if err != nil {
+		return nil, err
+	}
+
+	seasons, err := newSeasons(c)
+	if err != nil {
+		return nil, err
+	}
+
+	divisions, err := newDivisions(c)
+	if err != nil {
+		return nil, err
+	}
+
+	games, err := newGames(c)
+	if err != nil {
+		return nil, err
+	}
+
+	reviews, err := newReviews(c, games)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := newPlayerStats(c, games)
 	if err != nil {
 		return nil, err
 	}
 
-	players, err := newPlayers(db)
+	users, err := newUsers(c)
 	if err != nil {
 		return nil, err
 	}
 
-	staff, err := newStaff(db)
+	clients, err := newClients(c)
 	if err != nil {
 		return nil, err
 	}
 
 	return &localStore{
 		db,
+		nil,
 		facilities,
 		players,
 		staff,
+		teams,
+		leagues,
+		seasons,
+		divisions,
+		games,
+		reviews,
+		stats,
+		users,
+		clients,
 	}, nil
 }
+
+/**
+ *
+ * A transaction for one write. On a store scoped to a transaction it is
+ * that transaction, and committing or rolling back is left to the scope.
+ *
+ **/
+
+type writeTx struct {
+	*sql.Tx
+	owned bool
+}
+
+func begin(c conn) (writeTx, error) {
+	if tx, ok := c.(*sql.Tx); ok {
+		return writeTx{tx, false}, nil
+	}
+
+	tx, err := c.(*sql.DB).Begin()
+	return writeTx{tx, true}, err
+}
+
+func (tx writeTx) Commit() error {
+	if !tx.owned {
+		return nil
+	}
+
+	return tx.Tx.Commit()
+}
+
+func (tx writeTx) Rollback() error {
+	if !tx.owned {
+		return nil
+	}
+
+	return tx.Tx.Rollback()
+}
+
+// Executes an upsert statement whose first argument is the row id (0 for a
+// new row) and returns the id of the row written.
+func saveRow(stmt *sql.Stmt, id data.EntityID, args ...any) (data.EntityID, error) {
+	res, err := stmt.Exec(append([]any{id}, args...)...)
+	if err != nil {
+		return 0, err
+	}
+
+	if id != 0 {
+		return id, nil
+	}
+
+	nid, err := res.LastInsertId()
+	return data.EntityID(nid), err
+}
@@ -0,0 +1,264 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package local
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/data"
+	"shiftylogic.dev/hockey-tools/internal/test"
+)
+
+func TestMigrate(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "hockey.db")
+
+	pending, err := Migrate(file, true)
+	test.NoError(t, err, "dry run")
+	test.Expect(t, SchemaVersion(), len(pending), "everything pending on a new database")
+
+	applied, err := Migrate(file, false)
+	test.NoError(t, err, "migrate")
+	test.Expect(t, len(pending), len(applied), "applies what the dry run listed")
+
+	applied, err = Migrate(file, false)
+	test.NoError(t, err, "migrate again")
+	test.Expect(t, 0, len(applied), "nothing left to apply")
+
+	store, err := Open(file)
+	test.NoError(t, err, "open migrated database")
+	store.Close()
+}
+
+func TestSaveAndAccounts(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "hockey.db"))
+	test.NoError(t, err, "open")
+	defer store.Close()
+
+	id, err := store.Facilities().Save(data.NewFacility(0, "", "North Rink", "1 Ice Way", "Seattle", "WA"))
+	test.NoError(t, err, "insert facility")
+
+	_, err = store.Facilities().Save(data.NewFacility(id, "", "South Rink", "", "Tacoma", "WA"))
+	test.NoError(t, err, "replace facility")
+
+	f, err := store.Facilities().ByID(id)
+	test.NoError(t, err, "fetch facility")
+	test.Expect(t, "South Rink", f.Name(), "facility replaced")
+	test.Expect(t, "Tacoma", f.City(), "facility city")
+
+	_, err = store.Facilities().ByID(id + 1)
+	test.SpecificError(t, err, data.ErrorUnknownFacilityID, "missing facility")
+
+	_, err = store.Users().Add("dude@example.com", "hash-1")
+	test.NoError(t, err, "add user")
+
+	_, err = store.Users().Add("DUDE@example.com", "hash-2")
+	test.SpecificError(t, err, data.ErrorDuplicateUser, "emails are case-insensitive")
+
+	test.NoError(t, store.Users().SetPassword("dude@example.com", "hash-3"), "set password")
+	u, err := store.Users().ByEmail("Dude@Example.com")
+	test.NoError(t, err, "fetch user")
+	test.Expect(t, "hash-3", u.PasswordHash(), "password replaced")
+
+	test.SpecificError(t, store.Users().SetPassword("nobody@example.com", "x"), data.ErrorUnknownUser, "missing user")
+
+	c := data.NewClient("web", "Web", "", "https://a.example/cb", "https://b.example/cb")
+	test.NoError(t, store.Clients().Add(c), "add client")
+	test.SpecificError(t, store.Clients().Add(c), data.ErrorDuplicateClient, "duplicate client")
+
+	got, err := store.Clients().ByID("web")
+	test.NoError(t, err, "fetch client")
+	test.Expect(t, 2, len(got.RedirectURIs()), "redirect URIs round trip")
+}
+
+func TestSeasons(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "hockey.db"))
+	test.NoError(t, err, "open")
+	defer store.Close()
+
+	league, err := store.Leagues().Save(data.NewLeague(0, "L-1", "Puget Sound"))
+	test.NoError(t, err, "save league")
+
+	start := time.Date(2024, 9, 1, 0, 0, 0, 0, time.Local)
+	fall, err := store.Seasons().Save(data.NewSeason(0, "S-24", league, "Fall 2024", start, start.AddDate(0, 4, 0)))
+	test.NoError(t, err, "save season")
+	spring, err := store.Seasons().Save(data.NewSeason(0, "S-25", league, "Spring 2025", time.Time{}, time.Time{}))
+	test.NoError(t, err, "save open-ended season")
+
+	north, err := store.Divisions().Save(data.NewDivision(0, "", league, "North"))
+	test.NoError(t, err, "save division")
+	south, err := store.Divisions().Save(data.NewDivision(0, "", league, "South"))
+	test.NoError(t, err, "save division")
+
+	team, err := store.Teams().Save(data.NewTeam(0, "T-SEA", "Seattle"))
+	test.NoError(t, err, "save team")
+	other, err := store.Teams().Save(data.NewTeam(0, "T-TAC", "Tacoma"))
+	test.NoError(t, err, "save team")
+
+	test.NoError(t, store.Seasons().SetTeam(data.NewMembership(fall, team, north)), "join fall")
+	test.NoError(t, store.Seasons().SetTeam(data.NewMembership(fall, other, north)), "join fall")
+	test.NoError(t, store.Seasons().SetTeam(data.NewMembership(spring, team, north)), "join spring")
+	test.NoError(t, store.Seasons().SetTeam(data.NewMembership(spring, team, south)), "change divisions")
+
+	members, err := store.Seasons().Teams(spring)
+	test.NoError(t, err, "season teams")
+	test.Expect(t, 1, len(members), "one team in spring")
+	test.Expect(t, south, members[0].Division(), "moved to the south")
+
+	history, err := store.Seasons().TeamSeasons(team)
+	test.NoError(t, err, "team seasons")
+	test.Expect(t, 2, len(history), "played both seasons")
+
+	divisions, err := store.Divisions().BySeason(fall)
+	test.NoError(t, err, "divisions by season")
+	test.Expect(t, 1, len(divisions), "only the north played in the fall")
+
+	s, err := store.Seasons().ByExternalID("S-24")
+	test.NoError(t, err, "fetch season")
+	test.Require(t, s.Start().Equal(start), "start round trips")
+	test.Require(t, data.SeasonIncludes(s, start.AddDate(0, 1, 0)), "october is in the fall season")
+	test.Require(t, data.SeasonIncludes(s, start.AddDate(0, 4, 0).Add(20*time.Hour)), "end is inclusive")
+	test.Require(t, !data.SeasonIncludes(s, start.AddDate(0, 4, 1)), "the day after the end is not")
+
+	overview := data.NewGameOverview(0, "G-1", fall, nil, start.AddDate(0, 0, 7), 0, team, other, 3, 1)
+	_, err = store.Games().Save(data.NewGameDetails(overview, []int{900, 900, 900}, nil, nil))
+	test.NoError(t, err, "save game")
+
+	games, err := store.Games().BySeason(fall)
+	test.NoError(t, err, "games by season")
+	test.Expect(t, 1, len(games), "one fall game")
+	test.Expect(t, fall, games[0].Season(), "season round trips")
+
+	games, err = store.Games().BySeason(spring)
+	test.NoError(t, err, "games by season")
+	test.Expect(t, 0, len(games), "no spring games")
+
+	test.NoError(t, store.Seasons().RemoveTeam(fall, other), "leave fall")
+	test.SpecificError(t, store.Seasons().RemoveTeam(fall, other), data.ErrorNotInSeason, "already gone")
+
+	_, err = store.Seasons().ByID(spring + 1)
+	test.SpecificError(t, err, data.ErrorUnknownSeasonID, "missing season")
+}
+
+func TestStatsFollowTheLineup(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "hockey.db"))
+	test.NoError(t, err, "open")
+	defer store.Close()
+
+	sea, err := store.Teams().Save(data.NewTeam(0, "T-SEA", "Seattle"))
+	test.NoError(t, err, "save team")
+	tac, err := store.Teams().Save(data.NewTeam(0, "T-TAC", "Tacoma"))
+	test.NoError(t, err, "save team")
+
+	scorer, err := store.Players().Save(data.NewPlayer(0, "P-9", sea, "Sniper", 9))
+	test.NoError(t, err, "save player")
+	backup, err := store.Players().Save(data.NewPlayer(0, "P-30", sea, "Backup", 30))
+	test.NoError(t, err, "save player")
+
+	// Sniper scores in the first and last games, and dresses for all three
+	for i, score := range []int{1, 0, 1} {
+		when := time.Date(2024, 10, 1+i, 19, 0, 0, 0, time.Local)
+		overview := data.NewGameOverview(0, fmt.Sprintf("G-%d", i), 0, nil, when, 0, sea, tac, score, 0)
+		goals := []data.ScoringEvent{}
+		if score > 0 {
+			goals = append(goals, data.NewScoringEvent(100, sea, scorer, 0, 0, nil, nil))
+		}
+		_, err = store.Games().Save(data.NewGameDetails(overview, []int{900, 900, 900}, goals, nil))
+		test.NoError(t, err, "save game")
+	}
+
+	// Traded after the games; a rebuild must leave them with Seattle
+	_, err = store.Players().Save(data.NewPlayer(scorer, "P-9", tac, "Sniper", 9))
+	test.NoError(t, err, "trade player")
+
+	// Signed after the games; didn't dress for any of them
+	_, err = store.Players().Save(data.NewPlayer(0, "P-1", sea, "Latecomer", 1))
+	test.NoError(t, err, "save player")
+
+	_, err = store.Stats().Rebuild()
+	test.NoError(t, err, "rebuild")
+
+	totals, err := store.Stats().Players(data.StatsScope{})
+	test.NoError(t, err, "player stats")
+	test.Expect(t, 2, len(totals), "everyone who dressed gets their games")
+
+	byPlayer := map[data.EntityID]data.PlayerStats{}
+	for _, s := range totals {
+		byPlayer[s.Player] = s
+	}
+
+	sniper := byPlayer[scorer]
+	test.Expect(t, sea, sniper.Team, "scored for Seattle")
+	test.Expect(t, 3, sniper.GamesPlayed, "dressed for three")
+	test.Expect(t, 2, sniper.Points, "two points")
+	test.Expect(t, 1, sniper.PointStreak, "scoreless game broke the streak")
+	test.Expect(t, 1, sniper.LongestPointStreak, "never two in a row")
+
+	test.Expect(t, 3, byPlayer[backup].GamesPlayed, "backup dressed for three")
+	test.Expect(t, 0, byPlayer[backup].Points, "backup never scored")
+
+	totals, err = store.Stats().Players(data.StatsScope{Team: tac})
+	test.NoError(t, err, "player stats")
+	test.Expect(t, 0, len(totals), "nothing moved to Tacoma")
+}
+
+func TestDetailsByGames(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "hockey.db"))
+	test.NoError(t, err, "open")
+	defer store.Close()
+
+	sea, err := store.Teams().Save(data.NewTeam(0, "T-SEA", "Seattle"))
+	test.NoError(t, err, "save team")
+	tac, err := store.Teams().Save(data.NewTeam(0, "T-TAC", "Tacoma"))
+	test.NoError(t, err, "save team")
+
+	when := time.Date(2024, 10, 1, 19, 0, 0, 0, time.Local)
+	ids := []data.EntityID{}
+	for i := 0; i < 2; i++ {
+		overview := data.NewGameOverview(0, fmt.Sprintf("G-%d", i), 0, nil, when.AddDate(0, 0, i), 0, sea, tac, i, 0)
+		goals := []data.ScoringEvent{}
+		for n := 0; n < i; n++ {
+			goals = append(goals, data.NewScoringEvent(100+n, sea, 0, 0, 0, nil, nil))
+		}
+		penalties := []data.PenaltyEvent{data.NewPenaltyEvent(50, tac, 0, 2, "Tripping", 0)}
+
+		id, err := store.Games().Save(data.NewGameDetails(overview, []int{900, 900, 900}, goals, penalties))
+		test.NoError(t, err, "save game")
+		ids = append(ids, id)
+	}
+
+	details, err := store.Games().DetailsByGames([]data.EntityID{ids[1], 999, ids[0]})
+	test.NoError(t, err, "details")
+	test.Expect(t, 2, len(details), "unknown game left out")
+	test.Expect(t, ids[1], details[0].ID(), "in the order asked for")
+	test.Expect(t, 1, len(details[0].Goals()), "its own goals")
+	test.Expect(t, 0, len(details[1].Goals()), "no goals")
+	test.Expect(t, 1, len(details[1].Penalties()), "its own penalties")
+	test.Expect(t, 3, len(details[1].PeriodLengths()), "period lengths")
+
+	details, err = store.Games().DetailsByGames(nil)
+	test.NoError(t, err, "no games")
+	test.Expect(t, 0, len(details), "nothing asked for")
+}
@@ -0,0 +1,349 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package local
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+/**
+ *
+ * Versioned schema migrations. Each one runs in its own transaction and is
+ * recorded in 'schema_migrations', so a database is only ever moved forward
+ * from wherever it was left. Append new migrations; never edit applied ones.
+ *
+ **/
+
+type Migration struct {
+	Version int
+	Name    string
+
+	statements []string
+}
+
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "base tables",
+		statements: []string{`
+			CREATE TABLE IF NOT EXISTS facilities (
+				id INTEGER PRIMARY KEY,
+				name TEXT NOT NULL
+			)
+		`, `
+			CREATE TABLE IF NOT EXISTS players (
+				id INTEGER PRIMARY KEY,
+				team INTEGER,
+				name TEXT NOT NULL,
+				number INT,
+				UNIQUE(team, number)
+			)
+		`, `
+			CREATE TABLE IF NOT EXISTS staff (
+				id INTEGER PRIMARY KEY,
+				team INTEGER,
+				name TEXT NOT NULL,
+				role TEXT NOT NULL
+			)
+		`},
+	},
+	{
+		Version: 2,
+		Name:    "facility location",
+		statements: []string{
+			`ALTER TABLE facilities ADD COLUMN address TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE facilities ADD COLUMN city TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE facilities ADD COLUMN state TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		Version: 3,
+		Name:    "users and clients",
+		statements: []string{`
+			CREATE TABLE users (
+				id INTEGER PRIMARY KEY,
+				email TEXT NOT NULL UNIQUE COLLATE NOCASE,
+				password_hash TEXT NOT NULL,
+				created INTEGER NOT NULL
+			)
+		`, `
+			CREATE TABLE clients (
+				id TEXT PRIMARY KEY,
+				name TEXT NOT NULL,
+				secret_hash TEXT NOT NULL DEFAULT '',
+				redirect_uris TEXT NOT NULL
+			)
+		`},
+	},
+	{
+		Version: 4,
+		Name:    "teams and external ids",
+		statements: []string{`
+			CREATE TABLE teams (
+				id INTEGER PRIMARY KEY,
+				external_id TEXT,
+				name TEXT NOT NULL
+			)
+		`,
+			`CREATE UNIQUE INDEX teams_external_id ON teams (external_id)`,
+			`ALTER TABLE facilities ADD COLUMN external_id TEXT`,
+			`CREATE UNIQUE INDEX facilities_external_id ON facilities (external_id)`,
+			`ALTER TABLE players ADD COLUMN external_id TEXT`,
+			`CREATE UNIQUE INDEX players_external_id ON players (external_id)`,
+			`ALTER TABLE staff ADD COLUMN external_id TEXT`,
+			`CREATE UNIQUE INDEX staff_external_id ON staff (external_id)`,
+		},
+	},
+	{
+		Version: 5,
+		Name:    "games and review queue",
+		statements: []string{`
+			CREATE TABLE games (
+				id INTEGER PRIMARY KEY,
+				external_id TEXT,
+				tags TEXT NOT NULL DEFAULT '',
+				played INTEGER NOT NULL,
+				facility INTEGER NOT NULL DEFAULT 0,
+				home INTEGER NOT NULL,
+				visitor INTEGER NOT NULL,
+				home_score INT NOT NULL,
+				visitor_score INT NOT NULL,
+				period_lengths TEXT NOT NULL DEFAULT ''
+			)
+		`,
+			`CREATE UNIQUE INDEX games_external_id ON games (external_id)`,
+			`CREATE INDEX games_home ON games (home)`,
+			`CREATE INDEX games_visitor ON games (visitor)`,
+			`
+			CREATE TABLE game_goals (
+				game INTEGER NOT NULL,
+				idx INT NOT NULL,
+				time INT NOT NULL,
+				team INTEGER NOT NULL,
+				scorer INTEGER NOT NULL DEFAULT 0,
+				primary_assist INTEGER NOT NULL DEFAULT 0,
+				secondary_assist INTEGER NOT NULL DEFAULT 0,
+				on_ice TEXT NOT NULL DEFAULT '',
+				defenders TEXT NOT NULL DEFAULT '',
+				PRIMARY KEY (game, idx)
+			)
+		`, `
+			CREATE TABLE game_penalties (
+				game INTEGER NOT NULL,
+				idx INT NOT NULL,
+				time INT NOT NULL,
+				team INTEGER NOT NULL,
+				committer INTEGER NOT NULL DEFAULT 0,
+				minutes INT NOT NULL,
+				infraction TEXT NOT NULL DEFAULT '',
+				served_by INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY (game, idx)
+			)
+		`, `
+			CREATE TABLE review_queue (
+				id INTEGER PRIMARY KEY,
+				game INTEGER NOT NULL,
+				event TEXT NOT NULL,
+				idx INT NOT NULL,
+				role TEXT NOT NULL,
+				team INTEGER NOT NULL,
+				number INT NOT NULL,
+				reason TEXT NOT NULL,
+				status TEXT NOT NULL,
+				player INTEGER NOT NULL DEFAULT 0
+			)
+		`,
+			`CREATE INDEX review_queue_status ON review_queue (status, id)`,
+			`CREATE INDEX review_queue_game ON review_queue (game)`,
+		},
+	},
+	{
+		Version: 6,
+		Name:    "per-game player stats",
+		statements: []string{`
+			CREATE TABLE player_game_stats (
+				game INTEGER NOT NULL,
+				player INTEGER NOT NULL,
+				team INTEGER NOT NULL,
+				goals INT NOT NULL,
+				assists INT NOT NULL,
+				pim INT NOT NULL,
+				ppg INT NOT NULL,
+				shg INT NOT NULL,
+				gwg INT NOT NULL,
+				plus_minus INT NOT NULL,
+				PRIMARY KEY (game, player)
+			)
+		`,
+			`CREATE INDEX player_game_stats_player ON player_game_stats (player)`,
+		},
+	},
+	{
+		Version: 7,
+		Name:    "leagues, seasons and divisions",
+		statements: []string{`
+			CREATE TABLE leagues (
+				id INTEGER PRIMARY KEY,
+				external_id TEXT,
+				name TEXT NOT NULL
+			)
+		`,
+			`CREATE UNIQUE INDEX leagues_external_id ON leagues (external_id)`,
+			`
+			CREATE TABLE seasons (
+				id INTEGER PRIMARY KEY,
+				external_id TEXT,
+				league INTEGER NOT NULL,
+				name TEXT NOT NULL,
+				start INTEGER NOT NULL DEFAULT 0,
+				end INTEGER NOT NULL DEFAULT 0
+			)
+		`,
+			`CREATE UNIQUE INDEX seasons_external_id ON seasons (external_id)`,
+			`CREATE INDEX seasons_league ON seasons (league)`,
+			`
+			CREATE TABLE divisions (
+				id INTEGER PRIMARY KEY,
+				external_id TEXT,
+				league INTEGER NOT NULL,
+				name TEXT NOT NULL
+			)
+		`,
+			`CREATE UNIQUE INDEX divisions_external_id ON divisions (external_id)`,
+			`CREATE INDEX divisions_league ON divisions (league)`,
+			`
+			CREATE TABLE season_teams (
+				season INTEGER NOT NULL,
+				team INTEGER NOT NULL,
+				division INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY (season, team)
+			)
+		`,
+			`CREATE INDEX season_teams_team ON season_teams (team)`,
+			`ALTER TABLE games ADD COLUMN season INTEGER`,
+			`CREATE INDEX games_season ON games (season)`,
+		},
+	},
+	{
+		// Existing games get the rosters as they stand now; run a stats
+		// rebuild afterwards to give the dressed players their games.
+		Version: 8,
+		Name:    "game lineups",
+		statements: []string{`
+			CREATE TABLE game_lineups (
+				game INTEGER NOT NULL,
+				player INTEGER NOT NULL,
+				team INTEGER NOT NULL,
+				PRIMARY KEY (game, player)
+			)
+		`,
+			`CREATE INDEX game_lineups_player ON game_lineups (player)`,
+			`
+			INSERT INTO game_lineups (game, player, team)
+				SELECT g.id, p.id, p.team FROM games g JOIN players p ON p.team IN (g.home, g.visitor)
+		`,
+		},
+	},
+}
+
+const (
+	kMigrationsTableCreate = `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied INTEGER NOT NULL
+		)
+	`
+
+	kMigrationsVersionQuery = `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`
+	kMigrationsRecordQuery  = `INSERT INTO schema_migrations (version, name, applied) VALUES (?, ?, ?)`
+)
+
+func SchemaVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// Applies every pending migration to the database in dataFile and returns
+// the ones applied. With dryRun set, nothing is changed and the pending
+// migrations are returned instead.
+func Migrate(dataFile string, dryRun bool) ([]Migration, error) {
+	db, err := sql.Open("sqlite3", dataFile)
+	if err != nil {
+		return nil, fmt.Errorf("[local.Migrate] failed to open database file - %w", err)
+	}
+	defer db.Close()
+
+	return migrate(db, dryRun)
+}
+
+func migrate(db *sql.DB, dryRun bool) ([]Migration, error) {
+	if _, err := db.Exec(kMigrationsTableCreate); err != nil {
+		return nil, fmt.Errorf("[migrate] failed to create 'schema_migrations' table - %w", err)
+	}
+
+	var current int
+	if err := db.QueryRow(kMigrationsVersionQuery).Scan(&current); err != nil {
+		return nil, fmt.Errorf("[migrate] failed to read schema version - %w", err)
+	}
+
+	pending := []Migration{}
+	for _, m := range migrations {
+		if m.Version > current {
+			pending = append(pending, m)
+		}
+	}
+
+	if dryRun {
+		return pending, nil
+	}
+
+	for i, m := range pending {
+		if err := applyMigration(db, m); err != nil {
+			return pending[:i], err
+		}
+	}
+
+	return pending, nil
+}
+
+func applyMigration(db *sql.DB, m Migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range m.statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("[migrate] migration %d (%s) failed - %w", m.Version, m.Name, err)
+		}
+	}
+
+	if _, err := tx.Exec(kMigrationsRecordQuery, m.Version, m.Name, time.Now().Unix()); err != nil {
+		return fmt.Errorf("[migrate] failed to record migration %d - %w", m.Version, err)
+	}
+
+	return tx.Commit()
+}
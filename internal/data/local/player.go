@@ -25,60 +25,80 @@ package local
 import (
 	"database/sql"
 	"errors"
-	"fmt"
+	"time"
 
 	"shiftylogic.dev/hockey-tools/internal/data"
 )
 
 const (
 	kFetchPlayersQuery = `
-		SELECT id, team, name, number FROM players
+		SELECT id, COALESCE(external_id, ''), team, name, number FROM players
 			WHERE id > ?
 			ORDER BY id ASC
 			LIMIT 100
 	`
 
 	kFetchPlayerQuery = `
-		SELECT id, team, name, number FROM players
+		SELECT id, COALESCE(external_id, ''), team, name, number FROM players
 			WHERE id = ?
 	`
 
+	kFetchPlayerExternalQuery = `
+		SELECT id, COALESCE(external_id, ''), team, name, number FROM players
+			WHERE external_id = ?
+	`
+
 	kFetchPlayersTeamQuery = `
-		SELECT id, team, name, number FROM players
+		SELECT id, COALESCE(external_id, ''), team, name, number FROM players
 			WHERE team = ?
 	`
+
+	kSavePlayerQuery = `
+		INSERT INTO players (id, external_id, team, name, number)
+			VALUES (NULLIF(?, 0), NULLIF(?, ''), ?, ?, ?)
+			ON CONFLICT(id) DO UPDATE SET
+				external_id = excluded.external_id,
+				team = excluded.team,
+				name = excluded.name,
+				number = excluded.number
+	`
 )
 
 type player struct {
-	id     int64
-	team   int64
-	name   string
-	number int
+	id         int64
+	externalID string
+	team       int64
+	name       string
+	number     int
 }
 
 func (p *player) ID() data.EntityID   { return data.EntityID(p.id) }
+func (p *player) ExternalID() string  { return p.externalID }
 func (p *player) Team() data.EntityID { return data.EntityID(p.team) }
 func (p *player) Name() string        { return p.name }
 func (p *player) Number() int         { return p.number }
 
 type players struct {
-	db        *sql.DB
+	db        conn
 	fetchList *sql.Stmt
 	fetchID   *sql.Stmt
+	fetchExt  *sql.Stmt
 	fetchTeam *sql.Stmt
+	save      *sql.Stmt
 }
 
-func newPlayers(db *sql.DB) (*players, error) {
-	if err := ensurePlayers(db); err != nil {
+func newPlayers(db conn) (*players, error) {
+	fetchList, err := db.Prepare(kFetchPlayersQuery)
+	if err != nil {
 		return nil, err
 	}
 
-	fetchList, err := db.Prepare(kFetchPlayersQuery)
+	fetchID, err := db.Prepare(kFetchPlayerQuery)
 	if err != nil {
 		return nil, err
 	}
 
-	fetchID, err := db.Prepare(kFetchPlayerQuery)
+	fetchExt, err := db.Prepare(kFetchPlayerExternalQuery)
 	if err != nil {
 		return nil, err
 	}
@@ -88,24 +108,34 @@ func newPlayers(db *sql.DB) (*players, error) {
 		return nil, err
 	}
 
+	save, err := db.Prepare(kSavePlayerQuery)
+	if err != nil {
+		return nil, err
+	}
+
 	return &players{
 		db,
 		fetchList,
 		fetchID,
+		fetchExt,
 		fetchTeam,
+		save,
 	}, nil
 }
 
 func (p *players) List(token int64) ([]data.Player, int64, error) {
+	defer observeQuery("players.list", time.Now())
+
 	rows, err := p.fetchList.Query(token)
 	if err != nil {
 		return nil, -1, err
 	}
+	defer rows.Close()
 
 	data := []data.Player{}
 	for rows.Next() {
 		np := &player{}
-		err = rows.Scan(&np.id, &np.team, &np.name, &np.number)
+		err = rows.Scan(&np.id, &np.externalID, &np.team, &np.name, &np.number)
 		if err != nil {
 			return nil, token, err
 		}
@@ -114,34 +144,39 @@ func (p *players) List(token int64) ([]data.Player, int64, error) {
 		data = append(data, np)
 	}
 
-	return data, token, nil
+	return data, token, rows.Err()
 }
 
 func (p *players) ByID(id data.EntityID) (data.Player, error) {
+	defer observeQuery("players.byID", time.Now())
+
 	ret := &player{}
 
-	err := p.fetchID.QueryRow(id).Scan(&ret.id, &ret.team, &ret.name, &ret.number)
+	err := p.fetchID.QueryRow(id).Scan(&ret.id, &ret.externalID, &ret.team, &ret.name, &ret.number)
 	if err == nil {
 		return ret, nil
 	}
 
 	if errors.Is(err, sql.ErrNoRows) {
-		return nil, err
+		return nil, data.ErrorUnknownPlayerID
 	}
 
-	return nil, data.ErrorUnknownPlayerID
+	return nil, err
 }
 
 func (p *players) ByTeam(team data.EntityID) ([]data.Player, error) {
+	defer observeQuery("players.byTeam", time.Now())
+
 	rows, err := p.fetchTeam.Query(team)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	data := []data.Player{}
 	for rows.Next() {
 		np := &player{}
-		err = rows.Scan(&np.id, &np.team, &np.name, &np.number)
+		err = rows.Scan(&np.id, &np.externalID, &np.team, &np.name, &np.number)
 		if err != nil {
 			return nil, err
 		}
@@ -149,32 +184,28 @@ func (p *players) ByTeam(team data.EntityID) ([]data.Player, error) {
 		data = append(data, np)
 	}
 
-	return data, nil
-
+	return data, rows.Err()
 }
 
-/**
- *
- * Functions used for manipulating 'staff' tables.
- *
- */
+func (p *players) ByExternalID(ext string) (data.Player, error) {
+	defer observeQuery("players.byExternalID", time.Now())
 
-const (
-	kPlayersTableCreate = `
-		CREATE TABLE IF NOT EXISTS players (
-			id INTEGER PRIMARY KEY,
-			team INTEGER,
-			name TEXT NOT NULL,
-			number INT,
-			UNIQUE(team, number)
-		)
-	`
-)
+	ret := &player{}
 
-func ensurePlayers(db *sql.DB) error {
-	if _, err := db.Exec(kPlayersTableCreate); err != nil {
-		return fmt.Errorf("[ensurePlayers] failed to create 'players' table - %w", err)
+	err := p.fetchExt.QueryRow(ext).Scan(&ret.id, &ret.externalID, &ret.team, &ret.name, &ret.number)
+	if err == nil {
+		return ret, nil
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, data.ErrorUnknownPlayerID
 	}
 
-	return nil
+	return nil, err
+}
+
+func (p *players) Save(v data.Player) (data.EntityID, error) {
+	defer observeQuery("players.save", time.Now())
+
+	return saveRow(p.save, v.ID(), v.ExternalID(), v.Team(), v.Name(), v.Number())
 }
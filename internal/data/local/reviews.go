@@ -0,0 +1,303 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package local
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/data"
+)
+
+const (
+	kReviewColumns = `id, game, event, idx, role, team, number, reason, status, player`
+
+	kFetchOpenReviewsQuery = `
+		SELECT ` + kReviewColumns + ` FROM review_queue
+			WHERE status = 'open' AND id > ?
+			ORDER BY id ASC
+			LIMIT 100
+	`
+
+	kFetchGameReviewsQuery = `
+		SELECT ` + kReviewColumns + ` FROM review_queue
+			WHERE game = ?
+			ORDER BY id ASC
+	`
+
+	kFetchReviewQuery = `
+		SELECT ` + kReviewColumns + ` FROM review_queue
+			WHERE id = ?
+	`
+
+	kAddReviewQuery = `
+		INSERT INTO review_queue (game, event, idx, role, team, number, reason, status, player)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	kDeleteGameReviewsQuery = `DELETE FROM review_queue WHERE game = ?`
+	kCloseReviewQuery       = `UPDATE review_queue SET status = ?, player = ? WHERE id = ?`
+)
+
+// The event column each role fills in. On-ice lists get the player appended.
+var reviewColumns = map[data.EventKind]map[data.ReviewRole]string{
+	data.EventGoal: {
+		data.RoleScorer:          "scorer",
+		data.RolePrimaryAssist:   "primary_assist",
+		data.RoleSecondaryAssist: "secondary_assist",
+		data.RoleOnIce:           "on_ice",
+		data.RoleDefender:        "defenders",
+	},
+	data.EventPenalty: {
+		data.RoleCommitter: "committer",
+		data.RoleServedBy:  "served_by",
+	},
+}
+
+var reviewTables = map[data.EventKind]string{
+	data.EventGoal:    "game_goals",
+	data.EventPenalty: "game_penalties",
+}
+
+type reviewItem struct {
+	id     int64
+	game   int64
+	event  string
+	index  int
+	role   string
+	team   int64
+	number int
+	reason string
+	status string
+	player int64
+}
+
+func (r *reviewItem) ID() data.EntityID         { return data.EntityID(r.id) }
+func (r *reviewItem) Game() data.EntityID       { return data.EntityID(r.game) }
+func (r *reviewItem) Event() data.EventKind     { return data.EventKind(r.event) }
+func (r *reviewItem) Index() int                { return r.index }
+func (r *reviewItem) Role() data.ReviewRole     { return data.ReviewRole(r.role) }
+func (r *reviewItem) Team() data.EntityID       { return data.EntityID(r.team) }
+func (r *reviewItem) Number() int               { return r.number }
+func (r *reviewItem) Reason() string            { return r.reason }
+func (r *reviewItem) Status() data.ReviewStatus { return data.ReviewStatus(r.status) }
+func (r *reviewItem) Player() data.EntityID     { return data.EntityID(r.player) }
+
+func scanReview(row interface{ Scan(...any) error }) (*reviewItem, error) {
+	r := &reviewItem{}
+	err := row.Scan(&r.id, &r.game, &r.event, &r.index, &r.role, &r.team, &r.number, &r.reason, &r.status, &r.player)
+	return r, err
+}
+
+type reviews struct {
+	db        conn
+	games     *games
+	fetchOpen *sql.Stmt
+	fetchGame *sql.Stmt
+	add       *sql.Stmt
+}
+
+func newReviews(db conn, games *games) (*reviews, error) {
+	fetchOpen, err := db.Prepare(kFetchOpenReviewsQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchGame, err := db.Prepare(kFetchGameReviewsQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	add, err := db.Prepare(kAddReviewQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	return &reviews{
+		db,
+		games,
+		fetchOpen,
+		fetchGame,
+		add,
+	}, nil
+}
+
+func (r *reviews) Open(token int64) ([]data.ReviewItem, int64, error) {
+	defer observeQuery("reviews.open", time.Now())
+
+	rows, err := r.fetchOpen.Query(token)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer rows.Close()
+
+	data := []data.ReviewItem{}
+	for rows.Next() {
+		item, err := scanReview(rows)
+		if err != nil {
+			return nil, token, err
+		}
+
+		token = item.id
+		data = append(data, item)
+	}
+
+	return data, token, rows.Err()
+}
+
+func (r *reviews) ByGame(game data.EntityID) ([]data.ReviewItem, error) {
+	defer observeQuery("reviews.byGame", time.Now())
+
+	rows, err := r.fetchGame.Query(game)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	data := []data.ReviewItem{}
+	for rows.Next() {
+		item, err := scanReview(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		data = append(data, item)
+	}
+
+	return data, rows.Err()
+}
+
+func (r *reviews) Add(item data.ReviewItem) (data.EntityID, error) {
+	defer observeQuery("reviews.add", time.Now())
+
+	if _, ok := reviewColumns[item.Event()][item.Role()]; !ok {
+		return 0, fmt.Errorf("[reviews.Add] no '%s' role on a %s", item.Role(), item.Event())
+	}
+
+	res, err := r.add.Exec(
+		item.Game(), item.Event(), item.Index(), item.Role(), item.Team(),
+		item.Number(), item.Reason(), item.Status(), item.Player())
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	return data.EntityID(id), err
+}
+
+func (r *reviews) DeleteByGame(game data.EntityID) error {
+	defer observeQuery("reviews.deleteByGame", time.Now())
+
+	_, err := r.db.Exec(kDeleteGameReviewsQuery, game)
+	return err
+}
+
+func (r *reviews) Resolve(id, player data.EntityID) error {
+	defer observeQuery("reviews.resolve", time.Now())
+
+	return r.close(id, data.ReviewResolved, player)
+}
+
+func (r *reviews) Dismiss(id data.EntityID) error {
+	defer observeQuery("reviews.dismiss", time.Now())
+
+	return r.close(id, data.ReviewDismissed, 0)
+}
+
+func (r *reviews) close(id data.EntityID, status data.ReviewStatus, player data.EntityID) error {
+	tx, err := begin(r.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	item, err := scanReview(tx.QueryRow(kFetchReviewQuery, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return data.ErrorUnknownReviewID
+	} else if err != nil {
+		return err
+	}
+
+	if item.Status() != data.ReviewOpen {
+		return data.ErrorReviewClosed
+	}
+
+	if status == data.ReviewResolved {
+		if err := fillEvent(tx.Tx, item, player); err != nil {
+			return err
+		}
+
+		if err := r.refreshStats(tx.Tx, item.Game()); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(kCloseReviewQuery, status, player, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Column and table names only ever come from the fixed maps above
+func fillEvent(tx *sql.Tx, item *reviewItem, player data.EntityID) error {
+	table := reviewTables[item.Event()]
+	column := reviewColumns[item.Event()][item.Role()]
+
+	where := ` WHERE game = ? AND idx = ?`
+
+	value := any(player)
+	if item.Role() == data.RoleOnIce || item.Role() == data.RoleDefender {
+		var list string
+		if err := tx.QueryRow(`SELECT `+column+` FROM `+table+where, item.game, item.index).Scan(&list); err != nil {
+			return err
+		}
+
+		value = encodeIDs(append(decodeIDs(list), player))
+	}
+
+	res, err := tx.Exec(`UPDATE `+table+` SET `+column+` = ?`+where, value, item.game, item.index)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return fmt.Errorf("[reviews.Resolve] %s %d of game %d no longer exists", item.event, item.index, item.game)
+	}
+
+	return nil
+}
+
+// The resolved player is now on the sheet, so the recompute gives them a line
+func (r *reviews) refreshStats(tx *sql.Tx, game data.EntityID) error {
+	details, err := r.games.details(tx, game)
+	if err != nil {
+		return err
+	}
+
+	return refreshGameStats(tx, details)
+}
@@ -0,0 +1,344 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package local
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/data"
+)
+
+const (
+	kSeasonColumns = `id, COALESCE(external_id, ''), league, name, start, end`
+
+	kFetchSeasonsQuery = `
+		SELECT ` + kSeasonColumns + ` FROM seasons
+			WHERE id > ?
+			ORDER BY id ASC
+			LIMIT 100
+	`
+
+	kFetchSeasonQuery = `
+		SELECT ` + kSeasonColumns + ` FROM seasons
+			WHERE id = ?
+	`
+
+	kFetchSeasonExternalQuery = `
+		SELECT ` + kSeasonColumns + ` FROM seasons
+			WHERE external_id = ?
+	`
+
+	kFetchSeasonsLeagueQuery = `
+		SELECT ` + kSeasonColumns + ` FROM seasons
+			WHERE league = ?
+			ORDER BY start ASC, id ASC
+	`
+
+	kSaveSeasonQuery = `
+		INSERT INTO seasons (id, external_id, league, name, start, end)
+			VALUES (NULLIF(?, 0), NULLIF(?, ''), ?, ?, ?, ?)
+			ON CONFLICT(id) DO UPDATE SET
+				external_id = excluded.external_id,
+				league = excluded.league,
+				name = excluded.name,
+				start = excluded.start,
+				end = excluded.end
+	`
+
+	kFetchSeasonTeamsQuery = `
+		SELECT season, team, division FROM season_teams
+			WHERE season = ?
+			ORDER BY division ASC, team ASC
+	`
+
+	kFetchTeamSeasonsQuery = `
+		SELECT st.season, st.team, st.division FROM season_teams st
+			JOIN seasons s ON s.id = st.season
+			WHERE st.team = ?
+			ORDER BY s.start ASC, s.id ASC
+	`
+
+	kSetSeasonTeamQuery = `
+		INSERT INTO season_teams (season, team, division)
+			VALUES (?, ?, ?)
+			ON CONFLICT(season, team) DO UPDATE SET
+				division = excluded.division
+	`
+
+	kRemoveSeasonTeamQuery = `DELETE FROM season_teams WHERE season = ? AND team = ?`
+)
+
+type season struct {
+	id         int64
+	externalID string
+	league     int64
+	name       string
+	start      int64
+	end        int64
+}
+
+func (s *season) ID() data.EntityID     { return data.EntityID(s.id) }
+func (s *season) ExternalID() string    { return s.externalID }
+func (s *season) League() data.EntityID { return data.EntityID(s.league) }
+func (s *season) Name() string          { return s.name }
+func (s *season) Start() time.Time      { return fromUnix(s.start) }
+func (s *season) End() time.Time        { return fromUnix(s.end) }
+
+func scanSeason(row interface{ Scan(...any) error }) (*season, error) {
+	s := &season{}
+	return s, row.Scan(&s.id, &s.externalID, &s.league, &s.name, &s.start, &s.end)
+}
+
+type membership struct {
+	season   int64
+	team     int64
+	division int64
+}
+
+func (m *membership) Season() data.EntityID   { return data.EntityID(m.season) }
+func (m *membership) Team() data.EntityID     { return data.EntityID(m.team) }
+func (m *membership) Division() data.EntityID { return data.EntityID(m.division) }
+
+type seasons struct {
+	db          conn
+	fetchList   *sql.Stmt
+	fetchID     *sql.Stmt
+	fetchExt    *sql.Stmt
+	fetchLeague *sql.Stmt
+	save        *sql.Stmt
+	fetchTeams  *sql.Stmt
+	fetchByTeam *sql.Stmt
+	setTeam     *sql.Stmt
+	removeTeam  *sql.Stmt
+}
+
+func newSeasons(db conn) (*seasons, error) {
+	fetchList, err := db.Prepare(kFetchSeasonsQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchID, err := db.Prepare(kFetchSeasonQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchExt, err := db.Prepare(kFetchSeasonExternalQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchLeague, err := db.Prepare(kFetchSeasonsLeagueQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	save, err := db.Prepare(kSaveSeasonQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchTeams, err := db.Prepare(kFetchSeasonTeamsQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchByTeam, err := db.Prepare(kFetchTeamSeasonsQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	setTeam, err := db.Prepare(kSetSeasonTeamQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	removeTeam, err := db.Prepare(kRemoveSeasonTeamQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	return &seasons{
+		db,
+		fetchList,
+		fetchID,
+		fetchExt,
+		fetchLeague,
+		save,
+		fetchTeams,
+		fetchByTeam,
+		setTeam,
+		removeTeam,
+	}, nil
+}
+
+func (s *seasons) List(token int64) ([]data.Season, int64, error) {
+	defer observeQuery("seasons.list", time.Now())
+
+	rows, err := s.fetchList.Query(token)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer rows.Close()
+
+	data := []data.Season{}
+	for rows.Next() {
+		ns, err := scanSeason(rows)
+		if err != nil {
+			return nil, token, err
+		}
+
+		token = ns.id
+		data = append(data, ns)
+	}
+
+	return data, token, rows.Err()
+}
+
+func (s *seasons) ByID(id data.EntityID) (data.Season, error) {
+	defer observeQuery("seasons.byID", time.Now())
+
+	return fetchSeason(s.fetchID, id)
+}
+
+func (s *seasons) ByExternalID(ext string) (data.Season, error) {
+	defer observeQuery("seasons.byExternalID", time.Now())
+
+	return fetchSeason(s.fetchExt, ext)
+}
+
+func fetchSeason(stmt *sql.Stmt, arg any) (data.Season, error) {
+	ret, err := scanSeason(stmt.QueryRow(arg))
+	if err == nil {
+		return ret, nil
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, data.ErrorUnknownSeasonID
+	}
+
+	return nil, err
+}
+
+func (s *seasons) ByLeague(league data.EntityID) ([]data.Season, error) {
+	defer observeQuery("seasons.byLeague", time.Now())
+
+	rows, err := s.fetchLeague.Query(league)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	data := []data.Season{}
+	for rows.Next() {
+		ns, err := scanSeason(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		data = append(data, ns)
+	}
+
+	return data, rows.Err()
+}
+
+func (s *seasons) Save(v data.Season) (data.EntityID, error) {
+	defer observeQuery("seasons.save", time.Now())
+
+	return saveRow(s.save, v.ID(), v.ExternalID(), v.League(), v.Name(), toUnix(v.Start()), toUnix(v.End()))
+}
+
+func (s *seasons) Teams(season data.EntityID) ([]data.Membership, error) {
+	defer observeQuery("seasons.teams", time.Now())
+
+	return queryMemberships(s.fetchTeams, season)
+}
+
+func (s *seasons) TeamSeasons(team data.EntityID) ([]data.Membership, error) {
+	defer observeQuery("seasons.teamSeasons", time.Now())
+
+	return queryMemberships(s.fetchByTeam, team)
+}
+
+func queryMemberships(stmt *sql.Stmt, arg any) ([]data.Membership, error) {
+	rows, err := stmt.Query(arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	data := []data.Membership{}
+	for rows.Next() {
+		m := &membership{}
+		if err := rows.Scan(&m.season, &m.team, &m.division); err != nil {
+			return nil, err
+		}
+
+		data = append(data, m)
+	}
+
+	return data, rows.Err()
+}
+
+func (s *seasons) SetTeam(m data.Membership) error {
+	defer observeQuery("seasons.setTeam", time.Now())
+
+	_, err := s.setTeam.Exec(m.Season(), m.Team(), m.Division())
+	return err
+}
+
+func (s *seasons) RemoveTeam(season, team data.EntityID) error {
+	defer observeQuery("seasons.removeTeam", time.Now())
+
+	res, err := s.removeTeam.Exec(season, team)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return data.ErrorNotInSeason
+	}
+
+	return nil
+}
+
+// Unknown dates are stored as 0
+func toUnix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.Unix()
+}
+
+func fromUnix(secs int64) time.Time {
+	if secs == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(secs, 0)
+}
@@ -25,60 +25,80 @@ package local
 import (
 	"database/sql"
 	"errors"
-	"fmt"
+	"time"
 
 	"shiftylogic.dev/hockey-tools/internal/data"
 )
 
 const (
 	kFetchStaffQuery = `
-		SELECT id, team, name, role FROM staff
+		SELECT id, COALESCE(external_id, ''), team, name, role FROM staff
 			WHERE id > ?
 			ORDER BY id ASC
 			LIMIT 100
 	`
 
 	kFetchStaffMemberQuery = `
-		SELECT id, team, name, role FROM staff
+		SELECT id, COALESCE(external_id, ''), team, name, role FROM staff
 			WHERE id = ?
 	`
 
+	kFetchStaffMemberExternalQuery = `
+		SELECT id, COALESCE(external_id, ''), team, name, role FROM staff
+			WHERE external_id = ?
+	`
+
 	kFetchStaffTeamQuery = `
-		SELECT id, team, name, role FROM staff
+		SELECT id, COALESCE(external_id, ''), team, name, role FROM staff
 			WHERE team = ?
 	`
+
+	kSaveStaffMemberQuery = `
+		INSERT INTO staff (id, external_id, team, name, role)
+			VALUES (NULLIF(?, 0), NULLIF(?, ''), ?, ?, ?)
+			ON CONFLICT(id) DO UPDATE SET
+				external_id = excluded.external_id,
+				team = excluded.team,
+				name = excluded.name,
+				role = excluded.role
+	`
 )
 
 type staffMember struct {
-	id   int64
-	team int64
-	name string
-	role string
+	id         int64
+	externalID string
+	team       int64
+	name       string
+	role       string
 }
 
 func (sm *staffMember) ID() data.EntityID   { return data.EntityID(sm.id) }
+func (sm *staffMember) ExternalID() string  { return sm.externalID }
 func (sm *staffMember) Team() data.EntityID { return data.EntityID(sm.team) }
 func (sm *staffMember) Name() string        { return sm.name }
-func (sm *staffMember) Role() string        { return sm.name }
+func (sm *staffMember) Role() string        { return sm.role }
 
 type staff struct {
-	db        *sql.DB
+	db        conn
 	fetchList *sql.Stmt
 	fetchID   *sql.Stmt
+	fetchExt  *sql.Stmt
 	fetchTeam *sql.Stmt
+	save      *sql.Stmt
 }
 
-func newStaff(db *sql.DB) (*staff, error) {
-	if err := ensureStaff(db); err != nil {
+func newStaff(db conn) (*staff, error) {
+	fetchList, err := db.Prepare(kFetchStaffQuery)
+	if err != nil {
 		return nil, err
 	}
 
-	fetchList, err := db.Prepare(kFetchStaffQuery)
+	fetchID, err := db.Prepare(kFetchStaffMemberQuery)
 	if err != nil {
 		return nil, err
 	}
 
-	fetchID, err := db.Prepare(kFetchStaffMemberQuery)
+	fetchExt, err := db.Prepare(kFetchStaffMemberExternalQuery)
 	if err != nil {
 		return nil, err
 	}
@@ -88,24 +108,34 @@ func newStaff(db *sql.DB) (*staff, error) {
 		return nil, err
 	}
 
+	save, err := db.Prepare(kSaveStaffMemberQuery)
+	if err != nil {
+		return nil, err
+	}
+
 	return &staff{
 		db,
 		fetchList,
 		fetchID,
+		fetchExt,
 		fetchTeam,
+		save,
 	}, nil
 }
 
 func (s *staff) List(token int64) ([]data.StaffMember, int64, error) {
+	defer observeQuery("staff.list", time.Now())
+
 	rows, err := s.fetchList.Query(token)
 	if err != nil {
 		return nil, -1, err
 	}
+	defer rows.Close()
 
 	data := []data.StaffMember{}
 	for rows.Next() {
 		sm := &staffMember{}
-		err = rows.Scan(&sm.id, &sm.team, &sm.name, &sm.role)
+		err = rows.Scan(&sm.id, &sm.externalID, &sm.team, &sm.name, &sm.role)
 		if err != nil {
 			return nil, token, err
 		}
@@ -114,34 +144,39 @@ func (s *staff) List(token int64) ([]data.StaffMember, int64, error) {
 		data = append(data, sm)
 	}
 
-	return data, token, nil
+	return data, token, rows.Err()
 }
 
 func (s *staff) ByID(id data.EntityID) (data.StaffMember, error) {
+	defer observeQuery("staff.byID", time.Now())
+
 	ret := &staffMember{}
 
-	err := s.fetchID.QueryRow(id).Scan(&ret.id, &ret.team, &ret.name, &ret.role)
+	err := s.fetchID.QueryRow(id).Scan(&ret.id, &ret.externalID, &ret.team, &ret.name, &ret.role)
 	if err == nil {
 		return ret, nil
 	}
 
 	if errors.Is(err, sql.ErrNoRows) {
-		return nil, err
+		return nil, data.ErrorUnknownStaffID
 	}
 
-	return nil, data.ErrorUnknownStaffID
+	return nil, err
 }
 
 func (s *staff) ByTeam(team data.EntityID) ([]data.StaffMember, error) {
+	defer observeQuery("staff.byTeam", time.Now())
+
 	rows, err := s.fetchTeam.Query(team)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	data := []data.StaffMember{}
 	for rows.Next() {
 		sm := &staffMember{}
-		err = rows.Scan(&sm.id, &sm.team, &sm.name, &sm.role)
+		err = rows.Scan(&sm.id, &sm.externalID, &sm.team, &sm.name, &sm.role)
 		if err != nil {
 			return nil, err
 		}
@@ -149,31 +184,28 @@ func (s *staff) ByTeam(team data.EntityID) ([]data.StaffMember, error) {
 		data = append(data, sm)
 	}
 
-	return data, nil
-
+	return data, rows.Err()
 }
 
-/**
- *
- * Functions used for manipulating 'staff' tables.
- *
- */
+func (s *staff) ByExternalID(ext string) (data.StaffMember, error) {
+	defer observeQuery("staff.byExternalID", time.Now())
 
-const (
-	kStaffTableCreate = `
-		CREATE TABLE IF NOT EXISTS staff (
-			id INTEGER PRIMARY KEY,
-			team INTEGER,
-			name TEXT NOT NULL,
-			role TEXT NOT NULL
-		)
-	`
-)
+	ret := &staffMember{}
 
-func ensureStaff(db *sql.DB) error {
-	if _, err := db.Exec(kStaffTableCreate); err != nil {
-		return fmt.Errorf("[ensureStaff] failed to create 'staff' table - %w", err)
+	err := s.fetchExt.QueryRow(ext).Scan(&ret.id, &ret.externalID, &ret.team, &ret.name, &ret.role)
+	if err == nil {
+		return ret, nil
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, data.ErrorUnknownStaffID
 	}
 
-	return nil
+	return nil, err
+}
+
+func (s *staff) Save(v data.StaffMember) (data.EntityID, error) {
+	defer observeQuery("staff.save", time.Now())
+
+	return saveRow(s.save, v.ID(), v.ExternalID(), v.Team(), v.Name(), v.Role())
 }
@@ -0,0 +1,247 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package local
+
+import (
+	"database/sql"
+	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/data"
+	"shiftylogic.dev/hockey-tools/internal/data/stats"
+)
+
+/**
+ *
+ * Player stats are cached one line per player per game, recomputed in the
+ * same transaction as anything that changes the game. Queries only have to
+ * sum lines (and walk them in order for streaks).
+ *
+ **/
+
+const (
+	kDeleteGameStatsQuery = `DELETE FROM player_game_stats WHERE game = ?`
+
+	kInsertGameStatsQuery = `
+		INSERT INTO player_game_stats (game, player, team, goals, assists, pim, ppg, shg, gwg, plus_minus)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	// A team's lineup is taken from its roster the first time the game is
+	// saved with it, and kept from then on
+	kDropGameLineupQuery = `DELETE FROM game_lineups WHERE game = ?1 AND team NOT IN (?2, ?3)`
+
+	kSnapshotGameLineupQuery = `
+		INSERT INTO game_lineups (game, player, team)
+			SELECT ?1, id, team FROM players
+				WHERE team = ?2 AND NOT EXISTS (SELECT 1 FROM game_lineups WHERE game = ?1 AND team = ?2)
+	`
+
+	kFetchGameLineupQuery = `SELECT player, team FROM game_lineups WHERE game = ?`
+
+	kFetchStatLinesQuery = `
+		SELECT s.game, s.player, s.team, s.goals, s.assists, s.pim, s.ppg, s.shg, s.gwg, s.plus_minus, g.played
+			FROM player_game_stats s JOIN games g ON g.id = s.game
+			WHERE (?1 = 0 OR g.played >= ?1)
+				AND (?2 = 0 OR g.played < ?2)
+				AND (?3 = '' OR (',' || g.tags || ',') LIKE ('%,' || ?3 || ',%'))
+				AND (?4 = 0 OR s.team = ?4)
+				AND (?5 = 0 OR s.player = ?5)
+				AND (?6 = 0 OR g.season = ?6)
+	`
+
+	kFetchAllGameIDsQuery = `SELECT id FROM games ORDER BY id`
+)
+
+type playerStats struct {
+	db         conn
+	games      *games
+	fetchLines *sql.Stmt
+}
+
+func newPlayerStats(db conn, games *games) (*playerStats, error) {
+	fetchLines, err := db.Prepare(kFetchStatLinesQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	return &playerStats{
+		db,
+		games,
+		fetchLines,
+	}, nil
+}
+
+func (s *playerStats) Players(scope data.StatsScope) ([]data.PlayerStats, error) {
+	defer observeQuery("stats.players", time.Now())
+
+	return s.aggregate(scope, 0)
+}
+
+func (s *playerStats) Player(player data.EntityID, scope data.StatsScope) (data.PlayerStats, error) {
+	defer observeQuery("stats.player", time.Now())
+
+	totals, err := s.aggregate(scope, player)
+	if err != nil || len(totals) == 0 {
+		return data.PlayerStats{Player: player}, err
+	}
+
+	return totals[0], nil
+}
+
+func (s *playerStats) aggregate(scope data.StatsScope, player data.EntityID) ([]data.PlayerStats, error) {
+	rows, err := s.fetchLines.Query(toUnix(scope.From), toUnix(scope.To), scope.Tag, scope.Team, player, scope.Season)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := []stats.DatedLine{}
+	for rows.Next() {
+		var l stats.DatedLine
+		var played int64
+
+		err := rows.Scan(
+			&l.Game, &l.Player, &l.Team, &l.Goals, &l.Assists, &l.PenaltyMinutes,
+			&l.PowerPlayGoals, &l.ShortHandedGoals, &l.GameWinningGoals, &l.PlusMinus, &played)
+		if err != nil {
+			return nil, err
+		}
+
+		l.When = time.Unix(played, 0)
+		lines = append(lines, l)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return stats.Aggregate(lines), nil
+}
+
+func (s *playerStats) Rebuild() (int, error) {
+	defer observeQuery("stats.rebuild", time.Now())
+
+	ids := []data.EntityID{}
+
+	rows, err := s.db.Query(kFetchAllGameIDsQuery)
+	if err != nil {
+		return 0, err
+	}
+
+	for rows.Next() {
+		var id data.EntityID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	for i, id := range ids {
+		if err := s.rebuildGame(id); err != nil {
+			return i, err
+		}
+	}
+
+	return len(ids), nil
+}
+
+func (s *playerStats) rebuildGame(id data.EntityID) error {
+	tx, err := begin(s.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	details, err := s.games.details(tx.Tx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := refreshGameStats(tx.Tx, details); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Records who dressed for the game: each team's roster as it stood when the
+// game was first saved with them. Later trades and roster changes leave it be.
+func snapshotGameLineup(tx *sql.Tx, game data.GameOverview) error {
+	if _, err := tx.Exec(kDropGameLineupQuery, game.ID(), game.Home(), game.Visitor()); err != nil {
+		return err
+	}
+
+	for _, team := range []data.EntityID{game.Home(), game.Visitor()} {
+		if _, err := tx.Exec(kSnapshotGameLineupQuery, game.ID(), team); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Lines go to the recorded lineup plus anyone else named on the sheet, for
+// the team the sheet has them on, so every player who dressed gets a game.
+func refreshGameStats(tx *sql.Tx, details data.GameDetails) error {
+	roster := map[data.EntityID]data.EntityID{}
+
+	rows, err := tx.Query(kFetchGameLineupQuery, details.ID())
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		var player, team data.EntityID
+		if err := rows.Scan(&player, &team); err != nil {
+			rows.Close()
+			return err
+		}
+		roster[player] = team
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for player, team := range stats.SheetRoster(details) {
+		roster[player] = team
+	}
+
+	if _, err := tx.Exec(kDeleteGameStatsQuery, details.ID()); err != nil {
+		return err
+	}
+
+	for _, l := range stats.GameLines(details, roster) {
+		_, err := tx.Exec(kInsertGameStatsQuery,
+			l.Game, l.Player, l.Team, l.Goals, l.Assists, l.PenaltyMinutes,
+			l.PowerPlayGoals, l.ShortHandedGoals, l.GameWinningGoals, l.PlusMinus)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,199 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package local
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/data"
+)
+
+const (
+	kFetchTeamsQuery = `
+		SELECT id, COALESCE(external_id, ''), name FROM teams
+			WHERE id > ?
+			ORDER BY id ASC
+			LIMIT 100
+	`
+
+	kFetchTeamQuery = `
+		SELECT id, COALESCE(external_id, ''), name FROM teams
+			WHERE id = ?
+	`
+
+	kFetchTeamExternalQuery = `
+		SELECT id, COALESCE(external_id, ''), name FROM teams
+			WHERE external_id = ?
+	`
+
+	kFetchTeamsNameQuery = `
+		SELECT id, COALESCE(external_id, ''), name FROM teams
+			WHERE name = ?
+			ORDER BY id ASC
+	`
+
+	kSaveTeamQuery = `
+		INSERT INTO teams (id, external_id, name)
+			VALUES (NULLIF(?, 0), NULLIF(?, ''), ?)
+			ON CONFLICT(id) DO UPDATE SET
+				external_id = excluded.external_id,
+				name = excluded.name
+	`
+)
+
+type team struct {
+	id         int64
+	externalID string
+	name       string
+}
+
+func (t *team) ID() data.EntityID  { return data.EntityID(t.id) }
+func (t *team) ExternalID() string { return t.externalID }
+func (t *team) Name() string       { return t.name }
+
+type teams struct {
+	db        conn
+	fetchList *sql.Stmt
+	fetchID   *sql.Stmt
+	fetchExt  *sql.Stmt
+	fetchName *sql.Stmt
+	save      *sql.Stmt
+}
+
+func newTeams(db conn) (*teams, error) {
+	fetchList, err := db.Prepare(kFetchTeamsQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchID, err := db.Prepare(kFetchTeamQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchExt, err := db.Prepare(kFetchTeamExternalQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchName, err := db.Prepare(kFetchTeamsNameQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	save, err := db.Prepare(kSaveTeamQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	return &teams{
+		db,
+		fetchList,
+		fetchID,
+		fetchExt,
+		fetchName,
+		save,
+	}, nil
+}
+
+func (t *teams) List(token int64) ([]data.Team, int64, error) {
+	defer observeQuery("teams.list", time.Now())
+
+	rows, err := t.fetchList.Query(token)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer rows.Close()
+
+	data := []data.Team{}
+	for rows.Next() {
+		nt := &team{}
+		err = rows.Scan(&nt.id, &nt.externalID, &nt.name)
+		if err != nil {
+			return nil, token, err
+		}
+
+		token = nt.id
+		data = append(data, nt)
+	}
+
+	return data, token, rows.Err()
+}
+
+func (t *teams) ByID(id data.EntityID) (data.Team, error) {
+	defer observeQuery("teams.byID", time.Now())
+
+	return t.fetchOne(t.fetchID, id)
+}
+
+func (t *teams) ByExternalID(ext string) (data.Team, error) {
+	defer observeQuery("teams.byExternalID", time.Now())
+
+	return t.fetchOne(t.fetchExt, ext)
+}
+
+func (t *teams) fetchOne(stmt *sql.Stmt, arg any) (data.Team, error) {
+	ret := &team{}
+
+	err := stmt.QueryRow(arg).Scan(&ret.id, &ret.externalID, &ret.name)
+	if err == nil {
+		return ret, nil
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, data.ErrorUnknownTeamID
+	}
+
+	return nil, err
+}
+
+// Team names aren't unique (across leagues, say), so this may return several
+func (t *teams) ByName(name string) ([]data.Team, error) {
+	defer observeQuery("teams.byName", time.Now())
+
+	rows, err := t.fetchName.Query(name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	data := []data.Team{}
+	for rows.Next() {
+		nt := &team{}
+		if err = rows.Scan(&nt.id, &nt.externalID, &nt.name); err != nil {
+			return nil, err
+		}
+
+		data = append(data, nt)
+	}
+
+	return data, rows.Err()
+}
+
+func (t *teams) Save(v data.Team) (data.EntityID, error) {
+	defer observeQuery("teams.save", time.Now())
+
+	return saveRow(t.save, v.ID(), v.ExternalID(), v.Name())
+}
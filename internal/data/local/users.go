@@ -0,0 +1,182 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package local
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+
+	"shiftylogic.dev/hockey-tools/internal/data"
+)
+
+const (
+	kFetchUsersQuery = `
+		SELECT id, email, password_hash, created FROM users
+			WHERE id > ?
+			ORDER BY id ASC
+			LIMIT 100
+	`
+
+	kFetchUserEmailQuery = `
+		SELECT id, email, password_hash, created FROM users
+			WHERE email = ?
+	`
+
+	kAddUserQuery = `
+		INSERT INTO users (email, password_hash, created) VALUES (?, ?, ?)
+	`
+
+	kSetUserPasswordQuery = `
+		UPDATE users SET password_hash = ? WHERE email = ?
+	`
+)
+
+type user struct {
+	id           int64
+	email        string
+	passwordHash string
+	created      int64
+}
+
+func (u *user) ID() data.EntityID    { return data.EntityID(u.id) }
+func (u *user) Email() string        { return u.email }
+func (u *user) PasswordHash() string { return u.passwordHash }
+func (u *user) Created() time.Time   { return time.Unix(u.created, 0) }
+
+type users struct {
+	db          conn
+	fetchList   *sql.Stmt
+	fetchEmail  *sql.Stmt
+	add         *sql.Stmt
+	setPassword *sql.Stmt
+}
+
+func newUsers(db conn) (*users, error) {
+	fetchList, err := db.Prepare(kFetchUsersQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchEmail, err := db.Prepare(kFetchUserEmailQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	add, err := db.Prepare(kAddUserQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	setPassword, err := db.Prepare(kSetUserPasswordQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	return &users{
+		db,
+		fetchList,
+		fetchEmail,
+		add,
+		setPassword,
+	}, nil
+}
+
+func (u *users) List(token int64) ([]data.User, int64, error) {
+	defer observeQuery("users.list", time.Now())
+
+	rows, err := u.fetchList.Query(token)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer rows.Close()
+
+	data := []data.User{}
+	for rows.Next() {
+		nu := &user{}
+		err = rows.Scan(&nu.id, &nu.email, &nu.passwordHash, &nu.created)
+		if err != nil {
+			return nil, token, err
+		}
+
+		token = nu.id
+		data = append(data, nu)
+	}
+
+	return data, token, rows.Err()
+}
+
+func (u *users) ByEmail(email string) (data.User, error) {
+	defer observeQuery("users.byEmail", time.Now())
+
+	ret := &user{}
+
+	err := u.fetchEmail.QueryRow(email).Scan(&ret.id, &ret.email, &ret.passwordHash, &ret.created)
+	if err == nil {
+		return ret, nil
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, data.ErrorUnknownUser
+	}
+
+	return nil, err
+}
+
+func (u *users) Add(email, passwordHash string) (data.EntityID, error) {
+	defer observeQuery("users.add", time.Now())
+
+	res, err := u.add.Exec(email, passwordHash, time.Now().Unix())
+	if isConstraintError(err) {
+		return 0, data.ErrorDuplicateUser
+	} else if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	return data.EntityID(id), err
+}
+
+func (u *users) SetPassword(email, passwordHash string) error {
+	defer observeQuery("users.setPassword", time.Now())
+
+	res, err := u.setPassword.Exec(passwordHash, email)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return data.ErrorUnknownUser
+	}
+
+	return nil
+}
+
+func isConstraintError(err error) bool {
+	var serr sqlite3.Error
+	return errors.As(err, &serr) && serr.Code == sqlite3.ErrConstraint
+}
@@ -24,6 +24,7 @@ package data
 
 type Player interface {
 	ID() EntityID
+	ExternalID() string
 	Team() EntityID
 	Name() string
 	Number() int
@@ -32,5 +33,27 @@ type Player interface {
 type Players interface {
 	List(token int64) ([]Player, int64, error)
 	ByID(id EntityID) (Player, error)
+	ByExternalID(ext string) (Player, error)
 	ByTeam(team EntityID) ([]Player, error)
+
+	// Inserts the player, or replaces the existing one when ID() is set.
+	Save(p Player) (EntityID, error)
+}
+
+type player struct {
+	id         EntityID
+	externalID string
+	team       EntityID
+	name       string
+	number     int
+}
+
+func (p *player) ID() EntityID       { return p.id }
+func (p *player) ExternalID() string { return p.externalID }
+func (p *player) Team() EntityID     { return p.team }
+func (p *player) Name() string       { return p.name }
+func (p *player) Number() int        { return p.number }
+
+func NewPlayer(id EntityID, externalID string, team EntityID, name string, number int) Player {
+	return &player{id, externalID, team, name, number}
 }
@@ -0,0 +1,112 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package data
+
+/**
+ *
+ * Scoresheet entries that couldn't be matched to a player wait here for a
+ * person to decide. The event is saved with the player left as 0; resolving
+ * the item fills the player in.
+ *
+ **/
+
+type EventKind string
+
+const (
+	EventGoal    EventKind = "goal"
+	EventPenalty EventKind = "penalty"
+)
+
+type ReviewRole string
+
+const (
+	RoleScorer          ReviewRole = "scorer"
+	RolePrimaryAssist   ReviewRole = "primary_assist"
+	RoleSecondaryAssist ReviewRole = "secondary_assist"
+	RoleOnIce           ReviewRole = "on_ice"
+	RoleDefender        ReviewRole = "defender"
+	RoleCommitter       ReviewRole = "committer"
+	RoleServedBy        ReviewRole = "served_by"
+)
+
+type ReviewStatus string
+
+const (
+	ReviewOpen      ReviewStatus = "open"
+	ReviewResolved  ReviewStatus = "resolved"
+	ReviewDismissed ReviewStatus = "dismissed"
+)
+
+type ReviewItem interface {
+	ID() EntityID
+	Game() EntityID
+	Event() EventKind
+	Index() int // into GameDetails Goals() or Penalties()
+	Role() ReviewRole
+	Team() EntityID
+	Number() int // as written on the sheet; -1 when there wasn't one
+	Reason() string
+	Status() ReviewStatus
+	Player() EntityID // once resolved
+}
+
+type Reviews interface {
+	Open(token int64) ([]ReviewItem, int64, error)
+	ByGame(game EntityID) ([]ReviewItem, error)
+
+	Add(item ReviewItem) (EntityID, error)
+	DeleteByGame(game EntityID) error
+
+	// Sets the player on the event the item points at, and closes the item
+	Resolve(id, player EntityID) error
+	Dismiss(id EntityID) error
+}
+
+type reviewItem struct {
+	id     EntityID
+	game   EntityID
+	event  EventKind
+	index  int
+	role   ReviewRole
+	team   EntityID
+	number int
+	reason string
+	status ReviewStatus
+	player EntityID
+}
+
+func (r *reviewItem) ID() EntityID         { return r.id }
+func (r *reviewItem) Game() EntityID       { return r.game }
+func (r *reviewItem) Event() EventKind     { return r.event }
+func (r *reviewItem) Index() int           { return r.index }
+func (r *reviewItem) Role() ReviewRole     { return r.role }
+func (r *reviewItem) Team() EntityID       { return r.team }
+func (r *reviewItem) Number() int          { return r.number }
+func (r *reviewItem) Reason() string       { return r.reason }
+func (r *reviewItem) Status() ReviewStatus { return r.status }
+func (r *reviewItem) Player() EntityID     { return r.player }
+
+// A new, open item
+func NewReviewItem(game EntityID, event EventKind, index int, role ReviewRole, team EntityID, number int, reason string) ReviewItem {
+	return &reviewItem{0, game, event, index, role, team, number, reason, ReviewOpen, 0}
+}
@@ -0,0 +1,562 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package scoresheet
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/data"
+)
+
+const (
+	kDefaultPeriodMinutes = 20
+	kMaxAssists           = 2
+
+	kSideHome    = "home"
+	kSideVisitor = "visitor"
+)
+
+type Options struct {
+	// Sheet times are what was left on the clock rather than time elapsed
+	ClockCountsDown bool
+
+	// Match and validate everything, but save nothing
+	DryRun bool
+}
+
+type GameResult struct {
+	ExternalID string
+	Game       data.EntityID
+	Created    bool
+	Goals      int
+	Penalties  int
+
+	// Entries that couldn't be matched to a player
+	Queued   []data.ReviewItem
+	Warnings []string
+
+	// Any error means the game wasn't saved
+	Errors []error
+}
+
+func (r *GameResult) fail(format string, args ...any) {
+	r.Errors = append(r.Errors, fmt.Errorf(format, args...))
+}
+
+/**
+ *
+ * Each sheet is imported on its own: one bad game doesn't hold up the rest
+ * of a tournament. Games are matched on their external ID, so re-importing
+ * a corrected sheet replaces the game and its events, and any entries a
+ * person already resolved are applied again.
+ *
+ **/
+func Import(store data.Store, sheets []Sheet, opts Options) []GameResult {
+	results := make([]GameResult, len(sheets))
+	for i, sheet := range sheets {
+		results[i] = importSheet(store, sheet, opts)
+	}
+
+	return results
+}
+
+func importSheet(store data.Store, sheet Sheet, opts Options) GameResult {
+	result := GameResult{ExternalID: sheet.Game.ID}
+
+	g, err := newGameImport(store, sheet.Game, &result)
+	if err != nil {
+		result.Errors = append(result.Errors, err)
+		return result
+	}
+
+	goals := g.goals(sheet.Goals, opts)
+	penalties := g.penalties(sheet.Penalties, opts)
+	homeScore, visitorScore := g.score(goals, sheet.Game)
+
+	result.Goals, result.Penalties = len(goals), len(penalties)
+	if len(result.Errors) > 0 {
+		return result
+	}
+
+	overview := data.NewGameOverview(
+		g.id, sheet.Game.ID, g.season, sheet.Game.Tags, g.when, g.facility,
+		g.home, g.visitor, homeScore, visitorScore)
+
+	details := data.NewGameDetails(overview, g.periods, goals, penalties)
+
+	// The game, its events and its review queue are replaced together
+	save := func(store data.Store) error {
+		id := g.id
+		if !opts.DryRun {
+			var err error
+			if id, err = store.Games().Save(details); err != nil {
+				return err
+			}
+
+			if err := store.Reviews().DeleteByGame(id); err != nil {
+				return err
+			}
+		}
+
+		queued := make([]data.ReviewItem, 0, len(g.queue))
+		for _, q := range g.queue {
+			item := data.NewReviewItem(id, q.event, q.index, q.role, q.team, q.number, q.reason)
+			if !opts.DryRun {
+				if _, err := store.Reviews().Add(item); err != nil {
+					return err
+				}
+			}
+
+			queued = append(queued, item)
+		}
+
+		result.Game, result.Queued = id, queued
+		return nil
+	}
+
+	if opts.DryRun {
+		err = save(store)
+	} else {
+		err = store.Atomically(save)
+	}
+
+	if err != nil {
+		result.Errors = append(result.Errors, err)
+	}
+
+	return result
+}
+
+type slot struct {
+	team   data.EntityID
+	number int
+}
+
+type pending struct {
+	event  data.EventKind
+	index  int
+	role   data.ReviewRole
+	team   data.EntityID
+	number int
+	reason string
+}
+
+type gameImport struct {
+	store  data.Store
+	result *GameResult
+
+	id       data.EntityID
+	season   data.EntityID
+	when     time.Time
+	facility data.EntityID
+	home     data.EntityID
+	visitor  data.EntityID
+	periods  []int // seconds
+
+	rosters  map[data.EntityID]map[int]data.EntityID
+	resolved map[slot]data.EntityID
+	queue    []pending
+}
+
+func newGameImport(store data.Store, h Header, result *GameResult) (*gameImport, error) {
+	g := &gameImport{
+		store:    store,
+		result:   result,
+		rosters:  map[data.EntityID]map[int]data.EntityID{},
+		resolved: map[slot]data.EntityID{},
+	}
+
+	if h.ID == "" {
+		return nil, errors.New("game id is required (it makes re-imports update)")
+	}
+
+	var err error
+	if g.when, err = parseDate(h.Date); err != nil {
+		return nil, err
+	}
+
+	if g.home, err = g.team(h.Home); err != nil {
+		return nil, fmt.Errorf("home: %w", err)
+	}
+
+	if g.visitor, err = g.team(h.Visitor); err != nil {
+		return nil, fmt.Errorf("visitor: %w", err)
+	}
+
+	if g.home == g.visitor {
+		return nil, errors.New("home and visitor are the same team")
+	}
+
+	if h.Facility != "" {
+		f, err := store.Facilities().ByExternalID(h.Facility)
+		if errors.Is(err, data.ErrorUnknownFacilityID) {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("unknown facility '%s'; left blank", h.Facility))
+		} else if err != nil {
+			return nil, err
+		} else {
+			g.facility = f.ID()
+		}
+	}
+
+	minutes := h.Periods
+	if len(minutes) == 0 {
+		minutes = []int{kDefaultPeriodMinutes, kDefaultPeriodMinutes, kDefaultPeriodMinutes}
+	}
+
+	for _, m := range minutes {
+		if m <= 0 {
+			return nil, fmt.Errorf("period lengths must be positive, not %d", m)
+		}
+		g.periods = append(g.periods, m*60)
+	}
+
+	existing, err := store.Games().ByExternalID(h.ID)
+	if err == nil {
+		g.id = existing.ID()
+	} else if !errors.Is(err, data.ErrorUnknownGameID) {
+		return nil, err
+	}
+
+	if g.season, err = g.findSeason(h.Season); err != nil {
+		return nil, fmt.Errorf("season: %w", err)
+	}
+
+	// A corrected sheet without a season keeps the one the game had
+	if g.season == 0 && existing != nil {
+		g.season = existing.Season()
+	}
+
+	result.Created = g.id == 0
+	if !result.Created {
+		if err := g.loadResolved(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, team := range []data.EntityID{g.home, g.visitor} {
+		players, err := store.Players().ByTeam(team)
+		if err != nil {
+			return nil, err
+		}
+
+		g.rosters[team] = map[int]data.EntityID{}
+		for _, p := range players {
+			g.rosters[team][p.Number()] = p.ID()
+		}
+	}
+
+	return g, nil
+}
+
+// Seasons are given by external ID or id. Without one, the game goes in
+// the season both teams play in on that date, if there's exactly one.
+func (g *gameImport) findSeason(ref string) (data.EntityID, error) {
+	seasons := g.store.Seasons()
+
+	if ref != "" {
+		s, err := data.FindSeason(seasons, ref)
+		if err != nil {
+			return 0, err
+		}
+		return s.ID(), nil
+	}
+
+	memberships, err := seasons.TeamSeasons(g.home)
+	if err != nil {
+		return 0, err
+	}
+
+	candidates := []data.EntityID{}
+	for _, m := range memberships {
+		s, err := seasons.ByID(m.Season())
+		if err != nil {
+			return 0, err
+		}
+
+		if !data.SeasonIncludes(s, g.when) {
+			continue
+		}
+
+		visitors, err := seasons.Teams(s.ID())
+		if err != nil {
+			return 0, err
+		}
+
+		if slices.ContainsFunc(visitors, func(v data.Membership) bool { return v.Team() == g.visitor }) {
+			candidates = append(candidates, s.ID())
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return 0, nil
+	case 1:
+		return candidates[0], nil
+	}
+
+	g.result.Warnings = append(g.result.Warnings, "both teams share more than one season on that date; season left blank")
+	return 0, nil
+}
+
+func (g *gameImport) team(ref string) (data.EntityID, error) {
+	if ref == "" {
+		return 0, errors.New("team is required")
+	}
+
+	t, err := data.FindTeam(g.store.Teams(), ref)
+	if err != nil {
+		return 0, err
+	}
+
+	return t.ID(), nil
+}
+
+// What a person decided last time still holds for the same team and number
+func (g *gameImport) loadResolved() error {
+	items, err := g.store.Reviews().ByGame(g.id)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if item.Status() == data.ReviewResolved && item.Number() >= 0 {
+			g.resolved[slot{item.Team(), item.Number()}] = item.Player()
+		}
+	}
+
+	return nil
+}
+
+// Resolves an event's team from "home", "visitor" or a team reference
+func (g *gameImport) side(ref string) (data.EntityID, data.EntityID, error) {
+	switch strings.ToLower(ref) {
+	case kSideHome:
+		return g.home, g.visitor, nil
+	case kSideVisitor:
+		return g.visitor, g.home, nil
+	}
+
+	team, err := g.team(ref)
+	switch {
+	case err != nil:
+		return 0, 0, err
+	case team == g.home:
+		return g.home, g.visitor, nil
+	case team == g.visitor:
+		return g.visitor, g.home, nil
+	}
+
+	return 0, 0, fmt.Errorf("'%s' isn't playing in this game", ref)
+}
+
+func (g *gameImport) timestamp(period int, clock string, opts Options) (int, error) {
+	if period < 1 {
+		return 0, fmt.Errorf("bad period %d", period)
+	}
+
+	// Overtime periods past the listed ones are as long as the last one
+	start := 0
+	for p := 1; p < period; p++ {
+		start += g.periodLength(p)
+	}
+
+	t, err := parseClock(clock)
+	if err != nil {
+		return 0, err
+	}
+
+	length := g.periodLength(period)
+	if t > length {
+		return 0, fmt.Errorf("time '%s' is past the end of period %d", clock, period)
+	}
+
+	if opts.ClockCountsDown {
+		t = length - t
+	}
+
+	return start + t, nil
+}
+
+func (g *gameImport) periodLength(period int) int {
+	if period <= len(g.periods) {
+		return g.periods[period-1]
+	}
+
+	return g.periods[len(g.periods)-1]
+}
+
+// Matches a jersey number to a player, queuing it for review when it can't
+func (g *gameImport) player(team data.EntityID, number *int, event data.EventKind, index int, role data.ReviewRole) data.EntityID {
+	if number == nil {
+		g.queue = append(g.queue, pending{event, index, role, team, -1, "no number on the sheet"})
+		return 0
+	}
+
+	if id, ok := g.resolved[slot{team, *number}]; ok {
+		return id
+	}
+
+	if id, ok := g.rosters[team][*number]; ok {
+		return id
+	}
+
+	g.queue = append(g.queue, pending{event, index, role, team, *number, fmt.Sprintf("no #%d on the roster", *number)})
+	return 0
+}
+
+// Unmatched players are left out of on-ice lists; resolving adds them back
+func (g *gameImport) players(team data.EntityID, numbers []int, index int, role data.ReviewRole) []data.EntityID {
+	ids := []data.EntityID{}
+	for _, n := range numbers {
+		if id := g.player(team, &n, data.EventGoal, index, role); id != 0 {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+type timedGoal struct {
+	Goal
+	timestamp int
+}
+
+func (g *gameImport) goals(goals []Goal, opts Options) []data.ScoringEvent {
+	timed := []timedGoal{}
+	for i, goal := range goals {
+		ts, err := g.timestamp(goal.Period, goal.Time, opts)
+		if err != nil {
+			g.result.fail("goal %d: %w", i+1, err)
+			continue
+		}
+
+		timed = append(timed, timedGoal{goal, ts})
+	}
+
+	// Review items point at events by position, so settle the order first
+	slices.SortStableFunc(timed, func(a, b timedGoal) int { return a.timestamp - b.timestamp })
+
+	events := []data.ScoringEvent{}
+	for i, goal := range timed {
+		team, opponent, err := g.side(goal.Team)
+		if err != nil {
+			g.result.fail("goal at %s of period %d: %w", goal.Time, goal.Period, err)
+			continue
+		}
+
+		if len(goal.Assists) > kMaxAssists {
+			g.result.fail("goal at %s of period %d: more than %d assists", goal.Time, goal.Period, kMaxAssists)
+			continue
+		}
+
+		scorer := g.player(team, goal.Scorer, data.EventGoal, i, data.RoleScorer)
+
+		var assists [kMaxAssists]data.EntityID
+		roles := []data.ReviewRole{data.RolePrimaryAssist, data.RoleSecondaryAssist}
+		for a := range goal.Assists {
+			assists[a] = g.player(team, &goal.Assists[a], data.EventGoal, i, roles[a])
+		}
+
+		onIce := g.players(team, goal.OnIce, i, data.RoleOnIce)
+		defenders := g.players(opponent, goal.Defenders, i, data.RoleDefender)
+
+		events = append(events, data.NewScoringEvent(goal.timestamp, team, scorer, assists[0], assists[1], onIce, defenders))
+	}
+
+	return events
+}
+
+type timedPenalty struct {
+	Penalty
+	timestamp int
+}
+
+func (g *gameImport) penalties(penalties []Penalty, opts Options) []data.PenaltyEvent {
+	timed := []timedPenalty{}
+	for i, p := range penalties {
+		ts, err := g.timestamp(p.Period, p.Time, opts)
+		if err != nil {
+			g.result.fail("penalty %d: %w", i+1, err)
+			continue
+		}
+
+		if p.Minutes <= 0 {
+			g.result.fail("penalty %d: minutes must be positive", i+1)
+			continue
+		}
+
+		timed = append(timed, timedPenalty{p, ts})
+	}
+
+	slices.SortStableFunc(timed, func(a, b timedPenalty) int { return a.timestamp - b.timestamp })
+
+	events := []data.PenaltyEvent{}
+	for i, p := range timed {
+		team, _, err := g.side(p.Team)
+		if err != nil {
+			g.result.fail("penalty at %s of period %d: %w", p.Time, p.Period, err)
+			continue
+		}
+
+		// No player is a bench penalty, not something to review
+		var committer, servedBy data.EntityID
+		if p.Player != nil {
+			committer = g.player(team, p.Player, data.EventPenalty, i, data.RoleCommitter)
+		}
+
+		if p.ServedBy != nil {
+			servedBy = g.player(team, p.ServedBy, data.EventPenalty, i, data.RoleServedBy)
+		}
+
+		events = append(events, data.NewPenaltyEvent(p.timestamp, team, committer, p.Minutes, p.Infraction, servedBy))
+	}
+
+	return events
+}
+
+// The goals decide the score unless the sheet says otherwise
+func (g *gameImport) score(goals []data.ScoringEvent, h Header) (int, int) {
+	home, visitor := 0, 0
+	for _, goal := range goals {
+		if goal.TeamID() == g.home {
+			home++
+		} else {
+			visitor++
+		}
+	}
+
+	if h.HomeScore != nil && h.VisitorScore != nil {
+		if *h.HomeScore != home || *h.VisitorScore != visitor {
+			g.result.Warnings = append(g.result.Warnings,
+				fmt.Sprintf("final score %d-%d differs from the goals listed (%d-%d)", *h.HomeScore, *h.VisitorScore, home, visitor))
+		}
+
+		return *h.HomeScore, *h.VisitorScore
+	}
+
+	return home, visitor
+}
@@ -0,0 +1,181 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package scoresheet
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"shiftylogic.dev/hockey-tools/internal/data"
+	"shiftylogic.dev/hockey-tools/internal/data/local"
+	"shiftylogic.dev/hockey-tools/internal/data/transfer"
+	"shiftylogic.dev/hockey-tools/internal/test"
+)
+
+const (
+	kRosters = `external_id,team,name,number
+A,T-SEA,Alice,9
+B,T-SEA,Bob,10
+C,T-TAC,Carl,4
+`
+
+	kSheetCSV = `record,game,date,home,visitor,periods,period,time,team,player,assist1,assist2,on_ice,minutes,infraction
+game,G-1,2024-11-02 18:30,T-SEA,T-TAC,15 15 15,,,,,,,,,
+goal,G-1,,,,,2,03:00,visitor,4,23,,,,
+goal,G-1,,,,,1,04:12,home,9,10,,9 10,,
+penalty,G-1,,,,,3,01:00,home,,,,,2,Too many men
+`
+)
+
+func openStore(t *testing.T) data.Store {
+	store, err := local.Open(filepath.Join(t.TempDir(), "hockey.db"))
+	test.NoError(t, err, "open store")
+	t.Cleanup(store.Close)
+
+	teams := "external_id,name\nT-SEA,Seattle\nT-TAC,Tacoma\n"
+	_, err = transfer.Import(store, strings.NewReader(teams), transfer.Options{Kind: transfer.KindTeams, Format: transfer.FormatCSV})
+	test.NoError(t, err, "import teams")
+
+	_, err = transfer.Import(store, strings.NewReader(kRosters), transfer.Options{Kind: transfer.KindPlayers, Format: transfer.FormatCSV})
+	test.NoError(t, err, "import players")
+
+	return store
+}
+
+func importCSV(t *testing.T, store data.Store) GameResult {
+	sheets, err := Read(strings.NewReader(kSheetCSV), transfer.FormatCSV)
+	test.NoError(t, err, "read sheet")
+	test.Expect(t, 1, len(sheets), "one game")
+
+	results := Import(store, sheets, Options{})
+	test.Expect(t, 0, len(results[0].Errors), "no errors")
+	return results[0]
+}
+
+func TestImportQueuesUnmatchedPlayers(t *testing.T) {
+	store := openStore(t)
+
+	result := importCSV(t, store)
+	test.Require(t, result.Created, "game created")
+	test.Expect(t, 1, len(result.Queued), "unknown #23 is queued")
+
+	details, err := store.Games().DetailsByGame(result.Game)
+	test.NoError(t, err, "fetch game")
+	test.Expect(t, 1, details.Overview().HomeScore(), "home score from goals")
+	test.Expect(t, 1, details.Overview().VisitorScore(), "visitor score from goals")
+
+	goals := details.Goals()
+	test.Expect(t, 252, goals[0].Timestamp(), "goals are in time order")
+	test.Expect(t, 2, len(goals[0].Other()), "on-ice players matched")
+	test.Expect(t, data.EntityID(0), goals[1].PrimaryAssist(), "unmatched assist left empty")
+	test.Expect(t, data.EntityID(0), details.Penalties()[0].Committer(), "bench penalty has no player")
+
+	carl, err := store.Players().ByExternalID("C")
+	test.NoError(t, err, "fetch player")
+
+	stats, err := store.Stats().Player(carl.ID(), data.StatsScope{})
+	test.NoError(t, err, "player stats")
+	test.Expect(t, 1, stats.Points, "goal counted when the game is saved")
+
+	items, _, err := store.Reviews().Open(0)
+	test.NoError(t, err, "list queue")
+	test.NoError(t, store.Reviews().Resolve(items[0].ID(), carl.ID()), "resolve")
+	test.SpecificError(t, store.Reviews().Dismiss(items[0].ID()), data.ErrorReviewClosed, "already closed")
+
+	details, err = store.Games().DetailsByGame(result.Game)
+	test.NoError(t, err, "fetch game")
+	test.Expect(t, carl.ID(), details.Goals()[1].PrimaryAssist(), "resolution fills in the event")
+
+	stats, err = store.Stats().Player(carl.ID(), data.StatsScope{})
+	test.NoError(t, err, "player stats")
+	test.Expect(t, 2, stats.Points, "resolution refreshes the cached line")
+	test.Expect(t, 1, stats.GamesPlayed, "still one game")
+
+	result = importCSV(t, store)
+	test.Require(t, !result.Created, "re-import updates")
+	test.Expect(t, 0, len(result.Queued), "earlier resolutions are reapplied")
+
+	details, err = store.Games().DetailsByGame(result.Game)
+	test.NoError(t, err, "fetch game")
+	test.Expect(t, carl.ID(), details.Goals()[1].PrimaryAssist(), "resolution survives re-import")
+}
+
+func TestImportRejectsBadSheets(t *testing.T) {
+	store := openStore(t)
+
+	sheets, err := Read(strings.NewReader(`[
+		{"game": {"id": "G-2", "date": "2024-11-03", "home": "T-SEA", "visitor": "Nowhere"}},
+		{"game": {"id": "G-3", "date": "2024-11-03", "home": "T-SEA", "visitor": "T-TAC", "periods": [15, 15, 15]},
+		 "penalties": [{"period": 1, "time": "16:00", "team": "home", "player": 9, "minutes": 2}]}
+	]`), transfer.FormatJSON)
+	test.NoError(t, err, "read sheets")
+
+	results := Import(store, sheets, Options{})
+	test.Expect(t, 1, len(results[0].Errors), "unknown team")
+	test.Expect(t, 1, len(results[1].Errors), "time past the end of the period")
+
+	games, _, err := store.Games().List(0)
+	test.NoError(t, err, "list games")
+	test.Expect(t, 0, len(games), "nothing saved")
+}
+
+type failingStore struct {
+	data.Store
+}
+
+type failingReviews struct {
+	data.Reviews
+}
+
+func (s failingStore) Reviews() data.Reviews { return failingReviews{s.Store.Reviews()} }
+
+func (s failingStore) Atomically(fn func(data.Store) error) error {
+	return s.Store.Atomically(func(tx data.Store) error { return fn(failingStore{tx}) })
+}
+
+func (r failingReviews) Add(item data.ReviewItem) (data.EntityID, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestImportIsAllOrNothing(t *testing.T) {
+	store := openStore(t)
+	first := importCSV(t, store)
+
+	sheets, err := Read(strings.NewReader(kSheetCSV), transfer.FormatCSV)
+	test.NoError(t, err, "read sheet")
+
+	dryRun := Import(store, sheets, Options{DryRun: true})
+	test.Expect(t, 0, len(dryRun[0].Errors), "dry run errors")
+	test.Expect(t, first.Game, dryRun[0].Game, "dry run names the existing game")
+	test.Expect(t, first.Game, dryRun[0].Queued[0].Game(), "dry run queue names the existing game")
+
+	results := Import(failingStore{store}, sheets, Options{})
+	test.Expect(t, 1, len(results[0].Errors), "review write failure reported")
+
+	items, _, err := store.Reviews().Open(0)
+	test.NoError(t, err, "list queue")
+	test.Expect(t, 1, len(items), "review queue left as it was")
+	test.Expect(t, first.Game, items[0].Game(), "queued against the original game")
+}
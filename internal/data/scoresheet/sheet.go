@@ -0,0 +1,344 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package scoresheet
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/data/transfer"
+)
+
+/**
+ *
+ * The scoresheet exchange format. JSON is one sheet object or an array of
+ * them. CSV is one row per record, with a 'record' column of game, goal or
+ * penalty and a 'game' column tying goals and penalties to their game:
+ *
+ *   record,game,date,home,visitor,periods,period,time,team,player,assist1,assist2,minutes,infraction
+ *   game,G-1,2024-11-02 18:30,T-SEA,T-TAC,15 15 15,,,,,,,,
+ *   goal,G-1,,,,,1,04:12,home,9,10,,,
+ *   penalty,G-1,,,,,2,11:40,visitor,4,,,2,Tripping
+ *
+ * A game record may name its season (external ID or id) in a 'season'
+ * column; without one, it's worked out from the teams and the date.
+ *
+ * Teams are "home", "visitor" or a team reference (external ID or name).
+ * Players are jersey numbers; lists of them (on_ice, defenders) are space
+ * separated in CSV. Times are MM:SS into the period unless the import says
+ * the clock counts down.
+ *
+ **/
+
+type Sheet struct {
+	Game      Header    `json:"game"`
+	Goals     []Goal    `json:"goals"`
+	Penalties []Penalty `json:"penalties"`
+}
+
+type Header struct {
+	ID       string   `json:"id"`
+	Date     string   `json:"date"`
+	Facility string   `json:"facility,omitempty"`
+	Season   string   `json:"season,omitempty"` // external ID or id
+	Home     string   `json:"home"`
+	Visitor  string   `json:"visitor"`
+	Periods  []int    `json:"periods,omitempty"` // minutes; defaults to 3 x 20
+	Tags     []string `json:"tags,omitempty"`
+
+	// Final score, when it isn't just the goals (a shootout, say)
+	HomeScore    *int `json:"home_score,omitempty"`
+	VisitorScore *int `json:"visitor_score,omitempty"`
+}
+
+type Goal struct {
+	Period    int    `json:"period"`
+	Time      string `json:"time"`
+	Team      string `json:"team"`
+	Scorer    *int   `json:"scorer"`
+	Assists   []int  `json:"assists,omitempty"`
+	OnIce     []int  `json:"on_ice,omitempty"`
+	Defenders []int  `json:"defenders,omitempty"`
+}
+
+type Penalty struct {
+	Period     int    `json:"period"`
+	Time       string `json:"time"`
+	Team       string `json:"team"`
+	Player     *int   `json:"player"`
+	Minutes    int    `json:"minutes"`
+	Infraction string `json:"infraction"`
+	ServedBy   *int   `json:"served_by,omitempty"`
+}
+
+var kDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+func parseDate(s string) (time.Time, error) {
+	for _, layout := range kDateLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized date '%s'", s)
+}
+
+// Parses MM:SS (or plain seconds) into seconds
+func parseClock(s string) (int, error) {
+	m, sec, found := strings.Cut(strings.TrimSpace(s), ":")
+	if !found {
+		m, sec = "0", m
+	}
+
+	minutes, err := strconv.Atoi(m)
+	if err != nil || minutes < 0 {
+		return 0, fmt.Errorf("bad time '%s'", s)
+	}
+
+	seconds, err := strconv.Atoi(sec)
+	if err != nil || seconds < 0 || (found && seconds > 59) {
+		return 0, fmt.Errorf("bad time '%s'", s)
+	}
+
+	return minutes*60 + seconds, nil
+}
+
+func Read(in io.Reader, format transfer.Format) ([]Sheet, error) {
+	switch format {
+	case transfer.FormatJSON:
+		return readJSON(in)
+	case transfer.FormatCSV:
+		return readCSV(in)
+	}
+
+	return nil, fmt.Errorf("%w '%s'", transfer.ErrorUnknownFormat, format)
+}
+
+func readJSON(in io.Reader) ([]Sheet, error) {
+	raw, err := io.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+
+	if trimmed := strings.TrimSpace(string(raw)); strings.HasPrefix(trimmed, "{") {
+		var sheet Sheet
+		if err := json.Unmarshal(raw, &sheet); err != nil {
+			return nil, err
+		}
+		return []Sheet{sheet}, nil
+	}
+
+	var sheets []Sheet
+	if err := json.Unmarshal(raw, &sheets); err != nil {
+		return nil, err
+	}
+
+	return sheets, nil
+}
+
+func readCSV(in io.Reader) ([]Sheet, error) {
+	r := csv.NewReader(in)
+	r.TrimLeadingSpace = true
+	r.FieldsPerRecord = -1
+
+	headers, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("empty file")
+	} else if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]int{}
+	for i, h := range headers {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))] = i
+	}
+
+	for _, required := range []string{"record", "game"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing required column '%s'", required)
+		}
+	}
+
+	sheets := []*Sheet{}
+	byGame := map[string]*Sheet{}
+
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		line, _ := r.FieldPos(0)
+		c := &csvRow{record, columns}
+
+		id := c.get("game")
+		sheet, ok := byGame[id]
+		if !ok {
+			sheet = &Sheet{Game: Header{ID: id}}
+			byGame[id] = sheet
+			sheets = append(sheets, sheet)
+		}
+
+		if err := c.into(sheet); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+
+	ret := make([]Sheet, len(sheets))
+	for i, s := range sheets {
+		ret[i] = *s
+	}
+
+	return ret, nil
+}
+
+type csvRow struct {
+	record  []string
+	columns map[string]int
+}
+
+func (c *csvRow) get(column string) string {
+	if i, ok := c.columns[column]; ok && i < len(c.record) {
+		return strings.TrimSpace(c.record[i])
+	}
+
+	return ""
+}
+
+func (c *csvRow) int(column string) (int, error) {
+	s := c.get(column)
+	if s == "" {
+		return 0, nil
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("%s: '%s' is not a number", column, s)
+	}
+
+	return v, nil
+}
+
+func (c *csvRow) optional(column string) (*int, error) {
+	if c.get(column) == "" {
+		return nil, nil
+	}
+
+	v, err := c.int(column)
+	return &v, err
+}
+
+func (c *csvRow) ints(column string) ([]int, error) {
+	values := []int{}
+	for _, f := range strings.Fields(c.get(column)) {
+		v, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: '%s' is not a number", column, f)
+		}
+		values = append(values, v)
+	}
+
+	return values, nil
+}
+
+func (c *csvRow) into(sheet *Sheet) error {
+	var errs []error
+	check := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	period, err := c.int("period")
+	check(err)
+
+	switch kind := strings.ToLower(c.get("record")); kind {
+	case "game":
+		h := &sheet.Game
+		h.Date, h.Facility, h.Home, h.Visitor = c.get("date"), c.get("facility"), c.get("home"), c.get("visitor")
+		h.Season = c.get("season")
+
+		h.Periods, err = c.ints("periods")
+		check(err)
+
+		if tags := c.get("tags"); tags != "" {
+			h.Tags = strings.Fields(strings.ReplaceAll(tags, ",", " "))
+		}
+
+		h.HomeScore, err = c.optional("home_score")
+		check(err)
+		h.VisitorScore, err = c.optional("visitor_score")
+		check(err)
+
+	case "goal":
+		g := Goal{Period: period, Time: c.get("time"), Team: c.get("team")}
+
+		g.Scorer, err = c.optional("player")
+		check(err)
+
+		for _, column := range []string{"assist1", "assist2"} {
+			a, err := c.optional(column)
+			check(err)
+			if a != nil {
+				g.Assists = append(g.Assists, *a)
+			}
+		}
+
+		g.OnIce, err = c.ints("on_ice")
+		check(err)
+		g.Defenders, err = c.ints("defenders")
+		check(err)
+
+		sheet.Goals = append(sheet.Goals, g)
+
+	case "penalty":
+		p := Penalty{Period: period, Time: c.get("time"), Team: c.get("team"), Infraction: c.get("infraction")}
+
+		p.Player, err = c.optional("player")
+		check(err)
+		p.Minutes, err = c.int("minutes")
+		check(err)
+		p.ServedBy, err = c.optional("served_by")
+		check(err)
+
+		sheet.Penalties = append(sheet.Penalties, p)
+
+	default:
+		return fmt.Errorf("unknown record type '%s'", kind)
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,199 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package data
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+/**
+ *
+ * A league runs seasons and groups its teams into divisions. Divisions
+ * belong to the league so they carry over from season to season; which
+ * division a team plays in is part of its membership in a season, so a
+ * team can move between them (or sit a season out).
+ *
+ **/
+
+type League interface {
+	ID() EntityID
+	ExternalID() string
+	Name() string
+}
+
+type Leagues interface {
+	List(token int64) ([]League, int64, error)
+	ByID(id EntityID) (League, error)
+	ByExternalID(ext string) (League, error)
+
+	// Inserts the league, or replaces the existing one when ID() is set.
+	Save(l League) (EntityID, error)
+}
+
+type Season interface {
+	ID() EntityID
+	ExternalID() string
+	League() EntityID
+	Name() string
+
+	// Either may be zero when not known. Both days are in the season.
+	Start() time.Time
+	End() time.Time
+}
+
+// Whether a date falls in the season, including anything on its last day.
+// Open-ended seasons include everything on their open side.
+func SeasonIncludes(s Season, when time.Time) bool {
+	return (s.Start().IsZero() || !when.Before(s.Start())) && (s.End().IsZero() || when.Before(s.End().AddDate(0, 0, 1)))
+}
+
+type Membership interface {
+	Season() EntityID
+	Team() EntityID
+	Division() EntityID // 0 when the league doesn't use divisions
+}
+
+type Seasons interface {
+	List(token int64) ([]Season, int64, error)
+	ByID(id EntityID) (Season, error)
+	ByExternalID(ext string) (Season, error)
+	ByLeague(league EntityID) ([]Season, error)
+
+	// Inserts the season, or replaces the existing one when ID() is set.
+	Save(s Season) (EntityID, error)
+
+	// Team membership. Setting a membership that exists moves the team to
+	// the given division.
+	Teams(season EntityID) ([]Membership, error)
+	TeamSeasons(team EntityID) ([]Membership, error)
+	SetTeam(m Membership) error
+	RemoveTeam(season, team EntityID) error
+}
+
+type Division interface {
+	ID() EntityID
+	ExternalID() string
+	League() EntityID
+	Name() string
+}
+
+type Divisions interface {
+	List(token int64) ([]Division, int64, error)
+	ByID(id EntityID) (Division, error)
+	ByExternalID(ext string) (Division, error)
+	ByLeague(league EntityID) ([]Division, error)
+
+	// The divisions any team plays in that season
+	BySeason(season EntityID) ([]Division, error)
+
+	// Inserts the division, or replaces the existing one when ID() is set.
+	Save(d Division) (EntityID, error)
+}
+
+// Seasons are referenced by external ID or by id
+func FindSeason(seasons Seasons, ref string) (Season, error) {
+	s, err := seasons.ByExternalID(ref)
+	if err == nil || !errors.Is(err, ErrorUnknownSeasonID) {
+		return s, err
+	}
+
+	id, perr := strconv.ParseInt(ref, 10, 64)
+	if perr != nil {
+		return nil, fmt.Errorf("%w '%s'", ErrorUnknownSeasonID, ref)
+	}
+
+	return seasons.ByID(EntityID(id))
+}
+
+/**
+ *
+ * Plain implementations, for building values to save.
+ *
+ **/
+
+type league struct {
+	id         EntityID
+	externalID string
+	name       string
+}
+
+func (l *league) ID() EntityID       { return l.id }
+func (l *league) ExternalID() string { return l.externalID }
+func (l *league) Name() string       { return l.name }
+
+func NewLeague(id EntityID, externalID, name string) League {
+	return &league{id, externalID, name}
+}
+
+type season struct {
+	id         EntityID
+	externalID string
+	league     EntityID
+	name       string
+	start      time.Time
+	end        time.Time
+}
+
+func (s *season) ID() EntityID       { return s.id }
+func (s *season) ExternalID() string { return s.externalID }
+func (s *season) League() EntityID   { return s.league }
+func (s *season) Name() string       { return s.name }
+func (s *season) Start() time.Time   { return s.start }
+func (s *season) End() time.Time     { return s.end }
+
+func NewSeason(id EntityID, externalID string, league EntityID, name string, start, end time.Time) Season {
+	return &season{id, externalID, league, name, start, end}
+}
+
+type division struct {
+	id         EntityID
+	externalID string
+	league     EntityID
+	name       string
+}
+
+func (d *division) ID() EntityID       { return d.id }
+func (d *division) ExternalID() string { return d.externalID }
+func (d *division) League() EntityID   { return d.league }
+func (d *division) Name() string       { return d.name }
+
+func NewDivision(id EntityID, externalID string, league EntityID, name string) Division {
+	return &division{id, externalID, league, name}
+}
+
+type membership struct {
+	season   EntityID
+	team     EntityID
+	division EntityID
+}
+
+func (m *membership) Season() EntityID   { return m.season }
+func (m *membership) Team() EntityID     { return m.team }
+func (m *membership) Division() EntityID { return m.division }
+
+func NewMembership(season, team, division EntityID) Membership {
+	return &membership{season, team, division}
+}
@@ -24,6 +24,7 @@ package data
 
 type StaffMember interface {
 	ID() EntityID
+	ExternalID() string
 	Team() EntityID
 	Name() string
 	Role() string
@@ -32,5 +33,27 @@ type StaffMember interface {
 type Staff interface {
 	List(token int64) ([]StaffMember, int64, error)
 	ByID(id EntityID) (StaffMember, error)
+	ByExternalID(ext string) (StaffMember, error)
 	ByTeam(team EntityID) ([]StaffMember, error)
+
+	// Inserts the staff member, or replaces the existing one when ID() is set.
+	Save(sm StaffMember) (EntityID, error)
+}
+
+type staffMember struct {
+	id         EntityID
+	externalID string
+	team       EntityID
+	name       string
+	role       string
+}
+
+func (sm *staffMember) ID() EntityID       { return sm.id }
+func (sm *staffMember) ExternalID() string { return sm.externalID }
+func (sm *staffMember) Team() EntityID     { return sm.team }
+func (sm *staffMember) Name() string       { return sm.name }
+func (sm *staffMember) Role() string       { return sm.role }
+
+func NewStaffMember(id EntityID, externalID string, team EntityID, name, role string) StaffMember {
+	return &staffMember{id, externalID, team, name, role}
 }
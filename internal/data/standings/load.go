@@ -0,0 +1,57 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package standings
+
+import (
+	"slices"
+
+	"shiftylogic.dev/hockey-tools/internal/data"
+)
+
+// The details of every game in scope. Scope's Team is ignored; a table
+// needs everyone's games.
+func Load(games data.Games, scope data.StatsScope) ([]data.GameDetails, error) {
+	var all []data.GameOverview
+	var err error
+
+	if scope.Season != 0 {
+		all, err = games.BySeason(scope.Season)
+	} else {
+		all, err = data.ListAll(games.List)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []data.EntityID{}
+	for _, o := range all {
+		if !scope.Includes(o.When()) || (scope.Tag != "" && !slices.Contains(o.Tags(), scope.Tag)) {
+			continue
+		}
+
+		ids = append(ids, o.ID())
+	}
+
+	return games.DetailsByGames(ids)
+}
@@ -0,0 +1,153 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package standings
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrorUnknownPointSystem = errors.New("unknown points system")
+	ErrorUnknownTiebreaker  = errors.New("unknown tiebreaker")
+)
+
+/**
+ *
+ * Points for each result. A tie only happens when the league doesn't play
+ * overtime (or a shootout) to a decision.
+ *
+ **/
+
+type Points struct {
+	RegulationWin int `json:"regulationWin" yaml:"RegulationWin"`
+	OvertimeWin   int `json:"overtimeWin" yaml:"OvertimeWin"`
+	Tie           int `json:"tie" yaml:"Tie"`
+	OvertimeLoss  int `json:"overtimeLoss" yaml:"OvertimeLoss"`
+	Loss          int `json:"loss" yaml:"Loss"`
+}
+
+// The points system and regulation length used by DefaultRules
+const (
+	DefaultPointSystem       = "2-1-0"
+	DefaultRegulationPeriods = 3
+)
+
+var kPointSystems = map[string]Points{
+	"2-1-0":   {RegulationWin: 2, OvertimeWin: 2, Tie: 1, OvertimeLoss: 1, Loss: 0},
+	"3-2-1-0": {RegulationWin: 3, OvertimeWin: 2, Tie: 1, OvertimeLoss: 1, Loss: 0},
+}
+
+func PointSystem(name string) (Points, error) {
+	p, ok := kPointSystems[name]
+	if !ok {
+		return p, fmt.Errorf("%w '%s' (want one of %s)", ErrorUnknownPointSystem, name, strings.Join(PointSystems(), ", "))
+	}
+
+	return p, nil
+}
+
+func PointSystems() []string {
+	names := make([]string, 0, len(kPointSystems))
+	for name := range kPointSystems {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+	return names
+}
+
+/**
+ *
+ * Tiebreakers apply in order to teams level on points. Each one splits the
+ * tied group, and the rest apply to whatever is still tied; head-to-head
+ * only counts games between the teams still tied at that step.
+ *
+ **/
+
+type Tiebreaker string
+
+const (
+	HeadToHead       Tiebreaker = "head-to-head"
+	RegulationWins   Tiebreaker = "regulation-wins"
+	GoalDifferential Tiebreaker = "goal-differential"
+)
+
+var kTiebreakers = []Tiebreaker{HeadToHead, RegulationWins, GoalDifferential}
+
+func ParseTiebreakers(names []string) ([]Tiebreaker, error) {
+	ret := make([]Tiebreaker, 0, len(names))
+
+	for _, name := range names {
+		tb := Tiebreaker(strings.TrimSpace(name))
+		if !slices.Contains(kTiebreakers, tb) {
+			return nil, fmt.Errorf("%w '%s'", ErrorUnknownTiebreaker, name)
+		}
+
+		ret = append(ret, tb)
+	}
+
+	return ret, nil
+}
+
+type Rules struct {
+	Points      Points
+	Tiebreakers []Tiebreaker
+
+	// Goals after this many periods were scored in overtime
+	RegulationPeriods int
+}
+
+func DefaultRules() Rules {
+	return Rules{
+		Points:            kPointSystems[DefaultPointSystem],
+		Tiebreakers:       []Tiebreaker{RegulationWins, HeadToHead, GoalDifferential},
+		RegulationPeriods: DefaultRegulationPeriods,
+	}
+}
+
+// Rules for a named points system and tiebreaker list; empty values keep
+// the defaults.
+func NewRules(system string, tiebreakers []string) (Rules, error) {
+	rules := DefaultRules()
+
+	if system != "" {
+		p, err := PointSystem(system)
+		if err != nil {
+			return rules, err
+		}
+		rules.Points = p
+	}
+
+	if len(tiebreakers) > 0 {
+		tb, err := ParseTiebreakers(tiebreakers)
+		if err != nil {
+			return rules, err
+		}
+		rules.Tiebreakers = tb
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,323 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package standings
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"shiftylogic.dev/hockey-tools/internal/data"
+)
+
+type Outcome int
+
+const (
+	Win Outcome = iota
+	Loss
+	Tie
+	OvertimeLoss
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case Win:
+		return "W"
+	case Loss:
+		return "L"
+	case Tie:
+		return "T"
+	case OvertimeLoss:
+		return "OTL"
+	}
+
+	return "?"
+}
+
+type Record struct {
+	Wins           int `json:"w"`
+	Losses         int `json:"l"`
+	Ties           int `json:"t"`
+	OvertimeLosses int `json:"otl"`
+}
+
+func (r *Record) add(o Outcome) {
+	switch o {
+	case Win:
+		r.Wins++
+	case Loss:
+		r.Losses++
+	case Tie:
+		r.Ties++
+	case OvertimeLoss:
+		r.OvertimeLosses++
+	}
+}
+
+func (r Record) String() string {
+	return fmt.Sprintf("%d-%d-%d-%d", r.Wins, r.Losses, r.Ties, r.OvertimeLosses)
+}
+
+type Row struct {
+	Team data.EntityID `json:"team"`
+	Name string        `json:"name,omitempty"`
+
+	GamesPlayed int `json:"gp"`
+	Record
+	RegulationWins int `json:"rw"`
+	Points         int `json:"pts"`
+
+	GoalsFor     int `json:"gf"`
+	GoalsAgainst int `json:"ga"`
+	Diff         int `json:"diff"`
+
+	Home    Record `json:"home"`
+	Away    Record `json:"away"`
+	LastTen Record `json:"last_10"`
+
+	// The current run of one outcome, like "W3"
+	Streak string `json:"streak"`
+}
+
+// One game from one team's side
+type result struct {
+	team, opponent data.EntityID
+	outcome        Outcome
+	regulation     bool
+	points         int
+}
+
+type table struct {
+	rules   Rules
+	rows    map[data.EntityID]*Row
+	results map[data.EntityID][]result
+}
+
+/**
+ *
+ * Builds the table for a set of played games. Rows are ordered by points,
+ * then the rules' tiebreakers, then team ID so the order is stable.
+ *
+ **/
+func Compute(games []data.GameDetails, rules Rules) []Row {
+	t := &table{rules, map[data.EntityID]*Row{}, map[data.EntityID][]result{}}
+
+	games = slices.Clone(games)
+	slices.SortStableFunc(games, func(a, b data.GameDetails) int {
+		return cmp.Or(a.Overview().When().Compare(b.Overview().When()), cmp.Compare(a.ID(), b.ID()))
+	})
+
+	for _, g := range games {
+		t.add(g)
+	}
+
+	rows := make([]*Row, 0, len(t.rows))
+	for team, row := range t.rows {
+		t.finish(team, row)
+		rows = append(rows, row)
+	}
+
+	slices.SortFunc(rows, func(a, b *Row) int { return cmp.Compare(a.Team, b.Team) })
+	t.order(rows, func(r *Row) int { return r.Points }, rules.Tiebreakers)
+
+	ret := make([]Row, len(rows))
+	for i, r := range rows {
+		ret[i] = *r
+	}
+
+	return ret
+}
+
+func (t *table) row(team data.EntityID) *Row {
+	r, ok := t.rows[team]
+	if !ok {
+		r = &Row{Team: team}
+		t.rows[team] = r
+	}
+
+	return r
+}
+
+func (t *table) add(g data.GameDetails) {
+	o := g.Overview()
+	regulation := !decidedAfterRegulation(g, t.rules.RegulationPeriods)
+
+	t.side(o.Home(), o.Visitor(), o.HomeScore(), o.VisitorScore(), regulation, true)
+	t.side(o.Visitor(), o.Home(), o.VisitorScore(), o.HomeScore(), regulation, false)
+}
+
+func (t *table) side(team, opponent data.EntityID, gf, ga int, regulation, home bool) {
+	res := result{team: team, opponent: opponent, regulation: regulation}
+	p := t.rules.Points
+
+	switch {
+	case gf == ga:
+		res.outcome, res.points = Tie, p.Tie
+	case gf > ga && regulation:
+		res.outcome, res.points = Win, p.RegulationWin
+	case gf > ga:
+		res.outcome, res.points = Win, p.OvertimeWin
+	case regulation:
+		res.outcome, res.points = Loss, p.Loss
+	default:
+		res.outcome, res.points = OvertimeLoss, p.OvertimeLoss
+	}
+
+	r := t.row(team)
+	r.GamesPlayed++
+	r.Record.add(res.outcome)
+	r.Points += res.points
+	r.GoalsFor += gf
+	r.GoalsAgainst += ga
+
+	if res.outcome == Win && regulation {
+		r.RegulationWins++
+	}
+
+	if home {
+		r.Home.add(res.outcome)
+	} else {
+		r.Away.add(res.outcome)
+	}
+
+	t.results[team] = append(t.results[team], res)
+}
+
+func (t *table) finish(team data.EntityID, r *Row) {
+	r.Diff = r.GoalsFor - r.GoalsAgainst
+
+	results := t.results[team]
+	for _, res := range results[max(0, len(results)-10):] {
+		r.LastTen.add(res.outcome)
+	}
+
+	if len(results) == 0 {
+		return
+	}
+
+	last, count := results[len(results)-1].outcome, 0
+	for i := len(results) - 1; i >= 0 && results[i].outcome == last; i-- {
+		count++
+	}
+
+	r.Streak = fmt.Sprintf("%s%d", last, count)
+}
+
+// Sorts by key (highest first), then breaks any ties with the next
+// tiebreaker, recursively
+func (t *table) order(rows []*Row, key func(*Row) int, tiebreakers []Tiebreaker) {
+	slices.SortStableFunc(rows, func(a, b *Row) int { return cmp.Compare(key(b), key(a)) })
+
+	if len(tiebreakers) == 0 {
+		return
+	}
+
+	for start := 0; start < len(rows); {
+		end := start + 1
+		for end < len(rows) && key(rows[end]) == key(rows[start]) {
+			end++
+		}
+
+		if tied := rows[start:end]; len(tied) > 1 {
+			t.order(tied, t.key(tiebreakers[0], tied), tiebreakers[1:])
+		}
+
+		start = end
+	}
+}
+
+func (t *table) key(tb Tiebreaker, tied []*Row) func(*Row) int {
+	switch tb {
+	case RegulationWins:
+		return func(r *Row) int { return r.RegulationWins }
+	case GoalDifferential:
+		return func(r *Row) int { return r.Diff }
+	}
+
+	// Head-to-head: points taken from the other tied teams
+	group := map[data.EntityID]bool{}
+	for _, r := range tied {
+		group[r.Team] = true
+	}
+
+	points := map[data.EntityID]int{}
+	for _, r := range tied {
+		for _, res := range t.results[r.Team] {
+			if group[res.opponent] {
+				points[r.Team] += res.points
+			}
+		}
+	}
+
+	return func(r *Row) int { return points[r.Team] }
+}
+
+/**
+ *
+ * A game went past regulation when the winner has fewer goals on the sheet
+ * than on the scoreboard (the extra one is the shootout), or when its
+ * deciding goal came after the regulation periods. The sheet may only list
+ * the regulation periods, so a goal timed past their end is overtime even
+ * when no extra period is recorded; periods missing from a short list take
+ * the last listed length. Games recorded without goals or period lengths
+ * count as regulation.
+ *
+ **/
+func decidedAfterRegulation(g data.GameDetails, regulationPeriods int) bool {
+	o := g.Overview()
+	if o.HomeScore() == o.VisitorScore() {
+		return false
+	}
+
+	winner, winning, losing := o.Home(), o.HomeScore(), o.VisitorScore()
+	if o.VisitorScore() > o.HomeScore() {
+		winner, winning, losing = o.Visitor(), o.VisitorScore(), o.HomeScore()
+	}
+
+	count, decider := 0, -1
+	for _, goal := range g.Goals() {
+		if goal.TeamID() != winner {
+			continue
+		}
+
+		if count++; count == losing+1 {
+			decider = goal.Timestamp()
+		}
+	}
+
+	if len(g.Goals()) > 0 && count < winning {
+		return true
+	}
+
+	lengths := g.PeriodLengths()
+	if len(lengths) == 0 {
+		return false
+	}
+
+	regulation := 0
+	for i := 0; i < regulationPeriods; i++ {
+		regulation += lengths[min(i, len(lengths)-1)]
+	}
+
+	return decider >= regulation
+}
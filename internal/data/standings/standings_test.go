@@ -0,0 +1,118 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package standings
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/data"
+	"shiftylogic.dev/hockey-tools/internal/test"
+)
+
+func game(id data.EntityID, day int, home, visitor data.EntityID, hs, vs int, periods []int, goals ...data.ScoringEvent) data.GameDetails {
+	when := time.Date(2024, 11, day, 19, 0, 0, 0, time.UTC)
+	overview := data.NewGameOverview(id, "", 0, nil, when, 0, home, visitor, hs, vs)
+	return data.NewGameDetails(overview, periods, goals, nil)
+}
+
+func goal(ts int, team data.EntityID) data.ScoringEvent {
+	return data.NewScoringEvent(ts, team, 0, 0, 0, nil, nil)
+}
+
+/**
+ *
+ * 1 beats 2 in regulation, 2 beats 3 in overtime, 3 beats 1 in a shootout
+ * (one more goal on the scoreboard than on the sheet) and 1 ties 3. Teams 1
+ * and 3 finish level on points under 2-1-0.
+ *
+ **/
+func sampleGames() []data.GameDetails {
+	regulation := []int{900, 900, 900}
+	overtime := []int{900, 900, 900, 300}
+
+	return []data.GameDetails{
+		game(4, 4, 1, 3, 2, 2, regulation),
+		game(1, 1, 1, 2, 3, 1, regulation),
+		game(2, 2, 2, 3, 2, 1, overtime, goal(100, 3), goal(200, 2), goal(2750, 2)),
+		game(3, 3, 3, 1, 2, 1, overtime, goal(100, 3), goal(200, 1)),
+	}
+}
+
+func teams(rows []Row) []data.EntityID {
+	ids := make([]data.EntityID, len(rows))
+	for i, r := range rows {
+		ids[i] = r.Team
+	}
+	return ids
+}
+
+func TestComputeRecords(t *testing.T) {
+	rows := Compute(sampleGames(), DefaultRules())
+	test.Expect(t, 3, len(rows), "three teams")
+
+	first := rows[0]
+	test.Expect(t, data.EntityID(1), first.Team, "regulation wins break the tie")
+	test.Expect(t, Record{Wins: 1, Ties: 1, OvertimeLosses: 1}, first.Record, "W-L-T-OTL")
+	test.Expect(t, 4, first.Points, "points")
+	test.Expect(t, 1, first.RegulationWins, "regulation wins")
+	test.Expect(t, 1, first.Diff, "goal differential")
+	test.Expect(t, "1-0-1-0", first.Home.String(), "home record")
+	test.Expect(t, "0-0-0-1", first.Away.String(), "away record")
+	test.Expect(t, "1-0-1-1", first.LastTen.String(), "last ten")
+	test.Expect(t, "T1", first.Streak, "streak")
+
+	third := rows[2]
+	test.Expect(t, data.EntityID(2), third.Team, "fewest points last")
+	test.Expect(t, Record{Wins: 1, Losses: 1}, third.Record, "overtime win")
+	test.Expect(t, "W1", third.Streak, "streak")
+}
+
+func TestComputeRules(t *testing.T) {
+	rules, err := NewRules("", []string{"head-to-head"})
+	test.NoError(t, err, "rules")
+	test.Expect(t, []data.EntityID{3, 1, 2}, teams(Compute(sampleGames(), rules)), "3 took more points from 1")
+
+	rules, err = NewRules("3-2-1-0", nil)
+	test.NoError(t, err, "rules")
+
+	rows := Compute(sampleGames(), rules)
+	test.Expect(t, []data.EntityID{1, 3, 2}, teams(rows), "regulation win is worth more")
+	test.Expect(t, 5, rows[0].Points, "3 + 1 + 1")
+	test.Expect(t, 4, rows[1].Points, "1 + 2 + 1")
+
+	// Only the default three periods on the sheet, winner scores in the fourth
+	overtime := game(5, 5, 2, 1, 1, 0, []int{900, 900, 900}, goal(2750, 2))
+	rows = Compute([]data.GameDetails{overtime}, rules)
+	test.Expect(t, []data.EntityID{2, 1}, teams(rows), "overtime winner first")
+	test.Expect(t, 2, rows[0].Points, "overtime win")
+	test.Expect(t, 1, rows[1].Points, "overtime loss")
+	test.Expect(t, Record{OvertimeLosses: 1}, rows[1].Record, "overtime loss record")
+
+	_, err = NewRules("4-3-2-1", nil)
+	test.Require(t, errors.Is(err, ErrorUnknownPointSystem), "unknown system")
+
+	_, err = NewRules("", []string{"coin-flip"})
+	test.Require(t, errors.Is(err, ErrorUnknownTiebreaker), "unknown tiebreaker")
+}
@@ -0,0 +1,72 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package data
+
+import "time"
+
+// Which games count. Zero values don't filter.
+type StatsScope struct {
+	Season EntityID
+	From   time.Time
+	To     time.Time
+	Tag    string
+	Team   EntityID
+}
+
+func (s StatsScope) Includes(when time.Time) bool {
+	return (s.From.IsZero() || !when.Before(s.From)) && (s.To.IsZero() || when.Before(s.To))
+}
+
+type PlayerStats struct {
+	Player EntityID `json:"player"`
+	Team   EntityID `json:"team"`
+
+	// Games the player dressed for: on the team's roster when the game was
+	// first saved, or named on the sheet
+	GamesPlayed      int `json:"gp"`
+	Goals            int `json:"g"`
+	Assists          int `json:"a"`
+	Points           int `json:"pts"`
+	PenaltyMinutes   int `json:"pim"`
+	PowerPlayGoals   int `json:"ppg"`
+	ShortHandedGoals int `json:"shg"`
+	GameWinningGoals int `json:"gwg"`
+	PlusMinus        int `json:"plus_minus"`
+
+	PointsPerGame float64 `json:"points_per_game"`
+
+	// Consecutive games dressed for, counting back from the most recent in
+	// scope; a game without a point (or goal) breaks the streak
+	PointStreak        int `json:"point_streak"`
+	GoalStreak         int `json:"goal_streak"`
+	LongestPointStreak int `json:"longest_point_streak"`
+	LongestGoalStreak  int `json:"longest_goal_streak"`
+}
+
+type Stats interface {
+	Players(scope StatsScope) ([]PlayerStats, error)
+	Player(player EntityID, scope StatsScope) (PlayerStats, error)
+
+	// Recomputes the per-game cache for every game, returning how many
+	Rebuild() (int, error)
+}
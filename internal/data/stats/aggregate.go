@@ -0,0 +1,103 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package stats
+
+import (
+	"slices"
+	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/data"
+)
+
+// A cached line along with when its game was played
+type DatedLine struct {
+	Line
+	When time.Time
+}
+
+/**
+ *
+ * Sums lines into per-player totals. Streaks need the games in order, so
+ * lines are sorted by date first. A player's team is the one from their
+ * most recent game.
+ *
+ **/
+func Aggregate(lines []DatedLine) []data.PlayerStats {
+	slices.SortStableFunc(lines, func(a, b DatedLine) int { return a.When.Compare(b.When) })
+
+	totals := map[data.EntityID]*data.PlayerStats{}
+	order := []data.EntityID{}
+
+	for _, l := range lines {
+		s, ok := totals[l.Player]
+		if !ok {
+			s = &data.PlayerStats{Player: l.Player}
+			totals[l.Player] = s
+			order = append(order, l.Player)
+		}
+
+		s.Team = l.Team
+		s.GamesPlayed++
+		s.Goals += l.Goals
+		s.Assists += l.Assists
+		s.PenaltyMinutes += l.PenaltyMinutes
+		s.PowerPlayGoals += l.PowerPlayGoals
+		s.ShortHandedGoals += l.ShortHandedGoals
+		s.GameWinningGoals += l.GameWinningGoals
+		s.PlusMinus += l.PlusMinus
+
+		s.PointStreak = streak(s.PointStreak, l.Points() > 0)
+		s.GoalStreak = streak(s.GoalStreak, l.Goals > 0)
+		s.LongestPointStreak = max(s.LongestPointStreak, s.PointStreak)
+		s.LongestGoalStreak = max(s.LongestGoalStreak, s.GoalStreak)
+	}
+
+	ret := make([]data.PlayerStats, len(order))
+	for i, player := range order {
+		s := totals[player]
+		s.Points = s.Goals + s.Assists
+		s.PointsPerGame = float64(s.Points) / float64(s.GamesPlayed)
+		ret[i] = *s
+	}
+
+	// Scoring leaders first
+	slices.SortStableFunc(ret, func(a, b data.PlayerStats) int {
+		if a.Points != b.Points {
+			return b.Points - a.Points
+		}
+		if a.Goals != b.Goals {
+			return b.Goals - a.Goals
+		}
+		return a.GamesPlayed - b.GamesPlayed
+	})
+
+	return ret
+}
+
+func streak(current int, extended bool) int {
+	if extended {
+		return current + 1
+	}
+
+	return 0
+}
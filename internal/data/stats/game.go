@@ -0,0 +1,280 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package stats
+
+import (
+	"slices"
+
+	"shiftylogic.dev/hockey-tools/internal/data"
+)
+
+/**
+ *
+ * Per-game stat lines. These are what gets cached, one per player per game;
+ * season and career numbers are sums over them.
+ *
+ **/
+
+type Line struct {
+	Game   data.EntityID
+	Player data.EntityID
+	Team   data.EntityID
+
+	Goals            int
+	Assists          int
+	PenaltyMinutes   int
+	PowerPlayGoals   int
+	ShortHandedGoals int
+	GameWinningGoals int
+	PlusMinus        int
+}
+
+func (l *Line) Points() int { return l.Goals + l.Assists }
+
+const (
+	kMinorSeconds = 2 * 60
+)
+
+// Penalties of these lengths take a skater off the ice. Misconducts (10)
+// and game misconducts don't.
+var kStrengthPenalties = map[int]bool{2: true, 4: true, 5: true}
+
+type activePenalty struct {
+	team  data.EntityID
+	end   int
+	minor bool // released by a power play goal
+}
+
+/**
+ *
+ * Computes every player's line for one game. Roster maps player -> team for
+ * everyone who dressed; each gets a line (and so a game played) even with
+ * nothing on the sheet. Players that only show up in events get one too.
+ *
+ * Strength at each goal comes from the penalties running at the time: a
+ * goal by the team with more skaters is a power play goal and releases the
+ * opposing minor closest to expiring; with fewer, it's short-handed. Plus-
+ * minus skips power play goals, as usual.
+ *
+ **/
+func GameLines(game data.GameDetails, roster map[data.EntityID]data.EntityID) []Line {
+	lines := map[data.EntityID]*Line{}
+
+	line := func(player, team data.EntityID) *Line {
+		if player == 0 {
+			return nil
+		}
+
+		l, ok := lines[player]
+		if !ok {
+			l = &Line{Game: game.ID(), Player: player, Team: team}
+			lines[player] = l
+		}
+
+		return l
+	}
+
+	for player, team := range roster {
+		line(player, team)
+	}
+
+	overview := game.Overview()
+	opponent := func(team data.EntityID) data.EntityID {
+		if team == overview.Home() {
+			return overview.Visitor()
+		}
+		return overview.Home()
+	}
+
+	for _, p := range game.Penalties() {
+		if l := line(p.Committer(), p.TeamID()); l != nil {
+			l.PenaltyMinutes += p.Minutes()
+		}
+	}
+
+	gwg := gameWinningGoal(game)
+	penalties := game.Penalties()
+	active := []activePenalty{}
+	next := 0
+
+	for i, goal := range game.Goals() {
+		ts := goal.Timestamp()
+
+		for ; next < len(penalties) && penalties[next].Timestamp() < ts; next++ {
+			p := penalties[next]
+			if kStrengthPenalties[p.Minutes()] {
+				end := p.Timestamp() + p.Minutes()*60
+				active = append(active, activePenalty{p.TeamID(), end, p.Minutes() != 5})
+			}
+		}
+
+		active = slices.DeleteFunc(active, func(p activePenalty) bool { return p.end <= ts })
+
+		team, other := goal.TeamID(), opponent(goal.TeamID())
+		short := 0
+		for _, p := range active {
+			switch p.team {
+			case team:
+				short++
+			case other:
+				short--
+			}
+		}
+
+		powerPlay, shortHanded := short < 0, short > 0
+
+		if l := line(goal.Scorer(), team); l != nil {
+			l.Goals++
+			if powerPlay {
+				l.PowerPlayGoals++
+			}
+			if shortHanded {
+				l.ShortHandedGoals++
+			}
+			if i == gwg {
+				l.GameWinningGoals++
+			}
+		}
+
+		for _, a := range []data.EntityID{goal.PrimaryAssist(), goal.SecondaryAssist()} {
+			if l := line(a, team); l != nil {
+				l.Assists++
+			}
+		}
+
+		if powerPlay {
+			active = releaseMinor(active, other, ts)
+			continue
+		}
+
+		// The scorer and assists were on the ice, whether or not listed
+		plus := append([]data.EntityID{goal.Scorer(), goal.PrimaryAssist(), goal.SecondaryAssist()}, goal.Other()...)
+		slices.Sort(plus)
+		for _, player := range slices.Compact(plus) {
+			if l := line(player, team); l != nil {
+				l.PlusMinus++
+			}
+		}
+
+		for _, player := range goal.Defenders() {
+			if l := line(player, other); l != nil {
+				l.PlusMinus--
+			}
+		}
+	}
+
+	ret := make([]Line, 0, len(lines))
+	for _, l := range lines {
+		ret = append(ret, *l)
+	}
+
+	slices.SortFunc(ret, func(a, b Line) int { return int(a.Player - b.Player) })
+	return ret
+}
+
+/**
+ *
+ * Everyone the sheet names, mapped to the team they played for: scorers,
+ * assists and skaters on the ice (for the scoring team), defenders (for the
+ * other team) and players who took or served a penalty. Added to a game's
+ * recorded lineup, it catches call-ups who weren't on the roster.
+ *
+ **/
+func SheetRoster(game data.GameDetails) map[data.EntityID]data.EntityID {
+	roster := map[data.EntityID]data.EntityID{}
+
+	add := func(team data.EntityID, players ...data.EntityID) {
+		for _, player := range players {
+			if player != 0 {
+				roster[player] = team
+			}
+		}
+	}
+
+	overview := game.Overview()
+	for _, goal := range game.Goals() {
+		other := overview.Home()
+		if goal.TeamID() == other {
+			other = overview.Visitor()
+		}
+
+		add(goal.TeamID(), goal.Scorer(), goal.PrimaryAssist(), goal.SecondaryAssist())
+		add(goal.TeamID(), goal.Other()...)
+		add(other, goal.Defenders()...)
+	}
+
+	for _, p := range game.Penalties() {
+		add(p.TeamID(), p.Committer(), p.ServedBy())
+	}
+
+	return roster
+}
+
+// A power play goal ends the minor closest to expiring. A goal in the first
+// half of a double minor only ends the first half.
+func releaseMinor(active []activePenalty, team data.EntityID, ts int) []activePenalty {
+	idx := -1
+	for i, p := range active {
+		if p.team == team && p.minor && (idx < 0 || p.end < active[idx].end) {
+			idx = i
+		}
+	}
+
+	if idx < 0 {
+		return active
+	}
+
+	if active[idx].end-ts > kMinorSeconds {
+		active[idx].end = ts + kMinorSeconds
+		return active
+	}
+
+	return slices.Delete(active, idx, idx+1)
+}
+
+// The winner's goal that put them one past the loser's final total, or -1
+// (a tie, or a win decided by a shootout)
+func gameWinningGoal(game data.GameDetails) int {
+	o := game.Overview()
+
+	winner, losing := o.Home(), o.VisitorScore()
+	switch {
+	case o.HomeScore() == o.VisitorScore():
+		return -1
+	case o.VisitorScore() > o.HomeScore():
+		winner, losing = o.Visitor(), o.HomeScore()
+	}
+
+	count := 0
+	for i, goal := range game.Goals() {
+		if goal.TeamID() != winner {
+			continue
+		}
+
+		if count++; count == losing+1 {
+			return i
+		}
+	}
+
+	return -1
+}
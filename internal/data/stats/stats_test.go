@@ -0,0 +1,115 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package stats
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/data"
+	"shiftylogic.dev/hockey-tools/internal/test"
+)
+
+const (
+	kHome    data.EntityID = 1
+	kVisitor data.EntityID = 2
+)
+
+func sampleGame() data.GameDetails {
+	goals := []data.ScoringEvent{
+		// On the power play, with 20 in the box
+		data.NewScoringEvent(100, kHome, 10, 11, 0, []data.EntityID{10, 11, 12}, []data.EntityID{20, 21}),
+		// Short-handed, with 11 in the box; the game winner
+		data.NewScoringEvent(250, kHome, 12, 0, 0, []data.EntityID{12, 10}, []data.EntityID{21}),
+		// Even strength
+		data.NewScoringEvent(400, kVisitor, 21, 0, 0, []data.EntityID{21, 20}, []data.EntityID{10, 12}),
+	}
+
+	penalties := []data.PenaltyEvent{
+		data.NewPenaltyEvent(60, kVisitor, 20, 2, "Tripping", 0),
+		data.NewPenaltyEvent(200, kHome, 11, 2, "Hooking", 0),
+	}
+
+	overview := data.NewGameOverview(7, "G-7", 0, nil, time.Now(), 0, kHome, kVisitor, 2, 1)
+	return data.NewGameDetails(overview, []int{900, 900, 900}, goals, penalties)
+}
+
+func TestGameLines(t *testing.T) {
+	roster := map[data.EntityID]data.EntityID{10: kHome, 11: kHome, 12: kHome, 13: kHome, 20: kVisitor, 21: kVisitor}
+
+	lines := map[data.EntityID]Line{}
+	for _, l := range GameLines(sampleGame(), roster) {
+		lines[l.Player] = l
+	}
+
+	test.Expect(t, 6, len(lines), "a line for everyone dressed")
+
+	expected := []Line{
+		{Game: 7, Player: 10, Team: kHome, Goals: 1, PowerPlayGoals: 1},
+		{Game: 7, Player: 11, Team: kHome, Assists: 1, PenaltyMinutes: 2},
+		{Game: 7, Player: 12, Team: kHome, Goals: 1, ShortHandedGoals: 1, GameWinningGoals: 1},
+		{Game: 7, Player: 13, Team: kHome},
+		{Game: 7, Player: 20, Team: kVisitor, PenaltyMinutes: 2, PlusMinus: 1},
+		{Game: 7, Player: 21, Team: kVisitor, Goals: 1},
+	}
+
+	for _, e := range expected {
+		test.Expect(t, e, lines[e.Player], fmt.Sprintf("line for player %d", e.Player))
+	}
+}
+
+func TestSheetRoster(t *testing.T) {
+	penalty := data.NewPenaltyEvent(500, kHome, 0, 2, "Too many men", 14)
+	game := sampleGame()
+	game = data.NewGameDetails(game.Overview(), game.PeriodLengths(), game.Goals(), append(game.Penalties(), penalty))
+
+	roster := SheetRoster(game)
+	expected := map[data.EntityID]data.EntityID{10: kHome, 11: kHome, 12: kHome, 14: kHome, 20: kVisitor, 21: kVisitor}
+	test.Expect(t, expected, roster, "everyone named on the sheet")
+}
+
+func TestAggregateStreaks(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 11, d, 19, 0, 0, 0, time.UTC) }
+
+	// Out of order on purpose
+	lines := []DatedLine{
+		{Line{Game: 3, Player: 10, Goals: 1}, day(3)},
+		{Line{Game: 1, Player: 10, Assists: 2}, day(1)},
+		{Line{Game: 2, Player: 10}, day(2)},
+		{Line{Game: 4, Player: 10, Goals: 1, Assists: 1}, day(4)},
+		{Line{Game: 4, Player: 20, Goals: 1}, day(4)},
+	}
+
+	totals := Aggregate(lines)
+	test.Expect(t, 2, len(totals), "two players")
+
+	s := totals[0]
+	test.Expect(t, data.EntityID(10), s.Player, "leader first")
+	test.Expect(t, 4, s.GamesPlayed, "games played")
+	test.Expect(t, 5, s.Points, "points")
+	test.Expect(t, 1.25, s.PointsPerGame, "points per game")
+	test.Expect(t, 2, s.PointStreak, "current point streak")
+	test.Expect(t, 2, s.GoalStreak, "current goal streak")
+	test.Expect(t, 2, s.LongestPointStreak, "longest point streak")
+}
@@ -22,9 +22,26 @@
 
 package data
 
+import "context"
+
 type Store interface {
 	Close()
+	Ping(ctx context.Context) error
 
 	Facilities() Facilities
+	Players() Players
 	Staff() Staff
+	Teams() Teams
+	Leagues() Leagues
+	Seasons() Seasons
+	Divisions() Divisions
+	Games() Games
+	Reviews() Reviews
+	Stats() Stats
+	Users() Users
+	Clients() Clients
+
+	// Runs fn with a view of the store whose writes all commit together,
+	// or not at all when fn returns an error. Don't Close it.
+	Atomically(fn func(Store) error) error
 }
@@ -22,12 +22,57 @@
 
 package data
 
+import (
+	"errors"
+	"fmt"
+)
+
 type Team interface {
 	ID() EntityID
+	ExternalID() string
 	Name() string
 }
 
 type Teams interface {
-	List(token int64) ([]Team, int64)
-	ByID(id EntityID) Team
+	List(token int64) ([]Team, int64, error)
+	ByID(id EntityID) (Team, error)
+	ByExternalID(ext string) (Team, error)
+	ByName(name string) ([]Team, error)
+
+	// Inserts the team, or replaces the existing one when ID() is set.
+	Save(t Team) (EntityID, error)
+}
+
+// Finds a team by external ID or, failing that, by an unambiguous name
+func FindTeam(teams Teams, ref string) (Team, error) {
+	t, err := teams.ByExternalID(ref)
+	if err == nil || !errors.Is(err, ErrorUnknownTeamID) {
+		return t, err
+	}
+
+	matches, err := teams.ByName(ref)
+	switch {
+	case err != nil:
+		return nil, err
+	case len(matches) == 0:
+		return nil, fmt.Errorf("%w '%s'", ErrorUnknownTeamID, ref)
+	case len(matches) > 1:
+		return nil, fmt.Errorf("'%s' names %d teams; use an external id", ref, len(matches))
+	}
+
+	return matches[0], nil
+}
+
+type team struct {
+	id         EntityID
+	externalID string
+	name       string
+}
+
+func (t *team) ID() EntityID       { return t.id }
+func (t *team) ExternalID() string { return t.externalID }
+func (t *team) Name() string       { return t.name }
+
+func NewTeam(id EntityID, externalID, name string) Team {
+	return &team{id, externalID, name}
 }
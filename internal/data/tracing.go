@@ -0,0 +1,693 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package data
+
+import (
+	"context"
+	"log/slog"
+
+	"shiftylogic.dev/hockey-tools/internal/trace"
+)
+
+/**
+ *
+ * A Store view bound to a request context, so each call is recorded as a
+ * child span of the request.
+ *
+ **/
+
+type tracedStore struct {
+	ctx   context.Context
+	store Store
+}
+
+func Traced(ctx context.Context, store Store) Store {
+	return &tracedStore{ctx, store}
+}
+
+func (t *tracedStore) Close()                         { t.store.Close() }
+func (t *tracedStore) Ping(ctx context.Context) error { return t.store.Ping(ctx) }
+func (t *tracedStore) Facilities() Facilities         { return &tracedFacilities{t.ctx, t.store.Facilities()} }
+func (t *tracedStore) Players() Players               { return &tracedPlayers{t.ctx, t.store.Players()} }
+func (t *tracedStore) Staff() Staff                   { return &tracedStaff{t.ctx, t.store.Staff()} }
+func (t *tracedStore) Teams() Teams                   { return &tracedTeams{t.ctx, t.store.Teams()} }
+func (t *tracedStore) Leagues() Leagues               { return &tracedLeagues{t.ctx, t.store.Leagues()} }
+func (t *tracedStore) Seasons() Seasons               { return &tracedSeasons{t.ctx, t.store.Seasons()} }
+func (t *tracedStore) Divisions() Divisions           { return &tracedDivisions{t.ctx, t.store.Divisions()} }
+func (t *tracedStore) Games() Games                   { return &tracedGames{t.ctx, t.store.Games()} }
+func (t *tracedStore) Reviews() Reviews               { return &tracedReviews{t.ctx, t.store.Reviews()} }
+func (t *tracedStore) Stats() Stats                   { return &tracedStats{t.ctx, t.store.Stats()} }
+func (t *tracedStore) Users() Users                   { return &tracedUsers{t.ctx, t.store.Users()} }
+func (t *tracedStore) Clients() Clients               { return &tracedClients{t.ctx, t.store.Clients()} }
+
+func (t *tracedStore) Atomically(fn func(Store) error) error {
+	span := startSpan(t.ctx, "Store.Atomically")
+	defer span.End()
+
+	err := t.store.Atomically(func(s Store) error { return fn(Traced(t.ctx, s)) })
+	span.RecordError(err)
+	return err
+}
+
+func startSpan(ctx context.Context, op string) *trace.Span {
+	_, span := trace.Start(ctx, "data."+op, slog.String("db.system", "sqlite"))
+	return span
+}
+
+type tracedFacilities struct {
+	ctx        context.Context
+	facilities Facilities
+}
+
+func (t *tracedFacilities) List(token int64) ([]Facility, int64, error) {
+	span := startSpan(t.ctx, "Facilities.List")
+	defer span.End()
+
+	v, next, err := t.facilities.List(token)
+	span.RecordError(err)
+	return v, next, err
+}
+
+func (t *tracedFacilities) ByID(id EntityID) (Facility, error) {
+	span := startSpan(t.ctx, "Facilities.ByID")
+	defer span.End()
+
+	v, err := t.facilities.ByID(id)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedFacilities) ByExternalID(ext string) (Facility, error) {
+	span := startSpan(t.ctx, "Facilities.ByExternalID")
+	defer span.End()
+
+	v, err := t.facilities.ByExternalID(ext)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedFacilities) Save(f Facility) (EntityID, error) {
+	span := startSpan(t.ctx, "Facilities.Save")
+	defer span.End()
+
+	v, err := t.facilities.Save(f)
+	span.RecordError(err)
+	return v, err
+}
+
+type tracedPlayers struct {
+	ctx     context.Context
+	players Players
+}
+
+func (t *tracedPlayers) List(token int64) ([]Player, int64, error) {
+	span := startSpan(t.ctx, "Players.List")
+	defer span.End()
+
+	v, next, err := t.players.List(token)
+	span.RecordError(err)
+	return v, next, err
+}
+
+func (t *tracedPlayers) ByID(id EntityID) (Player, error) {
+	span := startSpan(t.ctx, "Players.ByID")
+	defer span.End()
+
+	v, err := t.players.ByID(id)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedPlayers) ByTeam(team EntityID) ([]Player, error) {
+	span := startSpan(t.ctx, "Players.ByTeam")
+	defer span.End()
+
+	v, err := t.players.ByTeam(team)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedPlayers) ByExternalID(ext string) (Player, error) {
+	span := startSpan(t.ctx, "Players.ByExternalID")
+	defer span.End()
+
+	v, err := t.players.ByExternalID(ext)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedPlayers) Save(p Player) (EntityID, error) {
+	span := startSpan(t.ctx, "Players.Save")
+	defer span.End()
+
+	v, err := t.players.Save(p)
+	span.RecordError(err)
+	return v, err
+}
+
+type tracedStaff struct {
+	ctx   context.Context
+	staff Staff
+}
+
+func (t *tracedStaff) List(token int64) ([]StaffMember, int64, error) {
+	span := startSpan(t.ctx, "Staff.List")
+	defer span.End()
+
+	v, next, err := t.staff.List(token)
+	span.RecordError(err)
+	return v, next, err
+}
+
+func (t *tracedStaff) ByID(id EntityID) (StaffMember, error) {
+	span := startSpan(t.ctx, "Staff.ByID")
+	defer span.End()
+
+	v, err := t.staff.ByID(id)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedStaff) ByTeam(team EntityID) ([]StaffMember, error) {
+	span := startSpan(t.ctx, "Staff.ByTeam")
+	defer span.End()
+
+	v, err := t.staff.ByTeam(team)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedStaff) ByExternalID(ext string) (StaffMember, error) {
+	span := startSpan(t.ctx, "Staff.ByExternalID")
+	defer span.End()
+
+	v, err := t.staff.ByExternalID(ext)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedStaff) Save(sm StaffMember) (EntityID, error) {
+	span := startSpan(t.ctx, "Staff.Save")
+	defer span.End()
+
+	v, err := t.staff.Save(sm)
+	span.RecordError(err)
+	return v, err
+}
+
+type tracedTeams struct {
+	ctx   context.Context
+	teams Teams
+}
+
+func (t *tracedTeams) List(token int64) ([]Team, int64, error) {
+	span := startSpan(t.ctx, "Teams.List")
+	defer span.End()
+
+	v, next, err := t.teams.List(token)
+	span.RecordError(err)
+	return v, next, err
+}
+
+func (t *tracedTeams) ByID(id EntityID) (Team, error) {
+	span := startSpan(t.ctx, "Teams.ByID")
+	defer span.End()
+
+	v, err := t.teams.ByID(id)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedTeams) ByExternalID(ext string) (Team, error) {
+	span := startSpan(t.ctx, "Teams.ByExternalID")
+	defer span.End()
+
+	v, err := t.teams.ByExternalID(ext)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedTeams) ByName(name string) ([]Team, error) {
+	span := startSpan(t.ctx, "Teams.ByName")
+	defer span.End()
+
+	v, err := t.teams.ByName(name)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedTeams) Save(team Team) (EntityID, error) {
+	span := startSpan(t.ctx, "Teams.Save")
+	defer span.End()
+
+	v, err := t.teams.Save(team)
+	span.RecordError(err)
+	return v, err
+}
+
+type tracedLeagues struct {
+	ctx     context.Context
+	leagues Leagues
+}
+
+func (t *tracedLeagues) List(token int64) ([]League, int64, error) {
+	span := startSpan(t.ctx, "Leagues.List")
+	defer span.End()
+
+	v, next, err := t.leagues.List(token)
+	span.RecordError(err)
+	return v, next, err
+}
+
+func (t *tracedLeagues) ByID(id EntityID) (League, error) {
+	span := startSpan(t.ctx, "Leagues.ByID")
+	defer span.End()
+
+	v, err := t.leagues.ByID(id)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedLeagues) ByExternalID(ext string) (League, error) {
+	span := startSpan(t.ctx, "Leagues.ByExternalID")
+	defer span.End()
+
+	v, err := t.leagues.ByExternalID(ext)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedLeagues) Save(league League) (EntityID, error) {
+	span := startSpan(t.ctx, "Leagues.Save")
+	defer span.End()
+
+	v, err := t.leagues.Save(league)
+	span.RecordError(err)
+	return v, err
+}
+
+type tracedSeasons struct {
+	ctx     context.Context
+	seasons Seasons
+}
+
+func (t *tracedSeasons) List(token int64) ([]Season, int64, error) {
+	span := startSpan(t.ctx, "Seasons.List")
+	defer span.End()
+
+	v, next, err := t.seasons.List(token)
+	span.RecordError(err)
+	return v, next, err
+}
+
+func (t *tracedSeasons) ByID(id EntityID) (Season, error) {
+	span := startSpan(t.ctx, "Seasons.ByID")
+	defer span.End()
+
+	v, err := t.seasons.ByID(id)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedSeasons) ByExternalID(ext string) (Season, error) {
+	span := startSpan(t.ctx, "Seasons.ByExternalID")
+	defer span.End()
+
+	v, err := t.seasons.ByExternalID(ext)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedSeasons) ByLeague(league EntityID) ([]Season, error) {
+	span := startSpan(t.ctx, "Seasons.ByLeague")
+	defer span.End()
+
+	v, err := t.seasons.ByLeague(league)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedSeasons) Save(season Season) (EntityID, error) {
+	span := startSpan(t.ctx, "Seasons.Save")
+	defer span.End()
+
+	v, err := t.seasons.Save(season)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedSeasons) Teams(season EntityID) ([]Membership, error) {
+	span := startSpan(t.ctx, "Seasons.Teams")
+	defer span.End()
+
+	v, err := t.seasons.Teams(season)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedSeasons) TeamSeasons(team EntityID) ([]Membership, error) {
+	span := startSpan(t.ctx, "Seasons.TeamSeasons")
+	defer span.End()
+
+	v, err := t.seasons.TeamSeasons(team)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedSeasons) SetTeam(m Membership) error {
+	span := startSpan(t.ctx, "Seasons.SetTeam")
+	defer span.End()
+
+	err := t.seasons.SetTeam(m)
+	span.RecordError(err)
+	return err
+}
+
+func (t *tracedSeasons) RemoveTeam(season, team EntityID) error {
+	span := startSpan(t.ctx, "Seasons.RemoveTeam")
+	defer span.End()
+
+	err := t.seasons.RemoveTeam(season, team)
+	span.RecordError(err)
+	return err
+}
+
+type tracedDivisions struct {
+	ctx       context.Context
+	divisions Divisions
+}
+
+func (t *tracedDivisions) List(token int64) ([]Division, int64, error) {
+	span := startSpan(t.ctx, "Divisions.List")
+	defer span.End()
+
+	v, next, err := t.divisions.List(token)
+	span.RecordError(err)
+	return v, next, err
+}
+
+func (t *tracedDivisions) ByID(id EntityID) (Division, error) {
+	span := startSpan(t.ctx, "Divisions.ByID")
+	defer span.End()
+
+	v, err := t.divisions.ByID(id)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedDivisions) ByExternalID(ext string) (Division, error) {
+	span := startSpan(t.ctx, "Divisions.ByExternalID")
+	defer span.End()
+
+	v, err := t.divisions.ByExternalID(ext)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedDivisions) ByLeague(league EntityID) ([]Division, error) {
+	span := startSpan(t.ctx, "Divisions.ByLeague")
+	defer span.End()
+
+	v, err := t.divisions.ByLeague(league)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedDivisions) BySeason(season EntityID) ([]Division, error) {
+	span := startSpan(t.ctx, "Divisions.BySeason")
+	defer span.End()
+
+	v, err := t.divisions.BySeason(season)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedDivisions) Save(division Division) (EntityID, error) {
+	span := startSpan(t.ctx, "Divisions.Save")
+	defer span.End()
+
+	v, err := t.divisions.Save(division)
+	span.RecordError(err)
+	return v, err
+}
+
+type tracedGames struct {
+	ctx   context.Context
+	games Games
+}
+
+func (t *tracedGames) List(token int64) ([]GameOverview, int64, error) {
+	span := startSpan(t.ctx, "Games.List")
+	defer span.End()
+
+	v, next, err := t.games.List(token)
+	span.RecordError(err)
+	return v, next, err
+}
+
+func (t *tracedGames) ByID(id EntityID) (GameOverview, error) {
+	span := startSpan(t.ctx, "Games.ByID")
+	defer span.End()
+
+	v, err := t.games.ByID(id)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedGames) ByExternalID(ext string) (GameOverview, error) {
+	span := startSpan(t.ctx, "Games.ByExternalID")
+	defer span.End()
+
+	v, err := t.games.ByExternalID(ext)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedGames) ByTeam(id EntityID) ([]GameOverview, error) {
+	span := startSpan(t.ctx, "Games.ByTeam")
+	defer span.End()
+
+	v, err := t.games.ByTeam(id)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedGames) BySeason(season EntityID) ([]GameOverview, error) {
+	span := startSpan(t.ctx, "Games.BySeason")
+	defer span.End()
+
+	v, err := t.games.BySeason(season)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedGames) DetailsByGame(game EntityID) (GameDetails, error) {
+	span := startSpan(t.ctx, "Games.DetailsByGame")
+	defer span.End()
+
+	v, err := t.games.DetailsByGame(game)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedGames) DetailsByGames(games []EntityID) ([]GameDetails, error) {
+	span := startSpan(t.ctx, "Games.DetailsByGames")
+	defer span.End()
+
+	v, err := t.games.DetailsByGames(games)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedGames) Save(game GameDetails) (EntityID, error) {
+	span := startSpan(t.ctx, "Games.Save")
+	defer span.End()
+
+	v, err := t.games.Save(game)
+	span.RecordError(err)
+	return v, err
+}
+
+type tracedReviews struct {
+	ctx     context.Context
+	reviews Reviews
+}
+
+func (t *tracedReviews) Open(token int64) ([]ReviewItem, int64, error) {
+	span := startSpan(t.ctx, "Reviews.Open")
+	defer span.End()
+
+	v, next, err := t.reviews.Open(token)
+	span.RecordError(err)
+	return v, next, err
+}
+
+func (t *tracedReviews) ByGame(game EntityID) ([]ReviewItem, error) {
+	span := startSpan(t.ctx, "Reviews.ByGame")
+	defer span.End()
+
+	v, err := t.reviews.ByGame(game)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedReviews) Add(item ReviewItem) (EntityID, error) {
+	span := startSpan(t.ctx, "Reviews.Add")
+	defer span.End()
+
+	v, err := t.reviews.Add(item)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedReviews) DeleteByGame(game EntityID) error {
+	span := startSpan(t.ctx, "Reviews.DeleteByGame")
+	defer span.End()
+
+	err := t.reviews.DeleteByGame(game)
+	span.RecordError(err)
+	return err
+}
+
+func (t *tracedReviews) Resolve(id, player EntityID) error {
+	span := startSpan(t.ctx, "Reviews.Resolve")
+	defer span.End()
+
+	err := t.reviews.Resolve(id, player)
+	span.RecordError(err)
+	return err
+}
+
+func (t *tracedReviews) Dismiss(id EntityID) error {
+	span := startSpan(t.ctx, "Reviews.Dismiss")
+	defer span.End()
+
+	err := t.reviews.Dismiss(id)
+	span.RecordError(err)
+	return err
+}
+
+type tracedStats struct {
+	ctx   context.Context
+	stats Stats
+}
+
+func (t *tracedStats) Players(scope StatsScope) ([]PlayerStats, error) {
+	span := startSpan(t.ctx, "Stats.Players")
+	defer span.End()
+
+	v, err := t.stats.Players(scope)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedStats) Player(player EntityID, scope StatsScope) (PlayerStats, error) {
+	span := startSpan(t.ctx, "Stats.Player")
+	defer span.End()
+
+	v, err := t.stats.Player(player, scope)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedStats) Rebuild() (int, error) {
+	span := startSpan(t.ctx, "Stats.Rebuild")
+	defer span.End()
+
+	v, err := t.stats.Rebuild()
+	span.RecordError(err)
+	return v, err
+}
+
+type tracedUsers struct {
+	ctx   context.Context
+	users Users
+}
+
+func (t *tracedUsers) List(token int64) ([]User, int64, error) {
+	span := startSpan(t.ctx, "Users.List")
+	defer span.End()
+
+	v, next, err := t.users.List(token)
+	span.RecordError(err)
+	return v, next, err
+}
+
+func (t *tracedUsers) ByEmail(email string) (User, error) {
+	span := startSpan(t.ctx, "Users.ByEmail")
+	defer span.End()
+
+	v, err := t.users.ByEmail(email)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedUsers) Add(email, passwordHash string) (EntityID, error) {
+	span := startSpan(t.ctx, "Users.Add")
+	defer span.End()
+
+	v, err := t.users.Add(email, passwordHash)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedUsers) SetPassword(email, passwordHash string) error {
+	span := startSpan(t.ctx, "Users.SetPassword")
+	defer span.End()
+
+	err := t.users.SetPassword(email, passwordHash)
+	span.RecordError(err)
+	return err
+}
+
+type tracedClients struct {
+	ctx     context.Context
+	clients Clients
+}
+
+func (t *tracedClients) List() ([]Client, error) {
+	span := startSpan(t.ctx, "Clients.List")
+	defer span.End()
+
+	v, err := t.clients.List()
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedClients) ByID(id string) (Client, error) {
+	span := startSpan(t.ctx, "Clients.ByID")
+	defer span.End()
+
+	v, err := t.clients.ByID(id)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedClients) Add(c Client) error {
+	span := startSpan(t.ctx, "Clients.Add")
+	defer span.End()
+
+	err := t.clients.Add(c)
+	span.RecordError(err)
+	return err
+}
@@ -0,0 +1,207 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package transfer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// A row as read, keyed by source header. Line is the CSV line number or the
+// JSON record number, whichever a person would look for in the file.
+type sourceRow struct {
+	line   int
+	values map[string]string
+}
+
+func readRows(in io.Reader, format Format) ([]string, []sourceRow, error) {
+	switch format {
+	case FormatCSV:
+		return readCSV(in)
+	case FormatJSON:
+		return readJSON(in)
+	}
+
+	return nil, nil, fmt.Errorf("%w '%s'", ErrorUnknownFormat, format)
+}
+
+func readCSV(in io.Reader) ([]string, []sourceRow, error) {
+	r := csv.NewReader(in)
+	r.TrimLeadingSpace = true
+
+	headers, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, errors.New("empty file")
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	// Spreadsheets love to save a byte order mark
+	headers[0] = strings.TrimPrefix(headers[0], "\ufeff")
+
+	rows := []sourceRow{}
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, nil, err
+		}
+
+		line, _ := r.FieldPos(0)
+		values := make(map[string]string, len(headers))
+		for i, h := range headers {
+			values[h] = record[i]
+		}
+
+		rows = append(rows, sourceRow{line, values})
+	}
+
+	return headers, rows, nil
+}
+
+func readJSON(in io.Reader) ([]string, []sourceRow, error) {
+	dec := json.NewDecoder(in)
+	dec.UseNumber()
+
+	var records []map[string]any
+	if err := dec.Decode(&records); err != nil {
+		return nil, nil, err
+	}
+
+	headers := []string{}
+	rows := make([]sourceRow, len(records))
+	for i, record := range records {
+		values := make(map[string]string, len(record))
+		for k, v := range record {
+			if !slices.Contains(headers, k) {
+				headers = append(headers, k)
+			}
+
+			switch v := v.(type) {
+			case nil:
+				values[k] = ""
+			case string:
+				values[k] = v
+			case json.Number, bool:
+				values[k] = fmt.Sprint(v)
+			default:
+				return nil, nil, fmt.Errorf("record %d: '%s' must be a string or number", i+1, k)
+			}
+		}
+
+		rows[i] = sourceRow{i + 1, values}
+	}
+
+	slices.Sort(headers)
+	return headers, rows, nil
+}
+
+/**
+ *
+ * Writers take rows in field order. JSON rows are objects with the empty
+ * values left out; CSV always has every column.
+ *
+ **/
+
+type rowWriter interface {
+	Write(values []string) error
+	Close() error
+}
+
+func newRowWriter(out io.Writer, format Format, fields []string) (rowWriter, error) {
+	switch format {
+	case FormatCSV:
+		w := csv.NewWriter(out)
+		return &csvWriter{w}, w.Write(fields)
+	case FormatJSON:
+		return &jsonWriter{out: out, fields: fields}, nil
+	}
+
+	return nil, fmt.Errorf("%w '%s'", ErrorUnknownFormat, format)
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvWriter) Write(values []string) error { return c.w.Write(values) }
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type jsonWriter struct {
+	out    io.Writer
+	fields []string
+	count  int
+}
+
+func (j *jsonWriter) Write(values []string) error {
+	// Build the object by hand to keep the field order stable
+	var b strings.Builder
+	if j.count == 0 {
+		b.WriteString("[\n  {")
+	} else {
+		b.WriteString(",\n  {")
+	}
+
+	first := true
+	for i, v := range values {
+		if v == "" {
+			continue
+		}
+
+		if !first {
+			b.WriteString(", ")
+		}
+		first = false
+
+		key, _ := json.Marshal(j.fields[i])
+		val, _ := json.Marshal(v)
+		b.Write(key)
+		b.WriteString(": ")
+		b.Write(val)
+	}
+	b.WriteString("}")
+
+	j.count++
+	_, err := io.WriteString(j.out, b.String())
+	return err
+}
+
+func (j *jsonWriter) Close() error {
+	end := "\n]\n"
+	if j.count == 0 {
+		end = "[]\n"
+	}
+
+	_, err := io.WriteString(j.out, end)
+	return err
+}
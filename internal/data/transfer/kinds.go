@@ -0,0 +1,734 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package transfer
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/data"
+)
+
+const (
+	kMaxJerseyNumber = 99
+	kDateLayout      = "2006-01-02"
+)
+
+type row struct {
+	line   int
+	values map[string]string
+	result *Result
+	failed bool
+}
+
+func (r *row) get(field string) string { return r.values[field] }
+
+// Columns missing from the source leave the existing value alone
+func (r *row) keep(field, existing string) string {
+	if v, ok := r.values[field]; ok {
+		return v
+	}
+
+	return existing
+}
+
+func (r *row) fail(field string, err error) {
+	r.failed = true
+	r.result.fail(r.line, field, err)
+}
+
+func (r *row) checkRequired(fields []string) bool {
+	for _, f := range fields {
+		if r.get(f) == "" {
+			r.fail(f, errors.New("required"))
+		}
+	}
+
+	return !r.failed
+}
+
+type op struct {
+	line   int
+	update bool
+	save   func(store data.Store) (data.EntityID, error)
+}
+
+type entity interface {
+	ID() data.EntityID
+	ExternalID() string
+}
+
+type slot struct {
+	team   data.EntityID
+	number int
+}
+
+type holder struct {
+	id   data.EntityID
+	name string
+	line int // 0 when already in the store
+}
+
+type teamRef struct {
+	id  data.EntityID
+	err error
+}
+
+type membershipKey struct {
+	season data.EntityID
+	team   data.EntityID
+}
+
+type importer struct {
+	store data.Store
+	kind  Kind
+
+	externalIDs map[string]int
+	targets     map[data.EntityID]int
+	teams       map[string]teamRef
+
+	// Jersey numbers, per team, as they'll be after the rows so far
+	slots       map[slot]holder
+	loaded      map[data.EntityID]bool
+	placeholder data.EntityID
+
+	// Season memberships, as they'll be after the rows so far
+	members map[membershipKey]int
+	rosters map[data.EntityID]bool
+}
+
+func newImporter(store data.Store, kind Kind) *importer {
+	return &importer{
+		store:       store,
+		kind:        kind,
+		externalIDs: map[string]int{},
+		targets:     map[data.EntityID]int{},
+		teams:       map[string]teamRef{},
+		slots:       map[slot]holder{},
+		loaded:      map[data.EntityID]bool{},
+		members:     map[membershipKey]int{},
+		rosters:     map[data.EntityID]bool{},
+	}
+}
+
+func (imp *importer) prepare(r *row) *op {
+	switch imp.kind {
+	case KindFacilities:
+		return imp.facility(r)
+	case KindTeams:
+		return imp.team(r)
+	case KindPlayers:
+		return imp.player(r)
+	case KindStaff:
+		return imp.staffMember(r)
+	case KindLeagues:
+		return imp.league(r)
+	case KindSeasons:
+		return imp.season(r)
+	case KindDivisions:
+		return imp.division(r)
+	case KindMemberships:
+		return imp.membership(r)
+	}
+
+	return nil
+}
+
+func (imp *importer) facility(r *row) *op {
+	facilities := imp.store.Facilities()
+
+	current, found := target(imp, r, facilities.ByID, facilities.ByExternalID, data.ErrorUnknownFacilityID)
+	if r.failed {
+		return nil
+	}
+
+	f := data.NewFacility(0, r.get(FieldExternalID), r.get(FieldName), r.get(FieldAddress), r.get(FieldCity), r.get(FieldState))
+	if found {
+		f = data.NewFacility(
+			current.ID(),
+			r.keep(FieldExternalID, current.ExternalID()),
+			r.get(FieldName),
+			r.keep(FieldAddress, current.Address()),
+			r.keep(FieldCity, current.City()),
+			r.keep(FieldState, current.State()))
+	}
+
+	return &op{r.line, found, func(store data.Store) (data.EntityID, error) { return store.Facilities().Save(f) }}
+}
+
+func (imp *importer) team(r *row) *op {
+	teams := imp.store.Teams()
+
+	current, found := target(imp, r, teams.ByID, teams.ByExternalID, data.ErrorUnknownTeamID)
+	if r.failed {
+		return nil
+	}
+
+	t := data.NewTeam(0, r.get(FieldExternalID), r.get(FieldName))
+	if found {
+		t = data.NewTeam(current.ID(), r.keep(FieldExternalID, current.ExternalID()), r.get(FieldName))
+	}
+
+	return &op{r.line, found, func(store data.Store) (data.EntityID, error) { return store.Teams().Save(t) }}
+}
+
+func (imp *importer) player(r *row) *op {
+	players := imp.store.Players()
+
+	current, found := target(imp, r, players.ByID, players.ByExternalID, data.ErrorUnknownPlayerID)
+	team, teamOK := imp.resolveTeam(r)
+
+	number, err := strconv.Atoi(r.get(FieldNumber))
+	if err != nil || number < 0 || number > kMaxJerseyNumber {
+		r.fail(FieldNumber, fmt.Errorf("'%s' is not a number from 0 to %d", r.get(FieldNumber), kMaxJerseyNumber))
+	}
+
+	if r.failed || !teamOK {
+		return nil
+	}
+
+	var id data.EntityID
+	ext := r.get(FieldExternalID)
+	if found {
+		id, ext = current.ID(), r.keep(FieldExternalID, current.ExternalID())
+	}
+
+	if !imp.claimNumber(r, current, id, team, number) {
+		return nil
+	}
+
+	p := data.NewPlayer(id, ext, team, r.get(FieldName), number)
+	return &op{r.line, found, func(store data.Store) (data.EntityID, error) { return store.Players().Save(p) }}
+}
+
+func (imp *importer) staffMember(r *row) *op {
+	staff := imp.store.Staff()
+
+	current, found := target(imp, r, staff.ByID, staff.ByExternalID, data.ErrorUnknownStaffID)
+	team, teamOK := imp.resolveTeam(r)
+	if r.failed || !teamOK {
+		return nil
+	}
+
+	sm := data.NewStaffMember(0, r.get(FieldExternalID), team, r.get(FieldName), r.get(FieldRole))
+	if found {
+		sm = data.NewStaffMember(current.ID(), r.keep(FieldExternalID, current.ExternalID()), team, r.get(FieldName), r.get(FieldRole))
+	}
+
+	return &op{r.line, found, func(store data.Store) (data.EntityID, error) { return store.Staff().Save(sm) }}
+}
+
+func (imp *importer) league(r *row) *op {
+	leagues := imp.store.Leagues()
+
+	current, found := target(imp, r, leagues.ByID, leagues.ByExternalID, data.ErrorUnknownLeagueID)
+	if r.failed {
+		return nil
+	}
+
+	l := data.NewLeague(0, r.get(FieldExternalID), r.get(FieldName))
+	if found {
+		l = data.NewLeague(current.ID(), r.keep(FieldExternalID, current.ExternalID()), r.get(FieldName))
+	}
+
+	return &op{r.line, found, func(store data.Store) (data.EntityID, error) { return store.Leagues().Save(l) }}
+}
+
+func (imp *importer) season(r *row) *op {
+	seasons := imp.store.Seasons()
+
+	current, found := target(imp, r, seasons.ByID, seasons.ByExternalID, data.ErrorUnknownSeasonID)
+	league, leagueOK := resolveRef(r, FieldLeague, imp.store.Leagues().ByID, imp.store.Leagues().ByExternalID, data.ErrorUnknownLeagueID)
+
+	var start, end time.Time
+	if found {
+		start, end = current.Start(), current.End()
+	}
+
+	start = parseDate(r, FieldStart, start)
+	end = parseDate(r, FieldEnd, end)
+
+	if !start.IsZero() && !end.IsZero() && end.Before(start) {
+		r.fail(FieldEnd, errors.New("must not be before the start"))
+	}
+
+	if r.failed || !leagueOK {
+		return nil
+	}
+
+	s := data.NewSeason(0, r.get(FieldExternalID), league.ID(), r.get(FieldName), start, end)
+	if found {
+		s = data.NewSeason(current.ID(), r.keep(FieldExternalID, current.ExternalID()), league.ID(), r.get(FieldName), start, end)
+	}
+
+	return &op{r.line, found, func(store data.Store) (data.EntityID, error) { return store.Seasons().Save(s) }}
+}
+
+func (imp *importer) division(r *row) *op {
+	divisions := imp.store.Divisions()
+
+	current, found := target(imp, r, divisions.ByID, divisions.ByExternalID, data.ErrorUnknownDivisionID)
+	league, leagueOK := resolveRef(r, FieldLeague, imp.store.Leagues().ByID, imp.store.Leagues().ByExternalID, data.ErrorUnknownLeagueID)
+	if r.failed || !leagueOK {
+		return nil
+	}
+
+	d := data.NewDivision(0, r.get(FieldExternalID), league.ID(), r.get(FieldName))
+	if found {
+		d = data.NewDivision(current.ID(), r.keep(FieldExternalID, current.ExternalID()), league.ID(), r.get(FieldName))
+	}
+
+	return &op{r.line, found, func(store data.Store) (data.EntityID, error) { return store.Divisions().Save(d) }}
+}
+
+// Memberships have no id of their own; a row for a team already in the
+// season moves it to the row's division.
+func (imp *importer) membership(r *row) *op {
+	seasons := imp.store.Seasons()
+
+	season, seasonOK := resolveRef(r, FieldSeason, seasons.ByID, seasons.ByExternalID, data.ErrorUnknownSeasonID)
+	team, teamOK := imp.resolveTeam(r)
+	if !seasonOK || !teamOK {
+		return nil
+	}
+
+	var division data.EntityID
+	if r.get(FieldDivision) != "" {
+		divisions := imp.store.Divisions()
+
+		d, ok := resolveRef(r, FieldDivision, divisions.ByID, divisions.ByExternalID, data.ErrorUnknownDivisionID)
+		if !ok {
+			return nil
+		}
+
+		if d.League() != season.League() {
+			r.fail(FieldDivision, fmt.Errorf("'%s' is in a different league than the season", r.get(FieldDivision)))
+			return nil
+		}
+
+		division = d.ID()
+	}
+
+	if err := imp.loadRoster(season.ID()); err != nil {
+		r.fail(FieldSeason, err)
+		return nil
+	}
+
+	key := membershipKey{season.ID(), team}
+	line, exists := imp.members[key]
+	if line > 0 {
+		r.fail(FieldTeam, fmt.Errorf("also on row %d for this season", line))
+		return nil
+	}
+	imp.members[key] = r.line
+
+	m := data.NewMembership(season.ID(), team, division)
+	return &op{r.line, exists, func(store data.Store) (data.EntityID, error) { return 0, store.Seasons().SetTeam(m) }}
+}
+
+// Teams already in the season are recorded against row 0
+func (imp *importer) loadRoster(season data.EntityID) error {
+	if imp.rosters[season] {
+		return nil
+	}
+
+	members, err := imp.store.Seasons().Teams(season)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range members {
+		imp.members[membershipKey{season, m.Team()}] = 0
+	}
+
+	imp.rosters[season] = true
+	return nil
+}
+
+// Finds an entity named in a column, by external ID and then by id
+func resolveRef[T entity](
+	r *row,
+	field string,
+	byID func(data.EntityID) (T, error),
+	byExt func(string) (T, error),
+	notFound error,
+) (T, bool) {
+	ref := r.get(field)
+
+	v, err := byExt(ref)
+	if errors.Is(err, notFound) {
+		if n, perr := strconv.ParseInt(ref, 10, 64); perr == nil && n > 0 {
+			v, err = byID(data.EntityID(n))
+		}
+	}
+
+	if errors.Is(err, notFound) {
+		err = fmt.Errorf("unknown %s '%s'", field, ref)
+	}
+
+	if err != nil {
+		r.fail(field, err)
+		return v, false
+	}
+
+	return v, true
+}
+
+// Dates are YYYY-MM-DD. A missing column keeps the existing value; an
+// empty one clears it.
+func parseDate(r *row, field string, existing time.Time) time.Time {
+	s, ok := r.values[field]
+	if !ok {
+		return existing
+	}
+
+	if s == "" {
+		return time.Time{}
+	}
+
+	t, err := time.ParseInLocation(kDateLayout, s, time.Local)
+	if err != nil {
+		r.fail(field, fmt.Errorf("'%s' is not a YYYY-MM-DD date", s))
+	}
+
+	return t
+}
+
+// Finds the existing entity a row refers to, by external ID and then by
+// internal id. A row with neither (or with an external ID we haven't seen)
+// is new.
+func target[T entity](
+	imp *importer,
+	r *row,
+	byID func(data.EntityID) (T, error),
+	byExt func(string) (T, error),
+	notFound error,
+) (T, bool) {
+	var current T
+	found := false
+
+	if ext := r.get(FieldExternalID); ext != "" {
+		if line, dup := imp.externalIDs[ext]; dup {
+			r.fail(FieldExternalID, fmt.Errorf("'%s' is also used on row %d", ext, line))
+			return current, false
+		}
+		imp.externalIDs[ext] = r.line
+
+		v, err := byExt(ext)
+		if err == nil {
+			current, found = v, true
+		} else if !errors.Is(err, notFound) {
+			r.fail(FieldExternalID, err)
+			return current, false
+		}
+	}
+
+	if s := r.get(FieldID); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		id := data.EntityID(n)
+
+		switch {
+		case err != nil || n <= 0:
+			r.fail(FieldID, fmt.Errorf("'%s' is not a valid id", s))
+			return current, false
+		case found && current.ID() != id:
+			r.fail(FieldID, fmt.Errorf("external id '%s' belongs to id %d", r.get(FieldExternalID), current.ID()))
+			return current, false
+		case !found:
+			v, err := byID(id)
+			if errors.Is(err, notFound) {
+				err = fmt.Errorf("no %s with id %d", imp.kind, id)
+			}
+			if err != nil {
+				r.fail(FieldID, err)
+				return current, false
+			}
+			current, found = v, true
+		}
+	}
+
+	if found {
+		if line, dup := imp.targets[current.ID()]; dup {
+			r.fail("", fmt.Errorf("updates the same entry as row %d", line))
+			return current, false
+		}
+		imp.targets[current.ID()] = r.line
+	}
+
+	return current, found
+}
+
+// Teams are referenced by external ID or, failing that, by an unambiguous name
+func (imp *importer) resolveTeam(r *row) (data.EntityID, bool) {
+	ref := r.get(FieldTeam)
+
+	cached, ok := imp.teams[ref]
+	if !ok {
+		cached.id, cached.err = imp.lookupTeam(ref)
+		imp.teams[ref] = cached
+	}
+
+	if cached.err != nil {
+		r.fail(FieldTeam, cached.err)
+		return 0, false
+	}
+
+	return cached.id, true
+}
+
+func (imp *importer) lookupTeam(ref string) (data.EntityID, error) {
+	t, err := data.FindTeam(imp.store.Teams(), ref)
+	if errors.Is(err, data.ErrorUnknownTeamID) {
+		return 0, fmt.Errorf("unknown team '%s'", ref)
+	} else if err != nil {
+		return 0, err
+	}
+
+	return t.ID(), nil
+}
+
+// Enforces UNIQUE(team, number) across the store and the rows before this
+// one, so that a duplicate is reported against the row rather than failing
+// part way through the writes.
+func (imp *importer) claimNumber(r *row, current data.Player, id, team data.EntityID, number int) bool {
+	if current != nil {
+		if err := imp.loadTeam(current.Team()); err != nil {
+			r.fail(FieldTeam, err)
+			return false
+		}
+
+		old := slot{current.Team(), current.Number()}
+		if imp.slots[old].id == id {
+			delete(imp.slots, old)
+		}
+	}
+
+	if err := imp.loadTeam(team); err != nil {
+		r.fail(FieldTeam, err)
+		return false
+	}
+
+	if id == 0 {
+		imp.placeholder--
+		id = imp.placeholder
+	}
+
+	s := slot{team, number}
+	if h, taken := imp.slots[s]; taken && h.id != id {
+		if h.line == 0 {
+			r.fail(FieldNumber, fmt.Errorf("#%d is already worn by %s", number, h.name))
+		} else {
+			r.fail(FieldNumber, fmt.Errorf("#%d is already worn by %s (row %d)", number, h.name, h.line))
+		}
+		return false
+	}
+
+	imp.slots[s] = holder{id, r.get(FieldName), r.line}
+	return true
+}
+
+func (imp *importer) loadTeam(team data.EntityID) error {
+	if imp.loaded[team] {
+		return nil
+	}
+
+	players, err := imp.store.Players().ByTeam(team)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range players {
+		imp.slots[slot{team, p.Number()}] = holder{p.ID(), p.Name(), 0}
+	}
+
+	imp.loaded[team] = true
+	return nil
+}
+
+/**
+ *
+ * Export
+ *
+ **/
+
+func exportRows(store data.Store, kind Kind, w rowWriter) error {
+	teams := teamNames(store)
+
+	switch kind {
+	case KindFacilities:
+		return eachPage(store.Facilities().List, func(f data.Facility) error {
+			return w.Write([]string{formatID(f.ID()), f.ExternalID(), f.Name(), f.Address(), f.City(), f.State()})
+		})
+
+	case KindTeams:
+		return eachPage(store.Teams().List, func(t data.Team) error {
+			return w.Write([]string{formatID(t.ID()), t.ExternalID(), t.Name()})
+		})
+
+	case KindPlayers:
+		return eachPage(store.Players().List, func(p data.Player) error {
+			team, err := teams(p.Team())
+			if err != nil {
+				return err
+			}
+			return w.Write([]string{formatID(p.ID()), p.ExternalID(), team, p.Name(), strconv.Itoa(p.Number())})
+		})
+
+	case KindStaff:
+		return eachPage(store.Staff().List, func(sm data.StaffMember) error {
+			team, err := teams(sm.Team())
+			if err != nil {
+				return err
+			}
+			return w.Write([]string{formatID(sm.ID()), sm.ExternalID(), team, sm.Name(), sm.Role()})
+		})
+
+	case KindLeagues:
+		return eachPage(store.Leagues().List, func(l data.League) error {
+			return w.Write([]string{formatID(l.ID()), l.ExternalID(), l.Name()})
+		})
+
+	case KindSeasons:
+		return eachPage(store.Seasons().List, func(s data.Season) error {
+			league, err := store.Leagues().ByID(s.League())
+			if err != nil {
+				return err
+			}
+			return w.Write([]string{formatID(s.ID()), s.ExternalID(), formatRef(league), s.Name(), formatDate(s.Start()), formatDate(s.End())})
+		})
+
+	case KindDivisions:
+		return eachPage(store.Divisions().List, func(d data.Division) error {
+			league, err := store.Leagues().ByID(d.League())
+			if err != nil {
+				return err
+			}
+			return w.Write([]string{formatID(d.ID()), d.ExternalID(), formatRef(league), d.Name()})
+		})
+
+	case KindMemberships:
+		return eachPage(store.Seasons().List, func(s data.Season) error {
+			members, err := store.Seasons().Teams(s.ID())
+			if err != nil {
+				return err
+			}
+
+			for _, m := range members {
+				team, err := teams(m.Team())
+				if err != nil {
+					return err
+				}
+
+				division := ""
+				if m.Division() != 0 {
+					d, err := store.Divisions().ByID(m.Division())
+					if err != nil {
+						return err
+					}
+					division = formatRef(d)
+				}
+
+				if err := w.Write([]string{formatRef(s), team, division}); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+	}
+
+	return fmt.Errorf("%w '%s'", ErrorUnknownKind, kind)
+}
+
+// The way imports look an entity up: external ID, else id
+func formatRef(e entity) string {
+	if e.ExternalID() != "" {
+		return e.ExternalID()
+	}
+
+	return formatID(e.ID())
+}
+
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.Format(kDateLayout)
+}
+
+func formatID(id data.EntityID) string {
+	return strconv.FormatInt(int64(id), 10)
+}
+
+// Teams are exported the way imports look them up: external ID, else name.
+// Rows pointing at a team that doesn't exist get an empty team.
+func teamNames(store data.Store) func(data.EntityID) (string, error) {
+	cache := map[data.EntityID]string{}
+
+	return func(id data.EntityID) (string, error) {
+		if name, ok := cache[id]; ok {
+			return name, nil
+		}
+
+		t, err := store.Teams().ByID(id)
+		if errors.Is(err, data.ErrorUnknownTeamID) {
+			cache[id] = ""
+			return "", nil
+		} else if err != nil {
+			return "", err
+		}
+
+		name := t.ExternalID()
+		if name == "" {
+			name = t.Name()
+		}
+
+		cache[id] = name
+		return name, nil
+	}
+}
+
+// Pages through a List method until it runs dry
+func eachPage[T any](list func(token int64) ([]T, int64, error), fn func(T) error) error {
+	for token := int64(0); ; {
+		page, next, err := list(token)
+		if err != nil {
+			return err
+		}
+
+		if len(page) == 0 {
+			return nil
+		}
+
+		for _, v := range page {
+			if err := fn(v); err != nil {
+				return err
+			}
+		}
+
+		token = next
+	}
+}
@@ -0,0 +1,371 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package transfer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"shiftylogic.dev/hockey-tools/internal/data"
+)
+
+/**
+ *
+ * Bulk import/export of one kind of entity at a time, as CSV (what leagues
+ * send us) or JSON.
+ *
+ * Imports are validated in full before anything is written: a file with
+ * any bad row writes nothing, and every bad row is reported. Rows carrying
+ * an external ID (or an internal id) update the existing entity, so
+ * re-importing the same file is a no-op rather than a duplicate.
+ *
+ **/
+
+type Kind string
+
+const (
+	KindFacilities Kind = "facilities"
+	KindTeams      Kind = "teams"
+	KindPlayers    Kind = "players"
+	KindStaff      Kind = "staff"
+	KindLeagues    Kind = "leagues"
+	KindSeasons    Kind = "seasons"
+	KindDivisions  Kind = "divisions"
+
+	// Which teams play in a season, and in which division
+	KindMemberships Kind = "memberships"
+)
+
+type Format string
+
+const (
+	FormatCSV  Format = "csv"
+	FormatJSON Format = "json"
+)
+
+const (
+	FieldID         = "id"
+	FieldExternalID = "external_id"
+	FieldName       = "name"
+	FieldAddress    = "address"
+	FieldCity       = "city"
+	FieldState      = "state"
+	FieldTeam       = "team"
+	FieldNumber     = "number"
+	FieldRole       = "role"
+	FieldLeague     = "league"
+	FieldSeason     = "season"
+	FieldDivision   = "division"
+	FieldStart      = "start"
+	FieldEnd        = "end"
+)
+
+type kindSpec struct {
+	fields   []string
+	required []string
+}
+
+var kinds = map[Kind]kindSpec{
+	KindFacilities: {
+		[]string{FieldID, FieldExternalID, FieldName, FieldAddress, FieldCity, FieldState},
+		[]string{FieldName},
+	},
+	KindTeams: {
+		[]string{FieldID, FieldExternalID, FieldName},
+		[]string{FieldName},
+	},
+	KindPlayers: {
+		[]string{FieldID, FieldExternalID, FieldTeam, FieldName, FieldNumber},
+		[]string{FieldTeam, FieldName, FieldNumber},
+	},
+	KindStaff: {
+		[]string{FieldID, FieldExternalID, FieldTeam, FieldName, FieldRole},
+		[]string{FieldTeam, FieldName, FieldRole},
+	},
+	KindLeagues: {
+		[]string{FieldID, FieldExternalID, FieldName},
+		[]string{FieldName},
+	},
+	KindSeasons: {
+		[]string{FieldID, FieldExternalID, FieldLeague, FieldName, FieldStart, FieldEnd},
+		[]string{FieldLeague, FieldName},
+	},
+	KindDivisions: {
+		[]string{FieldID, FieldExternalID, FieldLeague, FieldName},
+		[]string{FieldLeague, FieldName},
+	},
+	KindMemberships: {
+		[]string{FieldSeason, FieldTeam, FieldDivision},
+		[]string{FieldSeason, FieldTeam},
+	},
+}
+
+var (
+	ErrorUnknownKind   = errors.New("unknown kind")
+	ErrorUnknownFormat = errors.New("unknown format")
+)
+
+func ParseKind(s string) (Kind, error) {
+	if _, ok := kinds[Kind(s)]; !ok {
+		return "", fmt.Errorf("%w '%s'", ErrorUnknownKind, s)
+	}
+
+	return Kind(s), nil
+}
+
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatCSV, FormatJSON:
+		return f, nil
+	}
+
+	return "", fmt.Errorf("%w '%s'", ErrorUnknownFormat, s)
+}
+
+// The fields a kind has, in export column order
+func Fields(kind Kind) []string {
+	return slices.Clone(kinds[kind].fields)
+}
+
+type Options struct {
+	Kind   Kind
+	Format Format
+
+	// Maps source column headers to fields (e.g. "Jersey #" -> "number").
+	// Headers that already name a field need no mapping.
+	Headers map[string]string
+
+	// Validate everything, but write nothing
+	DryRun bool
+}
+
+type RowError struct {
+	Row   int
+	Field string
+	Err   error
+}
+
+func (e RowError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+	}
+
+	return fmt.Sprintf("row %d: %s: %v", e.Row, e.Field, e.Err)
+}
+
+func (e RowError) Unwrap() error { return e.Err }
+
+type Result struct {
+	Rows    int
+	Created int
+	Updated int
+
+	// Source columns that didn't map to any field
+	Ignored []string
+	Errors  []RowError
+}
+
+// A row can fail more than one way; this counts each row once
+func (r *Result) FailedRows() int {
+	rows := map[int]bool{}
+	for _, e := range r.Errors {
+		rows[e.Row] = true
+	}
+
+	return len(rows)
+}
+
+func (r *Result) fail(row int, field string, err error) {
+	r.Errors = append(r.Errors, RowError{row, field, err})
+}
+
+/**
+ *
+ * Import
+ *
+ **/
+
+// Returns an error only when the input can't be read at all (bad syntax,
+// a missing required column). Problems with individual rows are reported
+// in the Result, and mean nothing was written.
+func Import(store data.Store, in io.Reader, opts Options) (*Result, error) {
+	spec, ok := kinds[opts.Kind]
+	if !ok {
+		return nil, fmt.Errorf("%w '%s'", ErrorUnknownKind, opts.Kind)
+	}
+
+	headers, rows, err := readRows(in, opts.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Rows: len(rows)}
+
+	columns, err := mapHeaders(headers, spec, opts.Headers, result)
+	if err != nil {
+		return nil, err
+	}
+
+	imp := newImporter(store, opts.Kind)
+
+	ops := []*op{}
+	for _, r := range rows {
+		values := map[string]string{}
+		for header, value := range r.values {
+			if field, ok := columns[header]; ok {
+				values[field] = strings.TrimSpace(value)
+			}
+		}
+
+		row := &row{line: r.line, values: values, result: result}
+		if !row.checkRequired(spec.required) {
+			continue
+		}
+
+		if o := imp.prepare(row); o != nil {
+			ops = append(ops, o)
+		}
+	}
+
+	if len(result.Errors) > 0 {
+		return result, nil
+	}
+
+	if opts.DryRun {
+		result.count(ops)
+		return result, nil
+	}
+
+	// All or nothing: a save that still fails (say, a constraint the checks
+	// above can't see) rolls back the rows before it.
+	rowFailed := false
+	err = store.Atomically(func(tx data.Store) error {
+		for _, o := range ops {
+			if _, err := o.save(tx); err != nil {
+				result.fail(o.line, "", err)
+				rowFailed = true
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil && !rowFailed {
+		return nil, fmt.Errorf("[transfer.Import] failed to write %s - %w", opts.Kind, err)
+	}
+
+	if err == nil {
+		result.count(ops)
+	}
+
+	return result, nil
+}
+
+// Tallies what the ops create and update, once they are written (or would
+// be, on a dry run)
+func (r *Result) count(ops []*op) {
+	for _, o := range ops {
+		if o.update {
+			r.Updated++
+		} else {
+			r.Created++
+		}
+	}
+}
+
+// Normalizes a column header for matching: "Jersey #" -> "jersey_#"
+func normalizeHeader(h string) string {
+	h = strings.ToLower(strings.TrimSpace(h))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(h)
+}
+
+// Returns source header -> field for every header that maps to a field
+func mapHeaders(headers []string, spec kindSpec, mapping map[string]string, result *Result) (map[string]string, error) {
+	explicit := map[string]string{}
+	for from, to := range mapping {
+		field := normalizeHeader(to)
+		if !slices.Contains(spec.fields, field) {
+			return nil, fmt.Errorf("header mapping '%s' names unknown field '%s'", from, to)
+		}
+
+		explicit[normalizeHeader(from)] = field
+	}
+
+	columns := map[string]string{}
+	seen := map[string]string{}
+	for _, h := range headers {
+		norm := normalizeHeader(h)
+
+		field, ok := explicit[norm]
+		if !ok && slices.Contains(spec.fields, norm) {
+			field, ok = norm, true
+		}
+
+		if !ok {
+			result.Ignored = append(result.Ignored, h)
+			continue
+		}
+
+		if other, dup := seen[field]; dup {
+			return nil, fmt.Errorf("columns '%s' and '%s' both map to '%s'", other, h, field)
+		}
+
+		seen[field] = h
+		columns[h] = field
+	}
+
+	for _, field := range spec.required {
+		if _, ok := seen[field]; !ok {
+			return nil, fmt.Errorf("missing required column '%s'", field)
+		}
+	}
+
+	return columns, nil
+}
+
+/**
+ *
+ * Export
+ *
+ **/
+
+func Export(store data.Store, out io.Writer, kind Kind, format Format) error {
+	spec, ok := kinds[kind]
+	if !ok {
+		return fmt.Errorf("%w '%s'", ErrorUnknownKind, kind)
+	}
+
+	w, err := newRowWriter(out, format, spec.fields)
+	if err != nil {
+		return err
+	}
+
+	if err := exportRows(store, kind, w); err != nil {
+		return err
+	}
+
+	return w.Close()
+}
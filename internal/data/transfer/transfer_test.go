@@ -0,0 +1,215 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package transfer
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"shiftylogic.dev/hockey-tools/internal/data"
+	"shiftylogic.dev/hockey-tools/internal/data/local"
+	"shiftylogic.dev/hockey-tools/internal/test"
+)
+
+const (
+	kTeamsCSV = "external_id,name\nT-SEA,Seattle Sharks\nT-TAC,Tacoma Tigers\n"
+
+	kRosterCSV = `Player ID,Team,Player,Jersey #,Notes
+P1,T-SEA,Alice,9,captain
+P2,Tacoma Tigers,Bob,9,
+`
+)
+
+var kRosterHeaders = map[string]string{
+	"Player ID": "external_id",
+	"Player":    "name",
+	"Jersey #":  "number",
+}
+
+func openStore(t *testing.T) data.Store {
+	store, err := local.Open(filepath.Join(t.TempDir(), "hockey.db"))
+	test.NoError(t, err, "open store")
+	t.Cleanup(store.Close)
+
+	result, err := Import(store, strings.NewReader(kTeamsCSV), Options{Kind: KindTeams, Format: FormatCSV})
+	test.NoError(t, err, "import teams")
+	test.Expect(t, 2, result.Created, "teams created")
+
+	return store
+}
+
+func importRoster(t *testing.T, store data.Store, csv string, dryRun bool) *Result {
+	result, err := Import(store, strings.NewReader(csv), Options{
+		Kind:    KindPlayers,
+		Format:  FormatCSV,
+		Headers: kRosterHeaders,
+		DryRun:  dryRun,
+	})
+	test.NoError(t, err, "import roster")
+	return result
+}
+
+func TestImportIsIdempotent(t *testing.T) {
+	store := openStore(t)
+
+	result := importRoster(t, store, kRosterCSV, true)
+	test.Expect(t, 0, len(result.Errors), "dry run is clean")
+	test.Expect(t, 2, result.Created, "dry run counts new rows")
+	test.Expect(t, []string{"Notes"}, result.Ignored, "unmapped columns are reported")
+
+	players, _, err := store.Players().List(0)
+	test.NoError(t, err, "list players")
+	test.Expect(t, 0, len(players), "dry run writes nothing")
+
+	importRoster(t, store, kRosterCSV, false)
+	result = importRoster(t, store, strings.Replace(kRosterCSV, "Alice,9", "Alice,19", 1), false)
+	test.Expect(t, 0, result.Created, "re-import creates nothing")
+	test.Expect(t, 2, result.Updated, "re-import updates")
+
+	p, err := store.Players().ByExternalID("P1")
+	test.NoError(t, err, "fetch by external id")
+	test.Expect(t, 19, p.Number(), "number updated")
+
+	players, _, err = store.Players().List(0)
+	test.NoError(t, err, "list players")
+	test.Expect(t, 2, len(players), "no duplicates")
+
+	var out bytes.Buffer
+	test.NoError(t, Export(store, &out, KindPlayers, FormatCSV), "export")
+	test.Require(t, strings.Contains(out.String(), "1,P1,T-SEA,Alice,19\n"), "export uses team external ids")
+}
+
+func TestImportReportsRowErrors(t *testing.T) {
+	store := openStore(t)
+	importRoster(t, store, kRosterCSV, false)
+
+	result := importRoster(t, store, `Player ID,Team,Player,Jersey #
+P3,T-SEA,Carl,9
+P4,T-SEA,Dan,
+P5,Nowhere,Eve,5
+P3,T-TAC,Fay,4
+`, false)
+
+	test.Expect(t, 4, len(result.Errors), "one error per bad row")
+	test.Expect(t, 4, result.FailedRows(), "failed rows")
+
+	expected := []struct {
+		row   int
+		field string
+	}{
+		{2, FieldNumber}, {3, FieldNumber}, {4, FieldTeam}, {5, FieldExternalID},
+	}
+	for i, e := range expected {
+		test.Expect(t, e.row, result.Errors[i].Row, "error row")
+		test.Expect(t, e.field, result.Errors[i].Field, "error field")
+	}
+
+	_, err := store.Players().ByExternalID("P3")
+	test.SpecificError(t, err, data.ErrorUnknownPlayerID, "nothing written when any row fails")
+
+	_, err = Import(store, strings.NewReader("name\nAlice\n"), Options{Kind: KindPlayers, Format: FormatCSV})
+	test.Require(t, err != nil && !errors.Is(err, ErrorUnknownKind), "missing required columns fail the whole import")
+}
+
+// Fails team saves once 'left' have gone through, to stand in for a write
+// error the row checks can't see coming
+type failingStore struct {
+	data.Store
+	left *int
+}
+
+type failingTeams struct {
+	data.Teams
+	left *int
+}
+
+func (s failingStore) Teams() data.Teams { return failingTeams{s.Store.Teams(), s.left} }
+
+func (s failingStore) Atomically(fn func(data.Store) error) error {
+	return s.Store.Atomically(func(tx data.Store) error { return fn(failingStore{tx, s.left}) })
+}
+
+func (t failingTeams) Save(team data.Team) (data.EntityID, error) {
+	if *t.left == 0 {
+		return 0, errors.New("disk full")
+	}
+
+	*t.left--
+	return t.Teams.Save(team)
+}
+
+func TestImportWritesNothingOnSaveError(t *testing.T) {
+	store := openStore(t)
+
+	left := 1
+	result, err := Import(failingStore{store, &left}, strings.NewReader("external_id,name\nT-OLY,Olympia\nT-SPO,Spokane\n"),
+		Options{Kind: KindTeams, Format: FormatCSV})
+	test.NoError(t, err, "save errors are row errors")
+	test.Expect(t, 1, len(result.Errors), "failed row reported")
+	test.Expect(t, 3, result.Errors[0].Row, "the second row failed")
+	test.Expect(t, 0, result.Created, "nothing counted as created")
+
+	_, err = store.Teams().ByExternalID("T-OLY")
+	test.SpecificError(t, err, data.ErrorUnknownTeamID, "first row rolled back")
+}
+
+func importKind(t *testing.T, store data.Store, kind Kind, csv string) *Result {
+	result, err := Import(store, strings.NewReader(csv), Options{Kind: kind, Format: FormatCSV})
+	test.NoError(t, err, "import "+string(kind))
+	return result
+}
+
+func TestImportSeasons(t *testing.T) {
+	store := openStore(t)
+
+	importKind(t, store, KindLeagues, "external_id,name\nL-1,Puget Sound\nL-2,Inland\n")
+	importKind(t, store, KindDivisions, "external_id,league,name\nD-N,L-1,North\nD-E,L-2,East\n")
+
+	result := importKind(t, store, KindSeasons, "external_id,league,name,start,end\nS-24,L-1,Fall 2024,2024-09-01,2024-08-01\n")
+	test.Expect(t, FieldEnd, result.Errors[0].Field, "season ends before it starts")
+
+	result = importKind(t, store, KindSeasons, "external_id,league,name,start,end\nS-24,L-1,Fall 2024,2024-09-01,2025-01-01\n")
+	test.Expect(t, 0, len(result.Errors), "season imported")
+
+	result = importKind(t, store, KindMemberships, `season,team,division
+S-24,T-SEA,D-N
+S-24,T-TAC,D-E
+S-24,T-SEA,
+`)
+	test.Expect(t, 2, len(result.Errors), "wrong league and duplicate team")
+	test.Expect(t, FieldDivision, result.Errors[0].Field, "division from another league")
+	test.Expect(t, FieldTeam, result.Errors[1].Field, "team listed twice")
+
+	result = importKind(t, store, KindMemberships, "season,team,division\nS-24,T-SEA,D-N\nS-24,Tacoma Tigers,\n")
+	test.Expect(t, 2, result.Created, "memberships created")
+
+	result = importKind(t, store, KindMemberships, "season,team,division\nS-24,T-TAC,D-N\n")
+	test.Expect(t, 1, result.Updated, "an existing membership is updated")
+
+	var out bytes.Buffer
+	test.NoError(t, Export(store, &out, KindMemberships, FormatCSV), "export")
+	test.Expect(t, "season,team,division\nS-24,T-SEA,D-N\nS-24,T-TAC,D-N\n", out.String(), "memberships export by reference")
+}
@@ -23,3 +23,22 @@
 package data
 
 type EntityID int64
+
+// Pages through a List method until it runs dry
+func ListAll[T any](list func(token int64) ([]T, int64, error)) ([]T, error) {
+	all := []T{}
+
+	for token := int64(0); ; {
+		page, next, err := list(token)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(page) == 0 {
+			return all, nil
+		}
+
+		all = append(all, page...)
+		token = next
+	}
+}
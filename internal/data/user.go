@@ -0,0 +1,77 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package data
+
+import "time"
+
+type User interface {
+	ID() EntityID
+	Email() string
+	PasswordHash() string
+	Created() time.Time
+}
+
+type Users interface {
+	List(token int64) ([]User, int64, error)
+	ByEmail(email string) (User, error)
+
+	Add(email, passwordHash string) (EntityID, error)
+	SetPassword(email, passwordHash string) error
+}
+
+/**
+ *
+ * OAuth2 clients allowed to request authorization codes. The secret is only
+ * ever stored hashed; public clients have none.
+ *
+ **/
+
+type Client interface {
+	ID() string
+	Name() string
+	SecretHash() string
+	RedirectURIs() []string
+}
+
+type Clients interface {
+	List() ([]Client, error)
+	ByID(id string) (Client, error)
+
+	Add(c Client) error
+}
+
+type client struct {
+	id           string
+	name         string
+	secretHash   string
+	redirectURIs []string
+}
+
+func (c *client) ID() string             { return c.id }
+func (c *client) Name() string           { return c.name }
+func (c *client) SecretHash() string     { return c.secretHash }
+func (c *client) RedirectURIs() []string { return c.redirectURIs }
+
+func NewClient(id, name, secretHash string, redirectURIs ...string) Client {
+	return &client{id, name, secretHash, redirectURIs}
+}
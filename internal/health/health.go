@@ -0,0 +1,270 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/**
+ *
+ * A registry of named health checks, served as liveness (/healthz) and
+ * readiness (/readyz) reports. Liveness checks should only fail when the
+ * process needs restarting; readiness checks cover dependencies (stores,
+ * certificates, templates) and whether the server is accepting traffic.
+ *
+ * No liveness checks are registered by default, so /healthz passing only
+ * means the process is still answering requests; it has nothing else to
+ * report until something registers a Liveness check.
+ *
+ **/
+
+const (
+	kDefaultTimeout = 2 * time.Second
+
+	StatusOK          = "ok"
+	StatusError       = "error"
+	StatusUnavailable = "unavailable"
+	StatusShutdown    = "shutting down"
+)
+
+type Check func(ctx context.Context) error
+
+type Kind int
+
+const (
+	Readiness Kind = iota
+	Liveness
+)
+
+type Registry struct {
+	mu      sync.Mutex
+	checks  map[string]entry
+	timeout time.Duration
+	ready   atomic.Bool
+}
+
+type entry struct {
+	kind  Kind
+	check Check
+}
+
+type CheckResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+var (
+	Default = NewRegistry()
+)
+
+func NewRegistry() *Registry {
+	reg := &Registry{
+		checks:  make(map[string]entry),
+		timeout: kDefaultTimeout,
+	}
+
+	reg.ready.Store(true)
+	return reg
+}
+
+// Adds (or replaces) a readiness check
+func (reg *Registry) Register(name string, check Check) {
+	reg.RegisterKind(name, Readiness, check)
+}
+
+func (reg *Registry) RegisterKind(name string, kind Kind, check Check) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	reg.checks[name] = entry{kind, check}
+}
+
+func (reg *Registry) Unregister(name string) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	delete(reg.checks, name)
+}
+
+// Upper bound on how long any single check may run
+func (reg *Registry) SetTimeout(timeout time.Duration) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	if timeout > 0 {
+		reg.timeout = timeout
+	}
+}
+
+// Readiness is forced to fail while 'ready' is false (e.g. during shutdown)
+func (reg *Registry) SetReady(ready bool) {
+	reg.ready.Store(ready)
+}
+
+func (reg *Registry) Ready() bool {
+	return reg.ready.Load()
+}
+
+/**
+ *
+ * Runs every check of the requested kind concurrently. Readiness reports
+ * include the liveness checks too, since a dead process is never ready.
+ *
+ **/
+func (reg *Registry) Run(ctx context.Context, kind Kind) Report {
+	reg.mu.Lock()
+	timeout := reg.timeout
+	selected := make(map[string]Check, len(reg.checks))
+	for name, e := range reg.checks {
+		if kind == Readiness || e.kind == kind {
+			selected[name] = e.check
+		}
+	}
+	reg.mu.Unlock()
+
+	report := Report{
+		Status: StatusOK,
+		Checks: make(map[string]CheckResult, len(selected)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range selected {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+
+			result := runCheck(ctx, check, timeout)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if result.Status != StatusOK {
+				report.Status = StatusUnavailable
+			}
+		}(name, check)
+	}
+	wg.Wait()
+
+	if kind == Readiness && !reg.Ready() {
+		report.Status = StatusUnavailable
+		report.Checks["shutdown"] = CheckResult{Status: StatusError, Error: StatusShutdown}
+	}
+
+	return report
+}
+
+func runCheck(ctx context.Context, check Check, timeout time.Duration) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- panicError{r}
+			}
+		}()
+		done <- check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := CheckResult{
+		Status:   StatusOK,
+		Duration: time.Since(start).Round(time.Microsecond).String(),
+	}
+
+	if err != nil {
+		result.Status = StatusError
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// Serves the full report, including each check's error
+func (reg *Registry) Handler(kind Kind) http.Handler {
+	return reg.handler(kind, true)
+}
+
+// Serves only the overall status; check errors can name hosts, files and
+// other internals that should not reach the public listener.
+func (reg *Registry) StatusHandler(kind Kind) http.Handler {
+	return reg.handler(kind, false)
+}
+
+func (reg *Registry) handler(kind Kind, detail bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := reg.Run(r.Context(), kind)
+		if !detail {
+			report.Checks = nil
+		}
+
+		status := http.StatusOK
+		if report.Status != StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+
+		if r.Method != http.MethodHead {
+			json.NewEncoder(w).Encode(report)
+		}
+	})
+}
+
+// Names of the registered checks, sorted; mostly for start-up logging
+func (reg *Registry) Names() []string {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	names := make([]string, 0, len(reg.checks))
+	for name := range reg.checks {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,107 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/test"
+)
+
+func serve(t *testing.T, reg *Registry, kind Kind) (int, Report) {
+	return serveHandler(t, reg.Handler(kind))
+}
+
+func serveHandler(t *testing.T, handler http.Handler) (int, Report) {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var report Report
+	test.NoError(t, json.NewDecoder(rec.Body).Decode(&report), "decode report")
+	return rec.Code, report
+}
+
+func TestHealthReports(t *testing.T) {
+	reg := NewRegistry()
+	reg.SetTimeout(50 * time.Millisecond)
+
+	reg.RegisterKind("process", Liveness, func(ctx context.Context) error { return nil })
+	reg.Register("store", func(ctx context.Context) error { return nil })
+
+	code, report := serve(t, reg, Liveness)
+	test.Expect(t, http.StatusOK, code, "liveness status")
+	test.Expect(t, 1, len(report.Checks), "liveness only runs liveness checks")
+
+	code, report = serve(t, reg, Readiness)
+	test.Expect(t, http.StatusOK, code, "readiness status")
+	test.Expect(t, 2, len(report.Checks), "readiness runs every check")
+
+	reg.Register("store", func(ctx context.Context) error { return errors.New("disk on fire") })
+	reg.Register("slow", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+
+	code, report = serve(t, reg, Readiness)
+	test.Expect(t, http.StatusServiceUnavailable, code, "failing readiness status")
+	test.Expect(t, StatusUnavailable, report.Status, "failing readiness report")
+	test.Expect(t, "disk on fire", report.Checks["store"].Error, "failing check detail")
+	test.Expect(t, context.DeadlineExceeded.Error(), report.Checks["slow"].Error, "slow check times out")
+	test.Expect(t, StatusOK, report.Checks["process"].Status, "passing check detail")
+
+	code, _ = serve(t, reg, Liveness)
+	test.Expect(t, http.StatusOK, code, "readiness failures do not affect liveness")
+
+	code, report = serveHandler(t, reg.StatusHandler(Readiness))
+	test.Expect(t, http.StatusServiceUnavailable, code, "status-only readiness status")
+	test.Expect(t, StatusUnavailable, report.Status, "status-only readiness report")
+	test.Expect(t, 0, len(report.Checks), "status-only readiness hides check detail")
+}
+
+func TestHealthShutdown(t *testing.T) {
+	reg := NewRegistry()
+	reg.Register("store", func(ctx context.Context) error { return nil })
+	reg.SetReady(false)
+
+	code, report := serve(t, reg, Readiness)
+	test.Expect(t, http.StatusServiceUnavailable, code, "readiness during shutdown")
+	test.Expect(t, StatusShutdown, report.Checks["shutdown"].Error, "shutdown detail")
+
+	code, _ = serve(t, reg, Liveness)
+	test.Expect(t, http.StatusOK, code, "liveness during shutdown")
+}
+
+func TestHealthCheckPanics(t *testing.T) {
+	reg := NewRegistry()
+	reg.Register("broken", func(ctx context.Context) error { panic("oops") })
+
+	code, report := serve(t, reg, Readiness)
+	test.Expect(t, http.StatusServiceUnavailable, code, "panicking check status")
+	test.Expect(t, "check panicked: oops", report.Checks["broken"].Error, "panic detail")
+}
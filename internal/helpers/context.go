@@ -0,0 +1,52 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package helpers
+
+import (
+	"context"
+)
+
+// Users and OAuth clients live under separate keys so that a client can
+// never be mistaken for a user of the same name.
+const (
+	SubjectContextKey = "sl.subject"
+	ClientContextKey  = "sl.client"
+)
+
+func SubjectFromContext(ctx context.Context) (string, bool) {
+	subject, ok := ctx.Value(SubjectContextKey).(string)
+	return subject, ok && subject != ""
+}
+
+func ContextWithSubject(ctx context.Context, subject string) context.Context {
+	return context.WithValue(ctx, SubjectContextKey, subject)
+}
+
+func ClientFromContext(ctx context.Context) (string, bool) {
+	client, ok := ctx.Value(ClientContextKey).(string)
+	return client, ok && client != ""
+}
+
+func ContextWithClient(ctx context.Context, client string) context.Context {
+	return context.WithValue(ctx, ClientContextKey, client)
+}
@@ -23,14 +23,13 @@
 package helpers
 
 import (
-	"log"
 	"os"
 )
 
 func ReadEnv(key string) string {
 	value, ok := os.LookupEnv(key)
 	if !ok {
-		log.Fatalf("Failed to read environment variable ('%s').\n", key)
+		Fatal("Failed to read environment variable", "key", key)
 	}
 
 	return value
@@ -40,7 +40,7 @@ func ParseHttpAuthBasic(r *http.Request) (string, string, error) {
 		return "", "", kNoAuthorizationHeader
 	}
 
-	if strings.ToLower(val[:6]) != "basic " {
+	if len(val) < 6 || strings.ToLower(val[:6]) != "basic " {
 		return "", "", kNotBasicAuthorization
 	}
 
@@ -0,0 +1,209 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/**
+ *
+ * A deliberately small metrics registry that renders the Prometheus text
+ * exposition format (version 0.0.4). Metrics are registered once, usually
+ * as package-level variables, and the registry is served from /metrics.
+ *
+ **/
+
+const (
+	kContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+type metric interface {
+	name() string
+	write(w *bufio.Writer)
+}
+
+type Registry struct {
+	mu       sync.Mutex
+	metrics  map[string]metric
+	ordered  []string
+	onGather []func()
+}
+
+var (
+	Default = NewRegistry()
+)
+
+func NewRegistry() *Registry {
+	return &Registry{
+		metrics: make(map[string]metric),
+	}
+}
+
+func (reg *Registry) register(m metric) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	if _, ok := reg.metrics[m.name()]; ok {
+		panic("metric already registered: " + m.name())
+	}
+
+	reg.metrics[m.name()] = m
+	reg.ordered = append(reg.ordered, m.name())
+	sort.Strings(reg.ordered)
+}
+
+// Runs 'fn' before every scrape, e.g. to refresh gauges from live state
+func (reg *Registry) OnGather(fn func()) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	reg.onGather = append(reg.onGather, fn)
+}
+
+func (reg *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", kContentType)
+		reg.WriteTo(w)
+	})
+}
+
+func (reg *Registry) WriteTo(w interface{ Write([]byte) (int, error) }) {
+	reg.mu.Lock()
+	hooks := append([]func(){}, reg.onGather...)
+	reg.mu.Unlock()
+
+	for _, fn := range hooks {
+		fn()
+	}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, name := range reg.ordered {
+		reg.metrics[name].write(bw)
+	}
+	bw.Flush()
+}
+
+/**
+ *
+ * Shared helpers for labelled series
+ *
+ **/
+
+type series[T any] struct {
+	mu     sync.Mutex
+	labels []string
+	values map[string]*T
+	keys   map[string][]string
+	create func() *T
+}
+
+func newSeries[T any](labels []string, create func() *T) series[T] {
+	return series[T]{
+		labels: labels,
+		values: make(map[string]*T),
+		keys:   make(map[string][]string),
+		create: create,
+	}
+}
+
+func (s *series[T]) with(values []string) *T {
+	if len(values) != len(s.labels) {
+		panic(fmt.Sprintf("expected %d label values, got %d", len(s.labels), len(values)))
+	}
+
+	key := strings.Join(values, "\xff")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.values[key]
+	if !ok {
+		v = s.create()
+		s.values[key] = v
+		s.keys[key] = append([]string{}, values...)
+	}
+
+	return v
+}
+
+func (s *series[T]) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clear(s.values)
+	clear(s.keys)
+}
+
+// Visits each series in a stable order along with its label values
+func (s *series[T]) each(fn func(values []string, v *T)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.values))
+	for k := range s.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		fn(s.keys[k], s.values[k])
+	}
+}
+
+func writeHeader(w *bufio.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+func formatLabels(names, values []string, extra ...string) string {
+	if len(names) == 0 && len(extra) == 0 {
+		return ""
+	}
+
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	parts := make([]string, 0, len(names)+len(extra)/2)
+
+	for i, n := range names {
+		parts = append(parts, n+`="`+escape.Replace(values[i])+`"`)
+	}
+
+	for i := 0; i+1 < len(extra); i += 2 {
+		parts = append(parts, extra[i]+`="`+escape.Replace(extra[i+1])+`"`)
+	}
+
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,67 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"shiftylogic.dev/hockey-tools/internal/test"
+)
+
+func TestTextExposition(t *testing.T) {
+	requests := NewCounterVec("test_requests_total", "Requests.", "route", "code")
+	latency := NewHistogramVec("test_latency_seconds", "Latency.", []float64{0.5, 0.1}, "route")
+	depth := NewGaugeVec("test_queue_depth", "Queue depth.")
+
+	requests.With("/a", "200").Inc()
+	requests.With("/a", "200").Add(2)
+	requests.With(`/b"x`, "500").Inc()
+	latency.With("/a").Observe(0.1)
+	latency.With("/a").Observe(0.3)
+	latency.With("/a").Observe(2)
+	depth.With().Set(4)
+
+	var out strings.Builder
+	Default.WriteTo(&out)
+
+	expected := strings.Join([]string{
+		"# HELP test_latency_seconds Latency.",
+		"# TYPE test_latency_seconds histogram",
+		`test_latency_seconds_bucket{route="/a",le="0.1"} 1`,
+		`test_latency_seconds_bucket{route="/a",le="0.5"} 2`,
+		`test_latency_seconds_bucket{route="/a",le="+Inf"} 3`,
+		`test_latency_seconds_sum{route="/a"} 2.4`,
+		`test_latency_seconds_count{route="/a"} 3`,
+		"# HELP test_queue_depth Queue depth.",
+		"# TYPE test_queue_depth gauge",
+		"test_queue_depth 4",
+		"# HELP test_requests_total Requests.",
+		"# TYPE test_requests_total counter",
+		`test_requests_total{route="/a",code="200"} 3`,
+		`test_requests_total{route="/b\"x",code="500"} 1`,
+		"",
+	}, "\n")
+
+	test.Expect(t, expected, out.String(), "exposition output")
+}
@@ -0,0 +1,218 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+)
+
+/**
+ *
+ * Counters
+ *
+ **/
+
+type Counter struct {
+	bits atomic.Uint64
+}
+
+func (c *Counter) Inc() { c.Add(1) }
+
+func (c *Counter) Add(v float64) {
+	for {
+		old := c.bits.Load()
+		if c.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+func (c *Counter) Value() float64 { return math.Float64frombits(c.bits.Load()) }
+
+type CounterVec struct {
+	metricName string
+	help       string
+	series     series[Counter]
+}
+
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{
+		metricName: name,
+		help:       help,
+		series:     newSeries(labels, func() *Counter { return &Counter{} }),
+	}
+
+	Default.register(c)
+	return c
+}
+
+func (c *CounterVec) With(values ...string) *Counter { return c.series.with(values) }
+func (c *CounterVec) name() string                   { return c.metricName }
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	writeHeader(w, c.metricName, c.help, "counter")
+	c.series.each(func(values []string, v *Counter) {
+		labels := formatLabels(c.series.labels, values)
+		fmt.Fprintf(w, "%s%s %s\n", c.metricName, labels, formatValue(v.Value()))
+	})
+}
+
+/**
+ *
+ * Gauges
+ *
+ **/
+
+type Gauge struct {
+	Counter
+}
+
+func (g *Gauge) Set(v float64) { g.bits.Store(math.Float64bits(v)) }
+func (g *Gauge) Dec()          { g.Add(-1) }
+
+type GaugeVec struct {
+	metricName string
+	help       string
+	series     series[Gauge]
+}
+
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{
+		metricName: name,
+		help:       help,
+		series:     newSeries(labels, func() *Gauge { return &Gauge{} }),
+	}
+
+	Default.register(g)
+	return g
+}
+
+func (g *GaugeVec) With(values ...string) *Gauge { return g.series.with(values) }
+func (g *GaugeVec) name() string                 { return g.metricName }
+
+// Drops every series; useful before repopulating from live state in OnGather
+func (g *GaugeVec) Reset() { g.series.reset() }
+
+func (g *GaugeVec) write(w *bufio.Writer) {
+	writeHeader(w, g.metricName, g.help, "gauge")
+	g.series.each(func(values []string, v *Gauge) {
+		labels := formatLabels(g.series.labels, values)
+		fmt.Fprintf(w, "%s%s %s\n", g.metricName, labels, formatValue(v.Value()))
+	})
+}
+
+/**
+ *
+ * Histograms
+ *
+ **/
+
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if i < len(h.counts) {
+		h.counts[i]++
+	}
+	h.sum += v
+	h.count++
+}
+
+func (h *Histogram) ObserveSince(start time.Time) {
+	h.Observe(time.Since(start).Seconds())
+}
+
+type HistogramVec struct {
+	metricName string
+	help       string
+	buckets    []float64
+	series     series[Histogram]
+}
+
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+
+	h := &HistogramVec{
+		metricName: name,
+		help:       help,
+		buckets:    buckets,
+	}
+
+	h.series = newSeries(labels, func() *Histogram {
+		return &Histogram{
+			buckets: h.buckets,
+			counts:  make([]uint64, len(h.buckets)),
+		}
+	})
+
+	Default.register(h)
+	return h
+}
+
+func (h *HistogramVec) With(values ...string) *Histogram { return h.series.with(values) }
+func (h *HistogramVec) name() string                     { return h.metricName }
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	writeHeader(w, h.metricName, h.help, "histogram")
+	h.series.each(func(values []string, v *Histogram) {
+		v.mu.Lock()
+		defer v.mu.Unlock()
+
+		names := h.series.labels
+		labels := formatLabels(names, values)
+
+		var cumulative uint64
+		for i, le := range v.buckets {
+			cumulative += v.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, formatLabels(names, values, "le", formatValue(le)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, formatLabels(names, values, "le", "+Inf"), v.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.metricName, labels, formatValue(v.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.metricName, labels, v.count)
+	})
+}
@@ -23,7 +23,12 @@
 package auth
 
 import (
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/services"
 )
 
 const (
@@ -34,7 +39,7 @@ const (
 
 type Config struct {
 	Path      string `json:"path" yaml:"Path"`
-	Secret    string `json:"secret" yaml:"Secret"`
+	Secret    string `json:"secret" yaml:"Secret" secret:"true"`
 	Templates string `json:"templates" yaml:"Templates"`
 
 	CodeTTL  time.Duration `json:"codeTTL" yaml:"CodeTTL"`
@@ -43,6 +48,10 @@ type Config struct {
 	QRScan QRScanConfig `json:"qrscan" yaml:"QRScan"`
 }
 
+var (
+	live atomic.Pointer[Config]
+)
+
 type QRScanConfig struct {
 	Enabled bool          `json:"enabled" yaml:"Enabled"`
 	Prefix  string        `json:"prefix" yaml:"Prefix"`
@@ -65,3 +74,47 @@ func DefaultConfig() Config {
 		},
 	}
 }
+
+// The active config, as last installed by WithOAuth2 or Reload
+func Current() Config {
+	return *live.Load()
+}
+
+/**
+ *
+ * Applies the settings that can change while serving: token lifetimes and
+ * the QR code prefix and TTL. Everything else (paths, templates, which
+ * routes exist) is fixed when the routes are built.
+ *
+ **/
+func Reload(config Config) {
+	next := Current()
+	next.CodeTTL = config.CodeTTL
+	next.TokenTTL = config.TokenTTL
+	next.QRScan.Prefix = config.QRScan.Prefix
+	next.QRScan.TTL = config.QRScan.TTL
+
+	live.Store(&next)
+}
+
+func (cfg Config) Validate() error {
+	var p services.Problems
+
+	p.Check(strings.HasPrefix(cfg.Path, "/"), "Auth.Path", "must start with '/'")
+	p.Check(cfg.Templates != "", "Auth.Templates", "required")
+
+	if cfg.Templates != "" {
+		p.CheckPath("Auth.Templates", cfg.Templates, true)
+		p.CheckPath("Auth.Templates", filepath.Join(cfg.Templates, kLoginTemplate), false)
+	}
+
+	p.Check(cfg.CodeTTL > 0, "Auth.CodeTTL", "must be positive")
+	p.Check(cfg.TokenTTL > 0, "Auth.TokenTTL", "must be positive")
+
+	if cfg.QRScan.Enabled {
+		p.Check(cfg.QRScan.Prefix != "", "Auth.QRScan.Prefix", "required when enabled")
+		p.Check(cfg.QRScan.TTL > 0, "Auth.QRScan.TTL", "must be positive")
+	}
+
+	return p.Err()
+}
@@ -23,12 +23,14 @@
 package auth
 
 import (
+	"context"
 	"fmt"
 	"html/template"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 
+	"shiftylogic.dev/hockey-tools/internal/health"
 	"shiftylogic.dev/hockey-tools/internal/services"
 	"shiftylogic.dev/hockey-tools/internal/web"
 )
@@ -62,17 +64,20 @@ type loginViewData struct {
 
 func WithOAuth2(config Config) web.RouterOptionFunc {
 	templates := template.Must(template.ParseFS(os.DirFS(config.Templates), "*.html"))
+	live.Store(&config)
 
 	return func(root web.Router) {
+		health.Default.Register("templates", TemplatesCheck(templates))
+
 		r := web.NewRouter()
 
 		r.With(web.NoIFrame).Get(kAuthorizeRoute, Authorize(templates, config))
-		r.Get(kLoginRoute, Login(config))
-		r.Post(kLoginRoute, Login(config))
+		r.Get(kLoginRoute, Login())
+		r.Post(kLoginRoute, Login())
 		r.Post(kTokenRoute, Token(config))
 
 		if config.QRScan.Enabled {
-			r.Get(kQRImageRoute, QRGenerator(config.QRScan))
+			r.Get(kQRImageRoute, QRGenerator())
 			// r.Get("/do-a-thing", DoThing(config.QRScan.TTL))
 		}
 
@@ -80,6 +85,19 @@ func WithOAuth2(config Config) web.RouterOptionFunc {
 	}
 }
 
+// Verifies every template the handlers render was parsed
+func TemplatesCheck(templates *template.Template) health.Check {
+	return func(ctx context.Context) error {
+		for _, name := range []string{kLoginTemplate} {
+			if templates.Lookup(name) == nil {
+				return fmt.Errorf("template '%s' not loaded", name)
+			}
+		}
+
+		return nil
+	}
+}
+
 func Authorize(templates *template.Template, config Config) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		data := loginViewData{
@@ -95,27 +113,28 @@ func Authorize(templates *template.Template, config Config) func(w http.Response
 		svcs := services.ServicesFromContext(r.Context())
 
 		if !svcs.Authorizer().ValidateClient(data.ClientID, data.RedirectURI) {
-			log.Print("[Error] Invalid client and / or redirect URL in authorize call.")
+			slog.WarnContext(r.Context(), "Invalid client and / or redirect URL in authorize call", "client_id", data.ClientID)
 			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
 			return
 		}
 
 		if rtype := r.URL.Query().Get("response_type"); rtype != "code" {
-			log.Print("[Error] Unsupported response_type in authorization request.")
+			slog.WarnContext(r.Context(), "Unsupported response_type in authorization request", "client_id", data.ClientID)
 			redirectAuthError(w, r, data.RedirectURI, kUnsupportedResponseType, data.State)
 			return
 		}
 
 		if err := templates.ExecuteTemplate(w, kLoginTemplate, data); err != nil {
-			log.Printf("[Error] Failed to execute 'login' template - %v", err)
+			slog.ErrorContext(r.Context(), "Failed to execute 'login' template", "error", err)
 			redirectAuthError(w, r, data.RedirectURI, kServerError, data.State)
 			return
 		}
 	}
 }
 
-func Login(config Config) func(w http.ResponseWriter, r *http.Request) {
+func Login() func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
+		config := Current()
 		svcs := services.ServicesFromContext(r.Context())
 		cid := r.FormValue("client_id")
 		data := services.AuthCodeData{
@@ -128,7 +147,7 @@ func Login(config Config) func(w http.ResponseWriter, r *http.Request) {
 		}
 
 		if !svcs.Authorizer().ValidateClient(cid, data.RedirectURI) {
-			log.Print("[Error] Invalid client and / or redirect URL in login call.")
+			slog.WarnContext(r.Context(), "Invalid client and / or redirect URL in login call", "client_id", cid)
 			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
 			return
 		}
@@ -138,15 +157,15 @@ func Login(config Config) func(w http.ResponseWriter, r *http.Request) {
 
 		uid, err := svcs.Authorizer().Authenticate(user, pwd)
 		if err != nil {
-			log.Printf("[Error] Authentication failed - %v", err)
+			slog.WarnContext(r.Context(), "Authentication failed", "client_id", cid, "error", err)
 			redirectAuthError(w, r, data.RedirectURI, kAccessDeniedError, data.State)
 			return
 		}
 
 		data.UID = uid
-		code, err := svcs.Authorizer().GenerateAuthorizationRequest(data, config.CodeTTL)
+		code, err := svcs.Authorizer().GenerateAuthorizationRequest(r.Context(), data, config.CodeTTL)
 		if err != nil {
-			log.Printf("[Error] Failed to generate authorization code - %v", err)
+			slog.ErrorContext(r.Context(), "Failed to generate authorization code", "error", err)
 			redirectAuthError(w, r, data.RedirectURI, kServerError, data.State)
 			return
 		}
@@ -24,7 +24,7 @@ package auth
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 
 	qrcode "github.com/skip2/go-qrcode"
@@ -36,12 +36,13 @@ const (
 	kQRErrorCorrectionQuality = qrcode.Low
 )
 
-func QRGenerator(qr QRScanConfig) func(http.ResponseWriter, *http.Request) {
+func QRGenerator() func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
+		qr := Current().QRScan
 		svcs := services.ServicesFromContext(r.Context())
-		ts, token, hash, err := svcs.Authorizer().GenerateQRRequest(qr.TTL)
+		ts, token, hash, err := svcs.Authorizer().GenerateQRRequest(r.Context(), qr.TTL)
 		if err != nil {
-			log.Printf("[Error] Failed to generate QR request - %v", err)
+			slog.ErrorContext(r.Context(), "Failed to generate QR request", "error", err)
 			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 			return
 		}
@@ -51,14 +52,14 @@ func QRGenerator(qr QRScanConfig) func(http.ResponseWriter, *http.Request) {
 			kQRErrorCorrectionQuality,
 		)
 		if err != nil {
-			log.Printf("[Error] Failed to generate QR Code - %v", err)
+			slog.ErrorContext(r.Context(), "Failed to generate QR Code", "error", err)
 			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 			return
 		}
 
 		png, err := code.PNG(kQRImageSize)
 		if err != nil {
-			log.Printf("[Error] Failed to generate QR Code PNG - %v", err)
+			slog.ErrorContext(r.Context(), "Failed to generate QR Code PNG", "error", err)
 			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 			return
 		}
@@ -0,0 +1,104 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package services
+
+import (
+	"reflect"
+	"strings"
+)
+
+/**
+ *
+ * Lists the (dotted, YAML-named) paths of config fields that differ
+ * between two values of the same type, e.g. "Root.CORS.AllowedOrigins".
+ * Lists and maps are compared as a whole.
+ *
+ **/
+func ChangedFields(old, new any) []string {
+	var changed []string
+	diffFields("", reflect.ValueOf(old), reflect.ValueOf(new), &changed)
+	return changed
+}
+
+func diffFields(prefix string, a, b reflect.Value, changed *[]string) {
+	if a.Kind() != reflect.Struct {
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			*changed = append(*changed, prefix)
+		}
+		return
+	}
+
+	t := a.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := fieldName(field)
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+
+		diffFields(name, a.Field(i), b.Field(i), changed)
+	}
+}
+
+// Whether 'field' is, or sits under, any of the given paths
+func FieldWithin(field string, paths []string) bool {
+	for _, p := range paths {
+		if field == p || strings.HasPrefix(field, p+".") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Copies the field at 'path' (as named by ChangedFields) from 'src' into
+// the struct pointed to by 'dst'
+func CopyField(dst, src any, path string) {
+	d := reflect.ValueOf(dst).Elem()
+	s := reflect.ValueOf(src)
+
+	for _, name := range strings.Split(path, ".") {
+		i := fieldIndex(d.Type(), name)
+		if i < 0 {
+			return
+		}
+
+		d, s = d.Field(i), s.Field(i)
+	}
+
+	d.Set(s)
+}
+
+func fieldIndex(t reflect.Type, name string) int {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() && fieldName(t.Field(i)) == name {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,352 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	kSecretFilePrefix = "file:"
+)
+
+var (
+	kExpandPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+	kDurationType  = reflect.TypeOf(time.Duration(0))
+)
+
+/**
+ *
+ * Replaces ${VAR} (or ${VAR:-default}) references in config values with
+ * values from the environment. Expansion happens on the decoded document,
+ * one value at a time, so substituted text is never parsed as YAML or JSON
+ * and references in comments are ignored. References to unset variables
+ * without a default are reported together.
+ *
+ **/
+type envExpander struct {
+	missing []string
+}
+
+func (e *envExpander) expand(value string) string {
+	return kExpandPattern.ReplaceAllStringFunc(value, func(ref string) string {
+		parts := kExpandPattern.FindStringSubmatch(ref)
+		if value, ok := os.LookupEnv(parts[1]); ok {
+			return value
+		}
+
+		if strings.Contains(ref, ":-") {
+			return parts[2]
+		}
+
+		e.missing = append(e.missing, parts[1])
+		return ref
+	})
+}
+
+func (e *envExpander) err() error {
+	if len(e.missing) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("unset environment variables referenced: %s", strings.Join(e.missing, ", "))
+}
+
+// Expands every scalar in a YAML document. An unquoted scalar has its type
+// resolved again after expansion, so "Port: ${PORT}" still decodes as a
+// number; quoted scalars stay strings.
+func (e *envExpander) yaml(n *yaml.Node) {
+	if n.Kind == yaml.ScalarNode && kExpandPattern.MatchString(n.Value) {
+		n.Value = e.expand(n.Value)
+		if n.Style == 0 {
+			n.Tag = ""
+		}
+	}
+
+	for _, child := range n.Content {
+		e.yaml(child)
+	}
+}
+
+// Expands every string in a decoded JSON document. A string that is only
+// a reference is decoded again as a number or boolean when the expanded
+// text is one, so "port": "${PORT}" still fills a numeric field.
+func (e *envExpander) json(v any) any {
+	switch v := v.(type) {
+	case string:
+		expanded := e.expand(v)
+		if loc := kExpandPattern.FindStringIndex(v); loc != nil && loc[0] == 0 && loc[1] == len(v) {
+			return jsonLiteral(expanded)
+		}
+		return expanded
+	case []any:
+		for i := range v {
+			v[i] = e.json(v[i])
+		}
+	case map[string]any:
+		for k := range v {
+			v[k] = e.json(v[k])
+		}
+	}
+
+	return v
+}
+
+func jsonLiteral(value string) any {
+	dec := json.NewDecoder(bytes.NewReader([]byte(value)))
+	dec.UseNumber()
+
+	var literal any
+	if err := dec.Decode(&literal); err != nil || dec.More() {
+		return value
+	}
+
+	switch literal.(type) {
+	case json.Number, bool:
+		return literal
+	}
+
+	return value
+}
+
+/**
+ *
+ * Overrides config fields from environment variables named after the
+ * field path: PREFIX_<SECTION>_<FIELD>, using each field's YAML name in
+ * upper snake case (e.g. SL_ROOT_PORT, SL_SERVICES_AUTH_CODE_TTL).
+ *
+ * Scalars, durations, comma-separated string lists and "k=v,k=v" string
+ * maps are supported; lists of structs can only be set from the file.
+ *
+ **/
+func ApplyEnvOverrides(prefix string, config any) error {
+	v := reflect.ValueOf(config)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return errors.New("config must be a pointer to a struct")
+	}
+
+	var errs []error
+	applyEnv(prefix, v.Elem(), &errs)
+	return errors.Join(errs...)
+}
+
+func applyEnv(prefix string, v reflect.Value, errs *[]error) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		key := prefix + "_" + envName(fieldName(field))
+		fv := v.Field(i)
+
+		if fv.Kind() == reflect.Struct {
+			applyEnv(key, fv, errs)
+			continue
+		}
+
+		value, ok := os.LookupEnv(key)
+		if !ok {
+			continue
+		}
+
+		if err := setFromString(fv, value); err != nil {
+			*errs = append(*errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+}
+
+func setFromString(fv reflect.Value, value string) error {
+	if fv.Type() == kDurationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+
+		fv.SetInt(int64(d))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(value)
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("cannot set a list of %s from the environment", fv.Type().Elem())
+		}
+
+		items := splitList(value)
+		slice := reflect.MakeSlice(fv.Type(), len(items), len(items))
+		for i, item := range items {
+			slice.Index(i).SetString(item)
+		}
+		fv.Set(slice)
+
+	case reflect.Map:
+		if fv.Type().Key().Kind() != reflect.String || fv.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("cannot set a %s from the environment", fv.Type())
+		}
+
+		m := reflect.MakeMap(fv.Type())
+		for _, item := range splitList(value) {
+			k, v, ok := strings.Cut(item, "=")
+			if !ok {
+				return fmt.Errorf("expected key=value, got '%s'", item)
+			}
+			m.SetMapIndex(reflect.ValueOf(strings.TrimSpace(k)), reflect.ValueOf(strings.TrimSpace(v)))
+		}
+		fv.Set(m)
+
+	default:
+		return fmt.Errorf("cannot set a %s from the environment", fv.Type())
+	}
+
+	return nil
+}
+
+/**
+ *
+ * Replaces "file:<path>" values in string fields tagged `secret:"true"`
+ * with the (trimmed) contents of that file, e.g. a mounted Docker or
+ * Kubernetes secret.
+ *
+ **/
+func ResolveSecrets(config any) error {
+	var errs []error
+	resolveSecrets(reflect.ValueOf(config).Elem(), &errs)
+	return errors.Join(errs...)
+}
+
+func resolveSecrets(v reflect.Value, errs *[]error) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fv := v.Field(i)
+
+		switch {
+		case !field.IsExported():
+		case fv.Kind() == reflect.Struct:
+			resolveSecrets(fv, errs)
+		case fv.Kind() == reflect.String && field.Tag.Get("secret") == "true":
+			file, ok := strings.CutPrefix(fv.String(), kSecretFilePrefix)
+			if !ok {
+				continue
+			}
+
+			data, err := os.ReadFile(file)
+			if err != nil {
+				*errs = append(*errs, fmt.Errorf("%s: failed to read secret file - %w", field.Name, err))
+				continue
+			}
+
+			fv.SetString(strings.TrimSpace(string(data)))
+		}
+	}
+}
+
+/**
+ *
+ * Helpers
+ *
+ **/
+
+func fieldName(field reflect.StructField) string {
+	if name, _, _ := strings.Cut(field.Tag.Get("yaml"), ","); name != "" && name != "-" {
+		return name
+	}
+
+	return field.Name
+}
+
+// "RateLimits" -> "RATE_LIMITS", "HTTPAddress" -> "HTTP_ADDRESS", "CodeTTL" -> "CODE_TTL"
+func envName(name string) string {
+	runes := []rune(name)
+
+	var sb strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || (unicode.IsUpper(prev) && nextLower) {
+				sb.WriteByte('_')
+			}
+		}
+
+		sb.WriteRune(unicode.ToUpper(r))
+	}
+
+	return sb.String()
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
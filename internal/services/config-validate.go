@@ -0,0 +1,257 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"shiftylogic.dev/hockey-tools/internal/web"
+	"shiftylogic.dev/hockey-tools/internal/web/throttle"
+)
+
+/**
+ *
+ * Config validation collects every problem rather than stopping at the
+ * first, so a broken deployment can be fixed in one pass.
+ *
+ **/
+
+type Problems struct {
+	list []string
+}
+
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%d config problem(s): %s", len(e.Problems), strings.Join(e.Problems, "; "))
+}
+
+func (p *Problems) Add(field, format string, args ...any) {
+	p.list = append(p.list, field+": "+fmt.Sprintf(format, args...))
+}
+
+// Records the problem when 'ok' is false
+func (p *Problems) Check(ok bool, field, format string, args ...any) {
+	if !ok {
+		p.Add(field, format, args...)
+	}
+}
+
+// Records a problem unless 'file' exists (and is a directory if 'dir')
+func (p *Problems) CheckPath(field, file string, dir bool) {
+	info, err := os.Stat(file)
+	switch {
+	case err != nil:
+		p.Add(field, "'%s' not found", file)
+	case dir && !info.IsDir():
+		p.Add(field, "'%s' is not a directory", file)
+	case !dir && info.IsDir():
+		p.Add(field, "'%s' is a directory", file)
+	}
+}
+
+// Folds in problems from a nested Validate call
+func (p *Problems) Merge(err error) {
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		p.list = append(p.list, verr.Problems...)
+	} else if err != nil {
+		p.list = append(p.list, err.Error())
+	}
+}
+
+func (p *Problems) Err() error {
+	if len(p.list) == 0 {
+		return nil
+	}
+
+	return &ValidationError{Problems: p.list}
+}
+
+func (cfg Config) Validate() error {
+	var p Problems
+
+	p.Check(cfg.Port > 0 && cfg.Port < 65536, "Port", "%d is not a valid port", cfg.Port)
+
+	if _, err := cfg.Log.level(); err != nil {
+		p.Add("Log.Level", "%v", err)
+	}
+
+	switch strings.ToLower(cfg.Log.Format) {
+	case kLogFormatJSON, kLogFormatText, "":
+	default:
+		p.Add("Log.Format", "unknown format '%s'", cfg.Log.Format)
+	}
+
+	cfg.TLS.validate(&p)
+
+	p.Check(!cfg.HTTP3.Enabled || cfg.TLS.Enabled(), "HTTP3", "requires TLS")
+
+	// /metrics is unauthenticated, so it is only served on the admin
+	// listener; without one, enabling metrics would silently serve nothing.
+	p.Check(!cfg.Metrics || cfg.HasAdminListener(), "Metrics", "requires an admin listener to serve /metrics")
+
+	if cfg.Tracing.Enabled {
+		switch strings.ToLower(cfg.Tracing.Exporter) {
+		case kTraceExporterOTLP, "":
+			p.Check(cfg.Tracing.Endpoint != "", "Tracing.Endpoint", "required for the OTLP exporter")
+		case kTraceExporterMemory:
+		default:
+			p.Add("Tracing.Exporter", "unknown exporter '%s'", cfg.Tracing.Exporter)
+		}
+
+		p.Check(cfg.Tracing.SampleRatio >= 0 && cfg.Tracing.SampleRatio <= 1, "Tracing.SampleRatio", "must be between 0 and 1")
+	}
+
+	for i, l := range cfg.Listeners {
+		field := fmt.Sprintf("Listeners[%d]", i)
+		switch l.Kind {
+		case web.ListenerRedirect, web.ListenerAdmin, web.ListenerUnix:
+		default:
+			p.Add(field+".Kind", "unknown listener kind '%s'", l.Kind)
+		}
+
+		p.Check(l.Address != "", field+".Address", "required")
+		p.Check(l.Kind != web.ListenerRedirect || cfg.TLS.Enabled(), field, "redirect listener requires TLS on the main listener")
+
+		// It serves metrics, health detail and the profiler without auth
+		p.Check(l.Kind != web.ListenerAdmin || l.Address == "" || isLoopback(l.Address), field+".Address",
+			"admin listener must use a loopback address, not '%s'", l.Address)
+	}
+
+	for i, s := range cfg.Statics {
+		field := fmt.Sprintf("Statics[%d]", i)
+		p.Check(strings.HasPrefix(s.Endpoint, "/"), field+".Endpoint", "must start with '/'")
+		p.CheckPath(field+".LocalPath", s.LocalPath, true)
+	}
+
+	// The database is created (and migrated) on open, so only its
+	// directory has to exist up front.
+	if cfg.DataFile != "" {
+		p.CheckPath("DataFile", filepath.Dir(cfg.DataFile), true)
+	}
+
+	if cfg.RateLimits.Enabled {
+		for _, proxy := range cfg.RateLimits.TrustedProxies {
+			if _, err := throttle.ParseTrustedProxy(proxy); err != nil {
+				p.Add("RateLimits.TrustedProxies", "%v", err)
+			}
+		}
+
+		for i, policy := range cfg.RateLimits.Policies {
+			field := fmt.Sprintf("RateLimits.Policies[%d]", i)
+			p.Check(policy.Name != "", field+".Name", "required")
+			p.Check(policy.Algorithm.Valid(), field+".Algorithm", "unknown algorithm '%s'", policy.Algorithm)
+			p.Check(len(policy.Routes) > 0, field+".Routes", "at least one route is required")
+
+			for _, name := range policy.Mapper {
+				if _, err := throttle.KeyByName(name); err != nil {
+					p.Add(field+".Mapper", "%v", err)
+				}
+			}
+		}
+	}
+
+	if cfg.Health.Enabled {
+		p.Check(cfg.Health.DrainDelay >= 0, "Health.DrainDelay", "must not be negative")
+	}
+
+	if cfg.Concurrency.Enabled {
+		p.Check(cfg.Concurrency.MaxInFlight > 0, "Concurrency.MaxInFlight", "must be positive")
+		p.Check(cfg.Concurrency.MaxQueue >= 0, "Concurrency.MaxQueue", "must not be negative")
+	}
+
+	return p.Err()
+}
+
+func (cfg TLSConfig) validate(p *Problems) {
+	p.Check((cfg.Certificate == "") == (cfg.Key == ""), "TLS", "Certificate and Key must be set together")
+
+	if cfg.Certificate != "" && cfg.Key != "" {
+		p.CheckPath("TLS.Certificate", cfg.Certificate, false)
+		p.CheckPath("TLS.Key", cfg.Key, false)
+	}
+
+	for i, pair := range cfg.SNI {
+		field := fmt.Sprintf("TLS.SNI[%d]", i)
+		p.Check(cfg.Certificate != "", field, "requires a default Certificate and Key")
+		p.CheckPath(field+".Certificate", pair.Certificate, false)
+		p.CheckPath(field+".Key", pair.Key, false)
+	}
+
+	if cfg.ACME.Enabled {
+		p.Check(len(cfg.ACME.Domains) > 0, "TLS.ACME.Domains", "at least one domain is required")
+		p.Check(cfg.ACME.CacheDir != "", "TLS.ACME.CacheDir", "required")
+
+		switch cfg.ACME.Challenge {
+		case web.ChallengeHTTP01, web.ChallengeTLSALPN01, "":
+		default:
+			p.Add("TLS.ACME.Challenge", "unknown challenge '%s'", cfg.ACME.Challenge)
+		}
+	}
+
+	if auth := cfg.ClientAuth; auth.Enabled() || len(auth.Routes) > 0 || len(auth.Mappings) > 0 {
+		p.Check(cfg.Enabled(), "TLS.ClientAuth", "requires TLS")
+		p.Check(auth.Enabled(), "TLS.ClientAuth.CAFile", "required when routes or mappings are set")
+
+		if auth.Enabled() {
+			p.CheckPath("TLS.ClientAuth.CAFile", auth.CAFile, false)
+		}
+
+		switch auth.Mode {
+		case web.ClientAuthRequest, web.ClientAuthRequire, "":
+		default:
+			p.Add("TLS.ClientAuth.Mode", "unknown mode '%s'", auth.Mode)
+		}
+
+		for i, m := range auth.Mappings {
+			field := fmt.Sprintf("TLS.ClientAuth.Mappings[%d]", i)
+			p.Check(m.CommonName != "" || m.Fingerprint != "", field, "CommonName or Fingerprint is required")
+			p.Check(m.Subject != "", field+".Subject", "required")
+			p.Check(m.Kind == web.ClientCertUser || m.Kind == web.ClientCertClient, field+".Kind", "must be '%s' or '%s'", web.ClientCertUser, web.ClientCertClient)
+		}
+	}
+}
+
+// Whether a host:port only accepts local connections. An empty host binds
+// every interface.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -23,24 +23,39 @@
 package services
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"io/fs"
-	"log"
+	"net/http"
 	"os"
 	"path"
+	"time"
 
 	"gopkg.in/yaml.v3"
 	"shiftylogic.dev/hockey-tools/internal/web"
+	"shiftylogic.dev/hockey-tools/internal/web/throttle"
 )
 
 type Config struct {
-	Address  string         `json:"address" yaml:"Address"`
-	Port     int            `json:"port" yaml:"Port"`
-	Logging  bool           `json:"logging" yaml:"Logging"`
-	Profiler bool           `json:"profiler" yaml:"Profiler"`
-	CORS     CORSConfig     `json:"cors" yaml:"CORS"`
-	TLS      TLSConfig      `json:"tls" yaml:"TLS"`
-	Statics  []StaticConfig `json:"statics" yaml:"Statics"`
+	Address     string            `json:"address" yaml:"Address"`
+	Port        int               `json:"port" yaml:"Port"`
+	Logging     bool              `json:"logging" yaml:"Logging"`
+	Log         LogConfig         `json:"log" yaml:"Log"`
+	Profiler    bool              `json:"profiler" yaml:"Profiler"`
+	Metrics     bool              `json:"metrics" yaml:"Metrics"`
+	Tracing     TracingConfig     `json:"tracing" yaml:"Tracing"`
+	Health      HealthConfig      `json:"health" yaml:"Health"`
+	DataFile    string            `json:"dataFile" yaml:"DataFile"`
+	CORS        CORSConfig        `json:"cors" yaml:"CORS"`
+	TLS         TLSConfig         `json:"tls" yaml:"TLS"`
+	H2C         bool              `json:"h2c" yaml:"H2C"`
+	HTTP3       HTTP3Config       `json:"http3" yaml:"HTTP3"`
+	Listeners   []ListenerConfig  `json:"listeners" yaml:"Listeners"`
+	Statics     []StaticConfig    `json:"statics" yaml:"Statics"`
+	Store       StoreConfig       `json:"store" yaml:"Store"`
+	RateLimits  RateLimitsConfig  `json:"rateLimits" yaml:"RateLimits"`
+	Concurrency ConcurrencyConfig `json:"concurrency" yaml:"Concurrency"`
 }
 
 type CORSConfig struct {
@@ -57,9 +72,93 @@ type StaticConfig struct {
 	LocalPath string `json:"localPath" yaml:"LocalPath"`
 }
 
+type StoreConfig struct {
+	MaxEntries     int           `json:"maxEntries" yaml:"MaxEntries"`
+	SnapshotFile   string        `json:"snapshotFile" yaml:"SnapshotFile"`
+	SnapshotPeriod time.Duration `json:"snapshotPeriod" yaml:"SnapshotPeriod"`
+}
+
+type RateLimitsConfig struct {
+	Enabled        bool                    `json:"enabled" yaml:"Enabled"`
+	LegacyHeaders  bool                    `json:"legacyHeaders" yaml:"LegacyHeaders"`
+	TrustedProxies []string                `json:"trustedProxies" yaml:"TrustedProxies"`
+	Exempt         []string                `json:"exempt" yaml:"Exempt"`
+	Policies       []RateLimitPolicyConfig `json:"policies" yaml:"Policies"`
+}
+
+type RateLimitPolicyConfig struct {
+	Name      string             `json:"name" yaml:"Name"`
+	Routes    []string           `json:"routes" yaml:"Routes"`
+	Algorithm throttle.Algorithm `json:"algorithm" yaml:"Algorithm"`
+	Limit     uint               `json:"limit" yaml:"Limit"`
+	Window    time.Duration      `json:"window" yaml:"Window"`
+	Burst     uint               `json:"burst" yaml:"Burst"`
+	Mapper    []string           `json:"mapper" yaml:"Mapper"`
+}
+
+type ConcurrencyConfig struct {
+	Enabled      bool          `json:"enabled" yaml:"Enabled"`
+	MaxInFlight  int           `json:"maxInFlight" yaml:"MaxInFlight"`
+	MaxQueue     int           `json:"maxQueue" yaml:"MaxQueue"`
+	QueueTimeout time.Duration `json:"queueTimeout" yaml:"QueueTimeout"`
+	RetryAfter   time.Duration `json:"retryAfter" yaml:"RetryAfter"`
+	HighPriority []string      `json:"highPriority" yaml:"HighPriority"`
+	LowPriority  []string      `json:"lowPriority" yaml:"LowPriority"`
+}
+
 type TLSConfig struct {
 	Certificate string `json:"certificate" yaml:"Certificate"`
 	Key         string `json:"key" yaml:"Key"`
+
+	// Further certificates, selected by SNI server name
+	SNI            []TLSPairConfig `json:"sni" yaml:"SNI"`
+	ReloadInterval time.Duration   `json:"reloadInterval" yaml:"ReloadInterval"`
+
+	// Obtains certificates automatically instead of loading the files above
+	ACME ACMEConfig `json:"acme" yaml:"ACME"`
+
+	ClientAuth ClientAuthConfig `json:"clientAuth" yaml:"ClientAuth"`
+}
+
+type ClientAuthConfig struct {
+	CAFile   string                    `json:"caFile" yaml:"CAFile"`
+	Mode     string                    `json:"mode" yaml:"Mode"`
+	Routes   []string                  `json:"routes" yaml:"Routes"`
+	Mappings []ClientCertMappingConfig `json:"mappings" yaml:"Mappings"`
+}
+
+type ClientCertMappingConfig struct {
+	CommonName  string `json:"commonName" yaml:"CommonName"`
+	Fingerprint string `json:"fingerprint" yaml:"Fingerprint"`
+	Kind        string `json:"kind" yaml:"Kind"`
+	Subject     string `json:"subject" yaml:"Subject"`
+}
+
+type ACMEConfig struct {
+	Enabled     bool     `json:"enabled" yaml:"Enabled"`
+	Directory   string   `json:"directory" yaml:"Directory"`
+	Email       string   `json:"email" yaml:"Email"`
+	Domains     []string `json:"domains" yaml:"Domains"`
+	CacheDir    string   `json:"cacheDir" yaml:"CacheDir"`
+	Challenge   string   `json:"challenge" yaml:"Challenge"`
+	HTTPAddress string   `json:"httpAddress" yaml:"HTTPAddress"`
+}
+
+// Extra listeners: "redirect" (HTTP to HTTPS), "admin" (metrics, health,
+// profiler) or "unix" (Address is the socket path)
+type ListenerConfig struct {
+	Kind    string `json:"kind" yaml:"Kind"`
+	Address string `json:"address" yaml:"Address"`
+}
+
+type HTTP3Config struct {
+	Enabled bool   `json:"enabled" yaml:"Enabled"`
+	Address string `json:"address" yaml:"Address"` // UDP; defaults to the TCP address
+}
+
+type TLSPairConfig struct {
+	Certificate string `json:"certificate" yaml:"Certificate"`
+	Key         string `json:"key" yaml:"Key"`
 }
 
 func DefaultConfig() Config {
@@ -67,9 +166,24 @@ func DefaultConfig() Config {
 		Address:  "localhost",
 		Port:     80,
 		Logging:  true,
+		Log:      DefaultLog(),
 		Profiler: false,
+		Metrics:  false,
+		Tracing:  DefaultTracing(),
+		Health:   DefaultHealth(),
 		CORS:     DefaultCORS(),
-		TLS:      TLSConfig{},
+		TLS:      TLSConfig{ACME: DefaultACME()},
+
+		RateLimits:  DefaultRateLimits(),
+		Concurrency: DefaultConcurrency(),
+	}
+}
+
+func DefaultACME() ACMEConfig {
+	return ACMEConfig{
+		Enabled:   false,
+		CacheDir:  "./.cert/acme",
+		Challenge: web.ChallengeTLSALPN01,
 	}
 }
 
@@ -84,24 +198,118 @@ func DefaultCORS() CORSConfig {
 	}
 }
 
-func LoadConfig(configFile string, config any) {
-	inFile, err := os.Open(configFile)
+func DefaultRateLimits() RateLimitsConfig {
+	return RateLimitsConfig{
+		Enabled:        false,
+		LegacyHeaders:  false,
+		TrustedProxies: []string{},
+		Exempt:         []string{"/healthz", "/readyz"},
+		Policies: []RateLimitPolicyConfig{
+			{
+				Name:      "login",
+				Routes:    []string{"/auth/login"},
+				Algorithm: throttle.AlgorithmSlidingWindow,
+				Limit:     10,
+				Window:    time.Minute,
+				Mapper:    []string{"ip"},
+			},
+			{
+				Name:      "token",
+				Routes:    []string{"/auth/token"},
+				Algorithm: throttle.AlgorithmSlidingWindow,
+				Limit:     30,
+				Window:    time.Minute,
+				Mapper:    []string{"client", "ip"},
+			},
+			{
+				Name:      "qrcode",
+				Routes:    []string{"/auth/qrcode"},
+				Algorithm: throttle.AlgorithmTokenBucket,
+				Limit:     10,
+				Window:    time.Minute,
+				Burst:     5,
+				Mapper:    []string{"ip"},
+			},
+			{
+				Name:      "api",
+				Routes:    []string{"/api/*"},
+				Algorithm: throttle.AlgorithmSlidingWindow,
+				Limit:     300,
+				Window:    time.Minute,
+				Mapper:    []string{"subject|ip"},
+			},
+		},
+	}
+}
+
+func DefaultConcurrency() ConcurrencyConfig {
+	return ConcurrencyConfig{
+		Enabled:      false,
+		MaxInFlight:  100,
+		MaxQueue:     200,
+		QueueTimeout: 5 * time.Second,
+		RetryAfter:   5 * time.Second,
+		HighPriority: []string{"/auth/*", "/api/scores/*"},
+		LowPriority:  []string{"/api/stats/*"},
+	}
+}
+
+// Decodes a YAML or JSON config file over 'config', expanding any ${VAR}
+// references in its values
+func LoadConfig(configFile string, config any) error {
+	raw, err := os.ReadFile(configFile)
 	if err != nil {
-		log.Fatalf("[ERROR] Failed to load provided config file - %v", err)
+		return fmt.Errorf("failed to read config file - %w", err)
 	}
 
+	var env envExpander
+
 	switch path.Ext(configFile) {
 	case ".yaml", ".yml":
-		if err := yaml.NewDecoder(inFile).Decode(config); err != nil {
-			log.Fatalf("[ERROR] Failed to parse / decode YAML config (%s) - %v", configFile, err)
+		var doc yaml.Node
+		if err := yaml.Unmarshal(raw, &doc); err != nil {
+			return fmt.Errorf("failed to parse YAML config '%s' - %w", configFile, err)
+		}
+
+		env.yaml(&doc)
+		if err := env.err(); err != nil {
+			return fmt.Errorf("config file '%s': %w", configFile, err)
+		}
+
+		// An empty file has no document to decode
+		if len(doc.Content) == 0 {
+			return nil
+		}
+
+		if err := doc.Decode(config); err != nil {
+			return fmt.Errorf("failed to parse YAML config '%s' - %w", configFile, err)
 		}
 	case ".json":
-		if err := json.NewDecoder(inFile).Decode(config); err != nil {
-			log.Fatalf("[ERROR] Failed to parse / decode JSON config (%s) - %v", configFile, err)
+		// Numbers are kept as written so that they survive the round trip
+		var doc any
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		if err := dec.Decode(&doc); err != nil {
+			return fmt.Errorf("failed to parse JSON config '%s' - %w", configFile, err)
+		}
+
+		doc = env.json(doc)
+		if err := env.err(); err != nil {
+			return fmt.Errorf("config file '%s': %w", configFile, err)
+		}
+
+		if raw, err = json.Marshal(doc); err != nil {
+			return fmt.Errorf("failed to parse JSON config '%s' - %w", configFile, err)
+		}
+
+		if err := json.Unmarshal(raw, config); err != nil {
+			return fmt.Errorf("failed to parse JSON config '%s' - %w", configFile, err)
 		}
 	default:
-		panic("unknown config file format")
+		return fmt.Errorf("unknown config file format '%s'", path.Ext(configFile))
 	}
+
+	return nil
 }
 
 /**
@@ -114,6 +322,15 @@ func (cfg CORSConfig) Enabled() bool {
 	return len(cfg.AllowedOrigins) > 0
 }
 
+// The CORS middleware, or nil (pass-through) when disabled
+func (cfg CORSConfig) Middleware() func(http.Handler) http.Handler {
+	if !cfg.Enabled() {
+		return nil
+	}
+
+	return web.Cors(cfg.Options())
+}
+
 func (cfg CORSConfig) Options() web.CorsOptions {
 	return web.CorsOptions{
 		AllowedOrigins:   cfg.AllowedOrigins,
@@ -135,6 +352,125 @@ func (cfg StaticConfig) FS() fs.FS {
 	return os.DirFS(cfg.LocalPath)
 }
 
+/**
+ *
+ * Helper methods on StoreConfig struct
+ *
+ **/
+
+func (cfg StoreConfig) Options() []MemoryStoreOptionFunc {
+	var options []MemoryStoreOptionFunc
+
+	if cfg.MaxEntries > 0 {
+		options = append(options, WithMaxEntries(cfg.MaxEntries))
+	}
+
+	if cfg.SnapshotFile != "" {
+		options = append(options, WithSnapshot(cfg.SnapshotFile, cfg.SnapshotPeriod))
+	}
+
+	return options
+}
+
+/**
+ *
+ * Helper methods on RateLimitsConfig struct
+ *
+ **/
+
+// The rate limiting middleware, or nil (pass-through) when disabled
+func (cfg RateLimitsConfig) Middleware() (func(http.Handler) http.Handler, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	limits, err := cfg.Options()
+	if err != nil {
+		return nil, err
+	}
+
+	return web.RateLimiter(limits), nil
+}
+
+func (cfg RateLimitsConfig) Options() (web.RateLimits, error) {
+	limits := web.RateLimits{
+		Exempt: cfg.Exempt,
+	}
+
+	for _, p := range cfg.Policies {
+		keys := make([]throttle.KeyFunc, len(p.Mapper))
+		for i, name := range p.Mapper {
+			key, err := throttle.KeyByName(name, cfg.TrustedProxies...)
+			if err != nil {
+				return web.RateLimits{}, fmt.Errorf("rate limit policy '%s': %w", p.Name, err)
+			}
+			keys[i] = key
+		}
+
+		throttler, err := throttle.NewThrottler(p.Throttle())
+		if err != nil {
+			return web.RateLimits{}, fmt.Errorf("rate limit policy '%s': %w", p.Name, err)
+		}
+		throttler.RequestMapper = throttle.MapRequest(keys...)
+		throttler.LegacyHeaders = cfg.LegacyHeaders
+
+		limits.Policies = append(limits.Policies, web.RateLimitPolicy{
+			Name:      p.Name,
+			Routes:    p.Routes,
+			Throttler: throttler,
+		})
+	}
+
+	return limits, nil
+}
+
+func (cfg RateLimitPolicyConfig) Throttle() throttle.Config {
+	return throttle.Config{
+		Name:      cfg.Name,
+		Algorithm: cfg.Algorithm,
+		Limit:     cfg.Limit,
+		Window:    cfg.Window,
+		Burst:     cfg.Burst,
+	}
+}
+
+/**
+ *
+ * Helper methods on ConcurrencyConfig struct
+ *
+ **/
+
+func (cfg ConcurrencyConfig) Options() web.ConcurrencyOptions {
+	return web.ConcurrencyOptions{
+		MaxInFlight:  cfg.MaxInFlight,
+		MaxQueue:     cfg.MaxQueue,
+		QueueTimeout: cfg.QueueTimeout,
+		RetryAfter:   cfg.RetryAfter,
+		Classify:     web.ClassifyByRoute(cfg.HighPriority, cfg.LowPriority),
+	}
+}
+
+/**
+ *
+ * Helper methods on Config struct
+ *
+ **/
+
+func (cfg Config) Listener(kind string) (ListenerConfig, bool) {
+	for _, l := range cfg.Listeners {
+		if l.Kind == kind {
+			return l, true
+		}
+	}
+
+	return ListenerConfig{}, false
+}
+
+func (cfg Config) HasAdminListener() bool {
+	_, ok := cfg.Listener(web.ListenerAdmin)
+	return ok
+}
+
 /**
  *
  * Helper methods on TLSConfig struct
@@ -142,5 +478,49 @@ func (cfg StaticConfig) FS() fs.FS {
  **/
 
 func (cfg TLSConfig) Enabled() bool {
-	return cfg.Certificate != "" && cfg.Key != ""
+	return cfg.ACME.Enabled || (cfg.Certificate != "" && cfg.Key != "")
+}
+
+func (cfg ACMEConfig) Options() web.ACMEOptions {
+	return web.ACMEOptions{
+		Directory:   cfg.Directory,
+		Email:       cfg.Email,
+		Domains:     cfg.Domains,
+		CacheDir:    cfg.CacheDir,
+		Challenge:   cfg.Challenge,
+		HTTPAddress: cfg.HTTPAddress,
+	}
+}
+
+func (cfg ClientAuthConfig) Enabled() bool {
+	return cfg.CAFile != ""
+}
+
+func (cfg ClientAuthConfig) Options() web.ClientCertOptions {
+	opts := web.ClientCertOptions{
+		CAFile: cfg.CAFile,
+		Mode:   cfg.Mode,
+		Routes: cfg.Routes,
+	}
+
+	for _, m := range cfg.Mappings {
+		opts.Mappings = append(opts.Mappings, web.ClientCertMapping{
+			CommonName:  m.CommonName,
+			Fingerprint: m.Fingerprint,
+			Kind:        m.Kind,
+			Subject:     m.Subject,
+		})
+	}
+
+	return opts
+}
+
+// The default pair first, followed by any SNI pairs
+func (cfg TLSConfig) Pairs() []web.CertificatePair {
+	pairs := []web.CertificatePair{{Certificate: cfg.Certificate, Key: cfg.Key}}
+	for _, pair := range cfg.SNI {
+		pairs = append(pairs, web.CertificatePair{Certificate: pair.Certificate, Key: pair.Key})
+	}
+
+	return pairs
 }
@@ -0,0 +1,243 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/test"
+)
+
+func TestEnvNames(t *testing.T) {
+	cases := map[string]string{
+		"Port":        "PORT",
+		"RateLimits":  "RATE_LIMITS",
+		"HTTPAddress": "HTTP_ADDRESS",
+		"CodeTTL":     "CODE_TTL",
+		"tokenTTL":    "TOKEN_TTL",
+		"QRScan":      "QR_SCAN",
+		"HTTP3":       "HTTP3",
+		"CAFile":      "CA_FILE",
+	}
+
+	for name, expected := range cases {
+		test.Expect(t, expected, envName(name), "env name for "+name)
+	}
+}
+
+func TestEnvOverrides(t *testing.T) {
+	type nested struct {
+		Secret string `yaml:"Secret" secret:"true"`
+	}
+
+	type app struct {
+		Root     Config `yaml:"Root"`
+		Services nested `yaml:"Services"`
+	}
+
+	t.Setenv("SL_ROOT_PORT", "8443")
+	t.Setenv("SL_ROOT_LOGGING", "false")
+	t.Setenv("SL_ROOT_STORE_SNAPSHOT_PERIOD", "90s")
+	t.Setenv("SL_ROOT_CORS_ALLOWED_ORIGINS", "a.example, b.example")
+	t.Setenv("SL_ROOT_TRACING_HEADERS", "x-team=blue,x-env=prod")
+	t.Setenv("SL_ROOT_TRACING_SAMPLE_RATIO", "0.25")
+
+	config := app{Root: DefaultConfig()}
+	test.NoError(t, ApplyEnvOverrides("SL", &config), "apply overrides")
+
+	test.Expect(t, 8443, config.Root.Port, "port")
+	test.Expect(t, false, config.Root.Logging, "logging")
+	test.Expect(t, 90*time.Second, config.Root.Store.SnapshotPeriod, "snapshot period")
+	test.Expect(t, 2, len(config.Root.CORS.AllowedOrigins), "origins")
+	test.Expect(t, "b.example", config.Root.CORS.AllowedOrigins[1], "second origin")
+	test.Expect(t, "prod", config.Root.Tracing.Headers["x-env"], "header map")
+	test.Expect(t, 0.25, config.Root.Tracing.SampleRatio, "sample ratio")
+
+	// Bad values are all reported together
+	t.Setenv("SL_ROOT_PORT", "eighty")
+	t.Setenv("SL_ROOT_METRICS", "sometimes")
+	err := ApplyEnvOverrides("SL", &config)
+	test.AnyError(t, err, "bad overrides")
+	test.Require(t, strings.Contains(err.Error(), "SL_ROOT_PORT") && strings.Contains(err.Error(), "SL_ROOT_METRICS"), "both bad overrides reported")
+
+	// Secrets may reference a file
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	test.NoError(t, os.WriteFile(secretFile, []byte("hunter2\n"), 0600), "write secret")
+	config.Services.Secret = "file:" + secretFile
+	test.NoError(t, ResolveSecrets(&config), "resolve secrets")
+	test.Expect(t, "hunter2", config.Services.Secret, "secret from file")
+}
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("SL_TEST_HOST", "rink.example")
+	t.Setenv("SL_TEST_SECRET", "a#b: 'c\"\nd")
+
+	dir := t.TempDir()
+	yamlFile := filepath.Join(dir, "config.yaml")
+	test.NoError(t, os.WriteFile(yamlFile, []byte(`# Set ${SL_TEST_COMMENTED} to change nothing
+Address: ${SL_TEST_HOST}
+Port: ${SL_TEST_PORT:-8080}
+DataFile: "${SL_TEST_SECRET}"
+Log:
+  Level: '${SL_TEST_EMPTY:-}'
+`), 0600), "write YAML config")
+
+	config := DefaultConfig()
+	test.NoError(t, LoadConfig(yamlFile, &config), "load YAML config")
+	test.Expect(t, "rink.example", config.Address, "expanded string")
+	test.Expect(t, 8080, config.Port, "expanded number")
+	test.Expect(t, "a#b: 'c\"\nd", config.DataFile, "special characters kept verbatim")
+	test.Expect(t, "", config.Log.Level, "empty default")
+
+	jsonFile := filepath.Join(dir, "config.json")
+	test.NoError(t, os.WriteFile(jsonFile, []byte(`{"address": "${SL_TEST_HOST}", "port": 9090, "dataFile": "${SL_TEST_SECRET}"}`), 0600), "write JSON config")
+
+	config = DefaultConfig()
+	test.NoError(t, LoadConfig(jsonFile, &config), "load JSON config")
+	test.Expect(t, "rink.example", config.Address, "expanded string")
+	test.Expect(t, 9090, config.Port, "number kept")
+	test.Expect(t, "a#b: 'c\"\nd", config.DataFile, "special characters kept verbatim")
+
+	test.NoError(t, os.WriteFile(yamlFile, []byte("Address: ${SL_TEST_MISSING_A}\nDataFile: ${SL_TEST_MISSING_B}\n"), 0600), "write YAML config")
+	err := LoadConfig(yamlFile, &config)
+	test.AnyError(t, err, "missing variables")
+	test.Require(t, strings.Contains(err.Error(), "SL_TEST_MISSING_A, SL_TEST_MISSING_B"), "all missing variables reported")
+}
+
+func TestExpandEnvJSON(t *testing.T) {
+	t.Setenv("SL_TEST_PORT", "8443")
+	t.Setenv("SL_TEST_METRICS", "true")
+	t.Setenv("SL_TEST_HOST", "8080")
+
+	jsonFile := filepath.Join(t.TempDir(), "config.json")
+	test.NoError(t, os.WriteFile(jsonFile, []byte(`{
+		"address": "rink-${SL_TEST_HOST}",
+		"port": "${SL_TEST_PORT}",
+		"metrics": "${SL_TEST_METRICS}",
+		"profiler": "${SL_TEST_PROFILER:-false}",
+		"dataFile": "${SL_TEST_DATA:-hockey.db}"
+	}`), 0600), "write JSON config")
+
+	config := DefaultConfig()
+	test.NoError(t, LoadConfig(jsonFile, &config), "load JSON config")
+	test.Expect(t, 8443, config.Port, "reference decoded as a number")
+	test.Expect(t, true, config.Metrics, "reference decoded as a boolean")
+	test.Expect(t, false, config.Profiler, "default decoded as a boolean")
+	test.Expect(t, "rink-8080", config.Address, "partial reference stays a string")
+	test.Expect(t, "hockey.db", config.DataFile, "non-literal stays a string")
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	config := DefaultConfig()
+	config.Port = 70000
+	config.TLS.Certificate = "/nope/cert.pem"
+	config.HTTP3.Enabled = true
+	config.Log.Level = "chatty"
+	config.Listeners = []ListenerConfig{{Kind: "ftp"}, {Kind: "admin", Address: ":9090"}}
+
+	err := config.Validate()
+	test.AnyError(t, err, "invalid config")
+
+	problems := err.(*ValidationError).Problems
+	for _, field := range []string{"Port", "TLS", "HTTP3", "Log.Level", "Listeners[0].Kind", "Listeners[0].Address", "Listeners[1].Address"} {
+		found := false
+		for _, problem := range problems {
+			found = found || strings.HasPrefix(problem, field+":")
+		}
+		test.Require(t, found, "problem reported for "+field)
+	}
+
+	test.NoError(t, DefaultConfig().Validate(), "default config is valid")
+
+	config = DefaultConfig()
+	config.Listeners = []ListenerConfig{{Kind: "admin", Address: "127.0.0.1:9090"}, {Kind: "admin", Address: "[::1]:9090"}, {Kind: "admin", Address: "localhost:9090"}}
+	test.NoError(t, config.Validate(), "loopback admin listeners are valid")
+
+	config = DefaultConfig()
+	config.Metrics = true
+	test.AnyError(t, config.Validate(), "metrics without an admin listener")
+
+	config.Listeners = []ListenerConfig{{Kind: "admin", Address: "127.0.0.1:9090"}}
+	test.NoError(t, config.Validate(), "metrics with an admin listener")
+}
+
+func TestChangedFields(t *testing.T) {
+	type app struct {
+		Root Config `yaml:"Root"`
+	}
+
+	old := app{Root: DefaultConfig()}
+	next := app{Root: DefaultConfig()}
+	next.Root.Port = 8443
+	next.Root.CORS.AllowedOrigins = []string{"rink.example"}
+	next.Root.Log.Level = "debug"
+
+	changed := ChangedFields(old, next)
+	test.Expect(t, 3, len(changed), "changed field count")
+	test.Expect(t, "Root.Port", changed[0], "port changed")
+	test.Expect(t, "Root.Log.Level", changed[1], "log level changed")
+	test.Expect(t, "Root.CORS.AllowedOrigins", changed[2], "origins changed")
+
+	reloadable := []string{"Root.CORS", "Root.Log.Level"}
+	test.Require(t, !FieldWithin("Root.Port", reloadable), "port needs a restart")
+	test.Require(t, FieldWithin("Root.CORS.AllowedOrigins", reloadable), "origins reload")
+	test.Require(t, !FieldWithin("Root.CORSExtra", reloadable), "prefix match respects path segments")
+
+	CopyField(&next, old, "Root.Port")
+	test.Expect(t, old.Root.Port, next.Root.Port, "port copied back")
+	test.Expect(t, 2, len(ChangedFields(old, next)), "remaining changes")
+}
+
+func TestRateLimitOptionsRejectUnknownNames(t *testing.T) {
+	cfg := RateLimitsConfig{
+		Enabled:  true,
+		Policies: []RateLimitPolicyConfig{{Name: "api", Routes: []string{"/api/*"}, Mapper: []string{"ip"}}},
+	}
+
+	_, err := cfg.Middleware()
+	test.NoError(t, err, "known mapper")
+
+	cfg.Policies[0].Mapper = []string{"shoe-size"}
+	_, err = cfg.Options()
+	test.AnyError(t, err, "unknown mapper")
+
+	cfg.Policies[0].Mapper = []string{"ip"}
+	cfg.Policies[0].Algorithm = "leaky-bucket"
+	_, err = cfg.Middleware()
+	test.AnyError(t, err, "unknown algorithm")
+
+	cfg.Policies[0].Algorithm = ""
+	cfg.TrustedProxies = []string{"10.0.0.0/33x"}
+	_, err = cfg.Middleware()
+	test.AnyError(t, err, "malformed trusted proxy")
+
+	config := DefaultConfig()
+	config.RateLimits = cfg
+	err = config.Validate()
+	test.AnyError(t, err, "malformed trusted proxy fails validation")
+	test.Require(t, strings.Contains(err.Error(), "RateLimits.TrustedProxies: "), "problem reported for RateLimits.TrustedProxies")
+}
@@ -33,6 +33,48 @@ type KeyValueStore interface {
 
 	Refresh(ns, key string, ttl time.Duration) error
 	Remove(ns, key string)
+
+	// Namespace-level operations
+	Scan(ns, prefix string, fn ScanFunc) error
+	RemoveAll(ns, prefix string) int
+	Count(ns string) int
+	Stats(ns string, buckets ...time.Duration) (NamespaceStats, error)
+
+	// Change notifications
+	Subscribe(ns string, fn KeyValueHandler) (cancel func())
+}
+
+type KeyValueEventKind int
+
+const (
+	KeyValueSet KeyValueEventKind = iota
+	KeyValueRemoved
+	KeyValueExpired
+	KeyValueEvicted
+)
+
+type KeyValueEvent struct {
+	Kind      KeyValueEventKind
+	Namespace string
+	Key       string
+	Value     any
+}
+
+// Handlers run on the goroutine that caused the event (the store's expiry
+// goroutine for KeyValueExpired) and should not block.
+type KeyValueHandler func(KeyValueEvent)
+
+// Called for each live item visited by Scan. Returning false stops the scan.
+type ScanFunc func(key string, value any, expires time.Time) bool
+
+type NamespaceStats struct {
+	Items   int // Live (unexpired) items in the namespace
+	Expired int // Expired items that have not been collected yet
+
+	// Expiry[i] counts the live items expiring within Buckets[i] (and after
+	// Buckets[i-1]). The final entry counts everything beyond the last bucket.
+	Buckets []time.Duration
+	Expiry  []int
 }
 
 type DataStore interface {
@@ -0,0 +1,96 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/data"
+	"shiftylogic.dev/hockey-tools/internal/health"
+)
+
+const (
+	kHealthNamespace = "sl.health"
+	kHealthTTL       = time.Minute
+
+	kDefaultDrainDelay = 5 * time.Second
+)
+
+type HealthConfig struct {
+	Enabled    bool          `json:"enabled" yaml:"Enabled"`
+	Timeout    time.Duration `json:"timeout" yaml:"Timeout"`
+	DrainDelay time.Duration `json:"drainDelay" yaml:"DrainDelay"`
+}
+
+/**
+ *
+ * DrainDelay is how long /readyz reports "shutting down" before the
+ * listeners close, so load balancers polling it stop routing new requests
+ * first. It should cover a couple of the balancer's probe intervals; zero
+ * closes the listeners as soon as readiness flips.
+ *
+ **/
+func DefaultHealth() HealthConfig {
+	return HealthConfig{
+		Enabled:    true,
+		Timeout:    2 * time.Second,
+		DrainDelay: kDefaultDrainDelay,
+	}
+}
+
+/**
+ *
+ * Writes, reads back and removes a probe value, so the check fails if the
+ * store is full of rejections or hands back something other than what was
+ * written.
+ *
+ **/
+func KeyValueCheck(kvs KeyValueStore) health.Check {
+	return func(ctx context.Context) error {
+		key := strconv.FormatInt(time.Now().UnixNano(), 36)
+
+		if err := kvs.Set(kHealthNamespace, key, key, kHealthTTL); err != nil {
+			return fmt.Errorf("write failed: %w", err)
+		}
+
+		v, err := kvs.ReadAndRemove(kHealthNamespace, key)
+		if err != nil {
+			return fmt.Errorf("read failed: %w", err)
+		}
+
+		if v != key {
+			return fmt.Errorf("read back %v, expected %s", v, key)
+		}
+
+		return nil
+	}
+}
+
+func DataStoreCheck(store data.Store) health.Check {
+	return func(ctx context.Context) error {
+		return store.Ping(ctx)
+	}
+}
@@ -0,0 +1,135 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/trace"
+)
+
+/**
+ *
+ * A KeyValueStore view bound to a request context, so each operation is
+ * recorded as a child span of the request. KeyValueStore methods take no
+ * context, hence the per-request wrapper.
+ *
+ **/
+
+type tracedKeyValues struct {
+	ctx context.Context
+	kvs KeyValueStore
+}
+
+func TraceKeyValues(ctx context.Context, kvs KeyValueStore) KeyValueStore {
+	return &tracedKeyValues{ctx, kvs}
+}
+
+func (t *tracedKeyValues) start(op, ns string) *trace.Span {
+	_, span := trace.Start(t.ctx, "kvs."+op, slog.String("kvs.namespace", ns))
+	return span
+}
+
+func (t *tracedKeyValues) Read(ns, key string) (any, error) {
+	span := t.start("Read", ns)
+	defer span.End()
+
+	v, err := t.kvs.Read(ns, key)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedKeyValues) ReadAndRemove(ns, key string) (any, error) {
+	span := t.start("ReadAndRemove", ns)
+	defer span.End()
+
+	v, err := t.kvs.ReadAndRemove(ns, key)
+	span.RecordError(err)
+	return v, err
+}
+
+func (t *tracedKeyValues) CheckAndSet(ns, key string, value any, ttl time.Duration) error {
+	span := t.start("CheckAndSet", ns)
+	defer span.End()
+
+	err := t.kvs.CheckAndSet(ns, key, value, ttl)
+	span.RecordError(err)
+	return err
+}
+
+func (t *tracedKeyValues) Set(ns, key string, value any, ttl time.Duration) error {
+	span := t.start("Set", ns)
+	defer span.End()
+
+	err := t.kvs.Set(ns, key, value, ttl)
+	span.RecordError(err)
+	return err
+}
+
+func (t *tracedKeyValues) Refresh(ns, key string, ttl time.Duration) error {
+	span := t.start("Refresh", ns)
+	defer span.End()
+
+	err := t.kvs.Refresh(ns, key, ttl)
+	span.RecordError(err)
+	return err
+}
+
+func (t *tracedKeyValues) Remove(ns, key string) {
+	span := t.start("Remove", ns)
+	defer span.End()
+
+	t.kvs.Remove(ns, key)
+}
+
+func (t *tracedKeyValues) Scan(ns, prefix string, fn ScanFunc) error {
+	span := t.start("Scan", ns)
+	defer span.End()
+
+	err := t.kvs.Scan(ns, prefix, fn)
+	span.RecordError(err)
+	return err
+}
+
+func (t *tracedKeyValues) RemoveAll(ns, prefix string) int {
+	span := t.start("RemoveAll", ns)
+	defer span.End()
+
+	n := t.kvs.RemoveAll(ns, prefix)
+	span.SetAttributes(slog.Int("kvs.removed", n))
+	return n
+}
+
+func (t *tracedKeyValues) Count(ns string) int {
+	return t.kvs.Count(ns)
+}
+
+func (t *tracedKeyValues) Stats(ns string, buckets ...time.Duration) (NamespaceStats, error) {
+	return t.kvs.Stats(ns, buckets...)
+}
+
+func (t *tracedKeyValues) Subscribe(ns string, fn KeyValueHandler) func() {
+	return t.kvs.Subscribe(ns, fn)
+}
@@ -0,0 +1,98 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package league
+
+import (
+	"path/filepath"
+	"strings"
+
+	"shiftylogic.dev/hockey-tools/internal/data/standings"
+	"shiftylogic.dev/hockey-tools/internal/services"
+)
+
+const (
+	kDefaultPath = "/league"
+)
+
+type Config struct {
+	Enabled   bool   `json:"enabled" yaml:"Enabled"`
+	Path      string `json:"path" yaml:"Path"`
+	Templates string `json:"templates" yaml:"Templates"`
+
+	// Named points system ("2-1-0" or "3-2-1-0") and tiebreakers, in order.
+	// Requests can override both.
+	Points            string   `json:"points" yaml:"Points"`
+	Tiebreakers       []string `json:"tiebreakers" yaml:"Tiebreakers"`
+	RegulationPeriods int      `json:"regulationPeriods" yaml:"RegulationPeriods"`
+}
+
+func DefaultConfig() Config {
+	rules := standings.DefaultRules()
+
+	tiebreakers := make([]string, len(rules.Tiebreakers))
+	for i, tb := range rules.Tiebreakers {
+		tiebreakers[i] = string(tb)
+	}
+
+	return Config{
+		Enabled:   false,
+		Path:      kDefaultPath,
+		Templates: "",
+
+		Points:            standings.DefaultPointSystem,
+		Tiebreakers:       tiebreakers,
+		RegulationPeriods: rules.RegulationPeriods,
+	}
+}
+
+func (cfg Config) Rules() (standings.Rules, error) {
+	rules, err := standings.NewRules(cfg.Points, cfg.Tiebreakers)
+	rules.RegulationPeriods = cfg.RegulationPeriods
+	return rules, err
+}
+
+func (cfg Config) Validate() error {
+	var p services.Problems
+
+	if !cfg.Enabled {
+		return nil
+	}
+
+	p.Check(strings.HasPrefix(cfg.Path, "/"), "League.Path", "must start with '/'")
+	p.Check(cfg.Templates != "", "League.Templates", "required")
+
+	if cfg.Templates != "" {
+		p.CheckPath("League.Templates", cfg.Templates, true)
+		p.CheckPath("League.Templates", filepath.Join(cfg.Templates, kStandingsTemplate), false)
+	}
+
+	_, err := standings.PointSystem(cfg.Points)
+	p.Check(err == nil, "League.Points", "%v", err)
+
+	_, err = standings.ParseTiebreakers(cfg.Tiebreakers)
+	p.Check(err == nil, "League.Tiebreakers", "%v", err)
+
+	p.Check(cfg.RegulationPeriods > 0, "League.RegulationPeriods", "must be positive")
+
+	return p.Err()
+}
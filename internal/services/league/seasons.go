@@ -0,0 +1,399 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package league
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/data"
+	"shiftylogic.dev/hockey-tools/internal/services"
+	"shiftylogic.dev/hockey-tools/internal/web"
+)
+
+const (
+	kSeasonsRoute         = "/seasons"
+	kSeasonRoute          = "/seasons/{season}"
+	kSeasonTeamsRoute     = "/teams"
+	kSeasonDivisionsRoute = "/divisions"
+	kSeasonGamesRoute     = "/games"
+	kSeasonStatsRoute     = "/stats"
+	kSeasonPlayersRoute   = "/players"
+	kSeasonStaffRoute     = "/staff"
+
+	kSeasonParam = "season"
+)
+
+var errBadRequest = errors.New("bad request")
+
+/**
+ *
+ * JSON views. The data entities are interfaces, so these give them a wire
+ * shape. References to other entities are ids.
+ *
+ **/
+
+type seasonView struct {
+	ID         data.EntityID `json:"id"`
+	ExternalID string        `json:"external_id,omitempty"`
+	League     data.EntityID `json:"league"`
+	Name       string        `json:"name"`
+	Start      *time.Time    `json:"start,omitempty"`
+	End        *time.Time    `json:"end,omitempty"`
+}
+
+func newSeasonView(s data.Season) seasonView {
+	v := seasonView{ID: s.ID(), ExternalID: s.ExternalID(), League: s.League(), Name: s.Name()}
+
+	if start := s.Start(); !start.IsZero() {
+		v.Start = &start
+	}
+
+	if end := s.End(); !end.IsZero() {
+		v.End = &end
+	}
+
+	return v
+}
+
+type memberView struct {
+	Team     data.EntityID `json:"team"`
+	Name     string        `json:"name"`
+	Division data.EntityID `json:"division,omitempty"`
+}
+
+type divisionView struct {
+	ID         data.EntityID `json:"id"`
+	ExternalID string        `json:"external_id,omitempty"`
+	Name       string        `json:"name"`
+}
+
+type playerView struct {
+	ID         data.EntityID `json:"id"`
+	ExternalID string        `json:"external_id,omitempty"`
+	Team       data.EntityID `json:"team"`
+	Name       string        `json:"name"`
+	Number     int           `json:"number"`
+}
+
+type staffView struct {
+	ID         data.EntityID `json:"id"`
+	ExternalID string        `json:"external_id,omitempty"`
+	Team       data.EntityID `json:"team"`
+	Name       string        `json:"name"`
+	Role       string        `json:"role"`
+}
+
+type gameView struct {
+	ID           data.EntityID `json:"id"`
+	ExternalID   string        `json:"external_id,omitempty"`
+	When         time.Time     `json:"when"`
+	Home         data.EntityID `json:"home"`
+	Visitor      data.EntityID `json:"visitor"`
+	HomeScore    int           `json:"home_score"`
+	VisitorScore int           `json:"visitor_score"`
+	Tags         []string      `json:"tags,omitempty"`
+}
+
+func Seasons() func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		store, ok := dataStore(w, r)
+		if !ok {
+			return
+		}
+
+		seasons, err := data.ListAll(store.Seasons().List)
+		if err != nil {
+			writeLookupError(w, r, err)
+			return
+		}
+
+		views := make([]seasonView, len(seasons))
+		for i, s := range seasons {
+			views[i] = newSeasonView(s)
+		}
+
+		writeJSON(w, r, views)
+	}
+}
+
+func Season() func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		store, ok := dataStore(w, r)
+		if !ok {
+			return
+		}
+
+		s, err := data.FindSeason(store.Seasons(), seasonRef(r))
+		if err != nil {
+			writeLookupError(w, r, err)
+			return
+		}
+
+		writeJSON(w, r, newSeasonView(s))
+	}
+}
+
+func SeasonTeams() func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		store, ok := dataStore(w, r)
+		if !ok {
+			return
+		}
+
+		season, ok := findSeason(w, r, store)
+		if !ok {
+			return
+		}
+
+		members, err := store.Seasons().Teams(season)
+		if err != nil {
+			writeLookupError(w, r, err)
+			return
+		}
+
+		views := make([]memberView, len(members))
+		for i, m := range members {
+			views[i] = memberView{Team: m.Team(), Division: m.Division()}
+			if t, err := store.Teams().ByID(m.Team()); err == nil {
+				views[i].Name = t.Name()
+			}
+		}
+
+		writeJSON(w, r, views)
+	}
+}
+
+func SeasonDivisions() func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		store, ok := dataStore(w, r)
+		if !ok {
+			return
+		}
+
+		season, ok := findSeason(w, r, store)
+		if !ok {
+			return
+		}
+
+		divisions, err := store.Divisions().BySeason(season)
+		if err != nil {
+			writeLookupError(w, r, err)
+			return
+		}
+
+		views := make([]divisionView, len(divisions))
+		for i, d := range divisions {
+			views[i] = divisionView{d.ID(), d.ExternalID(), d.Name()}
+		}
+
+		writeJSON(w, r, views)
+	}
+}
+
+func SeasonGames() func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		store, ok := dataStore(w, r)
+		if !ok {
+			return
+		}
+
+		season, ok := findSeason(w, r, store)
+		if !ok {
+			return
+		}
+
+		games, err := store.Games().BySeason(season)
+		if err != nil {
+			writeLookupError(w, r, err)
+			return
+		}
+
+		views := make([]gameView, len(games))
+		for i, g := range games {
+			views[i] = gameView{
+				g.ID(), g.ExternalID(), g.When(), g.Home(), g.Visitor(), g.HomeScore(), g.VisitorScore(), g.Tags(),
+			}
+		}
+
+		writeJSON(w, r, views)
+	}
+}
+
+func SeasonStats() func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		store, ok := dataStore(w, r)
+		if !ok {
+			return
+		}
+
+		season, ok := findSeason(w, r, store)
+		if !ok {
+			return
+		}
+
+		totals, err := store.Stats().Players(data.StatsScope{Season: season})
+		if err != nil {
+			writeLookupError(w, r, err)
+			return
+		}
+
+		writeJSON(w, r, totals)
+	}
+}
+
+// Rosters aren't kept per season, so these are the current players and
+// staff of the teams in the season
+func SeasonPlayers() func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		store, ok := dataStore(w, r)
+		if !ok {
+			return
+		}
+
+		season, ok := findSeason(w, r, store)
+		if !ok {
+			return
+		}
+
+		members, err := store.Seasons().Teams(season)
+		if err != nil {
+			writeLookupError(w, r, err)
+			return
+		}
+
+		views := []playerView{}
+		for _, m := range members {
+			players, err := store.Players().ByTeam(m.Team())
+			if err != nil {
+				writeLookupError(w, r, err)
+				return
+			}
+
+			for _, p := range players {
+				views = append(views, playerView{p.ID(), p.ExternalID(), p.Team(), p.Name(), p.Number()})
+			}
+		}
+
+		writeJSON(w, r, views)
+	}
+}
+
+func SeasonStaff() func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		store, ok := dataStore(w, r)
+		if !ok {
+			return
+		}
+
+		season, ok := findSeason(w, r, store)
+		if !ok {
+			return
+		}
+
+		members, err := store.Seasons().Teams(season)
+		if err != nil {
+			writeLookupError(w, r, err)
+			return
+		}
+
+		views := []staffView{}
+		for _, m := range members {
+			staff, err := store.Staff().ByTeam(m.Team())
+			if err != nil {
+				writeLookupError(w, r, err)
+				return
+			}
+
+			for _, sm := range staff {
+				views = append(views, staffView{sm.ID(), sm.ExternalID(), sm.Team(), sm.Name(), sm.Role()})
+			}
+		}
+
+		writeJSON(w, r, views)
+	}
+}
+
+/**
+ *
+ * Helpers shared by every handler
+ *
+ **/
+
+func dataStore(w http.ResponseWriter, r *http.Request) (data.Store, bool) {
+	store := services.ServicesFromContext(r.Context()).Data()
+	if store == nil {
+		http.Error(w, "no data store configured", http.StatusServiceUnavailable)
+		return nil, false
+	}
+
+	return data.Traced(r.Context(), store), true
+}
+
+// The season from the route, or else from ?season=
+func seasonRef(r *http.Request) string {
+	if ref := web.URLParam(r, kSeasonParam); ref != "" {
+		return ref
+	}
+
+	return r.URL.Query().Get(kSeasonParam)
+}
+
+// The season the request names (external ID or id), or 0 when it doesn't
+// name one. Writes the error response when the lookup fails.
+func findSeason(w http.ResponseWriter, r *http.Request, store data.Store) (data.EntityID, bool) {
+	ref := seasonRef(r)
+	if ref == "" {
+		return 0, true
+	}
+
+	s, err := data.FindSeason(store.Seasons(), ref)
+	if err != nil {
+		writeLookupError(w, r, err)
+		return 0, false
+	}
+
+	return s.ID(), true
+}
+
+func writeLookupError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, errBadRequest):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, data.ErrorUnknownSeasonID), errors.Is(err, data.ErrorUnknownDivisionID):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		slog.ErrorContext(r.Context(), "League data lookup failed", "error", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to write response", "error", err)
+	}
+}
@@ -0,0 +1,118 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package league
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"shiftylogic.dev/hockey-tools/internal/data"
+	"shiftylogic.dev/hockey-tools/internal/test"
+)
+
+func TestSeasons(t *testing.T) {
+	f := newFixture(t)
+
+	seasons := decode[[]seasonView](t, get(f.router, "/league/seasons"))
+	test.Expect(t, 1, len(seasons), "one season")
+	test.Expect(t, "F24", seasons[0].ExternalID, "external id")
+	test.Require(t, seasons[0].Start != nil && seasons[0].End != nil, "dates included")
+
+	for _, ref := range []string{"F24", fmt.Sprint(f.season)} {
+		s := decode[seasonView](t, get(f.router, "/league/seasons/"+ref))
+		test.Expect(t, "Fall 2024", s.Name, "season by "+ref)
+	}
+
+	test.Expect(t, http.StatusNotFound, get(f.router, "/league/seasons/W25").Code, "unknown season")
+	test.Expect(t, http.StatusServiceUnavailable, get(newRouter(t, nil), "/league/seasons").Code, "no data store")
+}
+
+func TestSeasonTeamsAndDivisions(t *testing.T) {
+	f := newFixture(t)
+
+	members := decode[[]memberView](t, get(f.router, "/league/seasons/F24/teams"))
+	test.Expect(t, 3, len(members), "three teams")
+
+	names := map[data.EntityID]string{}
+	divisions := map[data.EntityID]data.EntityID{}
+	for _, m := range members {
+		names[m.Team], divisions[m.Team] = m.Name, m.Division
+	}
+	test.Expect(t, "Tacoma", names[f.tac], "team names filled in")
+	test.Expect(t, f.north, divisions[f.sea], "division membership")
+
+	views := decode[[]divisionView](t, get(f.router, "/league/seasons/F24/divisions"))
+	test.Expect(t, 2, len(views), "two divisions")
+
+	test.Expect(t, http.StatusNotFound, get(f.router, "/league/seasons/W25/teams").Code, "unknown season")
+	test.Expect(t, http.StatusNotFound, get(f.router, "/league/seasons/W25/divisions").Code, "unknown season")
+}
+
+func TestSeasonGamesAndStats(t *testing.T) {
+	f := newFixture(t)
+
+	games := decode[[]gameView](t, get(f.router, "/league/seasons/F24/games"))
+	test.Expect(t, 3, len(games), "three games")
+
+	tagged := 0
+	for _, g := range games {
+		if len(g.Tags) > 0 {
+			tagged++
+			test.Expect(t, []string{"rivalry"}, g.Tags, "tags included")
+		}
+	}
+	test.Expect(t, 1, tagged, "one tagged game")
+
+	totals := decode[[]data.PlayerStats](t, get(f.router, "/league/seasons/F24/stats"))
+	test.Expect(t, 1, len(totals), "one player on a sheet")
+	test.Expect(t, f.scorer, totals[0].Player, "the scorer")
+	test.Expect(t, 1, totals[0].Goals, "the overtime winner")
+	test.Expect(t, 1, totals[0].GameWinningGoals, "game winner")
+
+	test.Expect(t, http.StatusNotFound, get(f.router, "/league/seasons/W25/games").Code, "unknown season")
+	test.Expect(t, http.StatusNotFound, get(f.router, "/league/seasons/W25/stats").Code, "unknown season")
+}
+
+func TestSeasonPlayersAndStaff(t *testing.T) {
+	f := newFixture(t)
+
+	_, err := f.store.Players().Save(data.NewPlayer(0, "P-50", 0, "Free Agent", 50))
+	test.NoError(t, err, "save player")
+	_, err = f.store.Staff().Save(data.NewStaffMember(0, "S-1", f.tac, "Coach Tacoma", "Head Coach"))
+	test.NoError(t, err, "save staff")
+
+	players := decode[[]playerView](t, get(f.router, "/league/seasons/F24/players"))
+	test.Expect(t, 1, len(players), "only players on the season's teams")
+	test.Expect(t, f.scorer, players[0].ID, "the scorer")
+	test.Expect(t, f.sea, players[0].Team, "with Seattle")
+	test.Expect(t, 9, players[0].Number, "number 9")
+
+	staff := decode[[]staffView](t, get(f.router, "/league/seasons/F24/staff"))
+	test.Expect(t, 1, len(staff), "one staff member")
+	test.Expect(t, "Head Coach", staff[0].Role, "role included")
+	test.Expect(t, f.tac, staff[0].Team, "with Tacoma")
+
+	test.Expect(t, http.StatusNotFound, get(f.router, "/league/seasons/W25/players").Code, "unknown season")
+	test.Expect(t, http.StatusNotFound, get(f.router, "/league/seasons/W25/staff").Code, "unknown season")
+}
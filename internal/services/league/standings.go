@@ -0,0 +1,294 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package league
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/data"
+	"shiftylogic.dev/hockey-tools/internal/data/standings"
+	"shiftylogic.dev/hockey-tools/internal/health"
+	"shiftylogic.dev/hockey-tools/internal/helpers"
+	"shiftylogic.dev/hockey-tools/internal/services"
+	"shiftylogic.dev/hockey-tools/internal/web"
+)
+
+const (
+	kStandingsRoute      = "/standings"
+	kStandingsPrintRoute = "/standings/print"
+
+	// UI Templates
+	kStandingsTemplate = "standings.html"
+
+	kDateLayout = "2006-01-02"
+)
+
+var kTemplateFuncs = template.FuncMap{
+	"inc": func(i int) int { return i + 1 },
+}
+
+type standingsViewData struct {
+	Title     string
+	Generated time.Time
+	Rules     standings.Rules
+	Rows      []standings.Row
+}
+
+func WithLeague(config Config) web.RouterOptionFunc {
+	templates := template.Must(template.New("").Funcs(kTemplateFuncs).ParseFS(os.DirFS(config.Templates), "*.html"))
+
+	rules, err := config.Rules()
+	if err != nil {
+		helpers.Fatal("Invalid league rules", "error", err)
+	}
+
+	return func(root web.Router) {
+		health.Default.Register("league-templates", TemplatesCheck(templates))
+
+		r := web.NewRouter()
+
+		r.Get(kStandingsRoute, Standings(rules))
+		r.Get(kStandingsPrintRoute, PrintStandings(templates, rules))
+
+		r.Get(kSeasonsRoute, Seasons())
+		r.Route(kSeasonRoute, func(r web.Router) {
+			r.Get("/", Season())
+			r.Get(kSeasonTeamsRoute, SeasonTeams())
+			r.Get(kSeasonDivisionsRoute, SeasonDivisions())
+			r.Get(kSeasonGamesRoute, SeasonGames())
+			r.Get(kSeasonStatsRoute, SeasonStats())
+			r.Get(kSeasonPlayersRoute, SeasonPlayers())
+			r.Get(kSeasonStaffRoute, SeasonStaff())
+			r.Get(kStandingsRoute, Standings(rules))
+			r.Get(kStandingsPrintRoute, PrintStandings(templates, rules))
+		})
+
+		root.Mount(config.Path, r)
+	}
+}
+
+func TemplatesCheck(templates *template.Template) health.Check {
+	return func(ctx context.Context) error {
+		if templates.Lookup(kStandingsTemplate) == nil {
+			return fmt.Errorf("template '%s' not loaded", kStandingsTemplate)
+		}
+
+		return nil
+	}
+}
+
+func Standings(rules standings.Rules) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rows, _, ok := computeStandings(w, r, rules)
+		if !ok {
+			return
+		}
+
+		writeJSON(w, r, rows)
+	}
+}
+
+func PrintStandings(templates *template.Template, rules standings.Rules) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rows, used, ok := computeStandings(w, r, rules)
+		if !ok {
+			return
+		}
+
+		title := "Standings"
+		if tag := r.URL.Query().Get("tag"); tag != "" {
+			title = "Standings: " + tag
+		}
+
+		if ref := seasonRef(r); ref != "" {
+			if s, err := data.FindSeason(services.ServicesFromContext(r.Context()).Data().Seasons(), ref); err == nil {
+				title = s.Name() + " Standings"
+			}
+		}
+
+		if division := r.URL.Query().Get("division"); division != "" {
+			title += ": " + division
+		}
+
+		view := standingsViewData{title, time.Now(), used, rows}
+		if err := templates.ExecuteTemplate(w, kStandingsTemplate, view); err != nil {
+			slog.ErrorContext(r.Context(), "Failed to execute 'standings' template", "error", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+	}
+}
+
+/**
+ *
+ * Shared by both views. The query can narrow the games (season, from, to,
+ * tag), keep only one division's teams (within a season) and override the
+ * points system and tiebreakers (comma separated). Writes the error
+ * response itself when it fails.
+ *
+ **/
+func computeStandings(w http.ResponseWriter, r *http.Request, rules standings.Rules) ([]standings.Row, standings.Rules, bool) {
+	store, ok := dataStore(w, r)
+	if !ok {
+		return nil, rules, false
+	}
+
+	scope, rules, err := parseQuery(r, rules)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, rules, false
+	}
+
+	if scope.Season, ok = findSeason(w, r, store); !ok {
+		return nil, rules, false
+	}
+
+	division, err := divisionTeams(r, store, scope.Season)
+	if err != nil {
+		writeLookupError(w, r, err)
+		return nil, rules, false
+	}
+
+	games, err := standings.Load(store.Games(), scope)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load games for standings", "error", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return nil, rules, false
+	}
+
+	teams, err := data.ListAll(store.Teams().List)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Failed to load teams for standings", "error", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return nil, rules, false
+	}
+
+	names := map[data.EntityID]string{}
+	for _, t := range teams {
+		names[t.ID()] = t.Name()
+	}
+
+	rows := []standings.Row{}
+	for _, row := range standings.Compute(games, rules) {
+		if division != nil && !division[row.Team] {
+			continue
+		}
+
+		row.Name = names[row.Team]
+
+		rows = append(rows, row)
+	}
+
+	return rows, rules, true
+}
+
+// Teams in the ?division= for the season, or nil when not asked for. The
+// table still counts their games against teams in other divisions.
+func divisionTeams(r *http.Request, store data.Store, season data.EntityID) (map[data.EntityID]bool, error) {
+	ref := r.URL.Query().Get("division")
+	if ref == "" {
+		return nil, nil
+	}
+
+	if season == 0 {
+		return nil, fmt.Errorf("%w: division standings need a season", errBadRequest)
+	}
+
+	d, err := store.Divisions().ByExternalID(ref)
+	if errors.Is(err, data.ErrorUnknownDivisionID) {
+		if id, perr := strconv.ParseInt(ref, 10, 64); perr == nil {
+			d, err = store.Divisions().ByID(data.EntityID(id))
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := store.Seasons().ByID(season)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.League() != s.League() {
+		return nil, fmt.Errorf("%w: division '%s' is in a different league than the season", errBadRequest, ref)
+	}
+
+	members, err := store.Seasons().Teams(season)
+	if err != nil {
+		return nil, err
+	}
+
+	teams := map[data.EntityID]bool{}
+	for _, m := range members {
+		if m.Division() == d.ID() {
+			teams[m.Team()] = true
+		}
+	}
+
+	return teams, nil
+}
+
+func parseQuery(r *http.Request, rules standings.Rules) (data.StatsScope, standings.Rules, error) {
+	q := r.URL.Query()
+	scope := data.StatsScope{Tag: q.Get("tag")}
+
+	for _, bound := range []struct {
+		name string
+		into *time.Time
+	}{{"from", &scope.From}, {"to", &scope.To}} {
+		if v := q.Get(bound.name); v != "" {
+			t, err := time.ParseInLocation(kDateLayout, v, time.Local)
+			if err != nil {
+				return scope, rules, fmt.Errorf("invalid '%s' date (want YYYY-MM-DD)", bound.name)
+			}
+			*bound.into = t
+		}
+	}
+
+	if v := q.Get("points"); v != "" {
+		p, err := standings.PointSystem(v)
+		if err != nil {
+			return scope, rules, err
+		}
+		rules.Points = p
+	}
+
+	if v := q.Get("tiebreakers"); v != "" {
+		tb, err := standings.ParseTiebreakers(strings.Split(v, ","))
+		if err != nil {
+			return scope, rules, err
+		}
+		rules.Tiebreakers = tb
+	}
+
+	return scope, rules, nil
+}
@@ -0,0 +1,255 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package league
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/data"
+	"shiftylogic.dev/hockey-tools/internal/data/local"
+	"shiftylogic.dev/hockey-tools/internal/data/standings"
+	"shiftylogic.dev/hockey-tools/internal/services"
+	"shiftylogic.dev/hockey-tools/internal/test"
+	"shiftylogic.dev/hockey-tools/internal/web"
+)
+
+type fixture struct {
+	store  data.Store
+	router web.Router
+
+	season, north data.EntityID
+	sea, tac, oly data.EntityID
+	scorer        data.EntityID
+}
+
+/**
+ *
+ * One season (F24) with Seattle and Tacoma in the North (N) and Olympia in
+ * the South (S). Tacoma beats Seattle in regulation, Seattle beats Olympia
+ * in overtime (on a goal by Seattle's number 9) and Olympia beats Tacoma in
+ * a game tagged "rivalry". Seattle
+ * and Tacoma finish level on points under 2-1-0; Tacoma has the regulation
+ * win and Seattle the better goal differential.
+ *
+ **/
+func newFixture(t *testing.T) fixture {
+	store, err := local.Open(filepath.Join(t.TempDir(), "hockey.db"))
+	test.NoError(t, err, "open")
+	t.Cleanup(store.Close)
+
+	var f fixture
+	f.store = store
+
+	league, err := store.Leagues().Save(data.NewLeague(0, "L-1", "Puget Sound"))
+	test.NoError(t, err, "save league")
+
+	start := time.Date(2024, 9, 1, 0, 0, 0, 0, time.Local)
+	f.season, err = store.Seasons().Save(data.NewSeason(0, "F24", league, "Fall 2024", start, start.AddDate(0, 4, 0)))
+	test.NoError(t, err, "save season")
+
+	f.north, err = store.Divisions().Save(data.NewDivision(0, "N", league, "North"))
+	test.NoError(t, err, "save division")
+	south, err := store.Divisions().Save(data.NewDivision(0, "S", league, "South"))
+	test.NoError(t, err, "save division")
+
+	for _, team := range []struct {
+		into     *data.EntityID
+		ext      string
+		name     string
+		division data.EntityID
+	}{
+		{&f.sea, "SEA", "Seattle", f.north},
+		{&f.tac, "TAC", "Tacoma", f.north},
+		{&f.oly, "OLY", "Olympia", south},
+	} {
+		*team.into, err = store.Teams().Save(data.NewTeam(0, team.ext, team.name))
+		test.NoError(t, err, "save team")
+		test.NoError(t, store.Seasons().SetTeam(data.NewMembership(f.season, *team.into, team.division)), "join season")
+	}
+
+	f.scorer, err = store.Players().Save(data.NewPlayer(0, "P-9", f.sea, "Sniper", 9))
+	test.NoError(t, err, "save player")
+
+	regulation := []int{900, 900, 900}
+	games := []data.GameDetails{
+		data.NewGameDetails(
+			data.NewGameOverview(0, "G-1", f.season, nil, start.AddDate(0, 1, 0), 0, f.tac, f.sea, 2, 1),
+			regulation, nil, nil),
+		data.NewGameDetails(
+			data.NewGameOverview(0, "G-2", f.season, nil, start.AddDate(0, 1, 7), 0, f.sea, f.oly, 2, 1),
+			regulation, []data.ScoringEvent{
+				data.NewScoringEvent(100, f.oly, 0, 0, 0, nil, nil),
+				data.NewScoringEvent(200, f.sea, 0, 0, 0, nil, nil),
+				data.NewScoringEvent(2750, f.sea, f.scorer, 0, 0, nil, nil),
+			}, nil),
+		data.NewGameDetails(
+			data.NewGameOverview(0, "G-3", f.season, []string{"rivalry"}, start.AddDate(0, 1, 14), 0, f.oly, f.tac, 5, 0),
+			regulation, nil, nil),
+	}
+
+	for _, g := range games {
+		_, err := store.Games().Save(g)
+		test.NoError(t, err, "save game")
+	}
+
+	f.router = newRouter(t, store)
+	return f
+}
+
+func newRouter(t *testing.T, store data.Store) web.Router {
+	config := DefaultConfig()
+	config.Enabled = true
+	config.Templates = filepath.Join("..", "..", "..", "views", "league")
+	test.NoError(t, config.Validate(), "league config")
+
+	return web.NewRouter(
+		services.WithServices(&services.ServicesContainer{DataStore: store}),
+		WithLeague(config))
+}
+
+func get(router web.Router, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	return w
+}
+
+func decode[T any](t *testing.T, w *httptest.ResponseRecorder) T {
+	var v T
+	test.Expect(t, http.StatusOK, w.Code, "status for "+w.Body.String())
+	test.NoError(t, json.NewDecoder(w.Body).Decode(&v), "decode response")
+	return v
+}
+
+func rowTeams(rows []standings.Row) []data.EntityID {
+	ids := make([]data.EntityID, len(rows))
+	for i, r := range rows {
+		ids[i] = r.Team
+	}
+	return ids
+}
+
+func TestStandings(t *testing.T) {
+	f := newFixture(t)
+
+	rows := decode[[]standings.Row](t, get(f.router, "/league/standings"))
+	test.Expect(t, []data.EntityID{f.oly, f.tac, f.sea}, rowTeams(rows), "regulation wins break the tie")
+	test.Expect(t, "Olympia", rows[0].Name, "team names filled in")
+	test.Expect(t, 3, rows[0].Points, "overtime loss and a win")
+	test.Expect(t, standings.Record{Wins: 1, Losses: 1}, rows[2].Record, "overtime win")
+
+	rows = decode[[]standings.Row](t, get(f.router, "/league/standings?tiebreakers=goal-differential"))
+	test.Expect(t, []data.EntityID{f.oly, f.sea, f.tac}, rowTeams(rows), "tiebreakers override")
+
+	rows = decode[[]standings.Row](t, get(f.router, "/league/standings?points=3-2-1-0"))
+	test.Expect(t, []data.EntityID{f.oly, f.tac, f.sea}, rowTeams(rows), "points override")
+	test.Expect(t, 4, rows[0].Points, "1 + 3")
+	test.Expect(t, 3, rows[1].Points, "3 + 0")
+
+	rows = decode[[]standings.Row](t, get(f.router, "/league/standings?tag=rivalry"))
+	test.Expect(t, []data.EntityID{f.oly, f.tac}, rowTeams(rows), "tagged games only")
+
+	rows = decode[[]standings.Row](t, get(f.router, "/league/standings?to=2024-10-05"))
+	test.Expect(t, []data.EntityID{f.tac, f.sea}, rowTeams(rows), "games up to a date")
+}
+
+func TestStandingsDivision(t *testing.T) {
+	f := newFixture(t)
+
+	for _, target := range []string{
+		"/league/seasons/F24/standings?division=N",
+		"/league/standings?season=F24&division=N",
+		fmt.Sprintf("/league/seasons/F24/standings?division=%d", f.north),
+	} {
+		rows := decode[[]standings.Row](t, get(f.router, target))
+		test.Expect(t, []data.EntityID{f.tac, f.sea}, rowTeams(rows), "north only: "+target)
+		test.Expect(t, 2, rows[1].GamesPlayed, "games against the south still count: "+target)
+	}
+}
+
+func TestStandingsErrors(t *testing.T) {
+	f := newFixture(t)
+
+	other, err := f.store.Leagues().Save(data.NewLeague(0, "L-2", "Inland Empire"))
+	test.NoError(t, err, "save league")
+	_, err = f.store.Divisions().Save(data.NewDivision(0, "X", other, "East"))
+	test.NoError(t, err, "save division")
+
+	for _, tc := range []struct {
+		target string
+		status int
+	}{
+		{"/league/standings?division=N", http.StatusBadRequest},
+		{"/league/standings?from=10/01/2024", http.StatusBadRequest},
+		{"/league/standings?points=4-3-2-1", http.StatusBadRequest},
+		{"/league/standings?tiebreakers=coin-flip", http.StatusBadRequest},
+		{"/league/standings/print?points=4-3-2-1", http.StatusBadRequest},
+		{"/league/standings?season=W25", http.StatusNotFound},
+		{"/league/seasons/W25/standings", http.StatusNotFound},
+		{"/league/seasons/F24/standings?division=E", http.StatusNotFound},
+		{"/league/seasons/F24/standings?division=X", http.StatusBadRequest},
+	} {
+		test.Expect(t, tc.status, get(f.router, tc.target).Code, tc.target)
+	}
+
+	router := newRouter(t, nil)
+	test.Expect(t, http.StatusServiceUnavailable, get(router, "/league/standings").Code, "no data store")
+	test.Expect(t, http.StatusServiceUnavailable, get(router, "/league/standings/print").Code, "no data store")
+}
+
+func TestPrintStandings(t *testing.T) {
+	f := newFixture(t)
+
+	w := get(f.router, "/league/seasons/F24/standings/print?division=N&points=3-2-1-0")
+	test.Expect(t, http.StatusOK, w.Code, "status")
+
+	body := w.Body.String()
+	test.Require(t, strings.Contains(body, "<title>Fall 2024 Standings: N</title>"), "season and division in the title")
+	test.Require(t, strings.Contains(body, "3 for a win (2 in overtime)"), "overridden points system")
+	test.Require(t, strings.Index(body, "Tacoma") < strings.Index(body, "Seattle"), "rows in standings order")
+	test.Require(t, !strings.Contains(body, "Olympia"), "other divisions left out")
+}
+
+func TestParseQuery(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/?from=2024-10-01&to=2024-11-01&tag=playoff&points=3-2-1-0&tiebreakers=head-to-head,+goal-differential", nil)
+
+	scope, rules, err := parseQuery(r, standings.DefaultRules())
+	test.NoError(t, err, "parse")
+	test.Expect(t, "playoff", scope.Tag, "tag")
+	test.Require(t, scope.From.Equal(time.Date(2024, 10, 1, 0, 0, 0, 0, time.Local)), "from")
+	test.Require(t, scope.To.Equal(time.Date(2024, 11, 1, 0, 0, 0, 0, time.Local)), "to")
+	test.Expect(t, 3, rules.Points.RegulationWin, "points system")
+	test.Expect(t, []standings.Tiebreaker{standings.HeadToHead, standings.GoalDifferential}, rules.Tiebreakers, "tiebreakers")
+	test.Expect(t, standings.DefaultRegulationPeriods, rules.RegulationPeriods, "regulation periods kept")
+
+	defaults := standings.DefaultRules()
+	_, rules, err = parseQuery(httptest.NewRequest(http.MethodGet, "/", nil), defaults)
+	test.NoError(t, err, "parse empty query")
+	test.Expect(t, defaults, rules, "defaults kept")
+}
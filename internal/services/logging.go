@@ -0,0 +1,112 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package services
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"shiftylogic.dev/hockey-tools/internal/web"
+)
+
+const (
+	kLogFormatJSON = "json"
+	kLogFormatText = "text"
+)
+
+type LogConfig struct {
+	Level  string `json:"level" yaml:"Level"`
+	Format string `json:"format" yaml:"Format"`
+}
+
+var (
+	logLevel = new(slog.LevelVar)
+)
+
+func DefaultLog() LogConfig {
+	return LogConfig{
+		Level:  "info",
+		Format: kLogFormatText,
+	}
+}
+
+/**
+ *
+ * Installs the default slog logger (which the standard 'log' package also
+ * writes through) per the config. Records logged with a request context
+ * carry the request ID.
+ *
+ **/
+func ConfigureLogging(cfg LogConfig) error {
+	return configureLogging(cfg, os.Stderr)
+}
+
+func configureLogging(cfg LogConfig, out io.Writer) error {
+	level, err := cfg.level()
+	if err != nil {
+		return err
+	}
+
+	options := &slog.HandlerOptions{Level: logLevel}
+
+	var handler slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case kLogFormatJSON:
+		handler = slog.NewJSONHandler(out, options)
+	case kLogFormatText, "":
+		handler = slog.NewTextHandler(out, options)
+	default:
+		return fmt.Errorf("unknown log format '%s'", cfg.Format)
+	}
+
+	logLevel.Set(level)
+	slog.SetDefault(slog.New(web.NewContextHandler(handler)))
+	return nil
+}
+
+// Changes the level of the installed logger; the format is fixed at start
+func SetLogLevel(cfg LogConfig) error {
+	level, err := cfg.level()
+	if err != nil {
+		return err
+	}
+
+	logLevel.Set(level)
+	return nil
+}
+
+func (cfg LogConfig) level() (slog.Level, error) {
+	var level slog.Level
+	if cfg.Level == "" {
+		return slog.LevelInfo, nil
+	}
+
+	if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+		return level, fmt.Errorf("unknown log level '%s'", cfg.Level)
+	}
+
+	return level, nil
+}
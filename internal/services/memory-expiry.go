@@ -0,0 +1,179 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package services
+
+import (
+	"container/heap"
+	"context"
+	"time"
+)
+
+/**
+ *
+ * Each namespace keeps a min-heap of item deadlines with exactly one entry
+ * per key, updated in place as items are written and removed alongside
+ * them. A single timer sleeps until the earliest deadline across all
+ * namespaces and then sweeps whatever has come due.
+ *
+ **/
+
+type expiryEntry struct {
+	key   string
+	item  *memoryItem
+	index int
+}
+
+type expiryHeap []*expiryEntry
+
+func (h expiryHeap) Len() int           { return len(h) }
+func (h expiryHeap) Less(i, j int) bool { return h[i].item.purge.Before(h[j].item.purge) }
+func (h expiryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expiryHeap) Push(x any) {
+	entry := x.(*expiryEntry)
+	entry.index = len(*h)
+	*h = append(*h, entry)
+}
+
+func (h *expiryHeap) Pop() any {
+	old := *h
+	n := len(old)
+	entry := old[n-1]
+	old[n-1] = nil
+	entry.index = -1
+	*h = old[:n-1]
+	return entry
+}
+
+/**
+ *
+ * Deadline tracking for a namespace. Callers hold the scope's lock.
+ *
+ **/
+
+// Records (or moves) the deadline for key and reports whether it is now the
+// earliest in the namespace.
+func (scope *memScope) track(key string, item *memoryItem) bool {
+	if scope.index == nil {
+		scope.index = make(map[string]*expiryEntry)
+	}
+
+	if entry, ok := scope.index[key]; ok {
+		entry.item = item
+		heap.Fix(&scope.deadlines, entry.index)
+		return entry.index == 0
+	}
+
+	entry := &expiryEntry{key: key, item: item}
+	heap.Push(&scope.deadlines, entry)
+	scope.index[key] = entry
+	return entry.index == 0
+}
+
+func (scope *memScope) untrack(key string) {
+	if entry, ok := scope.index[key]; ok {
+		heap.Remove(&scope.deadlines, entry.index)
+		delete(scope.index, key)
+	}
+}
+
+func (scope *memScope) next() (time.Time, bool) {
+	scope.mu.Lock()
+	defer scope.mu.Unlock()
+
+	if len(scope.deadlines) == 0 {
+		return time.Time{}, false
+	}
+
+	return scope.deadlines[0].item.purge, true
+}
+
+// Removes and returns every item whose deadline is at or before now
+func (scope *memScope) expired(now time.Time) []expiryEntry {
+	scope.mu.Lock()
+	defer scope.mu.Unlock()
+
+	var entries []expiryEntry
+	for len(scope.deadlines) > 0 && !scope.deadlines[0].item.purge.After(now) {
+		entry := heap.Pop(&scope.deadlines).(*expiryEntry)
+		delete(scope.index, entry.key)
+		scope.items.Delete(entry.key)
+		scope.count.Add(-1)
+		entries = append(entries, *entry)
+	}
+
+	return entries
+}
+
+/**
+ *
+ * The sweep timer
+ *
+ **/
+
+type expiryTimer struct {
+	wake chan struct{}
+}
+
+func newExpiryTimer() expiryTimer {
+	return expiryTimer{
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// Re-arms the timer after a write produced a new earliest deadline
+func (t expiryTimer) signal() {
+	select {
+	case t.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (t expiryTimer) run(ctx context.Context, sweep func(now time.Time) (time.Time, bool)) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.wake:
+		case <-timer.C:
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+
+		if deadline, ok := sweep(time.Now()); ok {
+			timer.Reset(time.Until(deadline))
+		}
+	}
+}
@@ -0,0 +1,212 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package services
+
+import (
+	"container/heap"
+	"slices"
+	"strings"
+	"time"
+)
+
+/**
+ *
+ * Caps the number of items held in each namespace. When a write pushes a
+ * namespace over the cap, the items closest to expiring (including any
+ * already expired) are evicted. A value <= 0 disables the cap.
+ *
+ **/
+func WithMaxEntries(max int) MemoryStoreOptionFunc {
+	return func(s *memStore) {
+		s.maxEntries = max
+	}
+}
+
+/**
+ *
+ * Namespace-level operations
+ *
+ **/
+
+func (store *memStore) Scan(ns, prefix string, fn ScanFunc) error {
+	scoped, ok := store.scopes.Load(ns)
+	if !ok {
+		return kErrorInvalidNamespace
+	}
+
+	now := time.Now()
+	scoped.(*memScope).items.Range(func(key, value any) bool {
+		item := value.(*memoryItem)
+		if !strings.HasPrefix(key.(string), prefix) || item.purge.Before(now) {
+			return true
+		}
+
+		return fn(key.(string), item.value, item.purge)
+	})
+
+	return nil
+}
+
+func (store *memStore) RemoveAll(ns, prefix string) int {
+	scoped, ok := store.scopes.Load(ns)
+	if !ok {
+		return 0
+	}
+
+	removed := 0
+	scope := scoped.(*memScope)
+	scope.items.Range(func(key, _ any) bool {
+		if !strings.HasPrefix(key.(string), prefix) {
+			return true
+		}
+
+		if item, ok := scope.delete(key.(string)); ok {
+			store.publish(KeyValueRemoved, ns, key.(string), item.value)
+			removed++
+		}
+		return true
+	})
+
+	return removed
+}
+
+// Counts live items only, matching Stats().Items; expired items awaiting
+// collection are excluded.
+func (store *memStore) Count(ns string) int {
+	scoped, ok := store.scopes.Load(ns)
+	if !ok {
+		return 0
+	}
+
+	return scoped.(*memScope).live(time.Now())
+}
+
+func (store *memStore) Stats(ns string, buckets ...time.Duration) (NamespaceStats, error) {
+	scoped, ok := store.scopes.Load(ns)
+	if !ok {
+		return NamespaceStats{}, kErrorInvalidNamespace
+	}
+
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+
+	stats := NamespaceStats{
+		Buckets: buckets,
+		Expiry:  make([]int, len(buckets)+1),
+	}
+
+	now := time.Now()
+	scoped.(*memScope).items.Range(func(key, value any) bool {
+		left := value.(*memoryItem).purge.Sub(now)
+		if left < 0 {
+			stats.Expired++
+			return true
+		}
+
+		stats.Items++
+		i, _ := slices.BinarySearch(buckets, left)
+		stats.Expiry[i]++
+		return true
+	})
+
+	return stats, nil
+}
+
+func (store *memStore) enforceCap(ns string, scope *memScope, keep string) {
+	if store.maxEntries <= 0 {
+		return
+	}
+
+	now := time.Now()
+	for _, e := range scope.trim(store.maxEntries, keep) {
+		if e.item.purge.Before(now) {
+			store.publish(KeyValueExpired, ns, e.key, e.item.value)
+		} else {
+			store.publish(KeyValueEvicted, ns, e.key, e.item.value)
+		}
+	}
+}
+
+/**
+ *
+ * Namespace queries on the deadline heap
+ *
+ **/
+
+// Removes the items closest to expiring (never keep) until the namespace
+// holds at most max items. The candidate is the heap's root, or one of its
+// children when the root is keep.
+func (scope *memScope) trim(max int, keep string) []expiryEntry {
+	scope.mu.Lock()
+	defer scope.mu.Unlock()
+
+	var evicted []expiryEntry
+	for len(scope.deadlines) > max {
+		candidates := []int{0}
+		if scope.deadlines[0].key == keep {
+			candidates = []int{1, 2}
+		}
+
+		victim := -1
+		for _, i := range candidates {
+			if i < len(scope.deadlines) && (victim < 0 || scope.deadlines.Less(i, victim)) {
+				victim = i
+			}
+		}
+
+		if victim < 0 {
+			break
+		}
+
+		entry := heap.Remove(&scope.deadlines, victim).(*expiryEntry)
+		delete(scope.index, entry.key)
+		scope.items.Delete(entry.key)
+		scope.count.Add(-1)
+		evicted = append(evicted, *entry)
+	}
+
+	return evicted
+}
+
+// Counts the items that have not expired. Expired items sit at the top of
+// the heap, so only they (and their immediate children) are visited.
+func (scope *memScope) live(now time.Time) int {
+	scope.mu.Lock()
+	defer scope.mu.Unlock()
+
+	expired := 0
+	pending := []int{0}
+	for len(pending) > 0 {
+		i := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		if i >= len(scope.deadlines) || !scope.deadlines[i].item.purge.Before(now) {
+			continue
+		}
+
+		expired++
+		pending = append(pending, 2*i+1, 2*i+2)
+	}
+
+	return len(scope.deadlines) - expired
+}
@@ -0,0 +1,118 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/test"
+)
+
+func TestMemoryNamespaceOps(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := NewMemoryStore(ctx)
+	for i := 0; i < 10; i++ {
+		test.NoError(t, store.Set("sessions", fmt.Sprintf("u1:%d", i), i, time.Minute), "set failed")
+		test.NoError(t, store.Set("sessions", fmt.Sprintf("u2:%d", i), i, time.Hour), "set failed")
+	}
+
+	test.Expect(t, 20, store.Count("sessions"), "count before removal")
+
+	seen := 0
+	test.NoError(t, store.Scan("sessions", "u1:", func(key string, value any, expires time.Time) bool {
+		seen++
+		return true
+	}), "scan failed")
+	test.Expect(t, 10, seen, "scan with prefix")
+
+	stats, err := store.Stats("sessions", 5*time.Minute)
+	test.NoError(t, err, "stats failed")
+	test.Expect(t, []int{10, 10}, stats.Expiry, "expiry histogram")
+
+	test.Expect(t, 10, store.RemoveAll("sessions", "u1:"), "removed count")
+	test.Expect(t, 10, store.Count("sessions"), "count after removal")
+	test.Expect(t, 0, store.Count("unknown"), "count of unknown namespace")
+
+	err = store.Scan("unknown", "", func(string, any, time.Time) bool { return true })
+	test.SpecificError(t, err, kErrorInvalidNamespace, "scan of unknown namespace")
+}
+
+func TestMemoryMaxEntries(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := NewMemoryStore(ctx, WithMaxEntries(3))
+
+	evicted := []string{}
+	defer store.Subscribe("qrc", func(e KeyValueEvent) {
+		if e.Kind == KeyValueEvicted {
+			evicted = append(evicted, e.Key)
+		}
+	})()
+
+	test.NoError(t, store.Set("qrc", "a", 1, 1*time.Minute), "set failed")
+	test.NoError(t, store.Set("qrc", "b", 2, 3*time.Minute), "set failed")
+	test.NoError(t, store.Set("qrc", "c", 3, 2*time.Minute), "set failed")
+	test.NoError(t, store.Set("qrc", "d", 4, 4*time.Minute), "set failed")
+	test.NoError(t, store.CheckAndSet("qrc", "e", 5, 5*time.Minute), "check-and-set failed")
+
+	test.Expect(t, 3, store.Count("qrc"), "count after eviction")
+	test.Expect(t, []string{"a", "c"}, evicted, "evicted the items closest to expiring")
+
+	_, err := store.Read("qrc", "e")
+	test.NoError(t, err, "newest item should survive")
+
+	// A new item that is itself closest to expiring is kept
+	test.NoError(t, store.Set("qrc", "f", 6, time.Second), "set failed")
+	test.Expect(t, []string{"a", "c", "b"}, evicted, "evicted the next closest item")
+
+	_, err = store.Read("qrc", "f")
+	test.NoError(t, err, "newest item should survive")
+}
+
+func TestMemoryCountMatchesStats(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := NewMemoryStore(ctx)
+	for i := 0; i < 5; i++ {
+		test.NoError(t, store.Set("qrc", fmt.Sprintf("live:%d", i), i, time.Hour), "set failed")
+	}
+
+	// Expired but not yet collected: a spare timer keeps the sweep asleep
+	scope := store.(*memStore).scope("qrc")
+	for i := 0; i < 3; i++ {
+		scope.put(fmt.Sprintf("stale:%d", i), &memoryItem{purge: time.Now().Add(-time.Minute), value: i}, newExpiryTimer())
+	}
+
+	stats, err := store.Stats("qrc")
+	test.NoError(t, err, "stats failed")
+	test.Expect(t, 3, stats.Expired, "expired items")
+	test.Expect(t, stats.Items, store.Count("qrc"), "count agrees with stats")
+	test.Expect(t, 5, store.Count("qrc"), "count excludes expired items")
+}
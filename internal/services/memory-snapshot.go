@@ -0,0 +1,224 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package services
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+/**
+ *
+ * Snapshot / restore support for the in-memory store. Snapshots are gob
+ * encoded, so any concrete type stored as a value (other than the basic
+ * built-in types) must be registered with RegisterSnapshotType. Items that
+ * can't be encoded are logged and left out of the snapshot.
+ *
+ **/
+
+const (
+	kSnapshotVersion = 1
+)
+
+var (
+	kErrorSnapshotVersion = errors.New("unsupported snapshot version")
+)
+
+type snapshotItem struct {
+	Namespace string
+	Key       string
+	Purge     time.Time
+	Value     any
+}
+
+type snapshot struct {
+	Version int
+	Written time.Time
+	Items   []snapshotItem
+}
+
+func init() {
+	RegisterSnapshotType(AuthCodeData{})
+}
+
+func RegisterSnapshotType(value any) {
+	gob.Register(value)
+}
+
+/**
+ *
+ * Restores the store from 'file' at creation (skipping anything that has
+ * expired) and writes a new snapshot when the store's context is done. If
+ * period is non-zero, snapshots are also written on that interval.
+ *
+ **/
+func WithSnapshot(file string, period time.Duration) MemoryStoreOptionFunc {
+	return func(s *memStore) {
+		s.snapshotFile = file
+		s.snapshotPeriod = period
+	}
+}
+
+/**
+ *
+ * Adds the shutdown snapshot to wg. Once the store's context is done,
+ * wg.Wait returns after the final snapshot has been written (or failed).
+ *
+ **/
+func WithSnapshotGroup(wg *sync.WaitGroup) MemoryStoreOptionFunc {
+	return func(s *memStore) {
+		s.snapshotGroup = wg
+	}
+}
+
+func (store *memStore) runSnapshots(ctx context.Context) {
+	if store.snapshotGroup != nil {
+		defer store.snapshotGroup.Done()
+	}
+
+	var tick <-chan time.Time
+	if store.snapshotPeriod > 0 {
+		ticker := time.NewTicker(store.snapshotPeriod)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := store.writeSnapshot(); err != nil {
+				slog.Error("Failed to write memory store snapshot on shutdown", "file", store.snapshotFile, "error", err)
+			}
+			return
+		case <-tick:
+			if err := store.writeSnapshot(); err != nil {
+				slog.Error("Failed to write memory store snapshot", "file", store.snapshotFile, "error", err)
+			}
+		}
+	}
+}
+
+func (store *memStore) writeSnapshot() error {
+	now := time.Now()
+	snap := snapshot{
+		Version: kSnapshotVersion,
+		Written: now,
+	}
+
+	// Each item is trial encoded so that a value whose type was never
+	// registered costs only that item rather than the whole snapshot.
+	probe := gob.NewEncoder(io.Discard)
+
+	store.scopes.Range(func(ns, value any) bool {
+		value.(*memScope).items.Range(func(key, value any) bool {
+			item := value.(*memoryItem)
+			if !item.purge.After(now) {
+				return true
+			}
+
+			si := snapshotItem{ns.(string), key.(string), item.purge, item.value}
+			if err := probe.Encode(&si); err != nil {
+				slog.Warn("Skipping memory store item in snapshot",
+					"namespace", si.Namespace, "key", si.Key, "type", fmt.Sprintf("%T", si.Value), "error", err)
+				return true
+			}
+
+			snap.Items = append(snap.Items, si)
+			return true
+		})
+		return true
+	})
+
+	// Write to a temporary file alongside the target and rename it into
+	// place so a crash mid-write never leaves a truncated snapshot behind.
+	tmp, err := os.CreateTemp(filepath.Dir(store.snapshotFile), filepath.Base(store.snapshotFile)+".*")
+	if err != nil {
+		return fmt.Errorf("[writeSnapshot] failed to create temporary file - %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := gob.NewEncoder(tmp).Encode(&snap); err != nil {
+		tmp.Close()
+		return fmt.Errorf("[writeSnapshot] failed to encode snapshot - %w", err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("[writeSnapshot] failed to sync snapshot - %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("[writeSnapshot] failed to close snapshot - %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), store.snapshotFile); err != nil {
+		return fmt.Errorf("[writeSnapshot] failed to move snapshot into place - %w", err)
+	}
+
+	return nil
+}
+
+func (store *memStore) readSnapshot() error {
+	inFile, err := os.Open(store.snapshotFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("[readSnapshot] failed to open snapshot - %w", err)
+	}
+	defer inFile.Close()
+
+	var snap snapshot
+	if err := gob.NewDecoder(inFile).Decode(&snap); err != nil {
+		return fmt.Errorf("[readSnapshot] failed to decode snapshot - %w", err)
+	}
+
+	if snap.Version != kSnapshotVersion {
+		return kErrorSnapshotVersion
+	}
+
+	now := time.Now()
+	for _, si := range snap.Items {
+		if !si.Purge.After(now) {
+			continue
+		}
+
+		item := &memoryItem{
+			purge: si.Purge,
+			value: si.Value,
+			gen:   store.gen.Add(1),
+		}
+
+		store.scope(si.Namespace).put(si.Key, item, store.expiry)
+	}
+
+	return nil
+}
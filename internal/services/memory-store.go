@@ -25,7 +25,9 @@ package services
 import (
 	"context"
 	"errors"
+	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -35,10 +37,6 @@ import (
  *
  **/
 
-const (
-	kCollectionPeriod = 5 * time.Minute
-)
-
 var (
 	kErrorInvalidNamespace  = errors.New("invalid namespace")
 	kErrorInvalidKey        = errors.New("invalid key")
@@ -47,47 +45,86 @@ var (
 	kErrorItemChanged       = errors.New("item changed during refresh")
 )
 
+type MemoryStoreOptionFunc func(*memStore)
+
+// Items are immutable once stored; writes replace the pointer. The generation
+// identifies a particular write so Refresh never has to compare values,
+// which may not be comparable.
 type memoryItem struct {
 	purge time.Time
 	value any
+	gen   uint64
+}
+
+// Reads go straight to the sync.Map. Writes take the lock so the item map,
+// the count and the deadline heap always change together.
+type memScope struct {
+	items sync.Map
+	count atomic.Int64
+
+	mu        sync.Mutex
+	deadlines expiryHeap
+	index     map[string]*expiryEntry
 }
 
 type memStore struct {
-	scopes sync.Map
-}
-
-func NewMemoryStore(ctx context.Context) KeyValueStore {
-	store := &memStore{}
-	ticker := time.NewTicker(kCollectionPeriod)
-
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				ticker.Stop()
-				return
-			case <-ticker.C:
-				store.collect()
-			}
+	scopes     sync.Map
+	maxEntries int
+
+	snapshotFile   string
+	snapshotPeriod time.Duration
+	snapshotGroup  *sync.WaitGroup
+
+	expiry expiryTimer
+	gen    atomic.Uint64
+
+	subsMu sync.RWMutex
+	subs   map[string]map[uint64]KeyValueHandler
+	subID  uint64
+}
+
+func NewMemoryStore(ctx context.Context, options ...MemoryStoreOptionFunc) KeyValueStore {
+	store := &memStore{
+		expiry: newExpiryTimer(),
+		subs:   make(map[string]map[uint64]KeyValueHandler),
+	}
+
+	for _, fn := range options {
+		fn(store)
+	}
+
+	if store.snapshotFile != "" {
+		if err := store.readSnapshot(); err != nil {
+			slog.Error("Failed to restore memory store snapshot", "file", store.snapshotFile, "error", err)
 		}
-	}()
+
+		if store.snapshotGroup != nil {
+			store.snapshotGroup.Add(1)
+		}
+
+		go store.runSnapshots(ctx)
+	}
+
+	go store.expiry.run(ctx, store.sweep)
 
 	return store
 }
 
-func (s *memStore) collect() {
-	now := time.Now()
+func (store *memStore) scope(ns string) *memScope {
+	scoped, ok := store.scopes.Load(ns)
+	if !ok {
+		scoped, _ = store.scopes.LoadOrStore(ns, new(memScope))
+	}
 
-	s.scopes.Range(func(ns, value any) bool {
-		scoped := value.(*sync.Map)
-		scoped.Range(func(key, value any) bool {
-			if value.(memoryItem).purge.Before(now) {
-				_ = scoped.CompareAndDelete(key, value)
-			}
-			return true
-		})
-		return true
-	})
+	return scoped.(*memScope)
+}
+
+func (store *memStore) newItem(value any, ttl time.Duration) *memoryItem {
+	return &memoryItem{
+		purge: time.Now().Add(ttl),
+		value: value,
+		gen:   store.gen.Add(1),
+	}
 }
 
 func (store *memStore) Read(ns, key string) (any, error) {
@@ -96,16 +133,16 @@ func (store *memStore) Read(ns, key string) (any, error) {
 		return nil, kErrorInvalidNamespace
 	}
 
-	item, ok := scoped.(*sync.Map).Load(key)
+	item, ok := scoped.(*memScope).items.Load(key)
 	if !ok {
 		return nil, kErrorInvalidKey
 	}
 
-	if item.(memoryItem).purge.Before(time.Now()) {
+	if item.(*memoryItem).purge.Before(time.Now()) {
 		return nil, kErrorExpiredItem
 	}
 
-	return item.(memoryItem).value, nil
+	return item.(*memoryItem).value, nil
 }
 
 func (store *memStore) ReadAndRemove(ns, key string) (any, error) {
@@ -114,46 +151,41 @@ func (store *memStore) ReadAndRemove(ns, key string) (any, error) {
 		return nil, kErrorInvalidNamespace
 	}
 
-	item, ok := scoped.(*sync.Map).LoadAndDelete(key)
+	item, ok := scoped.(*memScope).delete(key)
 	if !ok {
 		return nil, kErrorInvalidKey
 	}
 
-	if item.(memoryItem).purge.Before(time.Now()) {
+	if item.purge.Before(time.Now()) {
+		store.publish(KeyValueExpired, ns, key, item.value)
 		return nil, kErrorExpiredItem
 	}
 
-	return item.(memoryItem).value, nil
+	store.publish(KeyValueRemoved, ns, key, item.value)
+	return item.value, nil
 }
 
 func (store *memStore) CheckAndSet(ns, key string, value any, ttl time.Duration) error {
-	scoped, ok := store.scopes.Load(ns)
-	if !ok {
-		scoped, _ = store.scopes.LoadOrStore(ns, new(sync.Map))
-	}
-
-	_, loaded := scoped.(*sync.Map).LoadOrStore(key, memoryItem{
-		purge: time.Now().Add(ttl),
-		value: value,
-	})
-
-	if loaded {
+	scoped := store.scope(ns)
+	if !scoped.insert(key, store.newItem(value, ttl), store.expiry) {
 		return kErrorItemAlreadyExists
 	}
 
+	store.publish(KeyValueSet, ns, key, value)
+	store.enforceCap(ns, scoped, key)
+
 	return nil
 }
 
 func (store *memStore) Set(ns, key string, value any, ttl time.Duration) error {
-	scoped, ok := store.scopes.Load(ns)
-	if !ok {
-		scoped, _ = store.scopes.LoadOrStore(ns, new(sync.Map))
-	}
+	scoped := store.scope(ns)
+	replaced := scoped.put(key, store.newItem(value, ttl), store.expiry)
 
-	scoped.(*sync.Map).Store(key, memoryItem{
-		purge: time.Now().Add(ttl),
-		value: value,
-	})
+	store.publish(KeyValueSet, ns, key, value)
+
+	if !replaced {
+		store.enforceCap(ns, scoped, key)
+	}
 
 	return nil
 }
@@ -164,17 +196,14 @@ func (store *memStore) Refresh(ns, key string, ttl time.Duration) error {
 		return kErrorInvalidNamespace
 	}
 
-	item, ok := scoped.(*sync.Map).Load(key)
+	scope := scoped.(*memScope)
+	item, ok := scope.items.Load(key)
 	if !ok {
 		return kErrorInvalidKey
 	}
 
-	newItem := memoryItem{
-		purge: time.Now().Add(ttl),
-		value: item.(memoryItem).value,
-	}
-
-	if !scoped.(*sync.Map).CompareAndSwap(key, item, newItem) {
+	old := item.(*memoryItem)
+	if !scope.replace(key, old.gen, store.newItem(old.value, ttl), store.expiry) {
 		return kErrorItemChanged
 	}
 
@@ -183,6 +212,170 @@ func (store *memStore) Refresh(ns, key string, ttl time.Duration) error {
 
 func (store *memStore) Remove(ns, key string) {
 	if scoped, ok := store.scopes.Load(ns); ok {
-		scoped.(*sync.Map).Delete(key)
+		if item, ok := scoped.(*memScope).delete(key); ok {
+			store.publish(KeyValueRemoved, ns, key, item.value)
+		}
 	}
 }
+
+/**
+ *
+ * Change notifications
+ *
+ **/
+
+func (store *memStore) Subscribe(ns string, fn KeyValueHandler) func() {
+	store.subsMu.Lock()
+	defer store.subsMu.Unlock()
+
+	store.subID++
+	id := store.subID
+
+	if store.subs[ns] == nil {
+		store.subs[ns] = make(map[uint64]KeyValueHandler)
+	}
+	store.subs[ns][id] = fn
+
+	return func() {
+		store.subsMu.Lock()
+		defer store.subsMu.Unlock()
+
+		delete(store.subs[ns], id)
+		if len(store.subs[ns]) == 0 {
+			delete(store.subs, ns)
+		}
+	}
+}
+
+func (store *memStore) publish(kind KeyValueEventKind, ns, key string, value any) {
+	store.subsMu.RLock()
+	if len(store.subs[ns]) == 0 {
+		store.subsMu.RUnlock()
+		return
+	}
+
+	handlers := make([]KeyValueHandler, 0, len(store.subs[ns]))
+	for _, fn := range store.subs[ns] {
+		handlers = append(handlers, fn)
+	}
+	store.subsMu.RUnlock()
+
+	event := KeyValueEvent{
+		Kind:      kind,
+		Namespace: ns,
+		Key:       key,
+		Value:     value,
+	}
+
+	for _, fn := range handlers {
+		fn(event)
+	}
+}
+
+/**
+ *
+ * Expiry
+ *
+ **/
+
+// Invoked by the expiry timer. Removes everything that has come due and
+// returns the earliest remaining deadline across all namespaces.
+func (store *memStore) sweep(now time.Time) (time.Time, bool) {
+	var next time.Time
+	found := false
+
+	store.scopes.Range(func(ns, value any) bool {
+		scope := value.(*memScope)
+		for _, e := range scope.expired(now) {
+			store.publish(KeyValueExpired, ns.(string), e.key, e.item.value)
+		}
+
+		if deadline, ok := scope.next(); ok && (!found || deadline.Before(next)) {
+			next, found = deadline, true
+		}
+		return true
+	})
+
+	return next, found
+}
+
+/**
+ *
+ * Writes on memScope. Each takes the scope's lock so the item count and the
+ * deadline heap stay in step with the map.
+ *
+ **/
+
+// Stores item, replacing any existing one, and reports whether it replaced
+func (scope *memScope) put(key string, item *memoryItem, timer expiryTimer) bool {
+	scope.mu.Lock()
+	defer scope.mu.Unlock()
+
+	_, loaded := scope.items.Swap(key, item)
+	if !loaded {
+		scope.count.Add(1)
+	}
+
+	if scope.track(key, item) {
+		timer.signal()
+	}
+
+	return loaded
+}
+
+// Stores item only if key is not already present
+func (scope *memScope) insert(key string, item *memoryItem, timer expiryTimer) bool {
+	scope.mu.Lock()
+	defer scope.mu.Unlock()
+
+	if _, loaded := scope.items.LoadOrStore(key, item); loaded {
+		return false
+	}
+
+	scope.count.Add(1)
+	if scope.track(key, item) {
+		timer.signal()
+	}
+
+	return true
+}
+
+// Replaces the item at key only if it is still the write identified by gen.
+// A nil item removes it instead.
+func (scope *memScope) replace(key string, gen uint64, item *memoryItem, timer expiryTimer) bool {
+	scope.mu.Lock()
+	defer scope.mu.Unlock()
+
+	current, ok := scope.items.Load(key)
+	if !ok || current.(*memoryItem).gen != gen {
+		return false
+	}
+
+	if item == nil {
+		scope.items.Delete(key)
+		scope.count.Add(-1)
+		scope.untrack(key)
+		return true
+	}
+
+	scope.items.Store(key, item)
+	if scope.track(key, item) {
+		timer.signal()
+	}
+
+	return true
+}
+
+func (scope *memScope) delete(key string) (*memoryItem, bool) {
+	scope.mu.Lock()
+	defer scope.mu.Unlock()
+
+	item, ok := scope.items.LoadAndDelete(key)
+	if !ok {
+		return nil, false
+	}
+
+	scope.count.Add(-1)
+	scope.untrack(key)
+	return item.(*memoryItem), true
+}
@@ -0,0 +1,136 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/test"
+)
+
+func TestMemoryExpiryEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := NewMemoryStore(ctx)
+	events := make(chan KeyValueEvent, 10)
+	cancelSub := store.Subscribe("qrc", func(e KeyValueEvent) { events <- e })
+
+	test.NoError(t, store.Set("qrc", "slow", "s", time.Hour), "set failed")
+	test.NoError(t, store.Set("qrc", "fast", "f", 20*time.Millisecond), "set failed")
+	test.NoError(t, store.Set("other", "fast", "f", 20*time.Millisecond), "set failed")
+
+	test.Expect(t, KeyValueSet, (<-events).Kind, "first set event")
+	test.Expect(t, KeyValueSet, (<-events).Kind, "second set event")
+
+	select {
+	case e := <-events:
+		test.Expect(t, KeyValueEvent{KeyValueExpired, "qrc", "fast", "f"}, e, "expiry event")
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for expiry event")
+	}
+
+	test.Expect(t, 1, store.Count("qrc"), "expired item collected")
+
+	store.Remove("qrc", "slow")
+	test.Expect(t, KeyValueEvent{KeyValueRemoved, "qrc", "slow", "s"}, <-events, "remove event")
+
+	cancelSub()
+	test.NoError(t, store.Set("qrc", "quiet", "q", time.Hour), "set failed")
+	select {
+	case e := <-events:
+		t.Fatalf("unexpected event after cancel: %v", e)
+	default:
+	}
+}
+
+func TestMemoryExpiryIndex(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store := NewMemoryStore(ctx)
+	for i := 0; i < 5; i++ {
+		test.NoError(t, store.Set("qrc", "a", []string{"v"}, time.Duration(i+1)*time.Minute), "set failed")
+	}
+	test.NoError(t, store.Set("qrc", "b", map[string]int{"v": 1}, time.Minute), "set failed")
+	test.NoError(t, store.Set("qrc", "c", 3, time.Minute), "set failed")
+
+	// Non-comparable values must not trip up refresh or removal
+	test.NoError(t, store.Refresh("qrc", "a", time.Hour), "refresh failed")
+	test.NoError(t, store.Refresh("qrc", "b", time.Hour), "refresh failed")
+
+	scope := store.(*memStore).scope("qrc")
+	test.Expect(t, 3, len(scope.deadlines), "one deadline per key")
+
+	store.Remove("qrc", "a")
+	test.Expect(t, 1, store.RemoveAll("qrc", "b"), "removed count")
+	_, err := store.ReadAndRemove("qrc", "c")
+	test.NoError(t, err, "read and remove failed")
+
+	test.Expect(t, 0, len(scope.deadlines), "removals drop their deadlines")
+	test.Expect(t, 0, len(scope.index), "removals drop their index entries")
+}
+
+type unregisteredValue struct {
+	Name string
+}
+
+func TestMemorySnapshotRestore(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	file := filepath.Join(t.TempDir(), "kvs.snapshot")
+	code := AuthCodeData{UID: "1", RedirectURI: "https://example.com/cb", State: "xyz"}
+
+	// The shutdown snapshot is the only one written
+	var wg sync.WaitGroup
+	store := NewMemoryStore(ctx, WithSnapshot(file, 0), WithSnapshotGroup(&wg))
+	test.NoError(t, store.Set("auth_code", "abc", code, time.Hour), "set failed")
+	test.NoError(t, store.Set("qrc", "token", "secret", time.Hour), "set failed")
+	test.NoError(t, store.Set("qrc", "stale", "secret", 10*time.Millisecond), "set failed")
+	test.NoError(t, store.Set("qrc", "odd", unregisteredValue{"x"}, time.Hour), "set failed")
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	wg.Wait()
+
+	restored := NewMemoryStore(context.Background(), WithSnapshot(file, 0))
+
+	v, err := restored.Read("auth_code", "abc")
+	test.NoError(t, err, "restored auth code missing")
+	test.Expect(t, code, v, "restored auth code")
+
+	v, err = restored.Read("qrc", "token")
+	test.NoError(t, err, "restored qr token missing")
+	test.Expect(t, "secret", v, "restored qr token")
+
+	_, err = restored.Read("qrc", "stale")
+	test.SpecificError(t, err, kErrorInvalidKey, "expired item should be discarded")
+
+	_, err = restored.Read("qrc", "odd")
+	test.SpecificError(t, err, kErrorInvalidKey, "unregistered type should be skipped")
+	test.Expect(t, 1, restored.Count("qrc"), "restored count")
+}
@@ -26,6 +26,7 @@ import (
 	"context"
 	"time"
 
+	"shiftylogic.dev/hockey-tools/internal/data"
 	"shiftylogic.dev/hockey-tools/internal/web"
 )
 
@@ -43,8 +44,8 @@ type AuthCodeData struct {
 }
 
 type Authorizer interface {
-	GenerateAuthorizationRequest(data AuthCodeData, ttl time.Duration) (string, error)
-	GenerateQRRequest(ttl time.Duration) (string, string, string, error)
+	GenerateAuthorizationRequest(ctx context.Context, data AuthCodeData, ttl time.Duration) (string, error)
+	GenerateQRRequest(ctx context.Context, ttl time.Duration) (string, string, string, error)
 
 	Authenticate(user, pwd string) (string, error)
 	ValidateClient(cid, redir string) bool
@@ -53,6 +54,9 @@ type Authorizer interface {
 type Services interface {
 	Ephemeral() DataStore
 	Authorizer() Authorizer
+
+	// Nil when no data file is configured
+	Data() data.Store
 }
 
 func ServicesFromContext(ctx context.Context) Services {
@@ -75,6 +79,7 @@ func WithServices(svcs Services) web.RouterOptionFunc {
 type ServicesContainer struct {
 	EphemeralStore DataStore
 	Authy          Authorizer
+	DataStore      data.Store
 }
 
 func (svcs ServicesContainer) Ephemeral() DataStore {
@@ -84,3 +89,7 @@ func (svcs ServicesContainer) Ephemeral() DataStore {
 func (svcs ServicesContainer) Authorizer() Authorizer {
 	return svcs.Authy
 }
+
+func (svcs ServicesContainer) Data() data.Store {
+	return svcs.DataStore
+}
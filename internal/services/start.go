@@ -24,8 +24,9 @@ package services
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 
+	"shiftylogic.dev/hockey-tools/internal/health"
 	"shiftylogic.dev/hockey-tools/internal/web"
 )
 
@@ -36,8 +37,44 @@ func Start(config Config, router web.Router) {
 		web.WithHandler(router),
 	}
 
-	if config.TLS.Enabled() {
-		options = append(options, web.WithTLS(config.TLS.Certificate, config.TLS.Key))
+	if config.Health.Enabled {
+		options = append(options, web.WithReadiness(health.Default, config.Health.DrainDelay))
+	}
+
+	if config.TLS.ACME.Enabled {
+		options = append(options, web.WithACME(config.TLS.ACME.Options()))
+	} else if config.TLS.Enabled() {
+		options = append(options, web.WithCertificates(config.TLS.ReloadInterval, config.TLS.Pairs()...))
+	}
+
+	if config.TLS.Enabled() && config.TLS.ClientAuth.Enabled() {
+		options = append(options, web.WithClientAuth(config.TLS.ClientAuth.CAFile, config.TLS.ClientAuth.Mode))
+	}
+
+	for _, l := range config.Listeners {
+		switch l.Kind {
+		case web.ListenerRedirect:
+			options = append(options, web.WithRedirectListener(l.Address))
+		case web.ListenerAdmin:
+			options = append(options, web.WithListener(web.Listener{
+				Name:    web.ListenerAdmin,
+				Network: "tcp",
+				Address: l.Address,
+				Handler: web.AdminHandler(health.Default, config.Profiler),
+			}))
+		case web.ListenerUnix:
+			options = append(options, web.WithUnixListener(l.Address, router))
+		default:
+			panic("unknown listener kind: " + l.Kind)
+		}
+	}
+
+	if config.H2C {
+		options = append(options, web.WithH2C())
+	}
+
+	if config.HTTP3.Enabled {
+		options = append(options, web.WithHTTP3(config.HTTP3.Address))
 	}
 
 	//
@@ -45,7 +82,7 @@ func Start(config Config, router web.Router) {
 	//
 	web.DumpRouter(router)
 
-	log.Printf("Launching server (%s)\n", addr)
+	slog.Info("Launching server", "address", addr)
 	web.StartWithOptions(options...)
-	log.Print("Server stopped.")
+	slog.Info("Server stopped")
 }
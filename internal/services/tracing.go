@@ -0,0 +1,81 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"shiftylogic.dev/hockey-tools/internal/trace"
+)
+
+const (
+	kTraceExporterOTLP   = "otlp"
+	kTraceExporterMemory = "memory"
+)
+
+type TracingConfig struct {
+	Enabled     bool              `json:"enabled" yaml:"Enabled"`
+	Exporter    string            `json:"exporter" yaml:"Exporter"`
+	Endpoint    string            `json:"endpoint" yaml:"Endpoint"`
+	ServiceName string            `json:"serviceName" yaml:"ServiceName"`
+	SampleRatio float64           `json:"sampleRatio" yaml:"SampleRatio"`
+	Headers     map[string]string `json:"headers" yaml:"Headers"`
+}
+
+func DefaultTracing() TracingConfig {
+	return TracingConfig{
+		Enabled:     false,
+		Exporter:    kTraceExporterOTLP,
+		Endpoint:    "http://localhost:4318",
+		ServiceName: "hockey-tools",
+		SampleRatio: 1,
+	}
+}
+
+/**
+ *
+ * Installs the global trace provider per the config. Returns nil (and leaves
+ * tracing off) when disabled; otherwise the caller owns the provider and
+ * should Shutdown it to flush any pending spans.
+ *
+ **/
+func ConfigureTracing(cfg TracingConfig) (*trace.Provider, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	var exporter trace.Exporter
+	switch strings.ToLower(cfg.Exporter) {
+	case kTraceExporterOTLP, "":
+		exporter = trace.NewOTLPExporter(cfg.Endpoint, cfg.ServiceName, cfg.Headers)
+	case kTraceExporterMemory:
+		exporter = trace.NewMemoryExporter()
+	default:
+		return nil, fmt.Errorf("unknown trace exporter '%s'", cfg.Exporter)
+	}
+
+	provider := trace.NewProvider(exporter, trace.WithSampleRatio(cfg.SampleRatio))
+	trace.SetProvider(provider)
+	return provider, nil
+}
@@ -0,0 +1,263 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package trace
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+/**
+ *
+ * In-memory exporter, mainly for tests
+ *
+ **/
+
+type MemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func NewMemoryExporter() *MemoryExporter {
+	return &MemoryExporter{}
+}
+
+func (e *MemoryExporter) Export(ctx context.Context, spans []SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *MemoryExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+func (e *MemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return append([]SpanData{}, e.spans...)
+}
+
+func (e *MemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.spans = nil
+}
+
+/**
+ *
+ * OTLP/HTTP exporter using the JSON protobuf encoding, which any OTLP
+ * collector accepts on /v1/traces.
+ *
+ **/
+
+const (
+	kOTLPTracesPath = "/v1/traces"
+	kOTLPScopeName  = "shiftylogic.dev/hockey-tools"
+	kOTLPTimeout    = 10 * time.Second
+)
+
+type OTLPExporter struct {
+	endpoint string
+	headers  map[string]string
+	resource otlpResource
+	client   *http.Client
+}
+
+// 'endpoint' is the collector base URL, e.g. http://localhost:4318
+func NewOTLPExporter(endpoint, serviceName string, headers map[string]string) *OTLPExporter {
+	return &OTLPExporter{
+		endpoint: endpoint + kOTLPTracesPath,
+		headers:  headers,
+		resource: otlpResource{
+			Attributes: []otlpKeyValue{otlpAttr(slog.String("service.name", serviceName))},
+		},
+		client: &http.Client{Timeout: kOTLPTimeout},
+	}
+}
+
+func (e *OTLPExporter) Export(ctx context.Context, spans []SpanData) error {
+	body, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("collector returned %s", resp.Status)
+	}
+
+	return nil
+}
+
+func (e *OTLPExporter) Shutdown(ctx context.Context) error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+func (e *OTLPExporter) request(spans []SpanData) otlpRequest {
+	out := make([]otlpSpan, len(spans))
+
+	for i, s := range spans {
+		out[i] = otlpSpan{
+			TraceID:           s.TraceID.String(),
+			SpanID:            s.SpanID.String(),
+			Name:              s.Name,
+			Kind:              int(s.Kind),
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+			Status:            otlpStatus{Code: int(s.StatusCode), Message: s.StatusMessage},
+		}
+
+		if s.ParentID.IsValid() {
+			out[i].ParentSpanID = s.ParentID.String()
+		}
+
+		for _, a := range s.Attributes {
+			out[i].Attributes = append(out[i].Attributes, otlpAttr(a))
+		}
+	}
+
+	return otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: e.resource,
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: kOTLPScopeName},
+				Spans: out,
+			}},
+		}},
+	}
+}
+
+/**
+ *
+ * OTLP JSON message shapes (opentelemetry-proto, trace/v1)
+ *
+ **/
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+func otlpAttr(a slog.Attr) otlpKeyValue {
+	kv := otlpKeyValue{Key: a.Key}
+	v := a.Value.Resolve()
+
+	switch v.Kind() {
+	case slog.KindBool:
+		b := v.Bool()
+		kv.Value.BoolValue = &b
+	case slog.KindInt64:
+		i := strconv.FormatInt(v.Int64(), 10)
+		kv.Value.IntValue = &i
+	case slog.KindUint64:
+		i := strconv.FormatUint(v.Uint64(), 10)
+		kv.Value.IntValue = &i
+	case slog.KindFloat64:
+		f := v.Float64()
+		kv.Value.DoubleValue = &f
+	case slog.KindDuration:
+		i := strconv.FormatInt(v.Duration().Nanoseconds(), 10)
+		kv.Value.IntValue = &i
+	default:
+		s := v.String()
+		kv.Value.StringValue = &s
+	}
+
+	return kv
+}
@@ -0,0 +1,102 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package trace
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+/**
+ *
+ * W3C Trace Context propagation (https://www.w3.org/TR/trace-context/)
+ *
+ *   traceparent: 00-<32 hex trace-id>-<16 hex parent-id>-<2 hex flags>
+ *
+ **/
+
+const (
+	HeaderTraceParent = "traceparent"
+	HeaderTraceState  = "tracestate"
+
+	kTraceParentVersion = "00"
+)
+
+func ParseTraceParent(value string) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return SpanContext{}, false
+	}
+
+	// Version 00 has exactly four fields; later versions may append more
+	if parts[0] == kTraceParentVersion && len(parts) != 4 {
+		return SpanContext{}, false
+	}
+
+	var sc SpanContext
+	var flags [1]byte
+
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return SpanContext{}, false
+	}
+
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return SpanContext{}, false
+	}
+
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return SpanContext{}, false
+	}
+
+	if _, err := hex.Decode(flags[:], []byte(parts[3])); err != nil {
+		return SpanContext{}, false
+	}
+
+	sc.Flags = flags[0]
+	sc.Remote = true
+
+	return sc, sc.IsValid()
+}
+
+func FormatTraceParent(sc SpanContext) string {
+	return fmt.Sprintf("%s-%s-%s-%02x", kTraceParentVersion, sc.TraceID, sc.SpanID, sc.Flags)
+}
+
+// Returns a context parented to the caller's traceparent header, if any
+func Extract(ctx context.Context, header http.Header) context.Context {
+	if sc, ok := ParseTraceParent(header.Get(HeaderTraceParent)); ok {
+		return ContextWithRemote(ctx, sc)
+	}
+
+	return ctx
+}
+
+// Adds a traceparent header for the span in 'ctx' to an outgoing request
+func Inject(ctx context.Context, header http.Header) {
+	if sc := SpanFromContext(ctx).SpanContext(); sc.IsValid() {
+		header.Set(HeaderTraceParent, FormatTraceParent(sc))
+	}
+}
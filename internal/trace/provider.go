@@ -0,0 +1,280 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	kDefaultBatchSize     = 256
+	kDefaultQueueSize     = 2048
+	kDefaultFlushInterval = 2 * time.Second
+)
+
+type Exporter interface {
+	Export(ctx context.Context, spans []SpanData) error
+	Shutdown(ctx context.Context) error
+}
+
+type ProviderOptionFunc func(*Provider)
+
+type Provider struct {
+	exporter      Exporter
+	sampleRatio   float64
+	batchSize     int
+	flushInterval time.Duration
+
+	queue   chan SpanData
+	flush   chan chan struct{}
+	stop    chan struct{}
+	stopped sync.WaitGroup
+	dropped atomic.Uint64
+
+	shutdown    sync.Once
+	shutdownErr error
+}
+
+var (
+	active atomic.Pointer[Provider]
+)
+
+/**
+ *
+ * Spans are batched and exported from a background goroutine. If the queue
+ * fills up (the exporter is slow or down), new spans are dropped rather
+ * than blocking requests.
+ *
+ **/
+func NewProvider(exporter Exporter, options ...ProviderOptionFunc) *Provider {
+	p := &Provider{
+		exporter:      exporter,
+		sampleRatio:   1,
+		batchSize:     kDefaultBatchSize,
+		flushInterval: kDefaultFlushInterval,
+		queue:         make(chan SpanData, kDefaultQueueSize),
+		flush:         make(chan chan struct{}),
+		stop:          make(chan struct{}),
+	}
+
+	for _, fn := range options {
+		fn(p)
+	}
+
+	p.stopped.Add(1)
+	go p.run()
+
+	return p
+}
+
+// Fraction of new (root) traces to record; child spans follow their parent
+func WithSampleRatio(ratio float64) ProviderOptionFunc {
+	return func(p *Provider) {
+		p.sampleRatio = ratio
+	}
+}
+
+func WithBatching(size int, interval time.Duration) ProviderOptionFunc {
+	return func(p *Provider) {
+		p.batchSize = size
+		p.flushInterval = interval
+	}
+}
+
+// Installs the provider used by Start. Passing nil disables tracing.
+func SetProvider(p *Provider) {
+	active.Store(p)
+}
+
+func (p *Provider) Dropped() uint64 {
+	return p.dropped.Load()
+}
+
+// Blocks until every span ended before the call has been exported
+func (p *Provider) ForceFlush() {
+	done := make(chan struct{})
+	select {
+	case p.flush <- done:
+		<-done
+	case <-p.stop:
+	}
+}
+
+// Safe to call more than once; later calls return the first call's result
+func (p *Provider) Shutdown(ctx context.Context) error {
+	p.shutdown.Do(func() {
+		close(p.stop)
+		p.stopped.Wait()
+		p.shutdownErr = p.exporter.Shutdown(ctx)
+	})
+
+	return p.shutdownErr
+}
+
+func (p *Provider) enqueue(data SpanData) {
+	select {
+	case p.queue <- data:
+	default:
+		p.dropped.Add(1)
+	}
+}
+
+func (p *Provider) run() {
+	defer p.stopped.Done()
+
+	ticker := time.NewTicker(p.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]SpanData, 0, p.batchSize)
+	export := func() {
+		if len(batch) == 0 {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), p.flushInterval*5)
+		if err := p.exporter.Export(ctx, batch); err != nil {
+			slog.Warn("Failed to export trace spans", "count", len(batch), "error", err)
+		}
+		cancel()
+
+		batch = make([]SpanData, 0, p.batchSize)
+	}
+
+	drain := func() {
+		for {
+			select {
+			case data := <-p.queue:
+				batch = append(batch, data)
+				if len(batch) >= p.batchSize {
+					export()
+				}
+			default:
+				export()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case data := <-p.queue:
+			batch = append(batch, data)
+			if len(batch) >= p.batchSize {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		case done := <-p.flush:
+			drain()
+			close(done)
+		case <-p.stop:
+			drain()
+			return
+		}
+	}
+}
+
+/**
+ *
+ * Span creation
+ *
+ **/
+
+// Starts an internal span as a child of whatever span is in 'ctx'
+func Start(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, *Span) {
+	return StartKind(ctx, SpanKindInternal, name, attrs...)
+}
+
+func StartKind(ctx context.Context, kind SpanKind, name string, attrs ...slog.Attr) (context.Context, *Span) {
+	p := active.Load()
+	parent := SpanFromContext(ctx).SpanContext()
+
+	if p == nil && !parent.IsValid() {
+		return ctx, nil
+	}
+
+	sc := SpanContext{
+		TraceID: parent.TraceID,
+		SpanID:  newSpanID(),
+		Flags:   parent.Flags,
+	}
+
+	if !parent.IsValid() {
+		sc.TraceID = newTraceID()
+		if p.sample() {
+			sc.Flags |= FlagSampled
+		}
+	}
+
+	span := &Span{sc: sc}
+	if p != nil && sc.IsSampled() {
+		span.provider = p
+		span.data = SpanData{
+			Name:       name,
+			Kind:       kind,
+			TraceID:    sc.TraceID,
+			SpanID:     sc.SpanID,
+			ParentID:   parent.SpanID,
+			Start:      time.Now(),
+			Attributes: attrs,
+		}
+	}
+
+	return ContextWithSpan(ctx, span), span
+}
+
+func (p *Provider) sample() bool {
+	if p.sampleRatio >= 1 {
+		return true
+	}
+
+	if p.sampleRatio <= 0 {
+		return false
+	}
+
+	var b [8]byte
+	rand.Read(b[:])
+	return float64(binary.BigEndian.Uint64(b[:])>>11)/(1<<53) < p.sampleRatio
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
@@ -0,0 +1,194 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package trace
+
+import (
+	"context"
+	"encoding/hex"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+/**
+ *
+ * A small, OpenTelemetry-shaped tracing core. Spans are identified by W3C
+ * trace / span IDs, carry slog.Attr attributes and are handed to the active
+ * Provider's exporter when they end. All Span methods are safe to call on
+ * a nil or non-recording span.
+ *
+ **/
+
+const (
+	kSpanContextKey = "sl.span"
+
+	FlagSampled byte = 0x01
+)
+
+type TraceID [16]byte
+type SpanID [8]byte
+
+func (id TraceID) IsValid() bool  { return id != TraceID{} }
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+func (id SpanID) IsValid() bool   { return id != SpanID{} }
+func (id SpanID) String() string  { return hex.EncodeToString(id[:]) }
+
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Flags   byte
+	Remote  bool
+}
+
+func (sc SpanContext) IsValid() bool   { return sc.TraceID.IsValid() && sc.SpanID.IsValid() }
+func (sc SpanContext) IsSampled() bool { return sc.Flags&FlagSampled != 0 }
+
+type SpanKind int
+
+// Values match the OTLP SpanKind enum
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+)
+
+type StatusCode int
+
+// Values match the OTLP Status.StatusCode enum
+const (
+	StatusUnset StatusCode = 0
+	StatusOK    StatusCode = 1
+	StatusError StatusCode = 2
+)
+
+// An immutable record of a finished span, as handed to exporters
+type SpanData struct {
+	Name          string
+	Kind          SpanKind
+	TraceID       TraceID
+	SpanID        SpanID
+	ParentID      SpanID
+	Start         time.Time
+	End           time.Time
+	Attributes    []slog.Attr
+	StatusCode    StatusCode
+	StatusMessage string
+}
+
+type Span struct {
+	mu       sync.Mutex
+	provider *Provider
+	sc       SpanContext
+	data     SpanData
+	ended    bool
+}
+
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+func (s *Span) IsRecording() bool {
+	return s != nil && s.provider != nil
+}
+
+func (s *Span) SetName(name string) {
+	if !s.IsRecording() {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Name = name
+}
+
+func (s *Span) SetAttributes(attrs ...slog.Attr) {
+	if !s.IsRecording() {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Attributes = append(s.data.Attributes, attrs...)
+}
+
+func (s *Span) SetStatus(code StatusCode, msg string) {
+	if !s.IsRecording() {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.StatusCode = code
+	s.data.StatusMessage = msg
+}
+
+// Marks the span as failed. A nil error is ignored.
+func (s *Span) RecordError(err error) {
+	if err != nil {
+		s.SetStatus(StatusError, err.Error())
+	}
+}
+
+func (s *Span) End() {
+	if !s.IsRecording() {
+		return
+	}
+
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	s.provider.enqueue(data)
+}
+
+/**
+ *
+ * Context helpers
+ *
+ **/
+
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, kSpanContextKey, span)
+}
+
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(kSpanContextKey).(*Span)
+	return span
+}
+
+// Makes a remote span context (e.g. from a traceparent header) the parent
+// of the next span started from the returned context.
+func ContextWithRemote(ctx context.Context, sc SpanContext) context.Context {
+	sc.Remote = true
+	return ContextWithSpan(ctx, &Span{sc: sc})
+}
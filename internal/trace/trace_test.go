@@ -0,0 +1,115 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package trace
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"shiftylogic.dev/hockey-tools/internal/test"
+)
+
+func TestTraceParentRoundTrip(t *testing.T) {
+	cases := []struct {
+		value string
+		valid bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false},
+		{"00-xyz", false},
+		{"", false},
+	}
+
+	for _, c := range cases {
+		sc, ok := ParseTraceParent(c.value)
+		test.Expect(t, c.valid, ok, "traceparent validity: "+c.value)
+
+		if ok {
+			test.Expect(t, c.value, FormatTraceParent(sc), "traceparent round trip")
+		}
+	}
+}
+
+func TestSpansFollowParent(t *testing.T) {
+	exporter := NewMemoryExporter()
+	provider := NewProvider(exporter)
+	SetProvider(provider)
+	defer SetProvider(nil)
+
+	header := http.Header{}
+	header.Set(HeaderTraceParent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	ctx, server := StartKind(Extract(context.Background(), header), SpanKindServer, "server")
+	_, child := Start(ctx, "child")
+	child.RecordError(errors.New("boom"))
+	child.End()
+	server.End()
+
+	outgoing := http.Header{}
+	Inject(ctx, outgoing)
+	test.Expect(t, FormatTraceParent(server.SpanContext()), outgoing.Get(HeaderTraceParent), "injected traceparent")
+
+	provider.ForceFlush()
+	spans := exporter.Spans()
+	test.Expect(t, 2, len(spans), "exported span count")
+
+	remote, _ := ParseTraceParent(header.Get(HeaderTraceParent))
+	test.Expect(t, "child", spans[0].Name, "first ended span")
+	test.Expect(t, server.SpanContext().SpanID, spans[0].ParentID, "child parent")
+	test.Expect(t, StatusError, spans[0].StatusCode, "child status")
+	test.Expect(t, remote.SpanID, spans[1].ParentID, "server parent")
+
+	for _, s := range spans {
+		test.Expect(t, remote.TraceID, s.TraceID, "shared trace id")
+	}
+
+	test.NoError(t, provider.Shutdown(context.Background()), "provider shutdown")
+}
+
+func TestUnsampledSpansAreNotRecorded(t *testing.T) {
+	exporter := NewMemoryExporter()
+	provider := NewProvider(exporter, WithSampleRatio(0))
+	SetProvider(provider)
+	defer SetProvider(nil)
+
+	ctx, root := Start(context.Background(), "root")
+	_, child := Start(ctx, "child")
+
+	test.Require(t, root.SpanContext().IsValid(), "unsampled root still has an identity")
+	test.Require(t, !root.IsRecording() && !child.IsRecording(), "unsampled spans should not record")
+	test.Expect(t, root.SpanContext().TraceID, child.SpanContext().TraceID, "child shares trace id")
+
+	child.End()
+	root.End()
+	provider.ForceFlush()
+	test.Expect(t, 0, len(exporter.Spans()), "exported span count")
+
+	test.NoError(t, provider.Shutdown(context.Background()), "provider shutdown")
+	test.NoError(t, provider.Shutdown(context.Background()), "second provider shutdown")
+}
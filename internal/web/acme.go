@@ -0,0 +1,168 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package web
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+const (
+	ChallengeHTTP01    = "http-01"
+	ChallengeTLSALPN01 = "tls-alpn-01"
+
+	kDefaultACMEHTTPAddress = ":80"
+)
+
+type ACMEOptions struct {
+	Directory   string // ACME directory URL; empty for Let's Encrypt production
+	Email       string
+	Domains     []string
+	CacheDir    string
+	Challenge   string // ChallengeHTTP01 or ChallengeTLSALPN01
+	HTTPAddress string // HTTP-01 listener address; defaults to ":80"
+
+	// Trust roots for the directory itself (e.g. a local Pebble instance)
+	RootCAs *x509.CertPool
+}
+
+/**
+ *
+ * Obtains and renews certificates automatically (RFC 8555) via autocert.
+ * Certificates and the account key live in the cache directory, so
+ * restarts do not re-issue. TLS-ALPN-01 is answered by the TLS listener
+ * itself; HTTP-01 needs a plain HTTP listener, which also redirects any
+ * other request to HTTPS.
+ *
+ **/
+func NewACMEManager(opts ACMEOptions) *autocert.Manager {
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(opts.Domains...),
+		Cache:      autocert.DirCache(opts.CacheDir),
+		Email:      opts.Email,
+	}
+
+	if opts.Directory != "" || opts.RootCAs != nil {
+		m.Client = &acme.Client{DirectoryURL: opts.Directory}
+
+		if opts.RootCAs != nil {
+			m.Client.HTTPClient = &http.Client{
+				Transport: &http.Transport{
+					TLSClientConfig: &tls.Config{RootCAs: opts.RootCAs},
+				},
+			}
+		}
+	}
+
+	return m
+}
+
+func WithACME(opts ACMEOptions) ServerOptionFunc {
+	if len(opts.Domains) == 0 {
+		panic("ACME requires at least one domain")
+	}
+
+	switch opts.Challenge {
+	case ChallengeHTTP01, ChallengeTLSALPN01:
+	case "":
+		opts.Challenge = ChallengeTLSALPN01
+	default:
+		panic("unknown ACME challenge type: " + opts.Challenge)
+	}
+
+	return func(s *Server) {
+		m := NewACMEManager(opts)
+
+		s.ACME = m
+		s.ACMEDomains = opts.Domains
+		s.TLSConfig = m.TLSConfig()
+		s.TLSConfig.MinVersion = tls.VersionTLS12
+
+		if opts.Challenge == ChallengeHTTP01 {
+			s.ACMEHTTPAddress = opts.HTTPAddress
+			if s.ACMEHTTPAddress == "" {
+				s.ACMEHTTPAddress = kDefaultACMEHTTPAddress
+			}
+		}
+	}
+}
+
+// Requests (ECDSA) certificates up front rather than on the first
+// handshake, so readiness reflects whether issuance worked.
+func warmACME(m *autocert.Manager, domains []string) {
+	for _, domain := range domains {
+		hello := &tls.ClientHelloInfo{
+			ServerName:      domain,
+			CipherSuites:    []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+			SupportedCurves: []tls.CurveID{tls.CurveP256},
+			SignatureSchemes: []tls.SignatureScheme{
+				tls.ECDSAWithP256AndSHA256,
+			},
+		}
+
+		if _, err := m.GetCertificate(hello); err != nil {
+			slog.Error("ACME certificate request failed", "domain", domain, "error", err)
+			continue
+		}
+
+		slog.Info("ACME certificate ready", "domain", domain)
+	}
+}
+
+// Reads the issued certificates back from the cache (without triggering
+// issuance) for health reporting
+func acmeCertificates(m *autocert.Manager, domains []string) []*tls.Certificate {
+	var certs []*tls.Certificate
+
+	for _, domain := range domains {
+		data, err := m.Cache.Get(context.Background(), domain)
+		if err != nil {
+			if !errors.Is(err, autocert.ErrCacheMiss) {
+				slog.Warn("Failed to read ACME cache", "domain", domain, "error", err)
+			}
+			continue
+		}
+
+		cert := &tls.Certificate{}
+		for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+			if block.Type == "CERTIFICATE" {
+				cert.Certificate = append(cert.Certificate, block.Bytes)
+			}
+		}
+
+		if len(cert.Certificate) > 0 {
+			certs = append(certs, cert)
+		}
+	}
+
+	return certs
+}
@@ -0,0 +1,101 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package web
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+
+	"shiftylogic.dev/hockey-tools/internal/test"
+)
+
+const (
+	// e.g. https://localhost:14000/dir, with Pebble started using
+	// PEBBLE_VA_ALWAYS_VALID=1 so challenges need not be reachable
+	kPebbleDirectoryEnvKey = "ACME_TEST_DIRECTORY"
+	kPebbleRootCAEnvKey    = "ACME_TEST_ROOT_CA"
+)
+
+func TestACMECacheCertificates(t *testing.T) {
+	dir := t.TempDir()
+	pair := writeCertificate(t, dir, "cached", time.Now().Add(24*time.Hour), "cached.example")
+
+	certPEM, err := os.ReadFile(pair.Certificate)
+	test.NoError(t, err, "read cert")
+	keyPEM, err := os.ReadFile(pair.Key)
+	test.NoError(t, err, "read key")
+
+	m := NewACMEManager(ACMEOptions{
+		Domains:  []string{"cached.example", "missing.example"},
+		CacheDir: dir,
+	})
+
+	// autocert stores the key followed by the chain under the domain name
+	test.NoError(t, m.Cache.Put(context.Background(), "cached.example", append(keyPEM, certPEM...)), "populate cache")
+
+	certs := acmeCertificates(m, []string{"cached.example", "missing.example"})
+	test.Expect(t, 1, len(certs), "cached certificates")
+	test.NoError(t, CertificateCheck(func() []*tls.Certificate { return certs })(context.Background()), "certificate check")
+}
+
+func TestACMEIssuance(t *testing.T) {
+	directory := os.Getenv(kPebbleDirectoryEnvKey)
+	if directory == "" {
+		t.Skipf("set %s (and %s) to run against a local ACME server", kPebbleDirectoryEnvKey, kPebbleRootCAEnvKey)
+	}
+
+	roots := x509.NewCertPool()
+	if file := os.Getenv(kPebbleRootCAEnvKey); file != "" {
+		pem, err := os.ReadFile(file)
+		test.NoError(t, err, "read root CA")
+		test.Require(t, roots.AppendCertsFromPEM(pem), "parse root CA")
+	}
+
+	domains := []string{"hockey.localhost"}
+	cache := t.TempDir()
+	m := NewACMEManager(ACMEOptions{
+		Directory: directory,
+		Email:     "admin@hockey.localhost",
+		Domains:   domains,
+		CacheDir:  cache,
+		RootCAs:   roots,
+	})
+
+	warmACME(m, domains)
+
+	certs := acmeCertificates(m, domains)
+	test.Expect(t, 1, len(certs), "issued certificates")
+
+	leaf, err := x509.ParseCertificate(certs[0].Certificate[0])
+	test.NoError(t, err, "parse issued certificate")
+	test.Expect(t, "hockey.localhost", leaf.DNSNames[0], "issued name")
+
+	_, err = autocert.DirCache(cache).Get(context.Background(), "hockey.localhost")
+	test.NoError(t, err, "certificate cached on disk")
+}
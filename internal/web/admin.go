@@ -0,0 +1,53 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package web
+
+import (
+	"github.com/go-chi/chi/v5/middleware"
+
+	"shiftylogic.dev/hockey-tools/internal/health"
+	"shiftylogic.dev/hockey-tools/internal/metrics"
+)
+
+/**
+ *
+ * The handler for the admin listener (meant for localhost only): metrics,
+ * health reports and, optionally, the profiler. None of it is throttled
+ * or exposed on the public listeners.
+ *
+ **/
+func AdminHandler(reg *health.Registry, profiler bool) Router {
+	r := NewRouter(
+		WithPanicRecovery(),
+		WithNoCache(),
+		WithHealthDetail(reg),
+	)
+
+	r.Method("GET", kMetricsPath, metrics.Default.Handler())
+
+	if profiler {
+		r.Mount("/debug", middleware.Profiler())
+	}
+
+	return r
+}
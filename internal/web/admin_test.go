@@ -0,0 +1,59 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"shiftylogic.dev/hockey-tools/internal/health"
+	"shiftylogic.dev/hockey-tools/internal/test"
+)
+
+func TestAdminHandler(t *testing.T) {
+	reg := health.NewRegistry()
+	reg.Register("store", func(ctx context.Context) error { return nil })
+	public := NewRouter(WithHealth(reg), WithRequestMetrics())
+	public.Get("/", func(w http.ResponseWriter, r *http.Request) {})
+	admin := AdminHandler(reg, false)
+
+	get := func(h http.Handler, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	test.Expect(t, http.StatusOK, get(admin, kMetricsPath).Code, "admin serves metrics")
+	test.Expect(t, http.StatusNotFound, get(public, kMetricsPath).Code, "public router does not")
+
+	var report health.Report
+	test.NoError(t, json.NewDecoder(get(admin, kReadinessPath).Body).Decode(&report), "admin readiness")
+	test.Require(t, report.Checks != nil, "admin readiness includes check detail")
+
+	report = health.Report{}
+	test.NoError(t, json.NewDecoder(get(public, kReadinessPath).Body).Decode(&report), "public readiness")
+	test.Require(t, report.Checks == nil, "public readiness is status only")
+}
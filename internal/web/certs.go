@@ -0,0 +1,269 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package web
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const (
+	kDefaultCertPollInterval = time.Minute
+	kCertExpiryWarning       = 14 * 24 * time.Hour
+)
+
+type CertificatePair struct {
+	Certificate string
+	Key         string
+}
+
+/**
+ *
+ * Serves certificates through tls.Config.GetCertificate so that renewed
+ * files (e.g. from scripts/get-cert.sh) are picked up without a restart.
+ * Every pair is loaded and validated before any of them are swapped in; a
+ * bad renewal leaves the previous certificates in place.
+ *
+ * With several pairs, the certificate is chosen by SNI server name (exact
+ * match first, then wildcard). The first pair is the default for clients
+ * that do not send SNI or ask for an unknown name.
+ *
+ **/
+
+type CertificateStore struct {
+	pairs []CertificatePair
+	certs atomic.Pointer[certSet]
+
+	mu      sync.Mutex
+	modTime map[string]time.Time
+}
+
+type certSet struct {
+	all    []*tls.Certificate
+	byName map[string]*tls.Certificate
+}
+
+func NewCertificateStore(pairs ...CertificatePair) (*CertificateStore, error) {
+	if len(pairs) == 0 {
+		return nil, errors.New("no certificate pairs configured")
+	}
+
+	store := &CertificateStore{
+		pairs:   pairs,
+		modTime: make(map[string]time.Time),
+	}
+
+	if err := store.Reload(); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+func (store *CertificateStore) Reload() error {
+	set := &certSet{
+		byName: make(map[string]*tls.Certificate),
+	}
+
+	for _, pair := range store.pairs {
+		cert, err := loadCertificate(pair)
+		if err != nil {
+			return err
+		}
+
+		set.all = append(set.all, cert)
+		for _, name := range certificateNames(cert.Leaf) {
+			if _, ok := set.byName[name]; !ok {
+				set.byName[name] = cert
+			}
+		}
+	}
+
+	store.mu.Lock()
+	for _, pair := range store.pairs {
+		store.modTime[pair.Certificate] = fileModTime(pair.Certificate)
+		store.modTime[pair.Key] = fileModTime(pair.Key)
+	}
+	store.mu.Unlock()
+
+	store.certs.Store(set)
+
+	for _, cert := range set.all {
+		warnOnExpiry(cert.Leaf)
+	}
+
+	return nil
+}
+
+func (store *CertificateStore) Certificates() []*tls.Certificate {
+	return store.certs.Load().all
+}
+
+func (store *CertificateStore) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	set := store.certs.Load()
+
+	name := strings.TrimSuffix(strings.ToLower(hello.ServerName), ".")
+	if cert, ok := set.byName[name]; ok {
+		return cert, nil
+	}
+
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		if cert, ok := set.byName["*"+name[i:]]; ok {
+			return cert, nil
+		}
+	}
+
+	return set.all[0], nil
+}
+
+// A server TLS config that always presents the store's current certificates
+func (store *CertificateStore) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: store.GetCertificate,
+	}
+}
+
+/**
+ *
+ * Reloads when any cert/key file changes (polled every 'interval') or when
+ * the process receives SIGHUP. Blocks until 'ctx' is done.
+ *
+ **/
+func (store *CertificateStore) Watch(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = kDefaultCertPollInterval
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-hup:
+			store.reload("SIGHUP")
+
+		case <-ticker.C:
+			if store.changed() {
+				store.reload("file change")
+			}
+		}
+	}
+}
+
+func (store *CertificateStore) reload(reason string) {
+	if err := store.Reload(); err != nil {
+		slog.Error("Certificate reload failed; keeping current certificates", "reason", reason, "error", err)
+		return
+	}
+
+	slog.Info("Certificates reloaded", "reason", reason, "count", len(store.pairs))
+}
+
+func (store *CertificateStore) changed() bool {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	for file, seen := range store.modTime {
+		if !fileModTime(file).Equal(seen) {
+			return true
+		}
+	}
+
+	return false
+}
+
+/**
+ *
+ * Helpers
+ *
+ **/
+
+func loadCertificate(pair CertificatePair) (*tls.Certificate, error) {
+	// LoadX509KeyPair verifies the private key matches the certificate
+	cert, err := tls.LoadX509KeyPair(pair.Certificate, pair.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load key pair '%s' - %w", pair.Certificate, err)
+	}
+
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return nil, fmt.Errorf("failed to parse certificate '%s' - %w", pair.Certificate, err)
+		}
+	}
+
+	if time.Now().After(cert.Leaf.NotAfter) {
+		return nil, fmt.Errorf("certificate '%s' expired at %s", pair.Certificate, cert.Leaf.NotAfter.Format(time.RFC3339))
+	}
+
+	return &cert, nil
+}
+
+func certificateNames(leaf *x509.Certificate) []string {
+	names := make([]string, 0, len(leaf.DNSNames)+1)
+	for _, name := range leaf.DNSNames {
+		names = append(names, strings.ToLower(name))
+	}
+
+	if len(names) == 0 && leaf.Subject.CommonName != "" {
+		names = append(names, strings.ToLower(leaf.Subject.CommonName))
+	}
+
+	return names
+}
+
+func warnOnExpiry(leaf *x509.Certificate) {
+	if remaining := time.Until(leaf.NotAfter); remaining < kCertExpiryWarning {
+		slog.Warn("Certificate expires soon",
+			"subject", leaf.Subject.CommonName,
+			"expires", leaf.NotAfter.Format(time.RFC3339),
+			"remaining", remaining.Round(time.Hour).String())
+	}
+}
+
+func fileModTime(file string) time.Time {
+	info, err := os.Stat(file)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return info.ModTime()
+}
@@ -0,0 +1,136 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package web
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/test"
+)
+
+func writeCertificate(t *testing.T, dir, name string, notAfter time.Time, dnsNames ...string) CertificatePair {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	test.NoError(t, err, "generate key")
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	test.NoError(t, err, "create certificate")
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	test.NoError(t, err, "marshal key")
+
+	pair := CertificatePair{
+		Certificate: filepath.Join(dir, name+".crt"),
+		Key:         filepath.Join(dir, name+".key"),
+	}
+
+	test.NoError(t, os.WriteFile(pair.Certificate, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600), "write cert")
+	test.NoError(t, os.WriteFile(pair.Key, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600), "write key")
+
+	return pair
+}
+
+func servedName(t *testing.T, store *CertificateStore, serverName string) string {
+	cert, err := store.GetCertificate(&tls.ClientHelloInfo{ServerName: serverName})
+	test.NoError(t, err, "get certificate")
+	return cert.Leaf.Subject.CommonName
+}
+
+func TestCertificateSNI(t *testing.T) {
+	dir := t.TempDir()
+	expires := time.Now().Add(90 * 24 * time.Hour)
+
+	store, err := NewCertificateStore(
+		writeCertificate(t, dir, "main", expires, "hockey.example"),
+		writeCertificate(t, dir, "wild", expires, "*.rinks.example"),
+		writeCertificate(t, dir, "api", expires, "api.hockey.example"),
+	)
+	test.NoError(t, err, "load store")
+
+	cases := []struct {
+		serverName string
+		expected   string
+	}{
+		{"hockey.example", "hockey.example"},
+		{"API.hockey.example.", "api.hockey.example"},
+		{"north.rinks.example", "*.rinks.example"},
+		{"deep.north.rinks.example", "hockey.example"},
+		{"unknown.example", "hockey.example"},
+		{"", "hockey.example"},
+	}
+
+	for _, c := range cases {
+		test.Expect(t, c.expected, servedName(t, store, c.serverName), "certificate for '"+c.serverName+"'")
+	}
+}
+
+func TestCertificateReload(t *testing.T) {
+	dir := t.TempDir()
+	expires := time.Now().Add(90 * 24 * time.Hour)
+
+	pair := writeCertificate(t, dir, "main", expires, "old.example")
+	store, err := NewCertificateStore(pair)
+	test.NoError(t, err, "load store")
+	test.Require(t, !store.changed(), "no change right after load")
+
+	// A renewal is detected and swapped in
+	renewed := writeCertificate(t, dir, "main", expires, "new.example")
+	future := time.Now().Add(time.Minute)
+	test.NoError(t, os.Chtimes(renewed.Certificate, future, future), "touch cert")
+	test.Require(t, store.changed(), "change detected")
+
+	test.NoError(t, store.Reload(), "reload")
+	test.Expect(t, "new.example", servedName(t, store, ""), "renewed certificate served")
+
+	// A mismatched key is rejected and the current certificate kept
+	other := writeCertificate(t, dir, "other", expires, "other.example")
+	keyPEM, err := os.ReadFile(other.Key)
+	test.NoError(t, err, "read other key")
+	test.NoError(t, os.WriteFile(pair.Key, keyPEM, 0600), "swap in wrong key")
+
+	test.AnyError(t, store.Reload(), "reload with mismatched key")
+	test.Expect(t, "new.example", servedName(t, store, ""), "previous certificate kept")
+
+	// So is an expired certificate
+	writeCertificate(t, dir, "main", time.Now().Add(-time.Minute), "expired.example")
+	test.AnyError(t, store.Reload(), "reload with expired certificate")
+	test.Expect(t, "new.example", servedName(t, store, ""), "previous certificate kept")
+}
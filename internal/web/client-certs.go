@@ -0,0 +1,217 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package web
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"shiftylogic.dev/hockey-tools/internal/helpers"
+)
+
+const (
+	ClientCertContextKey = "sl.client_cert"
+
+	// Client certificates are verified when presented, but only the routes
+	// in ClientCertOptions.Routes demand one.
+	ClientAuthRequest = "request"
+
+	// The TLS handshake fails without a valid client certificate
+	ClientAuthRequire = "require"
+
+	ClientCertUser   = "user"
+	ClientCertClient = "client"
+)
+
+/**
+ *
+ * Mutual TLS for devices that can hold a certificate but not run an OAuth
+ * flow. Verified certificates are mapped (by fingerprint or common name)
+ * to a user, which becomes the request subject, or to a client, which is
+ * stored separately so the two can't be confused.
+ *
+ **/
+
+type ClientCertMapping struct {
+	CommonName  string // matched when Fingerprint is empty
+	Fingerprint string // SHA-256 of the DER certificate, hex
+	Kind        string // ClientCertUser or ClientCertClient
+	Subject     string // user or client ID
+}
+
+type ClientCertOptions struct {
+	CAFile   string
+	Mode     string
+	Routes   []string
+	Mappings []ClientCertMapping
+}
+
+type ClientCertificate struct {
+	Subject     string // distinguished name of the verified certificate
+	CommonName  string
+	Fingerprint string
+	Serial      string
+
+	// Set when the certificate maps to a known user or client
+	Kind   string
+	Mapped string
+}
+
+func ClientCertFromContext(ctx context.Context) (ClientCertificate, bool) {
+	cert, ok := ctx.Value(ClientCertContextKey).(ClientCertificate)
+	return cert, ok
+}
+
+// Configures the server's TLS listener to verify client certificates
+// against the CA bundle. Must follow the option that enables TLS.
+func WithClientAuth(caFile, mode string) ServerOptionFunc {
+	var auth tls.ClientAuthType
+	switch mode {
+	case ClientAuthRequest, "":
+		auth = tls.VerifyClientCertIfGiven
+	case ClientAuthRequire:
+		auth = tls.RequireAndVerifyClientCert
+	default:
+		panic("unknown client auth mode: " + mode)
+	}
+
+	return func(s *Server) {
+		if s.TLSConfig == nil {
+			helpers.Fatal("Client certificate auth requires TLS")
+		}
+
+		pool, err := loadCertPool(caFile)
+		if err != nil {
+			helpers.Fatal("Failed to load client CA bundle", "file", caFile, "error", err)
+		}
+
+		s.TLSConfig.ClientCAs = pool
+		s.TLSConfig.ClientAuth = auth
+	}
+}
+
+func WithClientCertificates(opts ClientCertOptions) RouterOptionFunc {
+	return func(r Router) {
+		r.Use(ClientCertificates(opts))
+	}
+}
+
+func ClientCertificates(opts ClientCertOptions) func(next http.Handler) http.Handler {
+	byFingerprint := make(map[string]ClientCertMapping)
+	byName := make(map[string]ClientCertMapping)
+	for _, m := range opts.Mappings {
+		if m.Fingerprint != "" {
+			byFingerprint[normalizeFingerprint(m.Fingerprint)] = m
+		} else if m.CommonName != "" {
+			byName[m.CommonName] = m
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var cert ClientCertificate
+			var found bool
+
+			if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+				cert, found = describeCertificate(r.TLS.VerifiedChains[0][0]), true
+
+				m, ok := byFingerprint[cert.Fingerprint]
+				if !ok {
+					m, ok = byName[cert.CommonName]
+				}
+
+				if ok {
+					cert.Kind, cert.Mapped = m.Kind, m.Subject
+				}
+			}
+
+			if matchesAnyRoute(opts.Routes, r.URL.Path) && cert.Mapped == "" {
+				status := http.StatusUnauthorized
+				if found {
+					status = http.StatusForbidden
+				}
+
+				http.Error(w, http.StatusText(status), status)
+				return
+			}
+
+			if found {
+				ctx := context.WithValue(r.Context(), ClientCertContextKey, cert)
+				switch {
+				case cert.Mapped == "":
+				case cert.Kind == ClientCertClient:
+					ctx = helpers.ContextWithClient(ctx, cert.Mapped)
+				default:
+					ctx = helpers.ContextWithSubject(ctx, cert.Mapped)
+				}
+
+				r = r.WithContext(ctx)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+/**
+ *
+ * Helpers
+ *
+ **/
+
+func describeCertificate(leaf *x509.Certificate) ClientCertificate {
+	sum := sha256.Sum256(leaf.Raw)
+
+	return ClientCertificate{
+		Subject:     leaf.Subject.String(),
+		CommonName:  leaf.Subject.CommonName,
+		Fingerprint: hex.EncodeToString(sum[:]),
+		Serial:      leaf.SerialNumber.Text(16),
+	}
+}
+
+// Accepts the common "AB:CD:..." rendering as well as plain hex
+func normalizeFingerprint(fp string) string {
+	return strings.ToLower(strings.ReplaceAll(fp, ":", ""))
+}
+
+func loadCertPool(file string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in '%s'", file)
+	}
+
+	return pool, nil
+}
@@ -0,0 +1,157 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package web
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/helpers"
+	"shiftylogic.dev/hockey-tools/internal/test"
+)
+
+func issueCertificate(t *testing.T, cn string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (tls.Certificate, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	test.NoError(t, err, "generate key")
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn, Organization: []string{"Rinks"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	test.NoError(t, err, "create certificate")
+
+	leaf, err := x509.ParseCertificate(der)
+	test.NoError(t, err, "parse certificate")
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, leaf
+}
+
+func TestClientCertificates(t *testing.T) {
+	caCert, ca := issueCertificate(t, "Rink CA", nil, nil)
+	caKey := caCert.PrivateKey.(*ecdsa.PrivateKey)
+
+	scoreboard, scoreboardLeaf := issueCertificate(t, "scoreboard-1", ca, caKey)
+	clock, _ := issueCertificate(t, "clock-1", ca, caKey)
+	coach, _ := issueCertificate(t, "coach-1", ca, caKey)
+	stranger, _ := issueCertificate(t, "stranger", ca, caKey)
+	rogueCA, rogue := issueCertificate(t, "Rogue CA", nil, nil)
+	impostor, _ := issueCertificate(t, "clock-1", rogue, rogueCA.PrivateKey.(*ecdsa.PrivateKey))
+
+	sum := sha256.Sum256(scoreboardLeaf.Raw)
+	opts := ClientCertOptions{
+		Routes: []string{"/devices/*"},
+		Mappings: []ClientCertMapping{
+			{Fingerprint: hex.EncodeToString(sum[:]), Kind: ClientCertClient, Subject: "scoreboard"},
+			{CommonName: "clock-1", Kind: ClientCertClient, Subject: "clock"},
+			{CommonName: "coach-1", Kind: ClientCertUser, Subject: "clock"},
+		},
+	}
+
+	handler := ClientCertificates(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		subject, _ := helpers.SubjectFromContext(r.Context())
+		client, _ := helpers.ClientFromContext(r.Context())
+		cert, _ := ClientCertFromContext(r.Context())
+		w.Header().Set("X-Subject", subject)
+		w.Header().Set("X-Client", client)
+		w.Header().Set("X-Cert-CN", cert.CommonName)
+	}))
+
+	server := httptest.NewUnstartedServer(handler)
+	server.TLS = &tls.Config{
+		ClientCAs:  x509.NewCertPool(),
+		ClientAuth: tls.VerifyClientCertIfGiven,
+	}
+	server.TLS.ClientCAs.AddCert(ca)
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	cases := []struct {
+		name    string
+		cert    *tls.Certificate
+		path    string
+		status  int
+		subject string
+		client  string
+		fails   bool
+	}{
+		{"mapped by fingerprint", &scoreboard, "/devices/score", http.StatusOK, "", "scoreboard", false},
+		{"mapped by common name", &clock, "/devices/clock", http.StatusOK, "", "clock", false},
+		{"mapped to a user", &coach, "/devices/clock", http.StatusOK, "clock", "", false},
+		{"verified but unmapped", &stranger, "/devices/score", http.StatusForbidden, "", "", false},
+		{"unmapped on open route", &stranger, "/open", http.StatusOK, "", "", false},
+		{"no certificate", nil, "/devices/score", http.StatusUnauthorized, "", "", false},
+		{"no certificate on open route", nil, "/open", http.StatusOK, "", "", false},
+		{"untrusted issuer", &impostor, "/open", 0, "", "", true},
+	}
+
+	for _, c := range cases {
+		transport := server.Client().Transport.(*http.Transport).Clone()
+		if c.cert != nil {
+			// Always present the certificate, even if the server would not accept its issuer
+			cert := c.cert
+			transport.TLSClientConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+				return cert, nil
+			}
+		}
+
+		client := &http.Client{Transport: transport}
+		resp, err := client.Get(server.URL + c.path)
+		if c.fails {
+			test.AnyError(t, err, c.name)
+			continue
+		}
+
+		test.NoError(t, err, c.name)
+		resp.Body.Close()
+
+		test.Expect(t, c.status, resp.StatusCode, c.name+" status")
+		test.Expect(t, c.subject, resp.Header.Get("X-Subject"), c.name+" subject")
+		test.Expect(t, c.client, resp.Header.Get("X-Client"), c.name+" client")
+	}
+}
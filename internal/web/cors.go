@@ -23,6 +23,8 @@
 package web
 
 import (
+	"net/http"
+
 	"github.com/go-chi/cors"
 )
 
@@ -41,6 +43,10 @@ var (
 
 func WithCors(options CorsOptions) RouterOptionFunc {
 	return func(r Router) {
-		r.Use(cors.Handler(options))
+		r.Use(Cors(options))
 	}
 }
+
+func Cors(options CorsOptions) func(next http.Handler) http.Handler {
+	return cors.Handler(options)
+}
@@ -0,0 +1,130 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package web
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/health"
+)
+
+const (
+	kLivenessPath  = "/healthz"
+	kReadinessPath = "/readyz"
+)
+
+/**
+ *
+ * Serves the health registry's liveness and readiness reports (in the
+ * manner of middleware.Heartbeat). Add it early so probes are not subject
+ * to logging, throttling or load shedding.
+ *
+ * WithHealth only answers with the overall status, which is all a probe
+ * needs; WithHealthDetail adds each check's result and error and belongs
+ * on the admin listener.
+ *
+ **/
+func WithHealth(reg *health.Registry) RouterOptionFunc {
+	return func(r Router) {
+		r.Use(Health(reg, kLivenessPath, kReadinessPath))
+	}
+}
+
+func WithHealthDetail(reg *health.Registry) RouterOptionFunc {
+	return func(r Router) {
+		r.Use(HealthDetail(reg, kLivenessPath, kReadinessPath))
+	}
+}
+
+func Health(reg *health.Registry, liveness, readiness string) func(next http.Handler) http.Handler {
+	return probes(liveness, reg.StatusHandler(health.Liveness), readiness, reg.StatusHandler(health.Readiness))
+}
+
+func HealthDetail(reg *health.Registry, liveness, readiness string) func(next http.Handler) http.Handler {
+	return probes(liveness, reg.Handler(health.Liveness), readiness, reg.Handler(health.Readiness))
+}
+
+func probes(liveness string, live http.Handler, readiness string, ready http.Handler) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet || r.Method == http.MethodHead {
+				switch r.URL.Path {
+				case liveness:
+					live.ServeHTTP(w, r)
+					return
+				case readiness:
+					ready.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+/**
+ *
+ * Fails once any certificate the server would present has expired (or is
+ * not yet valid), or if none are loaded at all.
+ *
+ **/
+func CertificateCheck(certificates func() []*tls.Certificate) health.Check {
+	return func(ctx context.Context) error {
+		certs := certificates()
+		if len(certs) == 0 {
+			return errors.New("no certificates loaded")
+		}
+
+		now := time.Now()
+		for _, cert := range certs {
+			leaf := cert.Leaf
+			if leaf == nil {
+				if len(cert.Certificate) == 0 {
+					return errors.New("empty certificate chain")
+				}
+
+				var err error
+				if leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+					return err
+				}
+			}
+
+			if now.Before(leaf.NotBefore) {
+				return fmt.Errorf("certificate '%s' not valid until %s", leaf.Subject.CommonName, leaf.NotBefore.Format(time.RFC3339))
+			}
+
+			if now.After(leaf.NotAfter) {
+				return fmt.Errorf("certificate '%s' expired at %s", leaf.Subject.CommonName, leaf.NotAfter.Format(time.RFC3339))
+			}
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,268 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package web
+
+import (
+	"container/list"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/**
+ *
+ * Concurrency limiting with a bounded priority queue. Up to MaxInFlight
+ * requests run at once; the rest wait in a queue of at most MaxQueue
+ * entries and are admitted highest priority first (FIFO within a class).
+ *
+ * Load is shed with a 503 + Retry-After when:
+ *   - the queue is full and nothing of lower priority is waiting (a higher
+ *     priority arrival displaces the newest lowest priority waiter),
+ *   - a low priority request arrives and the queue is already half full,
+ *   - a request waits longer than QueueTimeout.
+ *
+ **/
+
+type Priority int
+
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+
+	kPriorityCount = 3
+)
+
+const (
+	kDefaultMaxInFlight  = 100
+	kDefaultQueueTimeout = 5 * time.Second
+	kDefaultRetryAfter   = 5 * time.Second
+)
+
+type ConcurrencyOptions struct {
+	MaxInFlight  int
+	MaxQueue     int
+	QueueTimeout time.Duration
+	RetryAfter   time.Duration
+	Classify     func(r *http.Request) Priority
+}
+
+type ConcurrencyStats struct {
+	InFlight int
+	Queued   [kPriorityCount]int
+	Shed     uint64
+}
+
+type waiter struct {
+	admit chan bool
+	elem  *list.Element
+}
+
+type ConcurrencyLimiter struct {
+	options  ConcurrencyOptions
+	mu       sync.Mutex
+	inFlight int
+	queued   int
+	queues   [kPriorityCount]*list.List
+	shed     atomic.Uint64
+}
+
+func NewConcurrencyLimiter(options ConcurrencyOptions) *ConcurrencyLimiter {
+	if options.MaxInFlight <= 0 {
+		options.MaxInFlight = kDefaultMaxInFlight
+	}
+
+	if options.QueueTimeout <= 0 {
+		options.QueueTimeout = kDefaultQueueTimeout
+	}
+
+	if options.RetryAfter <= 0 {
+		options.RetryAfter = kDefaultRetryAfter
+	}
+
+	if options.Classify == nil {
+		options.Classify = func(r *http.Request) Priority { return PriorityNormal }
+	}
+
+	l := &ConcurrencyLimiter{options: options}
+	for i := range l.queues {
+		l.queues[i] = list.New()
+	}
+
+	return l
+}
+
+func WithConcurrencyLimit(limiter *ConcurrencyLimiter) RouterOptionFunc {
+	limiter.registerMetrics()
+
+	return func(r Router) {
+		r.Use(limiter.Handler)
+	}
+}
+
+func (l *ConcurrencyLimiter) Handler(next http.Handler) http.Handler {
+	retryAfter := strconv.Itoa(int((l.options.RetryAfter + time.Second - 1) / time.Second))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !l.acquire(r, l.options.Classify(r)) {
+			l.shed.Add(1)
+			w.Header().Set("Retry-After", retryAfter)
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+		defer l.release()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (l *ConcurrencyLimiter) QueueDepth() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.queued
+}
+
+func (l *ConcurrencyLimiter) Stats() ConcurrencyStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := ConcurrencyStats{
+		InFlight: l.inFlight,
+		Shed:     l.shed.Load(),
+	}
+
+	for i, q := range l.queues {
+		stats.Queued[i] = q.Len()
+	}
+
+	return stats
+}
+
+func (l *ConcurrencyLimiter) acquire(r *http.Request, prio Priority) bool {
+	prio = min(max(prio, PriorityLow), PriorityHigh)
+
+	l.mu.Lock()
+
+	if l.inFlight < l.options.MaxInFlight && l.queued == 0 {
+		l.inFlight++
+		l.mu.Unlock()
+		return true
+	}
+
+	if !l.makeRoom(prio) {
+		l.mu.Unlock()
+		return false
+	}
+
+	w := &waiter{admit: make(chan bool, 1)}
+	w.elem = l.queues[prio].PushBack(w)
+	l.queued++
+	l.mu.Unlock()
+
+	timer := time.NewTimer(l.options.QueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case ok := <-w.admit:
+		return ok
+	case <-timer.C:
+	case <-r.Context().Done():
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Admitted (or displaced) while timing out; honour the decision already made
+	if w.elem == nil {
+		return <-w.admit
+	}
+
+	l.queues[prio].Remove(w.elem)
+	w.elem = nil
+	l.queued--
+	return false
+}
+
+// Called with the lock held. Reports whether a request of priority 'prio'
+// may join the queue, displacing a lower priority waiter if necessary.
+func (l *ConcurrencyLimiter) makeRoom(prio Priority) bool {
+	if prio == PriorityLow && l.queued*2 >= l.options.MaxQueue {
+		return false
+	}
+
+	if l.queued < l.options.MaxQueue {
+		return true
+	}
+
+	for p := PriorityLow; p < prio; p++ {
+		if back := l.queues[p].Back(); back != nil {
+			victim := l.queues[p].Remove(back).(*waiter)
+			victim.elem = nil
+			victim.admit <- false
+			l.queued--
+			return true
+		}
+	}
+
+	return false
+}
+
+func (l *ConcurrencyLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Hand the slot straight to the highest priority waiter, if any
+	for p := PriorityHigh; p >= PriorityLow; p-- {
+		if front := l.queues[p].Front(); front != nil {
+			w := l.queues[p].Remove(front).(*waiter)
+			w.elem = nil
+			w.admit <- true
+			l.queued--
+			return
+		}
+	}
+
+	l.inFlight--
+}
+
+/**
+ *
+ * Builds a classifier from route patterns (see matchesRoute). Anything not
+ * listed is PriorityNormal.
+ *
+ **/
+func ClassifyByRoute(high, low []string) func(r *http.Request) Priority {
+	return func(r *http.Request) Priority {
+		switch {
+		case matchesAnyRoute(high, r.URL.Path):
+			return PriorityHigh
+		case matchesAnyRoute(low, r.URL.Path):
+			return PriorityLow
+		default:
+			return PriorityNormal
+		}
+	}
+}
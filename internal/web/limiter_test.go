@@ -0,0 +1,90 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package web
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/test"
+)
+
+func TestConcurrencyLimiterPriorities(t *testing.T) {
+	l := NewConcurrencyLimiter(ConcurrencyOptions{
+		MaxInFlight:  1,
+		MaxQueue:     2,
+		QueueTimeout: time.Second,
+	})
+
+	r := httptest.NewRequest("GET", "/", nil)
+	test.Require(t, l.acquire(r, PriorityNormal), "first request should run immediately")
+
+	results := make(chan Priority, 4)
+	enqueue := func(prio Priority, depth int) {
+		go func() {
+			if l.acquire(r, prio) {
+				results <- prio
+				l.release()
+			} else {
+				results <- -1
+			}
+		}()
+
+		// Wait until the request is queued before moving on
+		for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+			if l.QueueDepth() == depth {
+				break
+			}
+		}
+	}
+
+	enqueue(PriorityNormal, 1)
+	test.Expect(t, 1, l.QueueDepth(), "normal request queued")
+
+	// Queue is half full, so low priority work is shed immediately
+	test.Require(t, !l.acquire(r, PriorityLow), "low priority should be shed")
+
+	enqueue(PriorityNormal, 2)
+	test.Expect(t, 2, l.QueueDepth(), "queue full")
+
+	// A full queue with nothing of lower priority sheds equal priority work...
+	test.Require(t, !l.acquire(r, PriorityNormal), "normal priority should be shed when full")
+
+	// ...but high priority work displaces the newest normal waiter
+	go func() {
+		if l.acquire(r, PriorityHigh) {
+			results <- PriorityHigh
+			l.release()
+		}
+	}()
+	test.Expect(t, Priority(-1), <-results, "newest normal waiter displaced")
+
+	l.release()
+	test.Expect(t, PriorityHigh, <-results, "high priority admitted first")
+	test.Expect(t, PriorityNormal, <-results, "normal priority admitted next")
+
+	stats := l.Stats()
+	test.Expect(t, 0, stats.InFlight, "nothing in flight")
+	test.Expect(t, 0, l.QueueDepth(), "queue drained")
+}
@@ -0,0 +1,195 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package web
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+
+	"shiftylogic.dev/hockey-tools/internal/helpers"
+)
+
+const (
+	ListenerRedirect = "redirect"
+	ListenerAdmin    = "admin"
+	ListenerUnix     = "unix"
+)
+
+/**
+ *
+ * Listeners beyond the main Address:Port one. Each gets its own
+ * http.Server (sharing the main server's timeouts), and all of them are
+ * shut down together when a termination signal arrives.
+ *
+ **/
+
+type Listener struct {
+	Name    string
+	Network string // "tcp" or "unix"
+	Address string // host:port, or the socket path for "unix"
+	Handler http.Handler
+	TLS     bool // use the main server's TLS config
+}
+
+func WithListener(l Listener) ServerOptionFunc {
+	return func(s *Server) {
+		s.Listeners = append(s.Listeners, l)
+	}
+}
+
+// A plain HTTP listener that only redirects to the main HTTPS listener
+// (and answers ACME HTTP-01 challenges when ACME is enabled)
+func WithRedirectListener(addr string) ServerOptionFunc {
+	return WithListener(Listener{Name: ListenerRedirect, Network: "tcp", Address: addr})
+}
+
+func WithUnixListener(path string, handler http.Handler) ServerOptionFunc {
+	return WithListener(Listener{Name: ListenerUnix, Network: "unix", Address: path, Handler: handler})
+}
+
+func startListeners(server *Server) {
+	hasRedirect := false
+	for _, l := range server.Listeners {
+		hasRedirect = hasRedirect || l.Name == ListenerRedirect
+	}
+
+	if server.ACMEHTTPAddress != "" && !hasRedirect {
+		server.Listeners = append(server.Listeners, Listener{Name: ListenerRedirect, Network: "tcp", Address: server.ACMEHTTPAddress})
+	}
+
+	for _, l := range server.Listeners {
+		handler := l.Handler
+		if l.Name == ListenerRedirect {
+			handler = RedirectToHTTPS(server.Addr)
+			if server.ACME != nil {
+				handler = server.ACME.HTTPHandler(handler)
+			}
+		}
+
+		// Proxies may reach us over the socket instead of TCP, so it has
+		// to speak the same protocols.
+		if server.H2C && l.Name == ListenerUnix && !l.TLS {
+			handler = withH2C(server, handler)
+		}
+
+		srv := &http.Server{
+			Addr:              l.Address,
+			Handler:           handler,
+			MaxHeaderBytes:    server.MaxHeaderBytes,
+			IdleTimeout:       server.IdleTimeout,
+			ReadTimeout:       server.ReadTimeout,
+			ReadHeaderTimeout: server.ReadHeaderTimeout,
+			WriteTimeout:      server.WriteTimeout,
+			ErrorLog:          server.ErrorLog,
+		}
+
+		ln, err := listen(l)
+		if err != nil {
+			helpers.Fatal("Listener failed", "name", l.Name, "address", l.Address, "error", err)
+		}
+
+		if l.TLS {
+			if server.TLSConfig == nil {
+				helpers.Fatal("Listener requires TLS", "name", l.Name)
+			}
+
+			ln = tls.NewListener(ln, server.TLSConfig)
+		}
+
+		server.extra = append(server.extra, srv)
+
+		slog.Info("Launching listener", "name", l.Name, "network", l.Network, "address", l.Address)
+		go func() {
+			if err := srv.Serve(ln); err != http.ErrServerClosed {
+				helpers.Fatal("Listener failed", "name", l.Name, "error", err)
+			}
+		}()
+	}
+}
+
+func listen(l Listener) (net.Listener, error) {
+	if l.Network != "unix" {
+		return net.Listen("tcp", l.Address)
+	}
+
+	// A socket left behind by an unclean exit would block the bind. Anything
+	// else at that path is left alone.
+	info, err := os.Lstat(l.Address)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, err
+	case info.Mode()&fs.ModeSocket == 0:
+		return nil, fmt.Errorf("'%s' exists and is not a socket", l.Address)
+	default:
+		if err := os.Remove(l.Address); err != nil {
+			return nil, err
+		}
+	}
+
+	ln, err := net.Listen("unix", l.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	// Close removes the socket file on shutdown
+	ln.(*net.UnixListener).SetUnlinkOnClose(true)
+	return ln, nil
+}
+
+// Redirects to the same host and path on the HTTPS listener at 'addr'
+func RedirectToHTTPS(addr string) http.Handler {
+	port := ""
+	if _, p, err := net.SplitHostPort(addr); err == nil && p != "443" && p != "" {
+		port = p
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+
+		if port != "" {
+			host = net.JoinHostPort(host, port)
+		}
+
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, redirectStatus(r.Method))
+	})
+}
+
+// Non-idempotent requests keep their method and body with a 308
+func redirectStatus(method string) int {
+	if method == http.MethodGet || method == http.MethodHead {
+		return http.StatusMovedPermanently
+	}
+
+	return http.StatusPermanentRedirect
+}
@@ -0,0 +1,127 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package web
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/net/http2"
+
+	"shiftylogic.dev/hockey-tools/internal/test"
+)
+
+func TestRedirectToHTTPS(t *testing.T) {
+	cases := []struct {
+		addr     string
+		method   string
+		target   string
+		location string
+		status   int
+	}{
+		{":443", http.MethodGet, "http://rink.example/scores?game=7", "https://rink.example/scores?game=7", http.StatusMovedPermanently},
+		{":8443", http.MethodGet, "http://rink.example:8080/", "https://rink.example:8443/", http.StatusMovedPermanently},
+		{"0.0.0.0:443", http.MethodPost, "http://rink.example/token", "https://rink.example/token", http.StatusPermanentRedirect},
+	}
+
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		RedirectToHTTPS(c.addr).ServeHTTP(rec, httptest.NewRequest(c.method, c.target, nil))
+
+		test.Expect(t, c.status, rec.Code, "redirect status for "+c.target)
+		test.Expect(t, c.location, rec.Header().Get("Location"), "redirect location for "+c.target)
+	}
+}
+
+func TestUnixListener(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "hockey.sock")
+
+	// Anything other than a socket at the path is left alone
+	test.NoError(t, os.WriteFile(socket, []byte("keep"), 0600), "regular file")
+	_, err := listen(Listener{Network: "unix", Address: socket})
+	test.AnyError(t, err, "refuses to replace a regular file")
+	contents, _ := os.ReadFile(socket)
+	test.Expect(t, "keep", string(contents), "regular file untouched")
+	test.NoError(t, os.Remove(socket), "remove regular file")
+
+	// A stale socket file must not prevent the bind
+	stale, err := net.Listen("unix", socket)
+	test.NoError(t, err, "stale socket")
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	server := makeDefaultServer()
+	WithH2C()(server)
+	WithUnixListener(socket, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "over unix ", r.Proto)
+	}))(server)
+
+	startListeners(server)
+	test.Expect(t, 1, len(server.extra), "listener count")
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+
+	resp, err := client.Get("http://unix/")
+	test.NoError(t, err, "request over socket")
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	test.Expect(t, "over unix HTTP/1.1", string(body), "response body")
+	client.CloseIdleConnections()
+
+	// h2c applies to the socket as it does to the main listener
+	h2client := &http.Client{
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, _, _ string, _ *tls.Config) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+
+	resp, err = h2client.Get("http://unix/")
+	test.NoError(t, err, "h2c request over socket")
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	test.Expect(t, "over unix HTTP/2.0", string(body), "h2c response body")
+	h2client.CloseIdleConnections()
+	test.NoError(t, server.extra[0].Shutdown(context.Background()), "shutdown")
+
+	_, err = os.Stat(socket)
+	test.Require(t, errors.Is(err, fs.ErrNotExist), "socket removed on shutdown")
+}
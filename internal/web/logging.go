@@ -0,0 +1,172 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package web
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"shiftylogic.dev/hockey-tools/internal/helpers"
+	"shiftylogic.dev/hockey-tools/internal/trace"
+)
+
+const (
+	RequestIDContextKey = "sl.request_id"
+
+	kHeaderRequestID    = "X-Request-ID"
+	kRequestIDSize      = 20
+	kMaxRequestIDLength = 64
+)
+
+/**
+ *
+ * Request IDs. An incoming X-Request-ID (e.g. from a proxy) is reused when it
+ * looks sane; otherwise a new one is generated. Either way the ID is put in
+ * the request context and echoed back in the response header.
+ *
+ **/
+
+func WithRequestID() RouterOptionFunc {
+	return func(r Router) {
+		r.Use(RequestID)
+	}
+}
+
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(kHeaderRequestID)
+		if !validRequestID(id) {
+			var err error
+			if id, err = helpers.GenerateStringSecure(kRequestIDSize, helpers.AlphaNumeric); err != nil {
+				slog.ErrorContext(r.Context(), "Failed to generate request ID", "error", err)
+				id = ""
+			}
+		}
+
+		if id != "" {
+			w.Header().Set(kHeaderRequestID, id)
+			r = r.WithContext(context.WithValue(r.Context(), RequestIDContextKey, id))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(RequestIDContextKey).(string)
+	return id
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > kMaxRequestIDLength {
+		return false
+	}
+
+	for _, c := range id {
+		if c <= ' ' || c > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+/**
+ *
+ * A slog.Handler wrapper that adds the request ID (and trace / span IDs when
+ * tracing) from the context to every record, so any *Context logging call
+ * made while serving a request can be correlated with it.
+ *
+ **/
+
+type contextHandler struct {
+	slog.Handler
+}
+
+func NewContextHandler(h slog.Handler) slog.Handler {
+	return &contextHandler{h}
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestIDFromContext(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+
+	if sc := trace.SpanFromContext(ctx).SpanContext(); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID.String()), slog.String("span_id", sc.SpanID.String()))
+	}
+
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{h.Handler.WithGroup(name)}
+}
+
+/**
+ *
+ * Access logging
+ *
+ **/
+
+func AccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		defer func() {
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			level := slog.LevelInfo
+			switch {
+			case status >= 500:
+				level = slog.LevelError
+			case status >= 400:
+				level = slog.LevelWarn
+			}
+
+			slog.LogAttrs(r.Context(), level, "HTTP request",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("proto", r.Proto),
+				slog.Int("status", status),
+				slog.Int("bytes", ww.BytesWritten()),
+				slog.Duration("duration", time.Since(start)),
+				slog.String("remote", r.RemoteAddr),
+			)
+		}()
+
+		next.ServeHTTP(ww, r)
+	})
+}
@@ -0,0 +1,72 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"shiftylogic.dev/hockey-tools/internal/test"
+)
+
+func TestRequestIDPropagation(t *testing.T) {
+	var out bytes.Buffer
+	logger := slog.New(NewContextHandler(slog.NewJSONHandler(&out, nil)))
+
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.InfoContext(r.Context(), "handled")
+	}))
+
+	cases := []struct {
+		incoming string
+		reused   bool
+	}{
+		{"", false},
+		{"abc-123", true},
+		{"has space", false},
+	}
+
+	for _, c := range cases {
+		out.Reset()
+
+		r := httptest.NewRequest("GET", "/", nil)
+		if c.incoming != "" {
+			r.Header.Set(kHeaderRequestID, c.incoming)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		id := w.Header().Get(kHeaderRequestID)
+		test.Require(t, id != "", "response should carry a request ID")
+		test.Expect(t, c.reused, id == c.incoming, "reuse of incoming ID '"+c.incoming+"'")
+
+		var record map[string]any
+		test.NoError(t, json.Unmarshal(out.Bytes(), &record), "log record should be JSON")
+		test.Expect(t, id, record["request_id"], "log record request ID")
+	}
+}
@@ -0,0 +1,135 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package web
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	"shiftylogic.dev/hockey-tools/internal/metrics"
+)
+
+const (
+	kMetricsPath    = "/metrics"
+	kUnmatchedRoute = "unmatched"
+)
+
+var (
+	httpRequests = metrics.NewCounterVec(
+		"http_requests_total",
+		"HTTP requests by route pattern, method and status code.",
+		"route", "method", "code")
+
+	httpDuration = metrics.NewHistogramVec(
+		"http_request_duration_seconds",
+		"HTTP request latency by route pattern and method.",
+		nil,
+		"route", "method")
+
+	httpInFlight = metrics.NewGaugeVec(
+		"http_requests_in_flight",
+		"HTTP requests admitted by the concurrency limiter and currently in a handler.")
+
+	httpQueued = metrics.NewGaugeVec(
+		"http_requests_queued",
+		"HTTP requests waiting in the concurrency limiter queue by priority.",
+		"priority")
+)
+
+/**
+ *
+ * Records per-route request counts and latencies, and serves the metrics
+ * registry at /metrics (in the manner of middleware.Heartbeat). Add it
+ * before other middleware so shed / throttled responses are counted too.
+ * The endpoint is unauthenticated, so keep it off public routers.
+ *
+ **/
+func WithMetrics() RouterOptionFunc {
+	return func(r Router) {
+		r.Use(Metrics(kMetricsPath))
+	}
+}
+
+// Records request metrics without serving them (e.g. when /metrics lives
+// on the admin listener instead)
+func WithRequestMetrics() RouterOptionFunc {
+	return func(r Router) {
+		r.Use(Metrics(""))
+	}
+}
+
+func Metrics(endpoint string) func(next http.Handler) http.Handler {
+	registry := metrics.Default.Handler()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if endpoint != "" && r.Method == http.MethodGet && r.URL.Path == endpoint {
+				registry.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			route := kUnmatchedRoute
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			httpRequests.With(route, r.Method, strconv.Itoa(status)).Inc()
+			httpDuration.With(route, r.Method).ObserveSince(start)
+		})
+	}
+}
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityHigh:
+		return "high"
+	default:
+		return "normal"
+	}
+}
+
+func (l *ConcurrencyLimiter) registerMetrics() {
+	metrics.Default.OnGather(func() {
+		stats := l.Stats()
+
+		httpInFlight.With().Set(float64(stats.InFlight))
+		for p, n := range stats.Queued {
+			httpQueued.With(Priority(p).String()).Set(float64(n))
+		}
+	})
+}
@@ -0,0 +1,118 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package web
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/quic-go/quic-go/http3"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	"shiftylogic.dev/hockey-tools/internal/helpers"
+)
+
+/**
+ *
+ * Optional protocols beyond HTTP/1.1 and (TLS-negotiated) HTTP/2.
+ *
+ * h2c serves HTTP/2 without TLS, for use behind a TLS-terminating proxy
+ * that speaks HTTP/2 to its upstreams. It only applies to plain listeners:
+ * the main one when it isn't serving TLS, and the unix socket listener.
+ *
+ * HTTP/3 runs a QUIC listener (UDP) next to the TLS one, sharing its
+ * certificates and handler. Responses over TCP advertise it via Alt-Svc
+ * so clients can switch on their next request.
+ *
+ **/
+
+func WithH2C() ServerOptionFunc {
+	return func(s *Server) {
+		s.H2C = true
+	}
+}
+
+// Listens for HTTP/3 on the given UDP address (the TCP address if empty)
+func WithHTTP3(addr string) ServerOptionFunc {
+	return func(s *Server) {
+		if addr == "" {
+			addr = s.Addr
+		}
+
+		s.HTTP3Address = addr
+	}
+}
+
+func configureProtocols(server *Server) {
+	if server.H2C {
+		if server.TLSConfig != nil {
+			slog.Warn("Ignoring h2c; TLS listeners negotiate HTTP/2 directly")
+		} else {
+			server.Handler = withH2C(server, server.Handler)
+		}
+	}
+
+	if server.HTTP3Address != "" {
+		if server.TLSConfig == nil {
+			helpers.Fatal("HTTP/3 requires TLS")
+		}
+
+		server.http3 = &http3.Server{
+			Addr:           server.HTTP3Address,
+			TLSConfig:      server.TLSConfig,
+			Handler:        server.Handler,
+			MaxHeaderBytes: server.MaxHeaderBytes,
+			IdleTimeout:    server.IdleTimeout,
+		}
+
+		server.Handler = AltSvc(server.http3)(server.Handler)
+		go startHTTP3(server.http3)
+	}
+}
+
+func withH2C(server *Server, handler http.Handler) http.Handler {
+	return h2c.NewHandler(handler, &http2.Server{
+		IdleTimeout: server.IdleTimeout,
+	})
+}
+
+// Advertises the HTTP/3 endpoint on every response
+func AltSvc(h3 *http3.Server) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ProtoMajor < 3 {
+				h3.SetQUICHeaders(w.Header())
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func startHTTP3(server *http3.Server) {
+	slog.Info("Launching HTTP/3 listener", "address", server.Addr)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		helpers.Fatal("HTTP/3 listener failed", "error", err)
+	}
+}
@@ -0,0 +1,111 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package web
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+	"golang.org/x/net/http2"
+
+	"shiftylogic.dev/hockey-tools/internal/test"
+)
+
+var protoHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprint(w, r.Proto)
+})
+
+func TestH2C(t *testing.T) {
+	server := &Server{Server: http.Server{Handler: protoHandler}, H2C: true}
+	configureProtocols(server)
+
+	ts := httptest.NewServer(server.Handler)
+	defer ts.Close()
+
+	client := &http.Client{
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, addr)
+			},
+		},
+	}
+
+	resp, err := client.Get(ts.URL)
+	test.NoError(t, err, "h2c request")
+	defer resp.Body.Close()
+
+	test.Expect(t, 2, resp.ProtoMajor, "h2c protocol")
+
+	// HTTP/1.1 clients are still served
+	resp, err = http.Get(ts.URL)
+	test.NoError(t, err, "http/1.1 request")
+	resp.Body.Close()
+	test.Expect(t, 1, resp.ProtoMajor, "fallback protocol")
+}
+
+func TestHTTP3(t *testing.T) {
+	ts := httptest.NewUnstartedServer(nil)
+	ts.StartTLS()
+	defer ts.Close()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	test.NoError(t, err, "listen udp")
+
+	h3 := &http3.Server{TLSConfig: ts.TLS.Clone(), Handler: protoHandler}
+	go h3.Serve(conn)
+	defer h3.Close()
+
+	ts.Config.Handler = AltSvc(h3)(protoHandler)
+	port := conn.LocalAddr().(*net.UDPAddr).Port
+
+	// Alt-Svc is advertised over TCP once the QUIC listener is up
+	var altSvc string
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		resp, err := ts.Client().Get(ts.URL)
+		test.NoError(t, err, "tcp request")
+		resp.Body.Close()
+
+		if altSvc = resp.Header.Get("Alt-Svc"); altSvc != "" {
+			break
+		}
+	}
+	test.Require(t, strings.Contains(altSvc, fmt.Sprintf(`h3=":%d"`, port)), "alt-svc advertises h3: "+altSvc)
+
+	transport := &http3.Transport{TLSClientConfig: ts.Client().Transport.(*http.Transport).TLSClientConfig}
+	defer transport.Close()
+
+	resp, err := (&http.Client{Transport: transport}).Get(fmt.Sprintf("https://127.0.0.1:%d/", port))
+	test.NoError(t, err, "http/3 request")
+	defer resp.Body.Close()
+
+	test.Expect(t, 3, resp.ProtoMajor, "http/3 protocol")
+}
@@ -0,0 +1,108 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package web
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"shiftylogic.dev/hockey-tools/internal/web/throttle"
+)
+
+/**
+ *
+ * Per-route rate limiting. Each request is matched against the exempt list
+ * and then the policies in order; the first policy with a matching route
+ * pattern throttles the request. Requests matching nothing pass through.
+ *
+ * Route patterns are matched against the request path before routing. A
+ * pattern ending in "/*" matches everything under that prefix; anything
+ * else is matched with path.Match (so "*" matches a single segment).
+ *
+ **/
+
+type RateLimitPolicy struct {
+	Name      string
+	Routes    []string
+	Throttler *throttle.Throttler
+}
+
+type RateLimits struct {
+	Policies []RateLimitPolicy
+	Exempt   []string
+}
+
+func WithRateLimits(limits RateLimits) RouterOptionFunc {
+	return func(r Router) {
+		r.Use(RateLimiter(limits))
+	}
+}
+
+func RateLimiter(limits RateLimits) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		handlers := make([]http.Handler, len(limits.Policies))
+		for i, p := range limits.Policies {
+			handlers[i] = p.Throttler.Handler(next)
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if matchesAnyRoute(limits.Exempt, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			for i, p := range limits.Policies {
+				if matchesAnyRoute(p.Routes, r.URL.Path) {
+					handlers[i].ServeHTTP(w, r)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func matchesAnyRoute(patterns []string, urlPath string) bool {
+	for _, pattern := range patterns {
+		if matchesRoute(pattern, urlPath) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchesRoute(pattern, urlPath string) bool {
+	if pattern == "*" || pattern == "/*" {
+		return true
+	}
+
+	if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+		return urlPath == prefix || strings.HasPrefix(urlPath, prefix+"/")
+	}
+
+	matched, err := path.Match(pattern, urlPath)
+	return err == nil && matched
+}
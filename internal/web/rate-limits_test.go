@@ -0,0 +1,107 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"shiftylogic.dev/hockey-tools/internal/test"
+	"shiftylogic.dev/hockey-tools/internal/web/throttle"
+)
+
+func TestMatchesRoute(t *testing.T) {
+	cases := []struct {
+		pattern string
+		path    string
+		matches bool
+	}{
+		{"*", "/anything/at/all", true},
+		{"/*", "/", true},
+		{"/api/*", "/api", true},
+		{"/api/*", "/api/", true},
+		{"/api/*", "/api/games/12", true},
+		{"/api/*", "/apis", false},
+		{"/api/*", "/other/api/games", false},
+		{"/auth/*/token", "/auth/qr/token", true},
+		{"/auth/*/token", "/auth/qr/code/token", false},
+		{"/health", "/health", true},
+		{"/health", "/healthz", false},
+		{"/games/[", "/games/[", false},
+	}
+
+	for _, c := range cases {
+		msg := fmt.Sprintf("pattern '%s' on '%s'", c.pattern, c.path)
+		test.Expect(t, c.matches, matchesRoute(c.pattern, c.path), msg)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	policy := func(name string, routes ...string) RateLimitPolicy {
+		throttler, err := throttle.NewThrottler(throttle.Config{Name: name, Limit: 100})
+		test.NoError(t, err, "throttler for "+name)
+		return RateLimitPolicy{Name: name, Routes: routes, Throttler: throttler}
+	}
+
+	limiter := RateLimiter(RateLimits{
+		Policies: []RateLimitPolicy{
+			policy("auth", "/auth/*"),
+			policy("api", "/api/*", "/auth/*"),
+			policy("all", "*"),
+		},
+		Exempt: []string{"/health", "/api/public/*"},
+	})
+
+	handler := limiter(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	cases := []struct {
+		path   string
+		policy string
+	}{
+		{"/auth/token", "auth"},
+		{"/api/games", "api"},
+		{"/teams", "all"},
+		{"/health", ""},
+		{"/api/public/schedule", ""},
+	}
+
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, c.path, nil))
+		test.Expect(t, http.StatusOK, rec.Code, "status for "+c.path)
+
+		applied, _, _ := strings.Cut(rec.Header().Get("RateLimit-Policy"), ";")
+		test.Expect(t, c.policy, strings.Trim(applied, `"`), "policy for "+c.path)
+	}
+
+	// Nothing matches without a catch-all policy
+	passThrough := RateLimiter(RateLimits{Policies: []RateLimitPolicy{policy("api", "/api/*")}})
+	rec := httptest.NewRecorder()
+	passThrough(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).
+		ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/teams", nil))
+	test.Expect(t, http.StatusOK, rec.Code, "pass-through status")
+	test.Expect(t, "", rec.Header().Get("RateLimit-Policy"), "pass-through policy")
+}
@@ -96,7 +96,7 @@ func WithPanicRecovery() RouterOptionFunc {
 
 func WithLogging() RouterOptionFunc {
 	return func(r Router) {
-		r.Use(middleware.Logger)
+		r.Use(AccessLog)
 	}
 }
 
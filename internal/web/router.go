@@ -23,10 +23,12 @@
 package web
 
 import (
-	"log"
+	"log/slog"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
+
+	"shiftylogic.dev/hockey-tools/internal/helpers"
 )
 
 /**
@@ -54,13 +56,18 @@ func NewRouter(options ...RouterOptionFunc) Router {
 	return r
 }
 
+// The value of a {name} segment in the matched route
+func URLParam(r *http.Request, name string) string {
+	return chi.URLParam(r, name)
+}
+
 func DumpRouter(r Router) {
 	walker := func(method, route string, h http.Handler, mws ...func(http.Handler) http.Handler) error {
-		log.Printf("[Route] %s %s\n", method, route)
+		slog.Info("Route", "method", method, "route", route)
 		return nil
 	}
 
 	if err := chi.Walk(r, walker); err != nil {
-		log.Fatalf("[ERROR] Failed to walk the router - %v", err)
+		helpers.Fatal("Failed to walk the router", "error", err)
 	}
 }
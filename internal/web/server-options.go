@@ -23,10 +23,11 @@
 package web
 
 import (
-	"crypto/tls"
-	"log"
 	"net/http"
 	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/health"
+	"shiftylogic.dev/hockey-tools/internal/helpers"
 )
 
 type ServerOptionFunc func(*Server)
@@ -56,15 +57,30 @@ func WithShutdownTimeout(timeout time.Duration) ServerOptionFunc {
 	}
 }
 
+// Ties readiness to the server lifecycle and, when serving TLS, registers
+// a "tls" check on the loaded certificates.
+func WithReadiness(reg *health.Registry, drainDelay time.Duration) ServerOptionFunc {
+	return func(s *Server) {
+		s.Health = reg
+		s.DrainDelay = drainDelay
+	}
+}
+
 func WithTLS(cert, key string) ServerOptionFunc {
+	return WithCertificates(0, CertificatePair{cert, key})
+}
+
+// Serves TLS from the given pairs (chosen by SNI), reloading them when the
+// files change or on SIGHUP. A zero interval uses the default poll period.
+func WithCertificates(reloadInterval time.Duration, pairs ...CertificatePair) ServerOptionFunc {
 	return func(s *Server) {
-		cert, err := tls.LoadX509KeyPair(cert, key)
+		store, err := NewCertificateStore(pairs...)
 		if err != nil {
-			log.Fatalf("Failed to load TLS key-pair: %v\n", err)
+			helpers.Fatal("Failed to load TLS certificates", "error", err)
 		}
 
-		s.TLSConfig = &tls.Config{
-			Certificates: []tls.Certificate{cert},
-		}
+		s.Certificates = store
+		s.CertReloadInterval = reloadInterval
+		s.TLSConfig = store.TLSConfig()
 	}
 }
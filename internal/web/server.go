@@ -24,12 +24,19 @@ package web
 
 import (
 	"context"
-	"log"
+	"crypto/tls"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+
+	"github.com/quic-go/quic-go/http3"
+	"golang.org/x/crypto/acme/autocert"
+
+	"shiftylogic.dev/hockey-tools/internal/health"
+	"shiftylogic.dev/hockey-tools/internal/helpers"
 )
 
 const (
@@ -47,6 +54,31 @@ type Server struct {
 	http.Server
 
 	ShutdownTimeout time.Duration
+
+	// Marked unready as soon as shutdown begins, then given DrainDelay for
+	// load balancers to notice before connections start being refused.
+	Health     *health.Registry
+	DrainDelay time.Duration
+
+	// When set, certificates are watched and reloaded while serving
+	Certificates       *CertificateStore
+	CertReloadInterval time.Duration
+
+	// When set, certificates come from ACME. HTTP-01 challenges (if used)
+	// are answered by the redirect listener, which is added at
+	// ACMEHTTPAddress if not configured explicitly.
+	ACME            *autocert.Manager
+	ACMEDomains     []string
+	ACMEHTTPAddress string
+
+	// Additional listeners served (and shut down) alongside the main one
+	Listeners []Listener
+	extra     []*http.Server
+
+	// Protocol options; see protocols.go
+	H2C          bool
+	HTTP3Address string
+	http3        *http3.Server
 }
 
 /**
@@ -74,7 +106,7 @@ func StartWithOptions(options ...ServerOptionFunc) {
 
 func makeDefaultServer() *Server {
 	return &Server{
-		http.Server{
+		Server: http.Server{
 			MaxHeaderBytes: kDefaultMaxHeaderBytes,
 
 			IdleTimeout:       kDefaultIdleTimeout,
@@ -84,13 +116,28 @@ func makeDefaultServer() *Server {
 		},
 
 		// Custom properties in "Server" wrapper
-		kDefaultShutdownTimeout,
+		ShutdownTimeout: kDefaultShutdownTimeout,
 	}
 }
 
 func startCore(server *Server) {
 	ctx := setupContextWithShutdown(server)
 
+	if server.Certificates != nil {
+		go server.Certificates.Watch(ctx, server.CertReloadInterval)
+	}
+
+	if server.ACME != nil {
+		go warmACME(server.ACME, server.ACMEDomains)
+	}
+
+	if server.Health != nil && server.TLSConfig != nil {
+		server.Health.Register("tls", CertificateCheck(server.certificates))
+	}
+
+	configureProtocols(server)
+	startListeners(server)
+
 	// This will listen and block until a shutdown is triggered.
 	if server.TLSConfig == nil {
 		startWithoutTLS(server)
@@ -103,15 +150,32 @@ func startCore(server *Server) {
 	<-ctx.Done()
 }
 
+func (server *Server) certificates() []*tls.Certificate {
+	if server.Certificates != nil {
+		return server.Certificates.Certificates()
+	}
+
+	if server.ACME != nil {
+		return acmeCertificates(server.ACME, server.ACMEDomains)
+	}
+
+	certs := make([]*tls.Certificate, len(server.TLSConfig.Certificates))
+	for i := range server.TLSConfig.Certificates {
+		certs[i] = &server.TLSConfig.Certificates[i]
+	}
+
+	return certs
+}
+
 func startWithoutTLS(server *Server) {
 	if err := server.ListenAndServe(); err != http.ErrServerClosed {
-		log.Fatalf("Server listen failed: %v\n", err)
+		helpers.Fatal("Server listen failed", "error", err)
 	}
 }
 
 func startWithTLS(server *Server) {
 	if err := server.ListenAndServeTLS("", ""); err != http.ErrServerClosed {
-		log.Fatalf("Server listen failed: %v\n", err)
+		helpers.Fatal("Server listen failed", "error", err)
 	}
 }
 
@@ -125,16 +189,32 @@ func setupContextWithShutdown(server *Server) context.Context {
 		// Wait for a signal to stop server
 		<-sig
 
-		stopCtx, _ := context.WithTimeout(ctx, server.ShutdownTimeout)
+		if server.Health != nil {
+			server.Health.SetReady(false)
+			time.Sleep(server.DrainDelay)
+		}
+
+		stopCtx, cancel := context.WithTimeout(ctx, server.ShutdownTimeout)
+		defer cancel()
 		go func() {
 			<-stopCtx.Done()
 			if stopCtx.Err() == context.DeadlineExceeded {
-				log.Fatal("Shutdown timed out. Terminating.")
+				helpers.Fatal("Shutdown timed out. Terminating.")
 			}
 		}()
 
+		for _, extra := range server.extra {
+			if err := extra.Shutdown(stopCtx); err != nil {
+				slog.Warn("Listener shutdown failed", "address", extra.Addr, "error", err)
+			}
+		}
+
+		if server.http3 != nil {
+			server.http3.Shutdown(stopCtx)
+		}
+
 		if err := server.Shutdown(stopCtx); err != nil {
-			log.Fatalf("Server shutdown failed: %v\n", err)
+			helpers.Fatal("Server shutdown failed", "error", err)
 		}
 
 		stop()
@@ -0,0 +1,95 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package web
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+/**
+ *
+ * A middleware slot whose implementation can be replaced while serving
+ * (e.g. on config reload). chi only lets middleware be added before routes
+ * are registered, so the slot is installed once and Swap rebuilds the
+ * wrapped handler for every chain it was applied to. Requests already in
+ * flight finish on the previous middleware.
+ *
+ **/
+
+type Swappable struct {
+	mu       sync.Mutex
+	mw       func(http.Handler) http.Handler
+	handlers []*swapHandler
+}
+
+type swapHandler struct {
+	next    http.Handler
+	current atomic.Pointer[http.Handler]
+}
+
+// A nil middleware passes requests straight through
+func NewSwappable(mw func(http.Handler) http.Handler) *Swappable {
+	return &Swappable{mw: mw}
+}
+
+func WithSwappable(s *Swappable) RouterOptionFunc {
+	return func(r Router) {
+		r.Use(s.Middleware)
+	}
+}
+
+func (s *Swappable) Middleware(next http.Handler) http.Handler {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h := &swapHandler{next: next}
+	h.install(s.mw)
+	s.handlers = append(s.handlers, h)
+
+	return h
+}
+
+func (s *Swappable) Swap(mw func(http.Handler) http.Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mw = mw
+	for _, h := range s.handlers {
+		h.install(mw)
+	}
+}
+
+func (h *swapHandler) install(mw func(http.Handler) http.Handler) {
+	handler := h.next
+	if mw != nil {
+		handler = mw(h.next)
+	}
+
+	h.current.Store(&handler)
+}
+
+func (h *swapHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	(*h.current.Load()).ServeHTTP(w, r)
+}
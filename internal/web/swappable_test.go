@@ -0,0 +1,64 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"shiftylogic.dev/hockey-tools/internal/test"
+)
+
+func tagging(value string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Tag", value)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestSwappable(t *testing.T) {
+	slot := NewSwappable(nil)
+	r := NewRouter(WithSwappable(slot))
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {})
+
+	tag := func() string {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		test.Expect(t, http.StatusOK, rec.Code, "status")
+		return rec.Header().Get("X-Tag")
+	}
+
+	test.Expect(t, "", tag(), "empty slot passes through")
+
+	slot.Swap(tagging("first"))
+	test.Expect(t, "first", tag(), "swapped in")
+
+	slot.Swap(tagging("second"))
+	test.Expect(t, "second", tag(), "swapped again")
+
+	slot.Swap(nil)
+	test.Expect(t, "", tag(), "swapped out")
+}
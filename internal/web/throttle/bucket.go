@@ -0,0 +1,166 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package throttle
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+/**
+ *
+ * Token bucket. Each caller has a bucket holding up to 'burst' tokens that
+ * refills at 'limit' tokens per window. A request takes one token and is
+ * rejected (without taking anything) when the bucket is empty.
+ *
+ * The values returned are the number of tokens in use (burst - available),
+ * so the limit reported is the burst size. Rejected requests report
+ * burst + 1.
+ *
+ **/
+
+type bucket struct {
+	tokens float64
+	last   int64 // in UNIX nanoseconds
+}
+
+type bucketTracker struct {
+	buckets      map[uint64]*bucket
+	windowLength time.Duration
+	burst        uint
+	refillRate   float64 // tokens per nanosecond
+	purgeDelay   time.Duration
+	purgeTime    time.Time
+	mu           sync.Mutex
+}
+
+func NewTokenBucketTracker(limit uint, windowLength time.Duration, burst uint) LimitTracker {
+	return newBucketTracker(limit, windowLength, burst, kPurgeDelay)
+}
+
+func newBucketTracker(limit uint, windowLength time.Duration, burst uint, purgeDelay time.Duration) *bucketTracker {
+	if burst == 0 {
+		burst = limit
+	}
+
+	return &bucketTracker{
+		buckets:      make(map[uint64]*bucket),
+		windowLength: windowLength,
+		burst:        burst,
+		refillRate:   float64(limit) / float64(windowLength.Nanoseconds()),
+		purgeDelay:   purgeDelay,
+		purgeTime:    time.Now().UTC().Add(purgeDelay),
+	}
+}
+
+func (tracker *bucketTracker) Get(id uint64, now time.Time) (uint, error) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	tracker.purge(now)
+
+	b, ok := tracker.buckets[id]
+	if !ok {
+		return 0, nil
+	}
+
+	return tracker.burst - uint(tracker.refill(b, now)), nil
+}
+
+func (tracker *bucketTracker) Increment(id uint64, now time.Time) (uint, error) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	tracker.purge(now)
+
+	b, ok := tracker.buckets[id]
+	if !ok {
+		b = &bucket{
+			tokens: float64(tracker.burst),
+			last:   now.UnixNano(),
+		}
+		tracker.buckets[id] = b
+	}
+
+	b.tokens = tracker.refill(b, now)
+	b.last = now.UnixNano()
+
+	if b.tokens < 1 {
+		return tracker.burst + 1, nil
+	}
+
+	b.tokens--
+	return tracker.burst - uint(b.tokens), nil
+}
+
+func (tracker *bucketTracker) WindowLength() time.Duration {
+	return tracker.windowLength
+}
+
+func (tracker *bucketTracker) Limit() uint {
+	return tracker.burst
+}
+
+// Time until the next whole token is available (zero once the bucket is full)
+func (tracker *bucketTracker) Reset(id uint64, now time.Time) time.Duration {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	b, ok := tracker.buckets[id]
+	if !ok {
+		return 0
+	}
+
+	tokens := tracker.refill(b, now)
+	if tokens >= float64(tracker.burst) {
+		return 0
+	}
+
+	missing := math.Floor(tokens) + 1 - tokens
+	return time.Duration(math.Ceil(missing / tracker.refillRate))
+}
+
+func (tracker *bucketTracker) refill(b *bucket, now time.Time) float64 {
+	elapsed := now.UnixNano() - b.last
+	if elapsed <= 0 {
+		return b.tokens
+	}
+
+	return min(float64(tracker.burst), b.tokens+float64(elapsed)*tracker.refillRate)
+}
+
+func (tracker *bucketTracker) purge(now time.Time) {
+	if tracker.purgeTime.After(now) {
+		return
+	}
+
+	// A full bucket carries no state worth keeping
+	for k, b := range tracker.buckets {
+		if tracker.refill(b, now) >= float64(tracker.burst) {
+			delete(tracker.buckets, k)
+		}
+	}
+
+	tracker.purgeTime = now.Add(tracker.purgeDelay)
+}
@@ -0,0 +1,105 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package throttle
+
+import (
+	"fmt"
+	"time"
+)
+
+type Algorithm string
+
+const (
+	AlgorithmSlidingWindow Algorithm = "sliding-window"
+	AlgorithmFixedWindow   Algorithm = "fixed-window"
+	AlgorithmTokenBucket   Algorithm = "token-bucket"
+	AlgorithmGCRA          Algorithm = "gcra"
+)
+
+// An empty algorithm selects the sliding window
+func (a Algorithm) Valid() bool {
+	switch a {
+	case "", AlgorithmSlidingWindow, AlgorithmFixedWindow, AlgorithmTokenBucket, AlgorithmGCRA:
+		return true
+	}
+
+	return false
+}
+
+type Config struct {
+	Name      string        `json:"name" yaml:"Name"`
+	Algorithm Algorithm     `json:"algorithm" yaml:"Algorithm"`
+	Limit     uint          `json:"limit" yaml:"Limit"`
+	Window    time.Duration `json:"window" yaml:"Window"`
+
+	// Bucket capacity for the token-bucket and gcra algorithms. Defaults
+	// to Limit when zero; ignored by the window algorithms.
+	Burst uint `json:"burst" yaml:"Burst"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Algorithm: AlgorithmSlidingWindow,
+		Limit:     kDefaultRequestLimit,
+		Window:    kDefaultWindowLength,
+		Burst:     0,
+	}
+}
+
+// Builds a Throttler with the tracker selected by the config
+func NewThrottler(cfg Config) (*Throttler, error) {
+	tracker, err := cfg.Tracker()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Throttler{
+		Name:    cfg.Name,
+		Tracker: tracker,
+	}, nil
+}
+
+func (cfg Config) Tracker() (LimitTracker, error) {
+	limit := cfg.Limit
+	if limit == 0 {
+		limit = kDefaultRequestLimit
+	}
+
+	window := cfg.Window
+	if window == 0 {
+		window = kDefaultWindowLength
+	}
+
+	switch cfg.Algorithm {
+	case AlgorithmFixedWindow:
+		return NewFixedWindowTracker(limit, window), nil
+	case AlgorithmTokenBucket:
+		return NewTokenBucketTracker(limit, window, cfg.Burst), nil
+	case AlgorithmGCRA:
+		return NewGCRATracker(limit, window, cfg.Burst), nil
+	case AlgorithmSlidingWindow, "":
+		return NewLocalTracker(limit, window), nil
+	default:
+		return nil, fmt.Errorf("unknown throttle algorithm '%s'", cfg.Algorithm)
+	}
+}
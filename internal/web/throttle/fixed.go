@@ -0,0 +1,139 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package throttle
+
+import (
+	"sync"
+	"time"
+)
+
+/**
+ *
+ * Fixed-window counter. Requests are counted per window and the count is
+ * reset at each window boundary, so a caller can burst up to twice the
+ * limit across a boundary. Cheaper and more predictable than the sliding
+ * window, and the reset time is exact.
+ *
+ **/
+
+type fixedCounter struct {
+	count  uint
+	window int64 // in UNIX milliseconds
+}
+
+type fixedTracker struct {
+	counters     map[uint64]*fixedCounter
+	limit        uint
+	windowLength time.Duration
+	purgeDelay   time.Duration
+	purgeTime    time.Time
+	mu           sync.Mutex
+}
+
+func NewFixedWindowTracker(limit uint, windowLength time.Duration) LimitTracker {
+	return newFixedTracker(limit, windowLength, kPurgeDelay)
+}
+
+func newFixedTracker(limit uint, windowLength, purgeDelay time.Duration) *fixedTracker {
+	if limit == 0 {
+		limit = kDefaultRequestLimit
+	}
+
+	return &fixedTracker{
+		counters:     make(map[uint64]*fixedCounter),
+		limit:        limit,
+		windowLength: windowLength,
+		purgeDelay:   purgeDelay,
+		purgeTime:    time.Now().UTC().Add(purgeDelay),
+	}
+}
+
+func (tracker *fixedTracker) Get(id uint64, now time.Time) (uint, error) {
+	scopedWindow := now.Truncate(tracker.windowLength).UnixMilli()
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	tracker.purge(now)
+
+	v, ok := tracker.counters[id]
+	if !ok || v.window != scopedWindow {
+		return 0, nil
+	}
+
+	return v.count, nil
+}
+
+func (tracker *fixedTracker) Increment(id uint64, now time.Time) (uint, error) {
+	scopedWindow := now.Truncate(tracker.windowLength).UnixMilli()
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	tracker.purge(now)
+
+	v, ok := tracker.counters[id]
+	if !ok {
+		tracker.counters[id] = &fixedCounter{
+			count:  1,
+			window: scopedWindow,
+		}
+		return 1, nil
+	}
+
+	if v.window != scopedWindow {
+		v.count = 0
+		v.window = scopedWindow
+	}
+
+	v.count++
+	return v.count, nil
+}
+
+func (tracker *fixedTracker) WindowLength() time.Duration {
+	return tracker.windowLength
+}
+
+func (tracker *fixedTracker) Limit() uint {
+	return tracker.limit
+}
+
+func (tracker *fixedTracker) Reset(id uint64, now time.Time) time.Duration {
+	return now.Truncate(tracker.windowLength).Add(tracker.windowLength).Sub(now)
+}
+
+func (tracker *fixedTracker) purge(now time.Time) {
+	if tracker.purgeTime.After(now) {
+		return
+	}
+
+	purgeTarget := now.Truncate(tracker.windowLength).UnixMilli()
+
+	for k, v := range tracker.counters {
+		if v.window < purgeTarget {
+			delete(tracker.counters, k)
+		}
+	}
+
+	tracker.purgeTime = now.Add(tracker.purgeDelay)
+}
@@ -0,0 +1,155 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package throttle
+
+import (
+	"sync"
+	"time"
+)
+
+/**
+ *
+ * Generic Cell Rate Algorithm (a leaky bucket as a meter). Each caller only
+ * needs a "theoretical arrival time" (TAT); requests are spaced one emission
+ * interval (window / limit) apart, with up to 'burst' intervals of slack.
+ *
+ * Like the token bucket, the values returned are the number of emission
+ * intervals currently in use, so the limit reported is the burst size.
+ * Rejected requests report burst + 1.
+ *
+ **/
+
+type gcraTracker struct {
+	tats         map[uint64]int64 // in UNIX nanoseconds
+	windowLength time.Duration
+	burst        uint
+	interval     int64 // emission interval in nanoseconds
+	tolerance    int64 // burst tolerance in nanoseconds
+	purgeDelay   time.Duration
+	purgeTime    time.Time
+	mu           sync.Mutex
+}
+
+func NewGCRATracker(limit uint, windowLength time.Duration, burst uint) LimitTracker {
+	return newGCRATracker(limit, windowLength, burst, kPurgeDelay)
+}
+
+func newGCRATracker(limit uint, windowLength time.Duration, burst uint, purgeDelay time.Duration) *gcraTracker {
+	if burst == 0 {
+		burst = limit
+	}
+
+	interval := windowLength.Nanoseconds() / int64(max(limit, 1))
+
+	return &gcraTracker{
+		tats:         make(map[uint64]int64),
+		windowLength: windowLength,
+		burst:        burst,
+		interval:     interval,
+		tolerance:    interval * int64(burst),
+		purgeDelay:   purgeDelay,
+		purgeTime:    time.Now().UTC().Add(purgeDelay),
+	}
+}
+
+func (tracker *gcraTracker) Get(id uint64, now time.Time) (uint, error) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	tracker.purge(now)
+
+	tat, ok := tracker.tats[id]
+	if !ok {
+		return 0, nil
+	}
+
+	return tracker.inUse(tat, now.UnixNano()), nil
+}
+
+func (tracker *gcraTracker) Increment(id uint64, now time.Time) (uint, error) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	tracker.purge(now)
+
+	ts := now.UnixNano()
+	tat := max(tracker.tats[id], ts) + tracker.interval
+
+	if tat-ts > tracker.tolerance {
+		return tracker.burst + 1, nil
+	}
+
+	tracker.tats[id] = tat
+	return tracker.inUse(tat, ts), nil
+}
+
+func (tracker *gcraTracker) WindowLength() time.Duration {
+	return tracker.windowLength
+}
+
+func (tracker *gcraTracker) Limit() uint {
+	return tracker.burst
+}
+
+// Time until the next emission interval drains (zero once fully drained)
+func (tracker *gcraTracker) Reset(id uint64, now time.Time) time.Duration {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+
+	ts := now.UnixNano()
+	tat, ok := tracker.tats[id]
+	if !ok || tat <= ts {
+		return 0
+	}
+
+	left := (tat - ts) % tracker.interval
+	if left == 0 {
+		left = tracker.interval
+	}
+
+	return time.Duration(left)
+}
+
+func (tracker *gcraTracker) inUse(tat, ts int64) uint {
+	if tat <= ts {
+		return 0
+	}
+
+	// Round up: a partially drained interval is still in use
+	return uint((tat - ts + tracker.interval - 1) / tracker.interval)
+}
+
+func (tracker *gcraTracker) purge(now time.Time) {
+	if tracker.purgeTime.After(now) {
+		return
+	}
+
+	ts := now.UnixNano()
+	for k, tat := range tracker.tats {
+		if tat <= ts {
+			delete(tracker.tats, k)
+		}
+	}
+
+	tracker.purgeTime = now.Add(tracker.purgeDelay)
+}
@@ -39,19 +39,25 @@ type counter struct {
 
 type localTracker struct {
 	counters     map[uint64]*counter
+	limit        uint
 	windowLength time.Duration
 	purgeDelay   time.Duration
 	purgeTime    time.Time
 	mu           sync.Mutex
 }
 
-func NewLocalTracker(windowLength time.Duration) LimitTracker {
-	return newLocalTracker(windowLength, kPurgeDelay)
+func NewLocalTracker(limit uint, windowLength time.Duration) LimitTracker {
+	return newLocalTracker(limit, windowLength, kPurgeDelay)
 }
 
-func newLocalTracker(windowLength, purgeDelay time.Duration) *localTracker {
+func newLocalTracker(limit uint, windowLength, purgeDelay time.Duration) *localTracker {
+	if limit == 0 {
+		limit = kDefaultRequestLimit
+	}
+
 	return &localTracker{
 		counters:     make(map[uint64]*counter),
+		limit:        limit,
 		windowLength: windowLength,
 		purgeDelay:   purgeDelay,
 		purgeTime:    time.Now().UTC().Add(purgeDelay),
@@ -127,10 +133,61 @@ func (tracker *localTracker) Increment(id uint64, now time.Time) (uint, error) {
 
 	return tracker.computeSlidingRate(v.current, v.previous, elapsed), nil
 }
+
 func (tracker *localTracker) WindowLength() time.Duration {
 	return tracker.windowLength
 }
 
+func (tracker *localTracker) Limit() uint {
+	return tracker.limit
+}
+
+/**
+ *
+ * Time until the weighted count drops below the limit. A caller over the
+ * limit on the current window alone has to wait for that count to decay
+ * into the next window, so this can run past the window boundary. Callers
+ * with headroom get the time to the end of the current window.
+ *
+ **/
+func (tracker *localTracker) Reset(id uint64, now time.Time) time.Duration {
+	scopedWindow := now.Truncate(tracker.windowLength)
+	previousWindow := scopedWindow.Add(-tracker.windowLength)
+	elapsed := now.Sub(scopedWindow)
+	untilEnd := tracker.windowLength - elapsed
+
+	var cv, pv uint
+
+	tracker.mu.Lock()
+	if v, ok := tracker.counters[id]; ok {
+		switch v.window {
+		case scopedWindow.UnixMilli():
+			cv, pv = v.current, v.previous
+		case previousWindow.UnixMilli():
+			pv = v.current
+		}
+	}
+	tracker.mu.Unlock()
+
+	if tracker.computeSlidingRate(cv, pv, elapsed) < tracker.limit {
+		return untilEnd
+	}
+
+	if cv >= tracker.limit {
+		return untilEnd + tracker.decayTime(cv, tracker.limit)
+	}
+
+	return tracker.decayTime(pv, tracker.limit-cv) - elapsed
+}
+
+// How far into a window a decaying count must be before its weighted share
+// falls below 'headroom' (matching computeSlidingRate's millisecond steps)
+func (tracker *localTracker) decayTime(count, headroom uint) time.Duration {
+	window := tracker.windowLength.Milliseconds()
+	share := (int64(headroom)*window + int64(count) - 1) / int64(count)
+	return time.Duration(window-share+1) * time.Millisecond
+}
+
 func (tracker *localTracker) computeSlidingRate(cv, pv uint, elapsed time.Duration) uint {
 	window := tracker.windowLength.Milliseconds()
 	decay := float64(window-elapsed.Milliseconds()) / float64(window)
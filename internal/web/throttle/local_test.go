@@ -32,7 +32,7 @@ import (
 
 func TestLocalBasicIncrement(t *testing.T) {
 	id := computeID("foo")
-	tracker := NewLocalTracker(10 * time.Second)
+	tracker := NewLocalTracker(kDefaultRequestLimit, 10*time.Second)
 
 	var i uint = 1
 	for ; i <= 100; i++ {
@@ -44,7 +44,7 @@ func TestLocalBasicIncrement(t *testing.T) {
 
 func TestLocalRollover(t *testing.T) {
 	id := computeID("bar")
-	tracker := NewLocalTracker(10 * time.Second)
+	tracker := NewLocalTracker(kDefaultRequestLimit, 10*time.Second)
 	now := time.Now().Truncate(10 * time.Second)
 
 	var i uint = 1
@@ -74,7 +74,7 @@ func TestLocalRollover(t *testing.T) {
 
 func TestLocalDecay(t *testing.T) {
 	id := computeID("bar")
-	tracker := NewLocalTracker(4 * time.Second)
+	tracker := NewLocalTracker(kDefaultRequestLimit, 4*time.Second)
 	now := time.Now().Truncate(4 * time.Second)
 
 	_, err := tracker.Increment(id, now)
@@ -112,7 +112,7 @@ func TestLocalDecay(t *testing.T) {
 
 func TestLocalDecayWithPurge(t *testing.T) {
 	id := computeID("bar")
-	tracker := newLocalTracker(4*time.Second, 1*time.Millisecond)
+	tracker := newLocalTracker(kDefaultRequestLimit, 4*time.Second, 1*time.Millisecond)
 	now := time.Now().Truncate(4 * time.Second)
 
 	_, err := tracker.Increment(id, now)
@@ -149,7 +149,7 @@ func TestLocalDecayWithPurge(t *testing.T) {
 }
 
 func TestLocalPurge(t *testing.T) {
-	tracker := newLocalTracker(100*time.Millisecond, 1*time.Millisecond)
+	tracker := newLocalTracker(kDefaultRequestLimit, 100*time.Millisecond, 1*time.Millisecond)
 	now := time.Now().Truncate(100 * time.Millisecond)
 
 	tracker.Increment(1, now)
@@ -199,3 +199,174 @@ func TestLocalPurge(t *testing.T) {
 	test.Require(t, v == 0, "expected id '1' to be 0")
 	test.Require(t, len(tracker.counters) == 0, fmt.Sprintf("expected 0 tracked (actual: %d)", len(tracker.counters)))
 }
+
+type trackerStep struct {
+	offset    time.Duration
+	increment bool
+	expected  uint
+}
+
+func TestTrackerBoundaries(t *testing.T) {
+	window := 10 * time.Second
+	start := time.Now().Truncate(window)
+
+	cases := []struct {
+		name    string
+		tracker LimitTracker
+		steps   []trackerStep
+	}{
+		{
+			name:    "sliding-window",
+			tracker: NewLocalTracker(kDefaultRequestLimit, window),
+			steps: []trackerStep{
+				{0, true, 1},
+				{0, true, 2},
+				{0, true, 3},
+				{window - time.Millisecond, false, 3},
+				{window, false, 3},
+				{window + window/2, true, 2},
+				{3 * window, false, 0},
+			},
+		},
+		{
+			name:    "fixed-window",
+			tracker: NewFixedWindowTracker(kDefaultRequestLimit, window),
+			steps: []trackerStep{
+				{0, true, 1},
+				{0, true, 2},
+				{window - time.Millisecond, true, 3},
+				{window - time.Millisecond, false, 3},
+				{window, false, 0},
+				{window, true, 1},
+				{3 * window, false, 0},
+			},
+		},
+		{
+			// 1 token / second, bucket of 3
+			name:    "token-bucket",
+			tracker: NewTokenBucketTracker(10, window, 3),
+			steps: []trackerStep{
+				{0, true, 1},
+				{0, true, 2},
+				{0, true, 3},
+				{0, true, 4},
+				{0, false, 3},
+				{2 * time.Second, false, 1},
+				{2 * time.Second, true, 2},
+				{2 * time.Second, true, 3},
+				{2 * time.Second, true, 4},
+				{window, false, 0},
+			},
+		},
+		{
+			// 1 second emission interval, burst of 3
+			name:    "gcra",
+			tracker: NewGCRATracker(10, window, 3),
+			steps: []trackerStep{
+				{0, true, 1},
+				{0, true, 2},
+				{0, true, 3},
+				{0, true, 4},
+				{0, false, 3},
+				{time.Second, true, 3},
+				{time.Second, true, 4},
+				{time.Second + time.Millisecond, false, 3},
+				{4 * time.Second, false, 0},
+				{4 * time.Second, true, 1},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			id := computeID(c.name)
+
+			for i, s := range c.steps {
+				var v uint
+				var err error
+
+				if s.increment {
+					v, err = c.tracker.Increment(id, start.Add(s.offset))
+				} else {
+					v, err = c.tracker.Get(id, start.Add(s.offset))
+				}
+
+				test.NoError(t, err, fmt.Sprintf("step %d failed", i))
+				test.Require(t, v == s.expected, fmt.Sprintf("step %d value incorrect (Actual: %d, Expected: %d)", i, v, s.expected))
+			}
+		})
+	}
+}
+
+func TestConfigSelectsTracker(t *testing.T) {
+	cases := []struct {
+		config  Config
+		limit   uint
+		tracker string
+	}{
+		{Config{Limit: 50, Window: time.Minute}, 50, "*throttle.localTracker"},
+		{Config{Algorithm: AlgorithmSlidingWindow, Limit: 50, Window: time.Minute}, 50, "*throttle.localTracker"},
+		{Config{Algorithm: AlgorithmFixedWindow, Limit: 50, Window: time.Minute, Burst: 5}, 50, "*throttle.fixedTracker"},
+		{Config{Algorithm: AlgorithmTokenBucket, Limit: 50, Window: time.Minute, Burst: 5}, 5, "*throttle.bucketTracker"},
+		{Config{Algorithm: AlgorithmGCRA, Limit: 50, Window: time.Minute}, 50, "*throttle.gcraTracker"},
+	}
+
+	for _, c := range cases {
+		throttler, err := NewThrottler(c.config)
+		test.NoError(t, err, string(c.config.Algorithm))
+		test.Expect(t, c.tracker, fmt.Sprintf("%T", throttler.Tracker), string(c.config.Algorithm))
+		test.Expect(t, c.limit, throttler.Tracker.Limit(), string(c.config.Algorithm))
+		test.Expect(t, time.Minute, throttler.Tracker.WindowLength(), string(c.config.Algorithm))
+	}
+
+	_, err := NewThrottler(Config{Algorithm: "leaky-bucket"})
+	test.AnyError(t, err, "unknown algorithm should be rejected")
+}
+
+func TestLocalResetWaitsForDecay(t *testing.T) {
+	window := 10 * time.Second
+	start := time.Now().Truncate(window)
+	tracker := NewLocalTracker(4, window)
+
+	// Over the limit on the current window alone: the count has to decay
+	// into the next window before there is headroom.
+	full := func(name string) uint64 {
+		id := computeID(name)
+		for i := 0; i < 4; i++ {
+			tracker.Increment(id, start)
+		}
+		return id
+	}
+
+	reset := tracker.Reset(full("full"), start)
+	test.Expect(t, window+time.Millisecond, reset, "reset for a full current window")
+
+	v, _ := tracker.Increment(full("full-early"), start.Add(reset-time.Millisecond))
+	test.Expect(t, uint(5), v, "still limited just before the reset")
+	v, _ = tracker.Increment(full("full-on-time"), start.Add(reset))
+	test.Expect(t, uint(4), v, "allowed at the reset")
+
+	// Over the limit from the previous window's weighted share: 8 before
+	// the boundary, 2 more halfway through the next window.
+	now := start.Add(window + window/2)
+	decaying := func(name string) uint64 {
+		id := computeID(name)
+		for i := 0; i < 8; i++ {
+			tracker.Increment(id, start)
+		}
+		tracker.Increment(id, now)
+		tracker.Increment(id, now)
+		return id
+	}
+
+	reset = tracker.Reset(decaying("decaying"), now)
+	test.Expect(t, 2501*time.Millisecond, reset, "reset for a decaying previous window")
+
+	v, _ = tracker.Increment(decaying("decaying-early"), now.Add(reset-time.Millisecond))
+	test.Expect(t, uint(5), v, "still limited just before the reset")
+	v, _ = tracker.Increment(decaying("decaying-on-time"), now.Add(reset))
+	test.Expect(t, uint(4), v, "allowed at the reset")
+
+	// Callers with headroom are told when the window ends
+	test.Expect(t, window/2, tracker.Reset(computeID("idle"), now), "reset with headroom")
+}
@@ -0,0 +1,286 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package throttle
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"shiftylogic.dev/hockey-tools/internal/helpers"
+)
+
+const (
+	kHeaderForwardedFor = "X-Forwarded-For"
+	kHeaderAPIKey       = "X-API-Key"
+)
+
+/**
+ *
+ * A KeyFunc extracts one component of the identity a request is throttled
+ * under. An empty key means the component is absent from the request (for
+ * example, an anonymous caller has no subject); all such requests share the
+ * same bucket unless composed with FirstOf.
+ *
+ **/
+
+type KeyFunc func(r *http.Request) (string, error)
+
+/**
+ *
+ * Builds a Throttler.RequestMapper from one or more key components, so
+ * callers can be limited per (IP), per (IP, route), per (client, route)...
+ *
+ **/
+func MapRequest(keys ...KeyFunc) func(r *http.Request) (uint64, error) {
+	return func(r *http.Request) (uint64, error) {
+		parts := make([]string, len(keys))
+
+		for i, fn := range keys {
+			key, err := fn(r)
+			if err != nil {
+				return 0, err
+			}
+
+			parts[i] = key
+		}
+
+		return computeID(strings.Join(parts, "\x00")), nil
+	}
+}
+
+// Uses the first non-empty key, e.g. the subject falling back to the IP.
+// Keys are tagged with their position so a subject can't share a bucket
+// with an IP address that happens to spell the same.
+func FirstOf(keys ...KeyFunc) KeyFunc {
+	return func(r *http.Request) (string, error) {
+		for i, fn := range keys {
+			key, err := fn(r)
+			if err != nil {
+				return "", err
+			}
+
+			if key != "" {
+				return strconv.Itoa(i) + ":" + key, nil
+			}
+		}
+
+		return "", nil
+	}
+}
+
+/**
+ *
+ * Keys on the client IP address. When the immediate peer is one of the
+ * trusted proxies (IP addresses or CIDR prefixes), X-Forwarded-For is walked
+ * from right to left and the first untrusted hop is used instead.
+ *
+ **/
+func ByRemoteIP(trustedProxies ...string) (KeyFunc, error) {
+	trusted := make([]netip.Prefix, 0, len(trustedProxies))
+
+	for _, p := range trustedProxies {
+		prefix, err := ParseTrustedProxy(p)
+		if err != nil {
+			return nil, err
+		}
+
+		trusted = append(trusted, prefix)
+	}
+
+	isTrusted := func(addr netip.Addr) bool {
+		for _, p := range trusted {
+			if p.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(r *http.Request) (string, error) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+
+		peer, err := netip.ParseAddr(host)
+		if err != nil {
+			return host, nil
+		}
+		peer = peer.Unmap()
+
+		if !isTrusted(peer) {
+			return peer.String(), nil
+		}
+
+		hops := strings.Split(strings.Join(r.Header.Values(kHeaderForwardedFor), ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				break
+			}
+
+			hop = hop.Unmap()
+			if !isTrusted(hop) {
+				return hop.String(), nil
+			}
+
+			peer = hop
+		}
+
+		// Every hop was a trusted proxy (or the chain was malformed), so the
+		// closest address we can vouch for is the best we have.
+		return peer.String(), nil
+	}, nil
+}
+
+// Parses a trusted proxy given as an IP address or a CIDR prefix
+func ParseTrustedProxy(proxy string) (netip.Prefix, error) {
+	prefix, err := netip.ParsePrefix(proxy)
+	if err != nil {
+		addr, aerr := netip.ParseAddr(proxy)
+		if aerr != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid trusted proxy address '%s'", proxy)
+		}
+
+		prefix = netip.PrefixFrom(addr, addr.BitLen())
+	}
+
+	return prefix.Masked(), nil
+}
+
+// Keys on the authenticated subject stored in the request context
+func BySubject() KeyFunc {
+	return func(r *http.Request) (string, error) {
+		subject, _ := helpers.SubjectFromContext(r.Context())
+		return subject, nil
+	}
+}
+
+// Keys on the client identified by a client certificate, or else the OAuth
+// client_id from the form / query or HTTP basic auth
+func ByClientID() KeyFunc {
+	return func(r *http.Request) (string, error) {
+		if cid, ok := helpers.ClientFromContext(r.Context()); ok {
+			return cid, nil
+		}
+
+		if cid := r.FormValue("client_id"); cid != "" {
+			return cid, nil
+		}
+
+		if cid, _, err := helpers.ParseHttpAuthBasic(r); err == nil {
+			return cid, nil
+		}
+
+		return "", nil
+	}
+}
+
+// Keys on an API key header (X-API-Key when no header name is given)
+func ByAPIKey(header string) KeyFunc {
+	if header == "" {
+		header = kHeaderAPIKey
+	}
+
+	return func(r *http.Request) (string, error) {
+		return r.Header.Get(header), nil
+	}
+}
+
+/**
+ *
+ * Keys on the chi route pattern, falling back to the raw path. As router
+ * level middleware nothing has been routed yet, so the pattern is resolved
+ * by matching the request against the router up front.
+ *
+ **/
+func ByRoute() KeyFunc {
+	return func(r *http.Request) (string, error) {
+		rctx := chi.RouteContext(r.Context())
+		if rctx == nil {
+			return r.URL.Path, nil
+		}
+
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			return pattern, nil
+		}
+
+		if rctx.Routes != nil {
+			routePath := rctx.RoutePath
+			if routePath == "" {
+				routePath = r.URL.Path
+			}
+
+			probe := chi.NewRouteContext()
+			if rctx.Routes.Match(probe, r.Method, routePath) {
+				if pattern := probe.RoutePattern(); pattern != "" {
+					return pattern, nil
+				}
+			}
+		}
+
+		return r.URL.Path, nil
+	}
+}
+
+/**
+ *
+ * Resolves a key component by name, as used in configuration. Alternatives
+ * separated by '|' are tried in order (see FirstOf), e.g. "subject|ip".
+ *
+ **/
+func KeyByName(name string, trustedProxies ...string) (KeyFunc, error) {
+	if alts := strings.Split(name, "|"); len(alts) > 1 {
+		keys := make([]KeyFunc, len(alts))
+		for i, alt := range alts {
+			key, err := KeyByName(alt, trustedProxies...)
+			if err != nil {
+				return nil, err
+			}
+			keys[i] = key
+		}
+
+		return FirstOf(keys...), nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "ip":
+		return ByRemoteIP(trustedProxies...)
+	case "subject":
+		return BySubject(), nil
+	case "client":
+		return ByClientID(), nil
+	case "apikey":
+		return ByAPIKey(""), nil
+	case "route":
+		return ByRoute(), nil
+	default:
+		return nil, fmt.Errorf("unknown request key '%s'", name)
+	}
+}
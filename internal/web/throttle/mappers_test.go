@@ -0,0 +1,148 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package throttle
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"shiftylogic.dev/hockey-tools/internal/helpers"
+	"shiftylogic.dev/hockey-tools/internal/test"
+)
+
+func TestByRemoteIP(t *testing.T) {
+	key, err := ByRemoteIP("10.0.0.0/8", "192.168.1.1")
+	test.NoError(t, err, "valid trusted proxies")
+
+	_, err = ByRemoteIP("10.0.0.0/33x")
+	test.AnyError(t, err, "malformed trusted proxy")
+
+	cases := []struct {
+		remote    string
+		forwarded string
+		expected  string
+	}{
+		{"203.0.113.9:4431", "", "203.0.113.9"},
+		{"203.0.113.9:4431", "198.51.100.7", "203.0.113.9"},
+		{"10.1.2.3:4431", "198.51.100.7", "198.51.100.7"},
+		{"10.1.2.3:4431", "198.51.100.7, 192.168.1.1", "198.51.100.7"},
+		{"10.1.2.3:4431", "1.1.1.1, 198.51.100.7, 10.9.9.9", "198.51.100.7"},
+		{"10.1.2.3:4431", "10.9.9.9", "10.9.9.9"},
+		{"10.1.2.3:4431", "garbage, 10.9.9.9", "10.9.9.9"},
+		{"[::ffff:203.0.113.9]:4431", "", "203.0.113.9"},
+	}
+
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = c.remote
+		if c.forwarded != "" {
+			r.Header.Set(kHeaderForwardedFor, c.forwarded)
+		}
+
+		v, err := key(r)
+		test.NoError(t, err, "mapping failed")
+		test.Expect(t, c.expected, v, c.remote+" / "+c.forwarded)
+	}
+}
+
+func TestMapRequestComposition(t *testing.T) {
+	byIP, err := ByRemoteIP()
+	test.NoError(t, err, "no trusted proxies")
+	mapper := MapRequest(FirstOf(BySubject(), byIP), ByAPIKey(""))
+
+	anon := httptest.NewRequest("GET", "/", nil)
+	anon.RemoteAddr = "203.0.113.9:4431"
+
+	user := httptest.NewRequest("GET", "/", nil)
+	user.RemoteAddr = "203.0.113.9:4431"
+	user = user.WithContext(helpers.ContextWithSubject(user.Context(), "1"))
+
+	keyed := httptest.NewRequest("GET", "/", nil)
+	keyed.RemoteAddr = "203.0.113.9:4431"
+	keyed.Header.Set(kHeaderAPIKey, "abc")
+
+	anonID, err := mapper(anon)
+	test.NoError(t, err, "mapping failed")
+	userID, err := mapper(user)
+	test.NoError(t, err, "mapping failed")
+	keyedID, err := mapper(keyed)
+	test.NoError(t, err, "mapping failed")
+
+	test.Require(t, anonID != userID, "subject should take precedence over IP")
+	test.Require(t, anonID != keyedID, "API key should be part of the identity")
+
+	again, _ := mapper(anon)
+	test.Expect(t, anonID, again, "mapping should be stable")
+}
+
+func TestFirstOfTagsSource(t *testing.T) {
+	key := FirstOf(BySubject(), ByAPIKey(""))
+
+	user := httptest.NewRequest("GET", "/", nil)
+	user = user.WithContext(helpers.ContextWithSubject(user.Context(), "abc"))
+
+	keyed := httptest.NewRequest("GET", "/", nil)
+	keyed.Header.Set(kHeaderAPIKey, "abc")
+
+	userKey, err := key(user)
+	test.NoError(t, err, "mapping failed")
+	keyedKey, err := key(keyed)
+	test.NoError(t, err, "mapping failed")
+
+	test.Expect(t, "0:abc", userKey, "subject key")
+	test.Expect(t, "1:abc", keyedKey, "API key")
+
+	none, err := key(httptest.NewRequest("GET", "/", nil))
+	test.NoError(t, err, "mapping failed")
+	test.Expect(t, "", none, "no key present")
+}
+
+func TestByRouteAsRouterMiddleware(t *testing.T) {
+	var seen string
+
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			seen, _ = ByRoute()(r)
+			next.ServeHTTP(w, r)
+		})
+	})
+	r.Get("/games/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	r.Route("/teams", func(r chi.Router) {
+		r.Get("/{team}/roster", func(w http.ResponseWriter, r *http.Request) {})
+	})
+
+	cases := map[string]string{
+		"/games/12":         "/games/{id}",
+		"/teams/sea/roster": "/teams/{team}/roster",
+		"/unknown/path":     "/unknown/path",
+	}
+
+	for path, expected := range cases {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+		test.Expect(t, expected, seen, "route key for "+path)
+	}
+}
@@ -23,36 +23,76 @@
 package throttle
 
 import (
+	"encoding/json"
 	"net/http"
 	"strconv"
 	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/metrics"
 )
 
 const (
-	kHeaderLimit      = "X-RateLimit-Limit"
-	kHeaderPolicy     = "X-RateLimit-Policy"
-	kHeaderRemaining  = "X-RateLimit-Remaining"
-	kHeaderReset      = "X-RateLimit-Reset"
-	kHeaderRetryAfter = "Retry-After"
+	// IETF RateLimit header fields (draft-ietf-httpapi-ratelimit-headers)
+	kHeaderRateLimit       = "RateLimit"
+	kHeaderRateLimitPolicy = "RateLimit-Policy"
+
+	// Legacy (pre-standard) header fields
+	kHeaderLimit     = "X-RateLimit-Limit"
+	kHeaderPolicy    = "X-RateLimit-Policy"
+	kHeaderRemaining = "X-RateLimit-Remaining"
+	kHeaderReset     = "X-RateLimit-Reset"
+
+	kHeaderRetryAfter  = "Retry-After"
+	kHeaderContentType = "Content-Type"
 
+	kDefaultPolicyName   = "default"
 	kDefaultRequestLimit = 100
 	kDefaultWindowLength = 60 * time.Second
+
+	kRateLimitedError = "rate_limited"
+)
+
+var (
+	throttleDecisions = metrics.NewCounterVec(
+		"throttle_decisions_total",
+		"Rate limit decisions by policy and outcome (allowed, limited, error).",
+		"policy", "decision")
 )
 
 type LimitTracker interface {
 	Get(id uint64, now time.Time) (uint, error)
 	Increment(id uint64, now time.Time) (uint, error)
 	WindowLength() time.Duration
+
+	// The count Increment may reach before a caller is limited; this is
+	// what the RateLimit headers report as the quota
+	Limit() uint
+
+	// Time until the caller identified by 'id' next gains headroom
+	Reset(id uint64, now time.Time) time.Duration
 }
 
 type Throttler struct {
-	RequestLimit    uint
+	Name            string
 	Tracker         LimitTracker
 	RequestMapper   func(r *http.Request) (uint64, error)
 	ExceededHandler http.HandlerFunc
+	LegacyHeaders   bool
+}
+
+type exceededBody struct {
+	Error      string `json:"error"`
+	Message    string `json:"message"`
+	Policy     string `json:"policy"`
+	RetryAfter uint64 `json:"retryAfter"`
 }
 
 func (t *Throttler) Handler(next http.Handler) http.Handler {
+	name := t.Name
+	if name == "" {
+		name = kDefaultPolicyName
+	}
+
 	mapper := t.RequestMapper
 	if mapper == nil {
 		mapper = func(r *http.Request) (uint64, error) {
@@ -62,54 +102,91 @@ func (t *Throttler) Handler(next http.Handler) http.Handler {
 
 	tracker := t.Tracker
 	if tracker == nil {
-		tracker = NewLocalTracker(kDefaultWindowLength)
+		tracker = NewLocalTracker(kDefaultRequestLimit, kDefaultWindowLength)
 	}
 
-	limit := t.RequestLimit
-	if limit == 0 {
-		limit = kDefaultRequestLimit
-	}
+	limit := tracker.Limit()
 
 	exceeded := t.ExceededHandler
 	if exceeded == nil {
 		exceeded = func(w http.ResponseWriter, r *http.Request) {
-			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			retryAfter, _ := strconv.ParseUint(w.Header().Get(kHeaderRetryAfter), 10, 64)
+
+			w.Header().Set(kHeaderContentType, "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(exceededBody{
+				Error:      kRateLimitedError,
+				Message:    http.StatusText(http.StatusTooManyRequests),
+				Policy:     name,
+				RetryAfter: retryAfter,
+			})
 		}
 	}
 
 	// Precompute some re-used values
+	quotedName := strconv.Quote(name)
 	limitValue := strconv.FormatUint(uint64(limit), 10)
-	retryAfterValue := strconv.Itoa(int(tracker.WindowLength().Seconds()))
-	policyValue := limitValue + ";w=" + retryAfterValue
+	windowValue := strconv.FormatUint(ceilSeconds(tracker.WindowLength()), 10)
+	policyValue := quotedName + ";q=" + limitValue + ";w=" + windowValue
+	legacyPolicyValue := limitValue + ";w=" + windowValue
+
+	allowed := throttleDecisions.With(name, "allowed")
+	limited := throttleDecisions.With(name, "limited")
+	failed := throttleDecisions.With(name, "error")
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		rid, err := mapper(r)
 		if err != nil {
+			failed.Inc()
 			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 			return
 		}
 
 		now := time.Now().UTC()
-		window := tracker.WindowLength()
-		windowLeft := uint64(now.Sub(now.Truncate(window)).Seconds())
 
 		rate, err := tracker.Increment(rid, now)
 		if err != nil {
-			w.Header().Set(kHeaderRetryAfter, retryAfterValue)
+			failed.Inc()
+			w.Header().Set(kHeaderRetryAfter, windowValue)
 			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 			return
 		}
 
-		w.Header().Set(kHeaderLimit, limitValue)
-		w.Header().Set(kHeaderPolicy, policyValue)
-		w.Header().Set(kHeaderRemaining, strconv.FormatUint(uint64(limit-rate), 10))
-		w.Header().Set(kHeaderReset, strconv.FormatUint(windowLeft, 10))
+		var remaining uint64
+		if rate < limit {
+			remaining = uint64(limit - rate)
+		}
+
+		reset := ceilSeconds(tracker.Reset(rid, now))
+		remainingValue := strconv.FormatUint(remaining, 10)
+		resetValue := strconv.FormatUint(reset, 10)
+
+		w.Header().Set(kHeaderRateLimitPolicy, policyValue)
+		w.Header().Set(kHeaderRateLimit, quotedName+";r="+remainingValue+";t="+resetValue)
+
+		if t.LegacyHeaders {
+			w.Header().Set(kHeaderLimit, limitValue)
+			w.Header().Set(kHeaderPolicy, legacyPolicyValue)
+			w.Header().Set(kHeaderRemaining, remainingValue)
+			w.Header().Set(kHeaderReset, resetValue)
+		}
 
 		if limit < rate {
+			limited.Inc()
+			w.Header().Set(kHeaderRetryAfter, strconv.FormatUint(max(reset, 1), 10))
 			exceeded(w, r)
 			return
 		}
 
+		allowed.Inc()
 		next.ServeHTTP(w, r)
 	})
 }
+
+func ceilSeconds(d time.Duration) uint64 {
+	if d <= 0 {
+		return 0
+	}
+
+	return uint64((d + time.Second - 1) / time.Second)
+}
@@ -0,0 +1,90 @@
+// MIT License
+//
+// Copyright (c) 2024-present Robert Anderson
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package throttle
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"shiftylogic.dev/hockey-tools/internal/test"
+)
+
+func TestThrottlerHeaders(t *testing.T) {
+	throttler := &Throttler{
+		Name:          "login",
+		Tracker:       NewFixedWindowTracker(2, time.Hour),
+		LegacyHeaders: true,
+	}
+
+	handler := throttler.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	serve := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		return w
+	}
+
+	w := serve()
+	test.Expect(t, http.StatusNoContent, w.Code, "first request status")
+	test.Expect(t, `"login";q=2;w=3600`, w.Header().Get(kHeaderRateLimitPolicy), "policy header")
+	test.Expect(t, "1", w.Header().Get(kHeaderRemaining), "legacy remaining header")
+
+	reset, err := strconv.Atoi(w.Header().Get(kHeaderReset))
+	test.NoError(t, err, "legacy reset header")
+	test.Require(t, reset > 0 && reset <= 3600, "reset should be the time left in the window")
+	test.Expect(t, `"login";r=1;t=`+strconv.Itoa(reset), w.Header().Get(kHeaderRateLimit), "ratelimit header")
+
+	w = serve()
+	test.Expect(t, http.StatusNoContent, w.Code, "second request status")
+	test.Expect(t, "0", w.Header().Get(kHeaderRemaining), "remaining at limit")
+
+	w = serve()
+	test.Expect(t, http.StatusTooManyRequests, w.Code, "third request status")
+	test.Expect(t, "0", w.Header().Get(kHeaderRemaining), "remaining must not underflow")
+	test.Expect(t, w.Header().Get(kHeaderReset), w.Header().Get(kHeaderRetryAfter), "retry-after matches reset")
+	test.Expect(t, "application/json", w.Header().Get(kHeaderContentType), "429 content type")
+
+	retryAfter, err := strconv.ParseUint(w.Header().Get(kHeaderRetryAfter), 10, 64)
+	test.NoError(t, err, "retry-after header")
+
+	var body exceededBody
+	test.NoError(t, json.NewDecoder(w.Body).Decode(&body), "429 body should be JSON")
+	test.Expect(t, exceededBody{kRateLimitedError, "Too Many Requests", "login", retryAfter}, body, "429 body")
+}
+
+func TestThrottlerNoLegacyHeaders(t *testing.T) {
+	throttler := &Throttler{}
+	handler := throttler.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	test.Expect(t, "", w.Header().Get(kHeaderLimit), "legacy headers are opt-in")
+	test.Expect(t, `"default";q=100;w=60`, w.Header().Get(kHeaderRateLimitPolicy), "default policy header")
+}
@@ -0,0 +1,22 @@
+#!/usr/bin/env bash
+
+# Runs the ACME tests against a local Pebble test server (in docker), so no
+# internet access or real domain is needed. Challenges are always accepted.
+
+SCRIPT_DIR=$(dirname $0)
+ROOT_DIR=$SCRIPT_DIR/..
+PEBBLE_IMAGE="ghcr.io/letsencrypt/pebble:latest"
+PEBBLE_CA="$ROOT_DIR/.cert/pebble.minica.pem"
+
+mkdir -p $ROOT_DIR/.cert
+
+CONTAINER=$(docker run -d --rm -p 14000:14000 -e PEBBLE_VA_ALWAYS_VALID=1 $PEBBLE_IMAGE)
+trap "docker stop $CONTAINER > /dev/null" EXIT
+
+# Pebble's listener is signed by its bundled test CA
+sleep 2
+docker cp $CONTAINER:/test/certs/pebble.minica.pem $PEBBLE_CA
+
+ACME_TEST_DIRECTORY="https://localhost:14000/dir" \
+ACME_TEST_ROOT_CA="$PEBBLE_CA" \
+  go test -run ACME -v $ROOT_DIR/internal/web